@@ -2,21 +2,30 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"plugin"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/tavgar/JSMiner/internal/metrics"
+	"github.com/tavgar/JSMiner/internal/notify"
 	"github.com/tavgar/JSMiner/internal/output"
 	"github.com/tavgar/JSMiner/internal/proxy"
 	"github.com/tavgar/JSMiner/internal/scan"
@@ -91,10 +100,19 @@ func reorderFlagArgs(args []string, fs *flag.FlagSet) []string {
 }
 
 func main() {
-	format := flag.String("format", "pretty", "output format: pretty, json or jsonl (NDJSON streaming)")
+	format := flag.String("format", "pretty", "output format: pretty, json, jsonl (NDJSON streaming), html (self-contained report), or the CI reports junit, codequality and secret-detection")
 	safe := flag.Bool("safe", false, "safe mode - only scan JS")
 	allowFile := flag.String("allow", "", "allowlist file")
-	rulesFile := flag.String("rules", "", "extra regex rules YAML")
+	triageFile := flag.String("triage", "", "triage file of reviewed findings, keyed by fingerprint, to withhold from the output (accepted risks and false positives, with reason, reviewer and expiry)")
+	notifyFile := flag.String("notify", "", "notifier file: webhook, Slack and email targets alerted as matching findings are confirmed, filtered by severity, pattern and target")
+	enrich := flag.Bool("enrich", false, "attach asset metadata to each finding: its host's IP, TLS certificate subject and issuer, Server header and technology fingerprint (implied by -asn-db and -asset-tags)")
+	asnDBFile := flag.String("asn-db", "", "offline IP-to-ASN table (iptoasn.com TSV, optionally .gz) used to add each finding's AS number, organisation and country; implies -enrich")
+	libraries := flag.Bool("libraries", false, "fingerprint JavaScript libraries and their versions from script banners, file names, source maps and rendered-page globals, reporting each as a library finding (implied by -vuln-db)")
+	vulnDBFiles := flag.String("vuln-db", "", "comma-separated offline vulnerability databases: retire.js jsrepository.json files, OSV advisory files or directories of them; versions they list are reported as vulnerable_library findings with CVE ids; implies -libraries")
+	assetTagsFile := flag.String("asset-tags", "", "CSV mapping hosts (globs allowed) to tags such as team and owner, added to each finding and usable to route -notify alerts and -o outputs; implies -enrich")
+	noInlineIgnores := flag.Bool("no-inline-ignores", false, "report findings even where a jsminer:ignore comment in a local file or stdin silences them")
+	rulesFile := flag.String("rules", "", "comma-separated extra rule files or directories: versioned rule packs (YAML or JSON), gitleaks configs (.toml), nuclei templates, trufflehog custom detectors, or flat name: regex YAML maps")
+	testRules := flag.Bool("test-rules", false, "run the embedded self-tests of the -rules files (and any rule files given as arguments), then exit; non-zero status on failure")
 	endpoints := flag.Bool("endpoints", false, "only return HTTP endpoints")
 	posts := flag.Bool("posts", false, "only return HTTP POST request endpoints")
 	external := flag.Bool("external", true, "follow external scripts and imports")
@@ -125,15 +143,22 @@ func main() {
 	templateSampleMax := flag.Int("template-sample-max", 3, "max representative pages to crawl per templated class when template dedup is on")
 	noSourceMaps := flag.Bool("no-source-maps", false, "disable recovering original source from JavaScript source maps advertised by scanned bundles")
 	targetsFile := flag.String("targets", "", "file with list of targets")
-	pluginsFlag := flag.String("plugins", "", "comma-separated plugin files")
+	warcFile := flag.String("warc", "", "record every HTTP request and response of the scan (fetches, redirects, source maps and rendered pages' traffic) to this WARC 1.1 file, gzip compressed when it ends in .gz")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on /metrics and a JSON view of running crawls on /status at this address (e.g. 127.0.0.1:9464) while the scan or proxy runs")
+	warcReplay := flag.String("warc-replay", "", "scan a WARC recorded with -warc instead of the network: URL targets (default: the recorded seeds) are answered from the archive only; rendered pages are scanned from their recorded DOM states; DOM scanning and passive sources are off")
+	pluginsFlag := flag.String("plugins", "", "comma-separated WebAssembly rule plugins (.wasm)")
+	pluginMemory := flag.Int("plugin-memory", int(scan.DefaultWASMLimits.MaxMemory>>20), "max linear memory in MiB for each WebAssembly plugin instance")
+	pluginTimeout := flag.Int("plugin-timeout", int(scan.DefaultWASMLimits.Timeout/time.Millisecond), "max time in milliseconds for each call into a WebAssembly plugin")
 	showSourceFlag := flag.Bool("show-source", false, "show source of each record (auto-enabled for multiple targets)")
 	snippet := flag.Bool("snippet", false, "show a JS-prettified, syntax-highlighted code snippet around each finding")
 	timeout := flag.Int("timeout", 8, "wait time in seconds for dynamic content to load when rendering pages (default: 8)")
 	httpTimeout := flag.Int("http-timeout", 10, "per-request timeout in seconds for HTTP fetches (page/script fetches, calibration and method probes, sitemaps)")
+	maxRuntime := flag.Duration("max-runtime", 0, "stop the scan after this long (e.g. 30m) and report the findings gathered so far, marked partial (0 = no limit)")
 	retries := flag.Int("retries", 2, "extra attempts for a bodyless HTTP fetch that fails with a transient transport error (0 = no retries)")
 	verbose1 := flag.Bool("v", false, "verbose: crawl narrative — matches per page, targets discovered, calibration/dedup skips")
 	verbose2 := flag.Bool("vv", false, "more verbose: also log every HTTP request/response and page render (implies -v)")
 	verbose3 := flag.Bool("vvv", false, "trace: also log per-target enqueue/skip, method probes, param replays and permutations (implies -vv)")
+	logFormat := flag.String("log-format", scan.LogFormatText, "format of the -v/-vv/-vvv diagnostics on stderr: text or json (one slog record per line, with trace IDs, parent page, method, status, bytes and duration)")
 	exploreStates := flag.Int("explore-states", 12, "when rendering, max additional application states to reach through interaction — client-side navigation and filled/submitted forms (0 = render each page once)")
 	rateLimit := flag.Float64("rate-limit", 0, "max HTTP requests per second per host (0 = no proactive limit; adaptive 429/503 backoff and rate-limit-header pre-emption are always on)")
 	rateLimitJitter := flag.Float64("rate-limit-jitter", 0, "randomise each inter-request gap by +/- this fraction (e.g. 0.2 = +/-20%) to avoid a lockstep cadence (0 = off)")
@@ -155,7 +180,7 @@ func main() {
 	domMaxParams := flag.Int("dom-max-params", 100, "max static/passive parameter and storage-key hints injected per DOM page")
 	domWorkers := flag.Int("dom-workers", 4, "DOM pages to scan in parallel (independent of -crawl-workers)")
 	domTimeout := flag.Int("dom-timeout", 25, "per-page DOM scan budget in seconds")
-	domSources := flag.String("dom-sources", "", "comma-separated source families to probe (default all): url_query,url_fragment,referrer,window_name,form_input,cookie,local_storage,session_storage,web_message")
+	domSources := flag.String("dom-sources", "", "comma-separated source families to probe (default all): url_query,url_fragment,referrer,window_name,form_input,cookie,local_storage,session_storage,web_message,websocket_message")
 	domSinks := flag.String("dom-sinks", "", "comma-separated sink families to hook (default all): innerHTML,outerHTML,insertAdjacentHTML,document.write,srcdoc,eval,Function,setTimeout,script.src,navigation,event-handler")
 	domMessages := flag.Bool("dom-messages", true, "analyse postMessage (listeners, messages, origin/source inspection, cross-origin leaks) as part of a DOM scan")
 	domAllowExternal := flag.Bool("dom-allow-external", false, "allow DOM navigation and probes to reach third-party origins and follow client-side redirects out of scope")
@@ -167,21 +192,112 @@ func main() {
 	// a URL target but not rendering, and reuses the DOM page/param/probe/worker
 	// bounds so the two param-driven scans are tuned together.
 	reflection := flag.Bool("reflection", false, "enable reflected-input scanning: replay gathered parameters (JS-mined, passive and on-page query names) and report server-side reflections in the HTTP response; needs a URL target, no rendering required")
+	minConfidence := flag.Int("min-confidence", 0, "drop candidate secrets whose confidence score (1-100) is below this; endpoints and other unscored findings are always kept (0 = keep all)")
+	redactMode := flag.String("redact", output.RedactNone, "mask secret values in every output: none, partial (first and last 4 characters; values under 16 characters fully masked) or hash (salted SHA-256); fingerprints are unaffected")
+	redactSalt := flag.String("redact-salt", os.Getenv("JSMINER_REDACT_SALT"), "salt for -redact hash, so digests of one secret match across runs (default $JSMINER_REDACT_SALT, else random per run)")
 	failOn := flag.String("fail-on", "", "exit non-zero when a finding at or above this severity is present: info|low|medium|high (empty = exit 1 on any finding)")
 
 	var headerFlags headerSlice
 	flag.Var(&headerFlags, "header", "HTTP header in 'Key: Value' format. May be repeated")
+	var outputFlags outputSlice
+	flag.Var(&outputFlags, "o", "output destination as format:path, path - for stdout (e.g. -o pretty:- -o jsonl:findings.jsonl -o html:report.html). May be repeated; every destination is written from the same scan. A trailing :tag=glob[,tag=glob] keeps only findings whose -asset-tags match (e.g. -o jsonl:payments.jsonl:team=payments). Replaces -format and -output")
 	if err := flag.CommandLine.Parse(reorderFlagArgs(os.Args[1:], flag.CommandLine)); err != nil {
 		log.Fatal(err)
 	}
 	leftover := flag.Args()
 	domModeExplicit := false
+	singleOutputExplicit := false
 	flag.CommandLine.Visit(func(f *flag.Flag) {
-		if f.Name == "dom-mode" {
+		switch f.Name {
+		case "dom-mode":
 			domModeExplicit = true
+		case "format", "output":
+			singleOutputExplicit = true
 		}
 	})
+	outputs := []outputSpec(outputFlags)
+	if len(outputs) == 0 {
+		path := *outFile
+		if path == "" {
+			path = "-"
+		}
+		outputs = []outputSpec{{format: *format, path: path}}
+	} else if singleOutputExplicit {
+		fmt.Fprintln(os.Stderr, "jsminer: -o cannot be combined with -format or -output")
+		os.Exit(2)
+	}
+	if err := validateOutputs(outputs); err != nil {
+		fmt.Fprintf(os.Stderr, "jsminer: %v\n", err)
+		os.Exit(2)
+	}
+	redactor, err := output.NewRedactor(*redactMode, *redactSalt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jsminer: -redact: %v\n", err)
+		os.Exit(2)
+	}
+	if err := scan.SetLogFormat(*logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "jsminer: -log-format: %v\n", err)
+		os.Exit(2)
+	}
+	var enrichCfg scan.EnrichConfig
+	if *asnDBFile != "" {
+		if enrichCfg.ASNDB, err = scan.LoadASNDB(*asnDBFile); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: -asn-db: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *assetTagsFile != "" {
+		if enrichCfg.Tags, err = scan.LoadAssetTags(*assetTagsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: -asset-tags: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	*enrich = *enrich || enrichCfg.ASNDB != nil || enrichCfg.Tags != nil
+	var libraryDB *scan.LibraryDB
+	if *libraries || *vulnDBFiles != "" {
+		libraryDB = scan.NewLibraryDB()
+		for _, file := range splitList(*vulnDBFiles) {
+			if err := libraryDB.LoadFile(file); err != nil {
+				fmt.Fprintf(os.Stderr, "jsminer: -vuln-db: %v\n", err)
+				os.Exit(2)
+			}
+		}
+	}
+	for _, spec := range outputs {
+		if len(spec.tags) > 0 && enrichCfg.Tags == nil {
+			fmt.Fprintf(os.Stderr, "jsminer: output %s:%s filters on tags; give the tags with -asset-tags\n", spec.format, spec.path)
+			os.Exit(2)
+		}
+	}
+	// A replayed scan must not reach the network, so everything that would —
+	// a browser, public web indexes — is off. Rendered pages are scanned from
+	// the DOM states the archive recorded instead.
+	var replay *scan.WARCArchive
+	if *warcReplay != "" {
+		switch {
+		case *warcFile != "":
+			fmt.Fprintln(os.Stderr, "jsminer: -warc cannot be combined with -warc-replay")
+			os.Exit(2)
+		case *proxyAddr != "":
+			fmt.Fprintln(os.Stderr, "jsminer: -warc-replay cannot be combined with -proxy")
+			os.Exit(2)
+		case *dom || *domConfirm:
+			fmt.Fprintln(os.Stderr, "jsminer: DOM scanning needs a live browser and cannot run from -warc-replay")
+			os.Exit(2)
+		}
+		if replay, err = scan.OpenWARC(*warcReplay); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: -warc-replay: %v\n", err)
+			os.Exit(2)
+		}
+		*crawlPassive = false
+	} else if *warcFile != "" && *proxyAddr != "" {
+		fmt.Fprintln(os.Stderr, "jsminer: -warc cannot be combined with -proxy")
+		os.Exit(2)
+	}
 	domEnabled, effectiveDOMMode := resolveDOMSettings(*dom, *domConfirm, *full, *domMode, domModeExplicit)
+	if replay != nil {
+		domEnabled = false
+	}
 	reflectionEnabled := *reflection || *full
 	// Both param-driven scans feed off the same hidden source-hint corpus, so the
 	// intelligence pass is enabled whenever either is on.
@@ -198,7 +314,6 @@ func main() {
 	if *chromePath == "" {
 		*chromePath = os.Getenv("JSMINER_CHROME")
 	}
-	scan.SetChromePath(*chromePath)
 	if *noDownloadBrowser {
 		scan.SetAutoDownloadBrowser(false)
 	}
@@ -216,6 +331,8 @@ func main() {
 			// Build a self-contained bundle: place chromium next to a copy of the
 			// binary in <dir>/chromium.
 			p, err = scan.ProvisionBundle(*browserDest)
+		} else if *chromePath != "" {
+			p = *chromePath
 		} else {
 			p = scan.ResolveBrowser()
 			if p == "" {
@@ -232,6 +349,11 @@ func main() {
 		}
 	}
 
+	if *testRules {
+		files := append(splitList(*rulesFile), leftover...)
+		os.Exit(runRuleTests(files, os.Stdout))
+	}
+
 	var targets []string
 	if *proxyAddr == "" && *targetsFile != "" {
 		f, err := os.Open(*targetsFile)
@@ -254,6 +376,9 @@ func main() {
 	if *proxyAddr == "" {
 		targets = append(targets, leftover...)
 	}
+	if replay != nil && len(targets) == 0 {
+		targets = replay.Targets()
+	}
 	if *proxyAddr == "" && len(targets) == 0 {
 		if !*quiet {
 			fmt.Fprintln(os.Stderr, output.Banner(version))
@@ -261,6 +386,10 @@ func main() {
 		fmt.Fprintln(os.Stderr, "usage: jsminer [URL|PATH|-] [flags]")
 		os.Exit(2)
 	}
+	if *minConfidence < 0 || *minConfidence > scan.MaxConfidence {
+		fmt.Fprintf(os.Stderr, "jsminer: invalid -min-confidence %d (want 0-%d)\n", *minConfidence, scan.MaxConfidence)
+		os.Exit(2)
+	}
 	if *proxyAddr == "" && (domEnabled || reflectionEnabled) {
 		hasURLTarget := false
 		for _, target := range targets {
@@ -281,18 +410,28 @@ func main() {
 		}
 	}
 
-	if *pluginsFlag != "" {
-		for _, pl := range strings.Split(*pluginsFlag, ",") {
-			pl = strings.TrimSpace(pl)
-			if pl == "" {
-				continue
-			}
-			if _, err := plugin.Open(pl); err != nil {
-				log.Fatal(err)
-			}
+	pluginLimits := scan.WASMLimits{
+		MaxMemory: uint64(*pluginMemory) << 20,
+		Timeout:   time.Duration(*pluginTimeout) * time.Millisecond,
+	}
+	if *pluginMemory <= 0 {
+		pluginLimits.MaxMemory = scan.DefaultWASMLimits.MaxMemory
+	}
+	if *pluginTimeout <= 0 {
+		pluginLimits.Timeout = scan.DefaultWASMLimits.Timeout
+	}
+	for _, pl := range splitList(*pluginsFlag) {
+		if strings.HasSuffix(pl, ".so") {
+			log.Fatalf("%s: Go plugins are no longer supported; rebuild the rule as a WebAssembly plugin (see README, Plugins)", pl)
+		}
+		r, err := scan.LoadWASMRule(pl, pluginLimits)
+		if err != nil {
+			log.Fatal(err)
 		}
+		scan.RegisterRule(r)
 	}
 
+	clientCfg := scan.DefaultClientConfig()
 	if len(headerFlags) > 0 {
 		h := http.Header{}
 		for _, hv := range headerFlags {
@@ -306,20 +445,49 @@ func main() {
 				h.Add(key, val)
 			}
 		}
-		scan.SetExtraHeaders(h)
+		clientCfg.Headers = h
 	}
 
 	// Set the render timeout if specified
 	if *timeout > 0 {
-		scan.SetRenderSleepDuration(*timeout)
+		clientCfg.RenderSleep = time.Duration(*timeout) * time.Second
+	}
+	clientCfg.MaxExploreStates = *exploreStates
+	clientCfg.SkipTLSVerification = *insecure
+	clientCfg.FollowRedirects = *redirect
+	clientCfg.Timeout = time.Duration(*httpTimeout) * time.Second
+	clientCfg.Retries = *retries
+	clientCfg.RateLimit = *rateLimit
+	clientCfg.RateLimitJitter = *rateLimitJitter
+	clientCfg.ChromePath = *chromePath
+	clientCfg.Replay = replay
+	clientCfg.RecordOrigins = *enrich
+	clientCfg.Libraries = libraryDB
+	if *metricsAddr != "" {
+		m, addr, err := serveMetrics(*metricsAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: -metrics-addr: %v\n", err)
+			os.Exit(2)
+		}
+		clientCfg.Metrics = m
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "jsminer: metrics on http://%s/metrics, status on http://%s/status\n", addr, addr)
+		}
+	}
+	var archive *scan.WARCWriter
+	if *warcFile != "" {
+		var seeds []string
+		for _, t := range targets {
+			if isURL(t) {
+				seeds = append(seeds, t)
+			}
+		}
+		if archive, err = scan.CreateWARC(*warcFile, scan.WARCInfo{Software: "JSMiner/" + version, Targets: seeds}); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: -warc: %v\n", err)
+			os.Exit(2)
+		}
+		clientCfg.Archive = archive
 	}
-	scan.SetMaxExploreStates(*exploreStates)
-	scan.SetSkipTLSVerification(*insecure)
-	scan.SetFollowRedirects(*redirect)
-	scan.SetHTTPTimeout(*httpTimeout)
-	scan.SetFetchRetries(*retries)
-	scan.SetRateLimit(*rateLimit)
-	scan.SetRateLimitJitter(*rateLimitJitter)
 
 	// -v/-vv/-vvv are cumulative: the highest one given wins, and each level
 	// implies the ones below it.
@@ -338,18 +506,25 @@ func main() {
 	// Provision the render browser up front (when rendering) so any first-run
 	// Chromium download happens with a visible notice before scanning begins,
 	// rather than silently stalling the first page render.
-	if *render && *proxyAddr == "" && len(targets) > 0 {
+	if *render && replay == nil && *proxyAddr == "" && len(targets) > 0 && *chromePath == "" {
 		scan.WarmBrowser()
 	}
 
 	extractor := scan.NewExtractor(*safe, *longSecret)
+	extractor.SetClient(scan.NewClient(clientCfg))
+	if *enrich {
+		// A replayed scan must not reach the network for DNS either.
+		enrichCfg.Resolve = replay == nil
+		extractor.SetEnricher(scan.NewEnricher(extractor.Client(), enrichCfg))
+	}
+	enricher := extractor.Enricher()
 	extractor.SetCollectDOMSourceHints(hintsEnabled)
 	extractor.SetSnippet(*snippet)
 	if *noSourceMaps {
 		extractor.SetRecoverSourceMaps(false)
 	}
-	if *rulesFile != "" {
-		if err := extractor.LoadRulesFile(*rulesFile); err != nil {
+	for _, rf := range splitList(*rulesFile) {
+		if err := extractor.LoadRulesFile(rf); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -359,42 +534,172 @@ func main() {
 		}
 	}
 
+	var triage *scan.Triage
+	if *triageFile != "" {
+		t, err := scan.LoadTriageFile(*triageFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		triage = t
+		if !*quiet {
+			for _, e := range triage.Expired() {
+				fmt.Fprintf(os.Stderr, "jsminer: triage of %s (%s) expired %s; reporting it again\n",
+					e.Fingerprint, e.Status, e.Expires.Format(time.DateOnly))
+			}
+		}
+	}
+	var notifier *notify.Dispatcher
+	if *notifyFile != "" {
+		cfg, err := notify.LoadConfig(*notifyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if notifier, err = notify.NewDispatcher(cfg); err != nil {
+			log.Fatal(err)
+		}
+		notifier.OnError = func(err error) { fmt.Fprintf(os.Stderr, "jsminer: notify: %v\n", err) }
+		notifier.Redact = redactor.Match
+	}
+	// suppressed counts what the triage file and jsminer:ignore comments
+	// withhold; it is reported in the output summary.
+	var suppressed scan.SuppressionSummary
+
 	if *proxyAddr != "" {
-		var out *os.File = os.Stdout
-		if *outFile != "" {
-			f, err := os.Create(*outFile)
-			if err != nil {
-				log.Fatal(err)
+		// Each proxied response is reported to every -o destination as it is
+		// scanned; there is no end-of-run report.
+		var dests []*outputDest
+		for _, spec := range outputs {
+			w := io.Writer(os.Stdout)
+			if spec.path != "-" {
+				f, err := os.Create(spec.path)
+				if err != nil {
+					log.Fatal(err)
+				}
+				defer f.Close()
+				w = f
 			}
-			defer f.Close()
-			out = f
+			d := &outputDest{outputSpec: spec, w: w, printer: output.NewPrinter(spec.format, !*quiet, true, *snippet, version)}
+			d.printer.SetRedactor(redactor)
+			dests = append(dests, d)
 		}
-		printer := output.NewPrinter(*format, !*quiet, true, *snippet, version)
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
-		if err := proxy.Run(ctx, *proxyAddr, extractor, printer, out, *endpoints); err != nil {
+		report := func(ms []scan.Match, startedAt time.Time) error {
+			if triage != nil {
+				ms = slices.DeleteFunc(slices.Clone(ms), triage.SuppressesMatch)
+			}
+			for _, d := range dests {
+				shown := ms
+				if len(d.tags) > 0 {
+					if shown = filterByAsset(ms, d.accepts, func(m scan.Match) *scan.Asset { return m.Asset }); len(shown) == 0 {
+						continue
+					}
+				}
+				if err := d.printer.PrintScan(d.w, shown, startedAt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := proxy.RunReporting(ctx, *proxyAddr, extractor, *endpoints, report); err != nil {
 			log.Fatal(err)
 		}
 		return
 	}
 
+	// One context bounds the whole run: an interrupt or -max-runtime stops every
+	// phase, and whatever was found up to that point is still reported. Once it
+	// has ended, the signal handler is released so a second interrupt kills the
+	// process outright.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *maxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxRuntime)
+		defer cancel()
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	showSource := *showSourceFlag || len(targets) > 1
 	scanStartedAt := time.Now().UTC()
+
+	// Every destination is opened before the scan, so a bad path fails fast
+	// rather than after an hour of crawling. jsonl destinations stream: each
+	// finding is written as soon as the scanner confirms it, and the summary
+	// follows once the whole run is done.
+	var dests []*outputDest
+	var streams []*outputDest
+	for _, spec := range outputs {
+		w := io.Writer(os.Stdout)
+		if spec.path != "-" {
+			f, err := os.Create(spec.path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		d := &outputDest{outputSpec: spec, w: w, printer: output.NewPrinter(spec.format, !*quiet, showSource, *snippet, version)}
+		d.printer.SetFailOn(*failOn)
+		d.printer.SetRedactor(redactor)
+		if spec.format == "jsonl" || spec.format == "ndjson" {
+			var err error
+			if d.stream, err = d.printer.StartJSONL(w, scanStartedAt); err != nil {
+				log.Fatal(err)
+			}
+			streams = append(streams, d)
+		}
+		dests = append(dests, d)
+	}
+	// streamMatches hands confirmed matches to the jsonl streams and the
+	// notifiers as the scan finds them; target is the scan target they came
+	// from, when known. It drops a value's lower-severity repeats the way the
+	// final result does, though it cannot withdraw a finding that a
+	// higher-severity one of the same value later supersedes.
+	streaming := len(streams) > 0 || notifier != nil
+	streamDedup := scan.NewStreamDedup()
+	streamMatches := func(target string, ms []scan.Match) {
+		for _, m := range enricher.Matches(ctx, ms) {
+			if triage.SuppressesMatch(m) || !streamDedup.Keep(m) {
+				continue
+			}
+			for _, d := range streams {
+				if d.accepts(m.Asset) {
+					d.stream.Match(m)
+				}
+			}
+			notifier.Notify(notify.MatchEvent(target, m))
+		}
+	}
+
 	var allMatches []scan.Match
+	counter := &matchCounter{m: clientCfg.Metrics, seen: make(map[string]bool)}
 	var domTargets []string
 	var domSourceHints []scan.DOMSourceHint
+	var crawls []output.CrawlRun
 	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
 		var ms []scan.Match
 		var err error
+		// local keeps the text of a local file or stdin as it is scanned, so
+		// the jsminer:ignore comments in it can be honoured afterwards.
+		var local *bytes.Buffer
 
 		if target == "-" {
-			reader := bufio.NewReader(os.Stdin)
+			local = new(bytes.Buffer)
+			reader := io.TeeReader(bufio.NewReader(os.Stdin), local)
 			if *posts {
-				ms, err = extractor.ScanReaderPostRequests("stdin", reader)
+				ms, err = extractor.ScanReaderPostRequests(ctx, "stdin", reader)
 				if err != nil {
 					err = fmt.Errorf("failed to scan POST requests from stdin: %w", err)
 				}
 			} else {
-				ms, err = extractor.ScanReaderWithEndpoints("stdin", reader)
+				ms, err = extractor.ScanReaderWithEndpoints(ctx, "stdin", reader)
 				if err != nil {
 					err = fmt.Errorf("failed to scan endpoints from stdin: %w", err)
 				}
@@ -427,10 +732,15 @@ func main() {
 				if *noWellKnown {
 					opts.DiscoverWellKnown = false
 				}
-				opts.DiscoverPassive = *crawlPassive || *full
+				opts.DiscoverPassive = (*crawlPassive || *full) && replay == nil
 				if hintsEnabled {
 					opts.OnDOMSourceHints = extractor.AddDOMSourceHints
 				}
+				if streaming {
+					opts.OnMatch = func(m scan.Match) {
+						streamMatches(target, filterOutputMatches([]scan.Match{m}, *posts, *endpoints, *minConfidence))
+					}
+				}
 				opts.PassiveMax = *crawlPassiveMax
 				if *crawlPassiveSources != "" {
 					opts.PassiveSources = strings.Split(*crawlPassiveSources, ",")
@@ -458,10 +768,12 @@ func main() {
 						fmt.Fprintf(os.Stderr, "[crawl] auto-calibration learned %d wildcard signature(s)\n", n)
 					}
 				}
-				// A one-line end-of-run summary is useful at every verbosity, so it is
-				// gated only on the banner being enabled, not on verbose logging.
-				if !*quiet {
-					opts.OnComplete = func(s scan.CrawlStats) {
+				// The stats are kept for the html report. A one-line end-of-run
+				// summary is useful at every verbosity, so it is gated only on the
+				// banner being enabled, not on verbose logging.
+				opts.OnComplete = func(s scan.CrawlStats) {
+					crawls = append(crawls, output.CrawlRun{Target: target, Stats: s})
+					if !*quiet {
 						fmt.Fprintf(os.Stderr,
 							"[crawl] done: %d page(s) fetched, %d error(s), %d target(s) discovered, %d enqueued, %d match(es)",
 							s.PagesFetched, s.PagesErrored, s.TargetsFound, s.Enqueued, s.Matches)
@@ -480,23 +792,23 @@ func main() {
 					}
 				}
 				if *posts {
-					ms, err = extractor.ScanURLPostsCrawl(target, *external, scanRender, opts)
+					ms, err = extractor.ScanURLPostsCrawl(ctx, target, *external, scanRender, opts)
 					if err != nil {
 						err = fmt.Errorf("failed to crawl POST requests from URL %s: %w", target, err)
 					}
 				} else {
-					ms, err = extractor.ScanURLCrawl(target, *endpoints, *external, scanRender, opts)
+					ms, err = extractor.ScanURLCrawl(ctx, target, *endpoints, *external, scanRender, opts)
 					if err != nil {
 						err = fmt.Errorf("failed to crawl endpoints from URL %s: %w", target, err)
 					}
 				}
 			} else if *posts {
-				ms, err = extractor.ScanURLPosts(target, *external, scanRender)
+				ms, err = extractor.ScanURLPosts(ctx, target, *external, scanRender)
 				if err != nil {
 					err = fmt.Errorf("failed to scan POST requests from URL %s: %w", target, err)
 				}
 			} else {
-				ms, err = extractor.ScanURL(target, *endpoints, *external, scanRender)
+				ms, err = extractor.ScanURL(ctx, target, *endpoints, *external, scanRender)
 				if err != nil {
 					err = fmt.Errorf("failed to scan endpoints from URL %s: %w", target, err)
 				}
@@ -507,14 +819,15 @@ func main() {
 				log.Printf("Error: failed to open file %s: %v", target, err2)
 				continue
 			}
-			reader := bufio.NewReader(f)
+			local = new(bytes.Buffer)
+			reader := io.TeeReader(bufio.NewReader(f), local)
 			if *posts {
-				ms, err = extractor.ScanReaderPostRequests(filepath.Base(target), reader)
+				ms, err = extractor.ScanReaderPostRequests(ctx, filepath.Base(target), reader)
 				if err != nil {
 					err = fmt.Errorf("failed to scan POST requests from file %s: %w", target, err)
 				}
 			} else {
-				ms, err = extractor.ScanReaderWithEndpoints(filepath.Base(target), reader)
+				ms, err = extractor.ScanReaderWithEndpoints(ctx, filepath.Base(target), reader)
 				if err != nil {
 					err = fmt.Errorf("failed to scan endpoints from file %s: %w", target, err)
 				}
@@ -522,6 +835,13 @@ func main() {
 			f.Close()
 		}
 
+		if local != nil && !*noInlineIgnores {
+			var n int
+			ms, n = scan.ApplyInlineIgnores(local.Bytes(), ms)
+			suppressed.Inline += n
+		}
+		counter.observe(ms, isURL(target) && (*crawl || *full))
+
 		// Feed the DOM/reflection phases before output filters discard endpoint
 		// classes. Static JS access names, request-body fields and passive archive
 		// query names are scoped to this seed; discovered document-like routes become
@@ -540,22 +860,18 @@ func main() {
 				scan.DOMSeedURLsFromMatches(target, ms, *domAllowExternal, *domMaxPages)...)
 		}
 
-		if *posts {
-			// A posts crawl harvests HTML markup links (as endpoint_url matches) to
-			// follow the link graph; keep only POST endpoints and gathered URLs in the
-			// output so those navigation-only links do not leak in.
-			ms = scan.FilterPostMatches(ms)
-		} else if *endpoints {
-			// FilterEndpointMatches keeps only endpoint_* patterns; preserve the
-			// crawl's gathered-URL findings, which are endpoint discoveries too.
-			gathered := scan.FilterGatheredMatches(ms)
-			ms = append(scan.FilterEndpointMatches(ms), gathered...)
-		}
+		ms = filterOutputMatches(ms, *posts, *endpoints, *minConfidence)
 
+		// A scan cut short by the run context still returns what it found; keep
+		// those matches rather than treating the stop as a failure.
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			err = nil
+		}
 		if err != nil {
 			log.Printf("Error processing %s: %v", target, err)
 			continue
 		}
+		streamMatches(target, ms)
 		if len(ms) > 0 {
 			allMatches = append(allMatches, ms...)
 		}
@@ -575,7 +891,7 @@ func main() {
 	// generic match model so their richer evidence is preserved.
 	var domResult scan.DOMScanResult
 	ranDOM := false
-	if domEnabled {
+	if domEnabled && ctx.Err() == nil {
 		urlTargets := uniqueStrings(domTargets)
 		switch {
 		case !*render:
@@ -600,25 +916,35 @@ func main() {
 		if !*quiet {
 			cfg.Progress = func(msg string) { fmt.Fprintln(os.Stderr, "jsminer: "+msg) }
 		}
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		if streaming {
+			cfg.OnFinding = func(f scan.DOMFinding) {
+				if triage.SuppressesDOMFinding(f) {
+					return
+				}
+				f = enricher.DOMFindings(ctx, []scan.DOMFinding{f})[0]
+				for _, d := range streams {
+					if d.accepts(f.Asset) {
+						d.stream.DOMFinding(f)
+					}
+				}
+				notifier.Notify(notify.DOMEvent(f))
+			}
+		}
 		domResult, err = extractor.ScanDOM(ctx, urlTargets, cfg)
-		stop()
-		if err != nil {
+		if err != nil && ctx.Err() == nil {
 			fmt.Fprintf(os.Stderr, "jsminer: DOM scan failed: %v\n", err)
 			os.Exit(2)
 		}
-		ranDOM = true
+		// A browser that could not start because the run was already stopping
+		// leaves nothing to report; the scan is simply partial.
+		ranDOM = err == nil
 
 		// The instrumented DOM navigation is also the rendered-discovery pass.
 		// Merge its ordinary findings only after DOM scanning so the initial static
 		// crawl never launches a second browser for the same page.
-		renderedMatches := domResult.Matches
-		if *posts {
-			renderedMatches = scan.FilterPostMatches(renderedMatches)
-		} else if *endpoints {
-			gathered := scan.FilterGatheredMatches(renderedMatches)
-			renderedMatches = append(scan.FilterEndpointMatches(renderedMatches), gathered...)
-		}
+		counter.observe(domResult.Matches, false)
+		renderedMatches := filterOutputMatches(domResult.Matches, *posts, *endpoints, *minConfidence)
+		streamMatches("", renderedMatches)
 		allMatches = scan.UniqueMatches(append(allMatches, renderedMatches...))
 
 		// Dynamic scripts scanned from the shared render can contribute additional
@@ -641,7 +967,7 @@ func main() {
 	// the same knobs.
 	var reflectionResult scan.ReflectionScanResult
 	ranReflection := false
-	if reflectionEnabled {
+	if reflectionEnabled && ctx.Err() == nil {
 		urlTargets := uniqueStrings(domTargets)
 		if len(urlTargets) == 0 {
 			fmt.Fprintln(os.Stderr, "jsminer: -reflection needs at least one URL target")
@@ -657,9 +983,21 @@ func main() {
 		if !*quiet {
 			cfg.Progress = func(msg string) { fmt.Fprintln(os.Stderr, "jsminer: "+msg) }
 		}
-		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		if streaming {
+			cfg.OnFinding = func(f scan.ReflectionFinding) {
+				if triage.SuppressesReflectionFinding(f) {
+					return
+				}
+				f = enricher.ReflectionFindings(ctx, []scan.ReflectionFinding{f})[0]
+				for _, d := range streams {
+					if d.accepts(f.Asset) {
+						d.stream.ReflectionFinding(f)
+					}
+				}
+				notifier.Notify(notify.ReflectionEvent(f))
+			}
+		}
 		res, err := extractor.ScanReflections(ctx, urlTargets, cfg)
-		stop()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "jsminer: reflection scan failed: %v\n", err)
 			os.Exit(2)
@@ -674,41 +1012,291 @@ func main() {
 	// secret is never buried under lower-signal echoes of the same string.
 	allMatches = scan.DedupMatchesByValueKeepSeverity(allMatches)
 
-	var out *os.File = os.Stdout
-	if *outFile != "" {
-		f, err := os.Create(*outFile)
-		if err != nil {
-			log.Fatal(err)
+	// Withhold what the team has already reviewed. Triaged findings neither
+	// print nor count towards -fail-on.
+	allMatches, domResult.Findings, reflectionResult.Findings =
+		triage.Apply(allMatches, domResult.Findings, reflectionResult.Findings, &suppressed)
+	// Enrichment runs last, once every origin the scan reached has been seen.
+	allMatches = enricher.Matches(ctx, allMatches)
+	domResult.Findings = enricher.DOMFindings(ctx, domResult.Findings)
+	reflectionResult.Findings = enricher.ReflectionFindings(ctx, reflectionResult.Findings)
+	var suppressedSummary *scan.SuppressionSummary
+	if triage != nil || suppressed.Inline > 0 {
+		suppressedSummary = &suppressed
+	}
+
+	partial := ctx.Err() != nil
+	if partial && !*quiet {
+		reason := "interrupted"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reason = fmt.Sprintf("-max-runtime %s reached", *maxRuntime)
 		}
-		defer f.Close()
-		out = f
+		fmt.Fprintf(os.Stderr, "jsminer: scan stopped early (%s); results are partial\n", reason)
 	}
 
-	showSource := *showSourceFlag || len(targets) > 1
-	printer := output.NewPrinter(*format, !*quiet, showSource, *snippet, version)
+	report := output.Report{
+		Matches: allMatches, DOM: domResult.Findings, ScanTime: scanStartedAt,
+		Partial: partial, Suppressed: suppressedSummary, Crawls: crawls,
+	}
+	if ranDOM {
+		summary := domResult.Summary
+		report.DOMSummary = &summary
+	}
+	if ranReflection {
+		report.Reflections = reflectionResult.Findings
+		rsum := reflectionResult.Summary
+		report.ReflectionSummary = &rsum
+	}
+	// A destination that cannot be written does not cost the others the
+	// results of the run.
+	useReport := ranDOM || ranReflection || suppressedSummary != nil
+	writeFailed := false
+	for _, d := range dests {
+		if err := d.write(report, useReport); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: writing %s output to %s: %v\n", d.format, d.path, err)
+			writeFailed = true
+		}
+	}
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "jsminer: writing WARC archive %s: %v\n", *warcFile, err)
+			writeFailed = true
+		} else if !*quiet {
+			fmt.Fprintf(os.Stderr, "jsminer: archived %d exchange(s) to %s\n", archive.Records(), *warcFile)
+		}
+	}
 
-	useReport := ranDOM || ranReflection || *format == "jsonl" || *format == "ndjson"
-	if useReport {
-		report := output.Report{Matches: allMatches, DOM: domResult.Findings, ScanTime: scanStartedAt}
-		if ranDOM {
-			summary := domResult.Summary
-			report.DOMSummary = &summary
+	// Alerts for anything the streaming path did not see (each finding alerts
+	// once), then a bounded wait for the queued deliveries.
+	if notifier != nil {
+		for _, m := range allMatches {
+			notifier.Notify(notify.MatchEvent("", m))
 		}
-		if ranReflection {
-			report.Reflections = reflectionResult.Findings
-			rsum := reflectionResult.Summary
-			report.ReflectionSummary = &rsum
+		for _, f := range domResult.Findings {
+			notifier.Notify(notify.DOMEvent(f))
 		}
-		if err := printer.PrintReport(out, report); err != nil {
-			log.Fatal(err)
+		for _, f := range reflectionResult.Findings {
+			notifier.Notify(notify.ReflectionEvent(f))
+		}
+		flushCtx, cancel := context.WithTimeout(context.Background(), notifyFlushTimeout)
+		stats := notifier.Close(flushCtx)
+		cancel()
+		if stats.Failed > 0 || stats.Dropped > 0 {
+			fmt.Fprintf(os.Stderr, "jsminer: notify: %d alert(s) sent, %d failed, %d dropped\n", stats.Sent, stats.Failed, stats.Dropped)
+		} else if !*quiet && stats.Sent > 0 {
+			fmt.Fprintf(os.Stderr, "jsminer: notify: %d alert(s) sent\n", stats.Sent)
 		}
-	} else if err := printer.PrintScan(out, allMatches, scanStartedAt); err != nil {
-		log.Fatal(err)
+	}
+
+	if writeFailed {
+		os.Exit(2)
 	}
 
 	os.Exit(exitCode(*failOn, allMatches, domResult.Findings, reflectionResult.Findings))
 }
 
+// serveMetrics starts the -metrics-addr server in the background and returns
+// the Metrics it reports and the address it listens on. It runs until the
+// process exits.
+func serveMetrics(addr string) (*scan.Metrics, net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	reg := metrics.NewRegistry()
+	m := scan.NewMetrics(reg)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(m.Status())
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return m, ln.Addr(), nil
+}
+
+// matchCounter feeds jsminer_matches_total from the scans that do not count
+// their matches as they go: single URL scans, local files, stdin and rendered
+// DOM pages. Crawls count theirs live, so their matches are only marked seen.
+// Each distinct match counts once per run.
+type matchCounter struct {
+	m    *scan.Metrics
+	seen map[string]bool
+}
+
+// observe counts the matches not seen before; counted marks matches a crawl
+// has already counted.
+func (c *matchCounter) observe(ms []scan.Match, counted bool) {
+	if c.m == nil {
+		return
+	}
+	for _, m := range ms {
+		key := m.Pattern + "|" + m.Value + "|" + m.Params
+		if c.seen[key] {
+			continue
+		}
+		c.seen[key] = true
+		if !counted {
+			c.m.ObserveMatches(m)
+		}
+	}
+}
+
+// notifyFlushTimeout bounds how long a finished scan waits for queued alerts
+// to be delivered before exiting.
+const notifyFlushTimeout = 30 * time.Second
+
+// knownFormats are the values -format and the format half of -o accept.
+var knownFormats = []string{
+	"pretty", "json", "jsonl", "ndjson", "html",
+	output.FormatJUnit, output.FormatCodeQuality, output.FormatSecretDetection,
+}
+
+// outputSpec is one output destination: a format and the file it is written
+// to, "-" meaning stdout. tags, when set, keeps only the findings whose asset
+// tags match its globs.
+type outputSpec struct {
+	format string
+	path   string
+	tags   map[string]string
+}
+
+// outputSlice collects repeated -o format:path[:tag=glob,...] flags. A bare
+// format writes to stdout.
+type outputSlice []outputSpec
+
+func (o *outputSlice) String() string {
+	parts := make([]string, len(*o))
+	for i, spec := range *o {
+		parts[i] = spec.format + ":" + spec.path
+		if len(spec.tags) > 0 {
+			filter := make([]string, 0, len(spec.tags))
+			for name, glob := range spec.tags {
+				filter = append(filter, name+"="+glob)
+			}
+			sort.Strings(filter)
+			parts[i] += ":" + strings.Join(filter, ",")
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// outputTagFilterRe recognises a trailing tag filter on an -o value, which a
+// file name (even a Windows one) does not look like.
+var outputTagFilterRe = regexp.MustCompile(`^[A-Za-z0-9_-]+=[^,]*(,[A-Za-z0-9_-]+=[^,]*)*$`)
+
+func (o *outputSlice) Set(v string) error {
+	format, path, ok := strings.Cut(v, ":")
+	if !ok {
+		path = "-"
+	}
+	var tags map[string]string
+	if i := strings.LastIndex(path, ":"); i >= 0 && outputTagFilterRe.MatchString(path[i+1:]) {
+		tags = make(map[string]string)
+		for _, kv := range strings.Split(path[i+1:], ",") {
+			name, glob, _ := strings.Cut(kv, "=")
+			if _, err := filepath.Match(glob, ""); err != nil || glob == "" {
+				return fmt.Errorf("output %q: bad tag glob %q", v, glob)
+			}
+			tags[strings.ToLower(name)] = glob
+		}
+		path = path[:i]
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if !slices.Contains(knownFormats, format) {
+		return fmt.Errorf("unknown output format %q (want %s)", format, strings.Join(knownFormats, ", "))
+	}
+	if path == "" {
+		return fmt.Errorf("output %q has an empty path; use - for stdout", v)
+	}
+	*o = append(*o, outputSpec{format: format, path: path, tags: tags})
+	return nil
+}
+
+// validateOutputs rejects destinations that would clobber each other: more
+// than one on stdout, or two writing the same file.
+func validateOutputs(specs []outputSpec) error {
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		key := spec.path
+		if key != "-" {
+			key = filepath.Clean(key)
+		}
+		if seen[key] {
+			if key == "-" {
+				return fmt.Errorf("more than one output writes to stdout")
+			}
+			return fmt.Errorf("more than one output writes to %s", spec.path)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// outputDest is an opened output destination. A jsonl destination has its
+// stream started before the scan; the others are rendered once it ends.
+type outputDest struct {
+	outputSpec
+	w       io.Writer
+	printer *output.Printer
+	stream  *output.JSONLStream
+}
+
+// accepts reports whether a finding on asset belongs in this destination.
+func (d *outputDest) accepts(asset *scan.Asset) bool {
+	return asset.MatchesTags(d.tags)
+}
+
+// write finishes the destination with the scan's report. useReport selects the
+// full report renderer even for the formats whose Match-only output is kept
+// byte-for-byte unchanged when there is nothing beyond matches to show.
+func (d *outputDest) write(r output.Report, useReport bool) error {
+	if len(d.tags) > 0 {
+		r.Matches = filterByAsset(r.Matches, d.accepts, func(m scan.Match) *scan.Asset { return m.Asset })
+		r.DOM = filterByAsset(r.DOM, d.accepts, func(f scan.DOMFinding) *scan.Asset { return f.Asset })
+		r.Reflections = filterByAsset(r.Reflections, d.accepts, func(f scan.ReflectionFinding) *scan.Asset { return f.Asset })
+	}
+	switch {
+	case d.stream != nil:
+		return d.stream.Close(r)
+	case useReport || (d.format != "pretty" && d.format != "json"):
+		return d.printer.PrintReport(d.w, r)
+	default:
+		return d.printer.PrintScan(d.w, r.Matches, r.ScanTime)
+	}
+}
+
+// filterByAsset keeps the findings whose asset accept selects.
+func filterByAsset[T any](fs []T, accept func(*scan.Asset) bool, asset func(T) *scan.Asset) []T {
+	var out []T
+	for _, f := range fs {
+		if accept(asset(f)) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// filterOutputMatches applies the output filters for -posts, -endpoints and
+// -min-confidence. A posts scan harvests HTML markup links (as endpoint_url
+// matches) to follow the link graph; only POST endpoints and gathered URLs are
+// kept so those navigation-only links do not leak in. FilterEndpointMatches
+// keeps only endpoint_* patterns, so the gathered-URL findings, which are
+// endpoint discoveries too, are preserved alongside them.
+func filterOutputMatches(ms []scan.Match, posts, endpoints bool, minConfidence int) []scan.Match {
+	if posts {
+		return scan.FilterPostMatches(ms)
+	}
+	if endpoints {
+		gathered := scan.FilterGatheredMatches(ms)
+		return append(scan.FilterEndpointMatches(ms), gathered...)
+	}
+	return scan.FilterMinConfidence(ms, minConfidence)
+}
+
 // exitCode maps the findings to the process exit status. With no threshold it
 // preserves the historical behaviour (exit 1 on any finding, 0 otherwise). With
 // a threshold it exits 1 only when a finding at or above it is present. A
@@ -740,6 +1328,53 @@ func exitCode(failOn string, matches []scan.Match, dom []scan.DOMFinding, reflec
 	return 0
 }
 
+// runRuleTests runs the embedded self-tests of each rule file, reporting one
+// line per rule, and returns the process exit status: 0 when every test
+// passes, 1 when any fails, 2 when a file cannot be loaded or none was given.
+func runRuleTests(files []string, w io.Writer) int {
+	if len(files) == 0 {
+		fmt.Fprintln(w, "usage: jsminer -test-rules -rules FILE [FILE...]")
+		return 2
+	}
+	rules, tests, failed := 0, 0, 0
+	for _, file := range files {
+		results, err := scan.RunRuleTests(file)
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
+			return 2
+		}
+		for _, r := range results {
+			rules++
+			tests += r.Tests
+			if len(r.Failures) == 0 {
+				fmt.Fprintf(w, "ok    %s (%d tests)\n", r.Rule, r.Tests)
+				continue
+			}
+			failed++
+			fmt.Fprintf(w, "FAIL  %s\n", r.Rule)
+			for _, f := range r.Failures {
+				fmt.Fprintf(w, "      %s\n", f)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d rule(s), %d test(s), %d failing rule(s)\n", rules, tests, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 // buildDOMConfig assembles a DOMScanConfig from the CLI flags, validating the
 // mode and the source/sink family lists. It reuses the crawl toggle and depth so
 // a DOM scan follows the same in-scope link graph the rest of the scanner does.
@@ -0,0 +1,81 @@
+//! Example jsminer rule plugin: flags base64-like runs of 20 or more
+//! characters whose Shannon entropy is at least 4.5 bits per byte.
+//!
+//! Build with `cargo build --target wasm32-unknown-unknown --release` and load
+//! the resulting `entropy.wasm` with `jsminer -plugins`.
+
+use std::mem;
+
+const NAME: &str = "entropy";
+const SEVERITY: &str = "high";
+const MIN_LEN: usize = 20;
+const MIN_ENTROPY: f64 = 4.5;
+
+/// Packs a pointer and length into the i64 the host expects.
+fn pack(ptr: *const u8, len: usize) -> u64 {
+    ((ptr as u64) << 32) | len as u64
+}
+
+/// Reserves memory for the host to write into. The host resets the instance
+/// after every scan, so nothing is ever freed.
+#[no_mangle]
+pub extern "C" fn alloc(size: u32) -> *mut u8 {
+    let mut buf = Vec::<u8>::with_capacity(size as usize);
+    let ptr = buf.as_mut_ptr();
+    mem::forget(buf);
+    ptr
+}
+
+#[no_mangle]
+pub extern "C" fn name() -> u64 {
+    pack(NAME.as_ptr(), NAME.len())
+}
+
+#[no_mangle]
+pub extern "C" fn severity() -> u64 {
+    pack(SEVERITY.as_ptr(), SEVERITY.len())
+}
+
+fn is_candidate(b: u8) -> bool {
+    b.is_ascii_alphanumeric() || b == b'+' || b == b'/' || b == b'='
+}
+
+fn entropy(s: &[u8]) -> f64 {
+    let mut freq = [0u32; 256];
+    for &b in s {
+        freq[b as usize] += 1;
+    }
+    let n = s.len() as f64;
+    freq.iter()
+        .filter(|&&c| c > 0)
+        .map(|&c| {
+            let p = c as f64 / n;
+            -p * p.log2()
+        })
+        .sum()
+}
+
+/// Scans `len` bytes at `ptr` and returns little-endian u32 start/end pairs.
+#[no_mangle]
+pub extern "C" fn find(ptr: *const u8, len: u32) -> u64 {
+    let data = unsafe { std::slice::from_raw_parts(ptr, len as usize) };
+    let mut out: Vec<u8> = Vec::new();
+    let mut i = 0;
+    while i < data.len() {
+        if !is_candidate(data[i]) {
+            i += 1;
+            continue;
+        }
+        let start = i;
+        while i < data.len() && is_candidate(data[i]) {
+            i += 1;
+        }
+        if i - start >= MIN_LEN && entropy(&data[start..i]) >= MIN_ENTROPY {
+            out.extend_from_slice(&(start as u32).to_le_bytes());
+            out.extend_from_slice(&(i as u32).to_le_bytes());
+        }
+    }
+    let packed = pack(out.as_ptr(), out.len());
+    mem::forget(out);
+    packed
+}
@@ -6,6 +6,7 @@ require (
 	github.com/chromedp/cdproto v0.0.0-20250611220608-a17eb1ae8ff0
 	github.com/chromedp/chromedp v0.13.6
 	github.com/elazarl/goproxy v1.7.2
+	github.com/tetratelabs/wazero v1.9.0
 )
 
 require (
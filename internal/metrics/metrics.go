@@ -0,0 +1,363 @@
+// Package metrics is a small Prometheus-compatible metrics registry: counters,
+// gauges and histograms, optionally labelled, written in the Prometheus text
+// exposition format. It covers what JSMiner's -metrics-addr endpoint needs
+// without a client-library dependency.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultDurationBuckets are histogram bounds, in seconds, suited to request
+// and render durations.
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds a set of metrics and writes them out. It is safe for
+// concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func (r *Registry) add(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric in the Prometheus text format, in
+// registration order.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	ms := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, m := range ms {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+// family is the state every metric type shares: its name, help, type and
+// label names, and its series keyed by their joined label values.
+type family[T any] struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.Mutex
+	series map[string]*T
+	values map[string][]string
+	newT   func() *T
+}
+
+func newFamily[T any](name, help, kind string, labels []string, newT func() *T) *family[T] {
+	return &family[T]{
+		name: name, help: help, kind: kind, labels: labels,
+		series: make(map[string]*T), values: make(map[string][]string), newT: newT,
+	}
+}
+
+func (f *family[T]) with(values []string) *T {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label value(s), got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := f.series[key]
+	if s == nil {
+		s = f.newT()
+		f.series[key] = s
+		f.values[key] = append([]string(nil), values...)
+	}
+	return s
+}
+
+// each calls fn for every series in label-value order.
+func (f *family[T]) each(fn func(labels string, s *T)) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	series := make([]*T, len(keys))
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		series[i] = f.series[k]
+		labels[i] = formatLabels(f.labels, f.values[k])
+	}
+	f.mu.Unlock()
+	for i := range keys {
+		fn(labels[i], series[i])
+	}
+}
+
+func (f *family[T]) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.v += v
+	c.mu.Unlock()
+}
+
+// Value returns the current count.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+// CounterVec is a family of counters told apart by label values.
+type CounterVec struct{ f *family[Counter] }
+
+// NewCounter registers a counter family. With no label names it has a single
+// series, reached with With().
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newFamily(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.add(name, v)
+	return v
+}
+
+// With returns the counter for the given label values, in label-name order.
+func (v *CounterVec) With(values ...string) *Counter { return v.f.with(values) }
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.f.header(w)
+	v.f.each(func(labels string, c *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", v.f.name, labels, formatFloat(c.Value()))
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.v = v
+	g.mu.Unlock()
+}
+
+// Add adds v, which may be negative.
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.v += v
+	g.mu.Unlock()
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// GaugeVec is a family of gauges told apart by label values.
+type GaugeVec struct{ f *family[Gauge] }
+
+// NewGauge registers a gauge family.
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newFamily(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.add(name, v)
+	return v
+}
+
+// With returns the gauge for the given label values.
+func (v *GaugeVec) With(values ...string) *Gauge { return v.f.with(values) }
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.f.header(w)
+	v.f.each(func(labels string, g *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", v.f.name, labels, formatFloat(g.Value()))
+	})
+}
+
+// Sample is one series of a collected gauge: its label values and value.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type gaugeFunc struct {
+	name, help string
+	labels     []string
+	collect    func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose series are computed by collect each
+// time the metrics are written, for values that already live elsewhere (a
+// queue's length, a throttle's current gap).
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func() []Sample) {
+	r.add(name, &gaugeFunc{name: name, help: help, labels: labels, collect: collect})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, escapeHelp(g.help), g.name)
+	samples := g.collect()
+	sort.SliceStable(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+	for _, s := range samples {
+		if len(s.Labels) != len(g.labels) {
+			continue
+		}
+		fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels(g.labels, s.Labels), formatFloat(s.Value))
+	}
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	counts  []uint64
+	sum     float64
+	samples uint64
+}
+
+// Observe records one observation.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.samples++
+}
+
+// Count returns how many observations were recorded.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.samples
+}
+
+// HistogramVec is a family of histograms told apart by label values.
+type HistogramVec struct{ f *family[Histogram] }
+
+// NewHistogram registers a histogram family with the given upper bucket
+// bounds, which must be sorted; the +Inf bucket is implicit.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bounds := append([]float64(nil), buckets...)
+	v := &HistogramVec{newFamily(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+	})}
+	r.add(name, v)
+	return v
+}
+
+// With returns the histogram for the given label values.
+func (v *HistogramVec) With(values ...string) *Histogram { return v.f.with(values) }
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.f.header(w)
+	v.f.each(func(labels string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, n := h.sum, h.samples
+		h.mu.Unlock()
+		for i, b := range h.bounds {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.f.name, withLabel(labels, "le", formatFloat(b)), counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.f.name, withLabel(labels, "le", "+Inf"), n)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.f.name, labels, formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.f.name, labels, n)
+	})
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel appends name="value" to an already formatted label set.
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTextFormat(t *testing.T) {
+	reg := NewRegistry()
+	reqs := reg.NewCounter("test_requests_total", "Requests sent.", "method", "code")
+	reqs.With("GET", "200").Add(2)
+	reqs.With("GET", "error").Inc()
+	reg.NewGauge("test_queue", "Queued \"things\".").With().Set(7)
+	h := reg.NewHistogram("test_seconds", "Durations.", []float64{0.5, 1}, "outcome")
+	h.With("ok").Observe(0.25)
+	h.With("ok").Observe(0.75)
+	h.With("ok").Observe(3)
+	reg.NewGaugeFunc("test_gap_seconds", "Gaps.", []string{"host"}, func() []Sample {
+		return []Sample{{Labels: []string{`b"\`}, Value: 1.5}, {Labels: []string{"a"}, Value: 0}}
+	})
+
+	var buf bytes.Buffer
+	if err := reg.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_requests_total Requests sent.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",code="200"} 2
+test_requests_total{method="GET",code="error"} 1
+# HELP test_queue Queued "things".
+# TYPE test_queue gauge
+test_queue 7
+# HELP test_seconds Durations.
+# TYPE test_seconds histogram
+test_seconds_bucket{outcome="ok",le="0.5"} 1
+test_seconds_bucket{outcome="ok",le="1"} 2
+test_seconds_bucket{outcome="ok",le="+Inf"} 3
+test_seconds_sum{outcome="ok"} 4
+test_seconds_count{outcome="ok"} 3
+# HELP test_gap_seconds Gaps.
+# TYPE test_gap_seconds gauge
+test_gap_seconds{host="a"} 0
+test_gap_seconds{host="b\"\\"} 1.5
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelArityAndDuplicatesPanic(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("x_total", "x", "a")
+	for name, fn := range map[string]func(){
+		"arity":     func() { c.With() },
+		"duplicate": func() { reg.NewGauge("x_total", "again") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: want a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+	var buf bytes.Buffer
+	reg.WriteText(&buf)
+	if !strings.Contains(buf.String(), "# TYPE x_total counter") {
+		t.Error("registered counter missing from output")
+	}
+}
@@ -0,0 +1,348 @@
+// Package notify alerts people about findings while a scan runs. Notifiers are
+// configured by file: generic JSON webhooks, Slack-compatible incoming
+// webhooks and SMTP email, each filtered by severity, pattern, target and
+// asset tag and fed from the same streaming finding path as the jsonl output.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// ConfigVersion is the newest notifier file schema this build reads.
+const ConfigVersion = 1
+
+// Notifier types.
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+)
+
+// Delivery defaults, used when a notifier does not set its own.
+const (
+	DefaultRetries       = 3
+	DefaultRatePerMinute = 30
+	DefaultTimeout       = 10 * time.Second
+)
+
+// Notifier is one configured alert target.
+type Notifier struct {
+	Name string
+	Type string
+
+	// URL is the webhook or Slack incoming-webhook address; Headers are sent
+	// with every webhook request.
+	URL     string
+	Headers map[string]string
+
+	// MinSeverity, Patterns, Targets and Tags select the findings to alert
+	// on. An empty filter passes everything; Patterns and Targets are
+	// path.Match globs over the finding's pattern and its target's host (or
+	// the whole target), and Tags maps an asset tag name to a glob its value
+	// must match, so a finding without the tag is not sent.
+	MinSeverity string
+	Patterns    []string
+	Targets     []string
+	Tags        map[string]string
+
+	// Template renders the payload from an Event: the whole request body for
+	// a webhook, the message text for Slack and the body for email. Subject
+	// renders an email's subject line.
+	Template *template.Template
+	Subject  *template.Template
+
+	// SMTP delivery for email notifiers.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	To           []string
+
+	Retries       int
+	RatePerMinute int
+	Timeout       time.Duration
+}
+
+// Config is a loaded notifier file.
+type Config struct {
+	Notifiers []Notifier
+
+	// StateFile, when set, records the findings each notifier has delivered
+	// so later runs do not alert on them again.
+	StateFile string
+}
+
+// LoadConfig reads a notifier file in YAML or JSON:
+//
+//	version: 1
+//	state_file: notify-state.json
+//	notifiers:
+//	  - name: security-pager
+//	    type: slack
+//	    url: ${SLACK_WEBHOOK_URL}
+//	    min_severity: high
+//	    patterns: [aws_*, github_token]
+//	    targets: ["*.example.com"]
+//	    tags:
+//	      team: payments
+//
+// Values may reference environment variables as $NAME or ${NAME}, so secrets
+// such as webhook URLs and SMTP passwords need not be written into the file.
+// Unknown fields and types are errors.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := scan.DecodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	cfg, err := decodeConfig(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+func decodeConfig(doc any) (*Config, error) {
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping with version and notifiers")
+	}
+	if err := checkFields(top, "version", "state_file", "notifiers"); err != nil {
+		return nil, err
+	}
+	version, err := intValue(top["version"])
+	if err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+	if version < 1 || version > ConfigVersion {
+		return nil, fmt.Errorf("unsupported notifier file version %d (this build reads up to %d)", version, ConfigVersion)
+	}
+	list, ok := top["notifiers"].([]any)
+	if !ok {
+		return nil, errors.New("notifiers must be a non-empty list")
+	}
+	cfg := &Config{StateFile: os.ExpandEnv(stringValue(top["state_file"]))}
+	names := make(map[string]bool, len(list))
+	for i, raw := range list {
+		n, err := decodeNotifier(raw)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %d: %w", i+1, err)
+		}
+		if n.Name == "" {
+			n.Name = fmt.Sprintf("%s-%d", n.Type, i+1)
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("notifier %d: duplicate name %q", i+1, n.Name)
+		}
+		names[n.Name] = true
+		cfg.Notifiers = append(cfg.Notifiers, n)
+	}
+	return cfg, nil
+}
+
+func decodeNotifier(raw any) (Notifier, error) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return Notifier{}, errors.New("expected a mapping")
+	}
+	if err := checkFields(m, "name", "type", "url", "headers", "min_severity", "patterns", "targets",
+		"tags", "template", "subject", "smtp_host", "smtp_port", "smtp_username", "smtp_password", "from", "to",
+		"retries", "rate_per_minute", "timeout"); err != nil {
+		return Notifier{}, err
+	}
+	n := Notifier{
+		Name:          stringValue(m["name"]),
+		Type:          strings.ToLower(stringValue(m["type"])),
+		URL:           os.ExpandEnv(stringValue(m["url"])),
+		MinSeverity:   strings.ToLower(stringValue(m["min_severity"])),
+		SMTPHost:      os.ExpandEnv(stringValue(m["smtp_host"])),
+		SMTPUsername:  os.ExpandEnv(stringValue(m["smtp_username"])),
+		SMTPPassword:  os.ExpandEnv(stringValue(m["smtp_password"])),
+		From:          os.ExpandEnv(stringValue(m["from"])),
+		Retries:       DefaultRetries,
+		RatePerMinute: DefaultRatePerMinute,
+		Timeout:       DefaultTimeout,
+	}
+	var err error
+	if n.Patterns, err = stringList(m["patterns"]); err != nil {
+		return n, fmt.Errorf("patterns: %w", err)
+	}
+	if n.Targets, err = stringList(m["targets"]); err != nil {
+		return n, fmt.Errorf("targets: %w", err)
+	}
+	if n.To, err = stringList(m["to"]); err != nil {
+		return n, fmt.Errorf("to: %w", err)
+	}
+	for i := range n.To {
+		n.To[i] = os.ExpandEnv(n.To[i])
+	}
+	for _, glob := range append(append([]string(nil), n.Patterns...), n.Targets...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return n, fmt.Errorf("bad glob %q: %w", glob, err)
+		}
+	}
+	if raw, ok := m["tags"]; ok && raw != nil {
+		tm, ok := raw.(map[string]any)
+		if !ok {
+			return n, errors.New("tags must be a mapping of tag name to glob")
+		}
+		n.Tags = make(map[string]string, len(tm))
+		for k, v := range tm {
+			glob := stringValue(v)
+			if _, err := path.Match(glob, ""); err != nil {
+				return n, fmt.Errorf("tags: bad glob %q: %w", glob, err)
+			}
+			n.Tags[strings.ToLower(k)] = glob
+		}
+	}
+	if raw, ok := m["headers"]; ok && raw != nil {
+		hm, ok := raw.(map[string]any)
+		if !ok {
+			return n, errors.New("headers must be a mapping")
+		}
+		n.Headers = make(map[string]string, len(hm))
+		for k, v := range hm {
+			n.Headers[k] = os.ExpandEnv(stringValue(v))
+		}
+	}
+	if n.MinSeverity != "" && scan.SeverityRank(n.MinSeverity) == 0 {
+		return n, fmt.Errorf("min_severity %q (want info|low|medium|high)", n.MinSeverity)
+	}
+	for field, dst := range map[string]*int{"smtp_port": &n.SMTPPort, "retries": &n.Retries, "rate_per_minute": &n.RatePerMinute} {
+		if raw, ok := m[field]; ok && raw != nil {
+			v, err := intValue(raw)
+			if err != nil || v < 0 {
+				return n, fmt.Errorf("%s: want a non-negative integer", field)
+			}
+			*dst = v
+		}
+	}
+	if raw, ok := m["timeout"]; ok && raw != nil {
+		d, err := time.ParseDuration(stringValue(raw))
+		if err != nil || d <= 0 {
+			return n, fmt.Errorf("timeout %q: want a positive duration such as 10s", stringValue(raw))
+		}
+		n.Timeout = d
+	}
+
+	text, subject := defaultTemplates[n.Type], defaultSubject
+	switch n.Type {
+	case TypeWebhook, TypeSlack:
+		if n.URL == "" {
+			return n, fmt.Errorf("%s notifier needs a url", n.Type)
+		}
+	case TypeEmail:
+		if n.SMTPHost == "" || n.From == "" || len(n.To) == 0 {
+			return n, errors.New("email notifier needs smtp_host, from and to")
+		}
+		if n.SMTPPort == 0 {
+			n.SMTPPort = 587
+		}
+	case "":
+		return n, errors.New("missing type (want webhook, slack or email)")
+	default:
+		return n, fmt.Errorf("unknown type %q (want webhook, slack or email)", n.Type)
+	}
+	if v := stringValue(m["template"]); v != "" {
+		text = v
+	}
+	if v := stringValue(m["subject"]); v != "" {
+		subject = v
+	}
+	if text != "" {
+		if n.Template, err = template.New("template").Funcs(templateFuncs).Parse(text); err != nil {
+			return n, fmt.Errorf("template: %w", err)
+		}
+	}
+	if n.Type == TypeEmail {
+		if n.Subject, err = template.New("subject").Funcs(templateFuncs).Parse(subject); err != nil {
+			return n, fmt.Errorf("subject: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// defaultTemplates are the payload templates of notifiers that set none. A
+// webhook without a template posts the Event as JSON.
+var defaultTemplates = map[string]string{
+	TypeSlack: ":rotating_light: *{{.Severity}}* `{{.Pattern}}` on {{.Target}}\n" +
+		"{{if .Value}}`{{.Value}}`\n{{end}}source: {{.Source}}\nfingerprint: {{.Fingerprint}}",
+	TypeEmail: "JSMiner found a {{.Severity}} {{.Kind}} finding.\n\n" +
+		"Pattern:     {{.Pattern}}\nTarget:      {{.Target}}\nSource:      {{.Source}}\n" +
+		"{{if .Value}}Value:       {{.Value}}\n{{end}}{{if .Confidence}}Confidence:  {{.Confidence}}\n{{end}}" +
+		"Fingerprint: {{.Fingerprint}}\n",
+}
+
+const defaultSubject = "[jsminer] {{.Severity}} {{.Pattern}} on {{.Target}}"
+
+func checkFields(m map[string]any, allowed ...string) error {
+	var unknown []string
+	for k := range m {
+		if !containsString(allowed, k) {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown field(s) %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func stringValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func intValue(v any) (int, error) {
+	n, err := strconv.Atoi(stringValue(v))
+	if err != nil {
+		return 0, fmt.Errorf("want an integer, got %q", stringValue(v))
+	}
+	return n, nil
+}
+
+func stringList(v any) ([]string, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{t}, nil
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			out = append(out, stringValue(item))
+		}
+		return out, nil
+	}
+	return nil, errors.New("want a list of strings")
+}
@@ -0,0 +1,459 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// Finding kinds an Event carries.
+const (
+	KindMatch      = "match"
+	KindDOM        = "dom"
+	KindReflection = "reflection"
+)
+
+// Event is one finding as notifiers see it: the fields templates and filters
+// use, plus the finding itself for templates that need more.
+type Event struct {
+	Kind        string `json:"kind"`
+	Severity    string `json:"severity"`
+	Pattern     string `json:"pattern"`
+	Value       string `json:"value,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Target      string `json:"target"`
+	Confidence  string `json:"confidence,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+
+	// Asset is the enrichment of the finding's host, when enabled.
+	Asset *scan.Asset `json:"asset,omitempty"`
+
+	Match      *scan.Match             `json:"match,omitempty"`
+	DOM        *scan.DOMFinding        `json:"dom,omitempty"`
+	Reflection *scan.ReflectionFinding `json:"reflection,omitempty"`
+}
+
+// MatchEvent describes an ordinary match found while scanning target. An empty
+// target falls back to the match's source.
+func MatchEvent(target string, m scan.Match) Event {
+	if target == "" {
+		target = m.Source
+	}
+	e := Event{
+		Kind: KindMatch, Severity: m.Severity, Pattern: m.Pattern, Value: m.Value, Source: m.Source,
+		Target: target, Fingerprint: scan.MatchFingerprint(m), Asset: m.Asset, Match: &m,
+	}
+	if m.Confidence > 0 {
+		e.Confidence = strconv.Itoa(m.Confidence)
+	}
+	return e
+}
+
+// DOMEvent describes a DOM finding. Its pattern is the finding type, with the
+// sink when there is one.
+func DOMEvent(f scan.DOMFinding) Event {
+	pattern := f.Type
+	if f.Sink != nil {
+		pattern += ":" + f.Sink.Name
+	}
+	return Event{
+		Kind: KindDOM, Severity: f.Severity, Pattern: pattern, Value: f.ValuePreview, Source: f.PageURL,
+		Target: f.Target, Confidence: f.Confidence, Fingerprint: f.Fingerprint, Asset: f.Asset, DOM: &f,
+	}
+}
+
+// ReflectionEvent describes a reflected-input finding. Its value is the
+// reflected parameter.
+func ReflectionEvent(f scan.ReflectionFinding) Event {
+	return Event{
+		Kind: KindReflection, Severity: f.Severity, Pattern: f.Type, Value: f.Parameter, Source: f.PageURL,
+		Target: f.Target, Confidence: f.Confidence, Fingerprint: f.Fingerprint, Asset: f.Asset, Reflection: &f,
+	}
+}
+
+// templateFuncs are available to payload templates. json quotes a value for
+// embedding in a JSON body.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Accepts reports whether n's filters select e.
+func (n *Notifier) Accepts(e Event) bool {
+	if n.MinSeverity != "" && scan.SeverityRank(e.Severity) < scan.SeverityRank(n.MinSeverity) {
+		return false
+	}
+	if len(n.Patterns) > 0 && !matchesAny(n.Patterns, e.Pattern) {
+		return false
+	}
+	if len(n.Targets) > 0 {
+		host := e.Target
+		if u, err := url.Parse(e.Target); err == nil && u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		if !matchesAny(n.Targets, host) && !matchesAny(n.Targets, e.Target) {
+			return false
+		}
+	}
+	return e.Asset.MatchesTags(n.Tags)
+}
+
+func matchesAny(globs []string, s string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats counts a Dispatcher's deliveries.
+type Stats struct {
+	Sent    int
+	Failed  int
+	Dropped int
+}
+
+// queueSize bounds the alerts waiting on one notifier; beyond it new alerts
+// are dropped rather than stalling the scan.
+const queueSize = 256
+
+// Dispatcher fans findings out to the configured notifiers. Notify never
+// blocks the scan: each notifier delivers from its own queue, paced by its
+// rate limit and retrying failed deliveries.
+type Dispatcher struct {
+	// OnError, when set, is told about each delivery that failed for good and
+	// about a state file that could not be saved.
+	OnError func(error)
+	// Redact, when set, masks a match's secret value before it is sent. The
+	// event keeps the fingerprint of the real value.
+	Redact func(scan.Match) scan.Match
+
+	client *http.Client
+	// backoff is the wait before the first retry; it doubles on each one.
+	backoff  time.Duration
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	workers []*worker
+	stats   Stats
+	closed  bool
+	wg      sync.WaitGroup
+	stop    sync.Once
+
+	// sent holds, by notifier name, the findings delivered in this run and,
+	// with a state file, in earlier ones.
+	sent      map[string]map[string]bool
+	stateFile string
+}
+
+type worker struct {
+	n     Notifier
+	queue chan Event
+	stop  chan struct{}
+}
+
+// NewDispatcher starts delivering for every notifier in cfg. When cfg has a
+// state file, the findings it records as delivered are not sent again.
+func NewDispatcher(cfg *Config) (*Dispatcher, error) {
+	d := &Dispatcher{
+		client:   &http.Client{},
+		backoff:  time.Second,
+		sendMail: smtp.SendMail,
+		seen:     make(map[string]bool),
+		sent:     make(map[string]map[string]bool),
+	}
+	if cfg.StateFile != "" {
+		sent, err := readState(cfg.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		d.sent, d.stateFile = sent, cfg.StateFile
+	}
+	for _, n := range cfg.Notifiers {
+		w := &worker{n: n, queue: make(chan Event, queueSize), stop: make(chan struct{})}
+		d.workers = append(d.workers, w)
+		d.wg.Add(1)
+		go d.run(w)
+	}
+	return d, nil
+}
+
+// Notify queues e for every notifier whose filters accept it and that has not
+// delivered it before. Each finding is considered once per run — the first
+// time its fingerprint is seen — so a finding streamed mid-crawl and reported
+// again at the end alerts once.
+func (d *Dispatcher) Notify(e Event) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	if e.Fingerprint != "" {
+		key := eventKey(e)
+		if d.seen[key] {
+			return
+		}
+		d.seen[key] = true
+	}
+	if e.Match != nil && d.Redact != nil {
+		m := d.Redact(*e.Match)
+		e.Match, e.Value = &m, m.Value
+	}
+	for _, w := range d.workers {
+		if !w.n.Accepts(e) || (e.Fingerprint != "" && d.sent[w.n.Name][eventKey(e)]) {
+			continue
+		}
+		select {
+		case w.queue <- e:
+		default:
+			d.stats.Dropped++
+		}
+	}
+}
+
+// Close stops accepting findings and waits for the queued alerts to be
+// delivered, or for ctx to end, whichever comes first. Alerts still queued
+// when ctx ends are counted as dropped. The delivered findings are then saved
+// to the state file, if there is one.
+func (d *Dispatcher) Close(ctx context.Context) Stats {
+	if d == nil {
+		return Stats{}
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, w := range d.workers {
+			close(w.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.stop.Do(func() {
+			for _, w := range d.workers {
+				close(w.stop)
+			}
+		})
+		<-done
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stateFile != "" {
+		if err := writeState(d.stateFile, d.sent); err != nil && d.OnError != nil {
+			d.OnError(fmt.Errorf("state file: %w", err))
+		}
+	}
+	return d.stats
+}
+
+func (d *Dispatcher) run(w *worker) {
+	defer d.wg.Done()
+	var gap time.Duration
+	if w.n.RatePerMinute > 0 {
+		gap = time.Minute / time.Duration(w.n.RatePerMinute)
+	}
+	var last time.Time
+	for e := range w.queue {
+		select {
+		case <-w.stop:
+			d.drop(w)
+			return
+		default:
+		}
+		if wait := time.Until(last.Add(gap)); wait > 0 && !last.IsZero() {
+			if !sleep(w.stop, wait) {
+				d.drop(w)
+				return
+			}
+		}
+		last = time.Now()
+		err := d.deliver(w, e)
+		d.mu.Lock()
+		if err != nil {
+			d.stats.Failed++
+		} else {
+			d.stats.Sent++
+			if e.Fingerprint != "" {
+				if d.sent[w.n.Name] == nil {
+					d.sent[w.n.Name] = make(map[string]bool)
+				}
+				d.sent[w.n.Name][eventKey(e)] = true
+			}
+		}
+		d.mu.Unlock()
+		if err != nil && d.OnError != nil {
+			d.OnError(fmt.Errorf("notifier %s: %s %s: %w", w.n.Name, e.Pattern, e.Fingerprint, err))
+		}
+	}
+}
+
+// drop counts the alerts left on a stopped worker's queue, including the one
+// it had already taken.
+func (d *Dispatcher) drop(w *worker) {
+	n := 1
+	for range w.queue {
+		n++
+	}
+	d.mu.Lock()
+	d.stats.Dropped += n
+	d.mu.Unlock()
+}
+
+// sleep waits for d unless stop closes first, reporting whether it slept the
+// whole time.
+func sleep(stop <-chan struct{}, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// deliver sends e to w's target, retrying transient failures with exponential
+// backoff.
+func (d *Dispatcher) deliver(w *worker, e Event) error {
+	backoff := d.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = d.send(w.n, e)
+		if err == nil || attempt >= w.n.Retries || errors.Is(err, errPermanent) {
+			return err
+		}
+		wait := max(backoff, retryAfter)
+		if !sleep(w.stop, wait) {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// errPermanent marks failures a retry cannot fix: a bad template or a request
+// the receiver rejected outright.
+var errPermanent = errors.New("permanent failure")
+
+// maxRetryAfter caps how long a receiver's Retry-After can hold a notifier.
+const maxRetryAfter = time.Minute
+
+func (d *Dispatcher) send(n Notifier, e Event) (time.Duration, error) {
+	if n.Type == TypeEmail {
+		return 0, d.sendEmail(n, e)
+	}
+	body, err := payload(n, e)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), n.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jsminer-notify")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		var wait time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			wait = min(time.Duration(secs)*time.Second, maxRetryAfter)
+		}
+		return wait, fmt.Errorf("HTTP %d", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("%w: HTTP %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// payload renders the request body for a webhook or Slack notifier.
+func payload(n Notifier, e Event) ([]byte, error) {
+	switch {
+	case n.Type == TypeSlack:
+		text, err := render(n.Template, e)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(map[string]string{"text": text})
+	case n.Template != nil:
+		text, err := render(n.Template, e)
+		return []byte(text), err
+	default:
+		return json.Marshal(e)
+	}
+}
+
+func render(t *template.Template, e Event) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, e); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (d *Dispatcher) sendEmail(n Notifier, e Event) error {
+	subject, err := render(n.Subject, e)
+	if err != nil {
+		return fmt.Errorf("%w: subject: %v", errPermanent, err)
+	}
+	body, err := render(n.Template, e)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if n.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", n.SMTPUsername, n.SMTPPassword, n.SMTPHost)
+	}
+	addr := net.JoinHostPort(n.SMTPHost, strconv.Itoa(n.SMTPPort))
+	return d.sendMail(addr, auth, n.From, n.To, msg.Bytes())
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// stateVersion is the format version of the state file.
+const stateVersion = 1
+
+// state is the state_file on disk: for each notifier, by name, the findings
+// it has delivered, as eventKey values.
+type state struct {
+	Version int                 `json:"version"`
+	Sent    map[string][]string `json:"sent"`
+}
+
+// eventKey identifies a finding across runs.
+func eventKey(e Event) string { return e.Kind + ":" + e.Fingerprint }
+
+// readState loads the sent findings recorded in path. A missing file is an
+// empty state, so the first run creates it.
+func readState(path string) (map[string]map[string]bool, error) {
+	sent := make(map[string]map[string]bool)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if st.Version != stateVersion {
+		return nil, fmt.Errorf("%s: unsupported state version %d", path, st.Version)
+	}
+	for name, keys := range st.Sent {
+		set := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			set[k] = true
+		}
+		sent[name] = set
+	}
+	return sent, nil
+}
+
+// writeState records sent in path atomically: it writes a sibling temp file
+// and renames it over path, so an interrupted save never loses the history.
+func writeState(path string, sent map[string]map[string]bool) error {
+	st := state{Version: stateVersion, Sent: make(map[string][]string, len(sent))}
+	for name, set := range sent {
+		keys := make([]string, 0, len(set))
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		st.Sent[name] = keys
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".jsminer-notify-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// The CI formats: JUnit XML for test-report annotations, and the GitLab Code
+// Quality and Secret Detection report schemas.
+const (
+	FormatJUnit           = "junit"
+	FormatCodeQuality     = "codequality"
+	FormatSecretDetection = "secret-detection"
+)
+
+// secretDetectionSchemaVersion is the GitLab security report schema version the
+// secret-detection format follows.
+const secretDetectionSchemaVersion = "15.0.7"
+
+// SetFailOn sets the -fail-on severity threshold. The junit format reports the
+// findings at or above it as failures; with no threshold every finding fails,
+// mirroring the exit code.
+func (p *Printer) SetFailOn(severity string) { p.failOn = severity }
+
+// ciFinding is one finding of any kind — an ordinary match, a DOM finding or a
+// reflection — flattened to what CI reports need: a check name, where it was
+// found and a stable fingerprint.
+type ciFinding struct {
+	Kind        string // "match", "dom" or "reflection"
+	Check       string
+	Secret      bool // a match that is a candidate secret
+	Path        string
+	Line        int
+	Severity    string
+	Description string
+	Fingerprint string
+}
+
+// ciFindings flattens every finding of r, ordered by severity then check and
+// path so the reports are stable between pipelines.
+func (p *Printer) ciFindings(r Report) []ciFinding {
+	var out []ciFinding
+	shown := p.redact.Matches(r.Matches)
+	for i, m := range r.Matches {
+		desc := m.Pattern + ": " + shown[i].Value
+		if params := normalizeParams(m.Params); params != "" {
+			desc += " (" + params + ")"
+		}
+		if m.Confidence > 0 {
+			desc += fmt.Sprintf(" [confidence %d]", m.Confidence)
+		}
+		if ids := m.Library.VulnIDs(); len(ids) > 0 {
+			desc += " (" + strings.Join(ids, ", ") + ")"
+		}
+		out = append(out, ciFinding{
+			Kind: "match", Check: m.Pattern, Secret: m.Secret, Path: m.Source, Line: 1, Severity: m.Severity,
+			Description: desc, Fingerprint: scan.MatchFingerprint(m),
+		})
+	}
+	for _, f := range r.DOM {
+		check := f.Type
+		desc := f.Type
+		if f.Source != nil {
+			desc += " from " + f.Source.Kind
+			if f.Source.Name != "" {
+				desc += "[" + f.Source.Name + "]"
+			}
+		}
+		if f.Sink != nil {
+			check += ":" + f.Sink.Name
+			desc += " to " + f.Sink.Name
+		}
+		if f.Triage != nil && f.Triage.Reason != "" {
+			desc += ": " + f.Triage.Reason
+		}
+		path, line := f.PageURL, 1
+		for _, fr := range f.Stack {
+			if fr.URL != "" {
+				path, line = fr.URL, max(fr.Line, 1)
+				break
+			}
+		}
+		out = append(out, ciFinding{
+			Kind: "dom", Check: check, Path: path, Line: line, Severity: f.Severity,
+			Description: desc + " on " + f.PageURL, Fingerprint: domFindingFingerprint(f),
+		})
+	}
+	for _, f := range r.Reflections {
+		desc := fmt.Sprintf("%s parameter %q reflected in %s context", f.Method, f.Parameter, f.Context)
+		if len(f.Unfiltered) > 0 {
+			desc += " with " + strings.Join(f.Unfiltered, " ") + " unfiltered"
+		}
+		out = append(out, ciFinding{
+			Kind: "reflection", Check: "reflection:" + f.Parameter, Path: f.PageURL, Line: 1,
+			Severity: f.Severity, Description: desc, Fingerprint: f.Fingerprint,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		ri, rj := scan.SeverityRank(out[i].Severity), scan.SeverityRank(out[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		if out[i].Check != out[j].Check {
+			return out[i].Check < out[j].Check
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Timestamp string           `xml:"timestamp,attr,omitempty"`
+	Cases     []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// printJUnit renders the report as JUnit XML: one suite per kind of finding
+// and one testcase per check and source, so CI annotates each pattern found in
+// each file once. A testcase fails when any of its findings reaches the
+// -fail-on threshold; findings below it are listed in its system-out.
+func (p *Printer) printJUnit(w io.Writer, r Report) error {
+	scanTime := r.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now()
+	}
+	threshold := scan.SeverityRank(p.failOn)
+
+	doc := junitTestSuites{Name: "jsminer"}
+	suites := make(map[string]*junitTestSuite)
+	cases := make(map[string]*junitTestCase)
+	for _, f := range p.ciFindings(r) {
+		suite := suites[f.Kind]
+		if suite == nil {
+			suite = &junitTestSuite{Name: "jsminer." + f.Kind, Timestamp: scanTime.UTC().Format("2006-01-02T15:04:05")}
+			suites[f.Kind] = suite
+		}
+		key := f.Kind + "\x1f" + f.Check + "\x1f" + f.Path
+		tc := cases[key]
+		if tc == nil {
+			tc = &junitTestCase{Name: f.Check, ClassName: f.Path}
+			suite.Cases = append(suite.Cases, tc)
+			cases[key] = tc
+		}
+		line := fmt.Sprintf("[%s] %s (fingerprint %s)\n", f.Severity, f.Description, f.Fingerprint)
+		if scan.SeverityRank(f.Severity) < threshold {
+			tc.SystemOut += line
+			continue
+		}
+		if tc.Failure == nil {
+			// Findings arrive highest severity first, so the first failing one
+			// names the testcase's failure type.
+			tc.Failure = &junitFailure{Type: f.Severity}
+		}
+		tc.Failure.Body += line
+	}
+	for _, kind := range []string{"match", "dom", "reflection"} {
+		suite := suites[kind]
+		if suite == nil {
+			continue
+		}
+		for _, tc := range suite.Cases {
+			suite.Tests++
+			if f := tc.Failure; f != nil {
+				suite.Failures++
+				n := strings.Count(f.Body, "\n")
+				f.Message = fmt.Sprintf("%d finding(s), highest severity %s", n, f.Type)
+			}
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, *suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// codeQualityIssue is one entry of a GitLab Code Quality report.
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// codeQualitySeverity maps a JSMiner severity onto the Code Quality scale.
+func codeQualitySeverity(sev string) string {
+	switch strings.ToLower(sev) {
+	case scan.SeverityHigh:
+		return "critical"
+	case scan.SeverityMedium:
+		return "major"
+	case scan.SeverityLow:
+		return "minor"
+	}
+	return "info"
+}
+
+// printCodeQuality renders every finding as a GitLab Code Quality report, a
+// JSON array of issues keyed by fingerprint, which GitLab shows inline on merge
+// requests.
+func (p *Printer) printCodeQuality(w io.Writer, r Report) error {
+	issues := make([]codeQualityIssue, 0, len(r.Matches)+len(r.DOM)+len(r.Reflections))
+	for _, f := range p.ciFindings(r) {
+		issues = append(issues, codeQualityIssue{
+			Description: f.Description,
+			CheckName:   "jsminer/" + f.Check,
+			Fingerprint: f.Fingerprint,
+			Severity:    codeQualitySeverity(f.Severity),
+			Location:    codeQualityLocation{Path: f.Path, Lines: codeQualityLines{Begin: f.Line}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+// secretDetectionReport is a GitLab Secret Detection security report.
+type secretDetectionReport struct {
+	Version         string                  `json:"version"`
+	Vulnerabilities []secretVulnerability   `json:"vulnerabilities"`
+	Scan            secretDetectionScanInfo `json:"scan"`
+}
+
+type secretVulnerability struct {
+	ID          string             `json:"id"`
+	Category    string             `json:"category"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Severity    string             `json:"severity"`
+	Scanner     secretToolRef      `json:"scanner"`
+	Location    secretLocation     `json:"location"`
+	Identifiers []secretIdentifier `json:"identifiers"`
+}
+
+type secretToolRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type secretLocation struct {
+	File      string       `json:"file"`
+	StartLine int          `json:"start_line"`
+	Commit    secretCommit `json:"commit"`
+}
+
+type secretCommit struct {
+	SHA string `json:"sha"`
+}
+
+type secretIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type secretDetectionScanInfo struct {
+	Analyzer  secretTool `json:"analyzer"`
+	Scanner   secretTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"`
+}
+
+type secretTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  secretVendor `json:"vendor"`
+}
+
+type secretVendor struct {
+	Name string `json:"name"`
+}
+
+// secretDetectionSeverity maps a JSMiner severity onto the security report
+// scale.
+func secretDetectionSeverity(sev string) string {
+	switch strings.ToLower(sev) {
+	case scan.SeverityHigh:
+		return "High"
+	case scan.SeverityMedium:
+		return "Medium"
+	case scan.SeverityLow:
+		return "Low"
+	case scan.SeverityInfo:
+		return "Info"
+	}
+	return "Unknown"
+}
+
+// printSecretDetection renders the candidate secrets as a GitLab Secret
+// Detection report. Only matches that are candidate secrets are included: the
+// schema files every entry as a leaked secret, so endpoints, DOM flows and
+// reflections belong in the junit and codequality reports instead.
+func (p *Printer) printSecretDetection(w io.Writer, r Report) error {
+	scanTime := r.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now()
+	}
+	version := p.version
+	if version == "" {
+		version = "dev"
+	}
+	tool := secretTool{ID: "jsminer", Name: "JSMiner", Version: version, Vendor: secretVendor{Name: "JSMiner"}}
+	report := secretDetectionReport{
+		Version:         secretDetectionSchemaVersion,
+		Vulnerabilities: []secretVulnerability{},
+		Scan: secretDetectionScanInfo{
+			Analyzer: tool, Scanner: tool, Type: "secret_detection",
+			StartTime: scanTime.UTC().Format("2006-01-02T15:04:05"),
+			EndTime:   time.Now().UTC().Format("2006-01-02T15:04:05"),
+			Status:    "success",
+		},
+	}
+	for _, f := range p.ciFindings(Report{Matches: r.Matches}) {
+		if !f.Secret {
+			continue
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, secretVulnerability{
+			ID:          f.Fingerprint,
+			Category:    "secret_detection",
+			Name:        "JSMiner " + f.Check,
+			Description: f.Description,
+			Severity:    secretDetectionSeverity(f.Severity),
+			Scanner:     secretToolRef{ID: tool.ID, Name: tool.Name},
+			Location:    secretLocation{File: f.Path, StartLine: f.Line, Commit: secretCommit{SHA: "0000000"}},
+			Identifiers: []secretIdentifier{{Type: "jsminer_rule_id", Name: "JSMiner rule " + f.Check, Value: f.Check}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
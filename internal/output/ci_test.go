@@ -0,0 +1,127 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+func ciSampleReport() Report {
+	r := sampleReport()
+	r.Matches = append(r.Matches,
+		scan.Match{Source: "https://app.test/app.js", Pattern: "aws_key", Value: "AKIA2", Severity: scan.SeverityHigh, Secret: true},
+		scan.Match{Source: "https://app.test/app.js", Pattern: "email", Value: "ops@app.test", Severity: scan.SeverityInfo},
+	)
+	r.Reflections = []scan.ReflectionFinding{{
+		Type: "reflection", PageURL: "https://app.test/search", Parameter: "q", Method: "GET",
+		Context: "html_text", Severity: scan.SeverityMedium, Fingerprint: "refl1",
+	}}
+	return r
+}
+
+func TestPrintReportJUnitGroupsAndFailsAboveThreshold(t *testing.T) {
+	p := NewPrinter(FormatJUnit, false, true, false, "")
+	p.SetFailOn(scan.SeverityMedium)
+	var buf bytes.Buffer
+	if err := p.PrintReport(&buf, ciSampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, buf.String())
+	}
+	// aws_key (two findings, one case) and email in app.js; two DOM; one reflection.
+	if doc.Tests != 5 {
+		t.Fatalf("tests = %d, want 5:\n%s", doc.Tests, buf.String())
+	}
+	// The info email and the info web-message finding pass.
+	if doc.Failures != 3 {
+		t.Fatalf("failures = %d, want 3:\n%s", doc.Failures, buf.String())
+	}
+	for _, s := range doc.Suites {
+		for _, tc := range s.Cases {
+			if tc.Name == "aws_key" {
+				if tc.Failure == nil || !strings.HasPrefix(tc.Failure.Message, "2 finding(s)") {
+					t.Errorf("aws_key case = %+v, want one failure covering both keys", tc)
+				}
+				if !strings.Contains(tc.Failure.Body, scan.MatchFingerprint(ciSampleReport().Matches[0])) {
+					t.Errorf("failure body lacks the match fingerprint: %s", tc.Failure.Body)
+				}
+			}
+			if tc.Name == "email" && (tc.Failure != nil || tc.SystemOut == "") {
+				t.Errorf("email case = %+v, want a pass listing the finding", tc)
+			}
+		}
+	}
+
+	// Without -fail-on every finding fails, as the exit code does.
+	buf.Reset()
+	if err := NewPrinter(FormatJUnit, false, true, false, "").PrintReport(&buf, ciSampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Failures != doc.Tests {
+		t.Errorf("failures = %d of %d tests, want all", doc.Failures, doc.Tests)
+	}
+}
+
+func TestPrintReportCodeQuality(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewPrinter(FormatCodeQuality, false, false, false, "").PrintReport(&buf, ciSampleReport()); err != nil {
+		t.Fatal(err)
+	}
+	var issues []codeQualityIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 6 {
+		t.Fatalf("got %d issues, want every match, DOM and reflection finding:\n%s", len(issues), buf.String())
+	}
+	seen := make(map[string]bool)
+	for _, is := range issues {
+		if is.Fingerprint == "" || seen[is.Fingerprint] {
+			t.Errorf("issue %q has a missing or duplicate fingerprint", is.CheckName)
+		}
+		seen[is.Fingerprint] = true
+		if is.Location.Path == "" || is.Location.Lines.Begin < 1 {
+			t.Errorf("issue %q has no location", is.CheckName)
+		}
+	}
+	if issues[0].Severity != "critical" {
+		t.Errorf("first issue severity = %q, want the high findings first as critical", issues[0].Severity)
+	}
+}
+
+func TestPrintReportSecretDetection(t *testing.T) {
+	r := ciSampleReport()
+	r.Matches = append(r.Matches,
+		scan.Match{Source: "https://app.test/graphql", Pattern: scan.GraphQLIntrospectionPattern, Value: "https://app.test/graphql", Severity: scan.SeverityInfo},
+		scan.Match{Source: "https://app.test/app.js", Pattern: "http_header", Value: "Content-Type: application/json", Severity: scan.SeverityLow},
+	)
+	var buf bytes.Buffer
+	if err := NewPrinter(FormatSecretDetection, false, false, false, "v1.0.0").PrintReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var doc secretDetectionReport
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Scan.Type != "secret_detection" || doc.Scan.Scanner.Version != "v1.0.0" {
+		t.Errorf("scan = %+v", doc.Scan)
+	}
+	// The two AWS keys; the email, GraphQL endpoint, header, DOM and reflection
+	// findings are not secrets.
+	if len(doc.Vulnerabilities) != 2 {
+		t.Fatalf("got %d vulnerabilities, want 2:\n%s", len(doc.Vulnerabilities), buf.String())
+	}
+	v := doc.Vulnerabilities[0]
+	if v.Category != "secret_detection" || v.Severity != "High" || v.Location.File != "https://app.test/app.js" {
+		t.Errorf("vulnerability = %+v", v)
+	}
+}
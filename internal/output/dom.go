@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +15,10 @@ import (
 
 // JSONLSchemaVersion documents the streaming (NDJSON) output schema. Consumers
 // key off it to detect an incompatible change. It is emitted on the leading
-// scan_meta record and on the trailing scan_summary record.
-const JSONLSchemaVersion = "jsminer.jsonl.1"
+// scan_meta record and on the trailing scan_summary record. Version 2 streams
+// findings in discovery order and moves the results checksum, which is only
+// known at the end, from scan_meta to scan_summary.
+const JSONLSchemaVersion = "jsminer.jsonl.2"
 
 // Stable record types for machine-readable output. Regular (non-DOM) findings
 // carry type "match"; DOM findings carry their own scan.DOMType* values.
@@ -36,27 +39,31 @@ type Report struct {
 	Reflections       []scan.ReflectionFinding
 	ReflectionSummary *scan.ReflectionScanSummary
 	ScanTime          time.Time
+	// Partial reports that the scan was cancelled or hit its runtime limit
+	// before finishing, so the findings cover only what was reached.
+	Partial bool
+	// Suppressed, when set, counts the findings withheld by a triage file or
+	// jsminer:ignore comments; it is reported in every format's summary.
+	Suppressed *scan.SuppressionSummary
+	// Crawls holds the statistics of each crawled target. Only the html
+	// format renders them; the others report crawl progress on stderr.
+	Crawls []CrawlRun
 }
 
 // jsonlMatch is one ordinary finding as a streaming record. Field names mirror
 // the json format's outMatch, plus a stable type and a deduplication
 // fingerprint.
 type jsonlMatch struct {
-	Type        string `json:"type"`
-	Pattern     string `json:"pattern"`
-	Value       string `json:"value"`
-	Params      string `json:"params,omitempty"`
-	Severity    string `json:"severity"`
-	Source      string `json:"source,omitempty"`
-	Fingerprint string `json:"fingerprint"`
-}
-
-// matchFingerprint is the deterministic dedup identity of an ordinary finding:
-// its pattern, value, params, severity and source, hashed. Transient output
-// concerns (snippets, display order) do not enter it.
-func matchFingerprint(m scan.Match) string {
-	h := sha256.Sum256([]byte(strings.Join([]string{m.Pattern, m.Value, m.Params, m.Severity, m.Source}, "\x1f")))
-	return fmt.Sprintf("%x", h[:16])
+	Type        string            `json:"type"`
+	Pattern     string            `json:"pattern"`
+	Value       string            `json:"value"`
+	Params      string            `json:"params,omitempty"`
+	Severity    string            `json:"severity"`
+	Confidence  int               `json:"confidence,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Fingerprint string            `json:"fingerprint"`
+	Asset       *scan.Asset       `json:"asset,omitempty"`
+	Library     *scan.LibraryInfo `json:"library,omitempty"`
 }
 
 // domFindingFingerprint returns a finding's fingerprint, computing a stable one
@@ -87,6 +94,14 @@ func (p *Printer) PrintReport(w io.Writer, r Report) error {
 		return p.printJSONL(w, r)
 	case "json":
 		return p.printJSONReport(w, r)
+	case "html":
+		return p.printHTML(w, r)
+	case FormatJUnit:
+		return p.printJUnit(w, r)
+	case FormatCodeQuality:
+		return p.printCodeQuality(w, r)
+	case FormatSecretDetection:
+		return p.printSecretDetection(w, r)
 	default:
 		return p.printPrettyReport(w, r)
 	}
@@ -95,66 +110,14 @@ func (p *Printer) PrintReport(w io.Writer, r Report) error {
 // printJSONL writes the report as newline-delimited JSON: a leading meta record
 // carrying the schema version, one record per finding, and a trailing
 // scan_summary. Every line is a complete JSON object and nothing else is written
-// to the stream, so stdout stays machine-parseable.
+// to the stream, so stdout stays machine-parseable. A report printed whole is
+// simply a JSONLStream that was handed nothing before it closed.
 func (p *Printer) printJSONL(w io.Writer, r Report) error {
-	enc := json.NewEncoder(w)
-	scanTime := r.ScanTime
-	if scanTime.IsZero() {
-		scanTime = time.Now()
-	}
-
-	meta := map[string]any{
-		"type":           recordTypeMeta,
-		"schema_version": JSONLSchemaVersion,
-		"scan_time":      scanTime.UTC().Format(time.RFC3339Nano),
-		"checksum":       ResultsChecksum(r.Matches),
-	}
-	if err := enc.Encode(meta); err != nil {
+	s, err := p.StartJSONL(w, r.ScanTime)
+	if err != nil {
 		return err
 	}
-
-	// Ordinary findings first, in severity order, then DOM findings.
-	matches := append([]scan.Match(nil), r.Matches...)
-	scan.SortBySeverity(matches)
-	for _, m := range matches {
-		rec := jsonlMatch{
-			Type:        recordTypeMatch,
-			Pattern:     m.Pattern,
-			Value:       m.Value,
-			Params:      normalizeParams(m.Params),
-			Severity:    m.Severity,
-			Fingerprint: matchFingerprint(m),
-		}
-		if p.showSource {
-			rec.Source = m.Source
-		}
-		if err := enc.Encode(rec); err != nil {
-			return err
-		}
-	}
-
-	dom := append([]scan.DOMFinding(nil), r.DOM...)
-	scan.SortDOMFindings(dom)
-	for i := range dom {
-		if dom[i].Fingerprint == "" {
-			dom[i].Fingerprint = domFindingFingerprint(dom[i])
-		}
-		if err := enc.Encode(dom[i]); err != nil {
-			return err
-		}
-	}
-
-	// Reflection findings carry their own "reflection" type, so each line is a
-	// self-describing NDJSON record alongside the match and DOM records.
-	refl := append([]scan.ReflectionFinding(nil), r.Reflections...)
-	scan.SortReflectionFindings(refl)
-	for i := range refl {
-		if err := enc.Encode(refl[i]); err != nil {
-			return err
-		}
-	}
-
-	return enc.Encode(p.summaryRecord(r, matches, dom, refl))
+	return s.Close(r)
 }
 
 // summaryRecord builds the trailing scan_summary object, merging DOM scan
@@ -181,6 +144,8 @@ func (p *Printer) summaryRecord(r Report, matches []scan.Match, dom []scan.DOMFi
 		"dom_findings":         len(dom),
 		"reflection_findings":  len(refl),
 		"findings_by_severity": bySev,
+		"partial":              r.Partial,
+		"checksum":             ResultsChecksum(r.Matches),
 	}
 	if r.DOMSummary != nil {
 		rec["dom"] = r.DOMSummary
@@ -188,6 +153,9 @@ func (p *Printer) summaryRecord(r Report, matches []scan.Match, dom []scan.DOMFi
 	if r.ReflectionSummary != nil {
 		rec["reflection"] = r.ReflectionSummary
 	}
+	if r.Suppressed != nil {
+		rec["suppressed"] = r.Suppressed
+	}
 	return rec
 }
 
@@ -203,13 +171,14 @@ type jsonReport struct {
 	DOMSummary        *scan.DOMScanSummary        `json:"dom_summary,omitempty"`
 	ReflectionResults []scan.ReflectionFinding    `json:"reflection_findings,omitempty"`
 	ReflectionSummary *scan.ReflectionScanSummary `json:"reflection_summary,omitempty"`
+	Suppressed        *scan.SuppressionSummary    `json:"suppressed,omitempty"`
 }
 
-// printJSONReport renders the json format. With no DOM or reflection findings it
-// defers to the legacy PrintScan so ordinary output is byte-for-byte unchanged;
-// otherwise it emits the superset document.
+// printJSONReport renders the json format. With no DOM or reflection findings
+// and nothing suppressed it defers to the legacy PrintScan so ordinary output is
+// byte-for-byte unchanged; otherwise it emits the superset document.
 func (p *Printer) printJSONReport(w io.Writer, r Report) error {
-	if len(r.DOM) == 0 && r.DOMSummary == nil && len(r.Reflections) == 0 && r.ReflectionSummary == nil {
+	if len(r.DOM) == 0 && r.DOMSummary == nil && len(r.Reflections) == 0 && r.ReflectionSummary == nil && r.Suppressed == nil {
 		return p.PrintScan(w, r.Matches, r.ScanTime)
 	}
 
@@ -217,11 +186,11 @@ func (p *Printer) printJSONReport(w io.Writer, r Report) error {
 	if scanTime.IsZero() {
 		scanTime = time.Now()
 	}
-	matches := append([]scan.Match(nil), r.Matches...)
+	matches := p.redact.Matches(append([]scan.Match(nil), r.Matches...))
 	scan.SortBySeverity(matches)
 	out := make([]outMatch, 0, len(matches))
 	for _, m := range matches {
-		om := outMatch{Pattern: m.Pattern, Value: m.Value, Params: normalizeParams(m.Params), Severity: m.Severity}
+		om := outMatch{Pattern: m.Pattern, Value: m.Value, Params: normalizeParams(m.Params), Severity: m.Severity, Confidence: m.Confidence, Asset: m.Asset, Library: m.Library}
 		if p.showSource {
 			om.Source = m.Source
 		}
@@ -245,6 +214,7 @@ func (p *Printer) printJSONReport(w io.Writer, r Report) error {
 		DOMSummary:        r.DOMSummary,
 		ReflectionResults: refl,
 		ReflectionSummary: r.ReflectionSummary,
+		Suppressed:        r.Suppressed,
 	})
 }
 
@@ -255,6 +225,21 @@ func (p *Printer) printPrettyReport(w io.Writer, r Report) error {
 	if err := p.PrintScan(w, r.Matches, r.ScanTime); err != nil {
 		return err
 	}
+	if s := r.Suppressed; s != nil {
+		fmt.Fprintf(w, "[scan] suppressed %d finding(s): %d by triage file", s.Total(), s.Triage)
+		if len(s.ByStatus) > 0 {
+			statuses := make([]string, 0, len(s.ByStatus))
+			for status, n := range s.ByStatus {
+				statuses = append(statuses, fmt.Sprintf("%s=%d", status, n))
+			}
+			sort.Strings(statuses)
+			fmt.Fprintf(w, " (%s)", strings.Join(statuses, " "))
+		}
+		fmt.Fprintf(w, ", %d by jsminer:ignore comment(s)\n", s.Inline)
+		if s.ExpiredEntries > 0 {
+			fmt.Fprintf(w, "[scan] %d triage decision(s) expired; their findings are reported again\n", s.ExpiredEntries)
+		}
+	}
 	if len(r.DOM) > 0 || r.DOMSummary != nil {
 		dom := append([]scan.DOMFinding(nil), r.DOM...)
 		scan.SortDOMFindings(dom)
@@ -358,6 +343,9 @@ func printDOMFinding(w io.Writer, f scan.DOMFinding) {
 		if f.URL.CanaryComponent != "" {
 			fmt.Fprintf(w, " marker=%s", f.URL.CanaryComponent)
 		}
+		if f.URL.Control != "" {
+			fmt.Fprintf(w, " control=%s", f.URL.Control)
+		}
 		if f.URL.InputKind != "" {
 			fmt.Fprintf(w, " input=%s", f.URL.InputKind)
 		}
@@ -365,6 +353,18 @@ func printDOMFinding(w io.Writer, f scan.DOMFinding) {
 			fmt.Fprint(w, " executable_scheme=true")
 		}
 	}
+	if f.CSP != nil {
+		fmt.Fprintf(w, "\n    csp=%s enforced=%t", f.CSP.Strength, f.CSP.Enforced)
+		if len(f.CSP.Delivery) > 0 {
+			fmt.Fprintf(w, " via=%s", strings.Join(f.CSP.Delivery, "+"))
+		}
+		if f.CSP.TrustedTypesRequired {
+			fmt.Fprint(w, " trusted_types=required")
+		}
+		if len(f.CSP.Issues) > 0 {
+			fmt.Fprintf(w, " issues=%s", strings.Join(f.CSP.Issues, "; "))
+		}
+	}
 	if loc := firstScriptLocation(f.Stack); loc != "" {
 		fmt.Fprintf(w, "\n    at=%s", loc)
 	}
@@ -13,7 +13,7 @@ import (
 func sampleReport() Report {
 	return Report{
 		Matches: []scan.Match{
-			{Source: "https://app.test/app.js", Pattern: "aws_key", Value: "AKIA...", Severity: scan.SeverityHigh},
+			{Source: "https://app.test/app.js", Pattern: "aws_key", Value: "AKIA...", Severity: scan.SeverityHigh, Secret: true},
 		},
 		DOM: []scan.DOMFinding{
 			{
@@ -78,6 +78,32 @@ func TestJSONLValidAndClean(t *testing.T) {
 	}
 }
 
+// TestJSONLSummaryPartial proves the scan_summary reports whether the scan was
+// cut short, so a consumer can tell an interrupted run from a clean one.
+func TestJSONLSummaryPartial(t *testing.T) {
+	for _, partial := range []bool{false, true} {
+		r := sampleReport()
+		r.Partial = partial
+		var buf bytes.Buffer
+		p := NewPrinter("jsonl", false, true, false, "0.01v")
+		if err := p.PrintReport(&buf, r); err != nil {
+			t.Fatalf("PrintReport: %v", err)
+		}
+		lines := splitNonEmpty(buf.String())
+		var summary map[string]any
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+			t.Fatalf("summary is not valid JSON: %v", err)
+		}
+		if got, ok := summary["partial"].(bool); !ok || got != partial {
+			t.Errorf("summary partial = %v, want %v", summary["partial"], partial)
+		}
+		// Partial findings are still emitted in full.
+		if len(lines) != 5 {
+			t.Errorf("partial=%v: expected 5 records, got %d", partial, len(lines))
+		}
+	}
+}
+
 // TestJSONWithDOMValid proves the json format carries DOM detail as a superset
 // document while staying valid JSON with no banner.
 func TestJSONWithDOMValid(t *testing.T) {
@@ -154,17 +180,21 @@ func TestPrettyWithDOMSection(t *testing.T) {
 	}
 }
 
-// TestMatchFingerprintStable proves identical findings hash identically and
-// differing ones do not.
-func TestMatchFingerprintStable(t *testing.T) {
-	a := scan.Match{Pattern: "p", Value: "v", Severity: "high", Source: "s"}
-	b := scan.Match{Pattern: "p", Value: "v", Severity: "high", Source: "s"}
-	if matchFingerprint(a) != matchFingerprint(b) {
-		t.Error("identical matches must share a fingerprint")
-	}
-	c := scan.Match{Pattern: "p", Value: "w", Severity: "high", Source: "s"}
-	if matchFingerprint(a) == matchFingerprint(c) {
-		t.Error("differing matches must not share a fingerprint")
+// TestPrettyDOMShowsCSPEvidence checks the CSP evidence line for a weak_csp
+// finding.
+func TestPrettyDOMShowsCSPEvidence(t *testing.T) {
+	r := Report{DOM: []scan.DOMFinding{{
+		Type: scan.DOMTypeWeakCSP, Target: "https://app.test", PageURL: "https://app.test/",
+		Severity: scan.SeverityLow, Confidence: scan.ConfidenceHigh, Fingerprint: "csp1",
+		CSP: &scan.DOMCSPEvidence{
+			Present: true, Enforced: true, Delivery: []string{"header"}, Strength: scan.CSPStrengthWeak,
+			Issues: []string{"script-src allows 'unsafe-inline'"},
+		},
+	}}}
+	var buf bytes.Buffer
+	NewPrinter("pretty", false, false, false, "0.01v").PrintReport(&buf, r)
+	if !strings.Contains(buf.String(), "csp=weak enforced=true via=header issues=script-src allows 'unsafe-inline'") {
+		t.Errorf("missing CSP evidence line:\n%s", buf.String())
 	}
 }
 
@@ -177,3 +207,51 @@ func splitNonEmpty(s string) []string {
 	}
 	return out
 }
+
+// TestSuppressedCountsInEveryFormat proves the suppression counts reach the
+// summary of each format, and that the json format only leaves its legacy
+// structure when there is something to report.
+func TestSuppressedCountsInEveryFormat(t *testing.T) {
+	r := Report{
+		Matches:  sampleReport().Matches,
+		ScanTime: sampleReport().ScanTime,
+		Suppressed: &scan.SuppressionSummary{
+			Inline: 2, Triage: 3, ByStatus: map[string]int{scan.TriageAcceptedRisk: 1, scan.TriageFalsePositive: 2},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewPrinter("jsonl", false, false, false, "0.01v").PrintReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	lines := splitNonEmpty(buf.String())
+	var summary struct {
+		Suppressed scan.SuppressionSummary `json:"suppressed"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Suppressed.Inline != 2 || summary.Suppressed.Triage != 3 || summary.Suppressed.ByStatus[scan.TriageFalsePositive] != 2 {
+		t.Errorf("jsonl summary suppressed = %+v", summary.Suppressed)
+	}
+
+	buf.Reset()
+	if err := NewPrinter("json", false, false, false, "0.01v").PrintReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var doc jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Suppressed == nil || doc.Suppressed.Total() != 5 {
+		t.Errorf("json suppressed = %+v", doc.Suppressed)
+	}
+
+	buf.Reset()
+	if err := NewPrinter("pretty", false, false, false, "0.01v").PrintReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "[scan] suppressed 5 finding(s): 3 by triage file (accepted_risk=1 false_positive=2), 2 by jsminer:ignore comment(s)") {
+		t.Errorf("pretty output missing the suppression line:\n%s", buf.String())
+	}
+}
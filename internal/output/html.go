@@ -0,0 +1,378 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// CrawlRun is the statistics of one crawled target, for reports that show how
+// much of the site a scan covered.
+type CrawlRun struct {
+	Target string
+	Stats  scan.CrawlStats
+}
+
+// htmlReport is the view of a Report the HTML template renders. Findings are
+// pre-sorted and snippets pre-highlighted so the template only lays them out.
+type htmlReport struct {
+	Version           string
+	ScanTime          string
+	Checksum          string
+	Partial           bool
+	Severities        []htmlCount
+	Total             int
+	Matches           []htmlMatch
+	Gathered          []htmlMatch
+	ShowSource        bool
+	ShowAssets        bool
+	DOM               []scan.DOMFinding
+	DOMSummary        *scan.DOMScanSummary
+	Reflections       []scan.ReflectionFinding
+	ReflectionSummary *scan.ReflectionScanSummary
+	Crawls            []CrawlRun
+	Suppressed        *scan.SuppressionSummary
+}
+
+type htmlCount struct {
+	Severity string
+	Count    int
+}
+
+type htmlMatch struct {
+	scan.Match
+	Params      string
+	Fingerprint string
+	Snippet     template.HTML
+}
+
+// snippetClasses maps the ANSI colors classify assigns to the CSS classes the
+// report styles.
+var snippetClasses = map[string]string{
+	colKeyword: "kw", colString: "str", colNumber: "num", colComment: "com", colLiteral: "lit",
+}
+
+// highlightSnippetHTML beautifies a raw source window as BeautifySnippet does
+// and marks it up for the HTML report: syntax classes from the same classifier
+// the terminal renderer uses, and the finding's value emphasised.
+func highlightSnippetHTML(raw, value string) template.HTML {
+	pretty := BeautifySnippet(raw)
+	if pretty == "" {
+		return ""
+	}
+	ms, me := -1, -1
+	if value != "" {
+		if idx := strings.Index(pretty, value); idx >= 0 {
+			ms, me = idx, idx+len(value)
+		}
+	}
+	colors := classify(pretty)
+	var b strings.Builder
+	for i := 0; i < len(pretty); {
+		class := snippetClasses[colors[i]]
+		emph := i >= ms && i < me
+		j := i + 1
+		for j < len(pretty) && snippetClasses[colors[j]] == class && (j >= ms && j < me) == emph {
+			j++
+		}
+		text := template.HTMLEscapeString(pretty[i:j])
+		switch {
+		case emph:
+			fmt.Fprintf(&b, `<mark>%s</mark>`, text)
+		case class != "":
+			fmt.Fprintf(&b, `<span class="%s">%s</span>`, class, text)
+		default:
+			b.WriteString(text)
+		}
+		i = j
+	}
+	return template.HTML(b.String())
+}
+
+// printHTML renders the report as one self-contained HTML page: styles and the
+// small filter/sort script are inline, so the file opens offline and can be
+// mailed as is.
+func (p *Printer) printHTML(w io.Writer, r Report) error {
+	scanTime := r.ScanTime
+	if scanTime.IsZero() {
+		scanTime = time.Now()
+	}
+	v := htmlReport{
+		Version:           p.version,
+		ScanTime:          scanTime.UTC().Format(time.RFC3339),
+		Checksum:          ResultsChecksum(r.Matches),
+		Partial:           r.Partial,
+		ShowSource:        p.showSource,
+		DOMSummary:        r.DOMSummary,
+		ReflectionSummary: r.ReflectionSummary,
+		Crawls:            r.Crawls,
+		Suppressed:        r.Suppressed,
+	}
+
+	findings, gathered := splitGathered(append([]scan.Match(nil), r.Matches...))
+	scan.SortBySeverity(findings)
+	ordered := append(findings, gathered...)
+	// Fingerprints identify the real findings; what is shown is masked.
+	shown := p.redact.Matches(ordered)
+	toHTML := func(from, to int) []htmlMatch {
+		out := make([]htmlMatch, 0, to-from)
+		for i := from; i < to; i++ {
+			m := shown[i]
+			hm := htmlMatch{Match: m, Params: normalizeParams(m.Params), Fingerprint: scan.MatchFingerprint(ordered[i])}
+			v.ShowAssets = v.ShowAssets || m.Asset != nil
+			if p.snippet && m.Snippet != "" {
+				hm.Snippet = highlightSnippetHTML(m.Snippet, m.Value)
+			}
+			out = append(out, hm)
+		}
+		return out
+	}
+	v.Matches = toHTML(0, len(findings))
+	v.Gathered = toHTML(len(findings), len(ordered))
+
+	v.DOM = append([]scan.DOMFinding(nil), r.DOM...)
+	scan.SortDOMFindings(v.DOM)
+	v.Reflections = append([]scan.ReflectionFinding(nil), r.Reflections...)
+	scan.SortReflectionFindings(v.Reflections)
+
+	bySev := make(map[string]int)
+	for _, m := range findings {
+		bySev[strings.ToLower(m.Severity)]++
+	}
+	for _, f := range v.DOM {
+		bySev[strings.ToLower(f.Severity)]++
+	}
+	for _, f := range v.Reflections {
+		bySev[strings.ToLower(f.Severity)]++
+	}
+	for _, sev := range []string{scan.SeverityHigh, scan.SeverityMedium, scan.SeverityLow, scan.SeverityInfo} {
+		v.Severities = append(v.Severities, htmlCount{Severity: sev, Count: bySev[sev]})
+		v.Total += bySev[sev]
+	}
+	return htmlTemplate.Execute(w, v)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"rank":  scan.SeverityRank,
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"ms":    func(d time.Duration) int64 { return d.Milliseconds() },
+	"statuses": func(m map[string]int) string {
+		out := make([]string, 0, len(m))
+		for status, n := range m {
+			out = append(out, fmt.Sprintf("%s=%d", status, n))
+		}
+		sort.Strings(out)
+		return strings.Join(out, ", ")
+	},
+	"tags": func(m map[string]string) string {
+		out := make([]string, 0, len(m))
+		for name, value := range m {
+			out = append(out, name+"="+value)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ", ")
+	},
+}).Parse(htmlReportTemplate))
+
+const htmlReportTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>JSMiner report — {{.ScanTime}}</title>
+<style>
+body{font:14px/1.45 system-ui,-apple-system,"Segoe UI",sans-serif;margin:0;color:#1f2328;background:#f6f8fa}
+header,section{max-width:1400px;margin:0 auto;padding:16px 24px}
+header{background:#24292f;color:#fff;max-width:none}
+header h1{margin:0 0 4px;font-size:20px}
+header .meta{color:#c9d1d9;font-size:12px;word-break:break-all}
+h2{font-size:17px;margin:24px 0 8px}
+.cards{display:flex;gap:12px;flex-wrap:wrap}
+.card{background:#fff;border:1px solid #d0d7de;border-radius:6px;padding:10px 16px;min-width:110px}
+.card b{display:block;font-size:24px}
+.notice{background:#fff8c5;border:1px solid #d4a72c;border-radius:6px;padding:8px 12px;margin-top:12px}
+.controls{display:flex;gap:8px;margin:8px 0}
+.controls input{flex:1;max-width:420px}
+input,select{font:inherit;padding:4px 8px;border:1px solid #d0d7de;border-radius:6px}
+table{width:100%;border-collapse:collapse;background:#fff;border:1px solid #d0d7de}
+th,td{text-align:left;vertical-align:top;padding:6px 8px;border-bottom:1px solid #eaeef2}
+th{background:#f6f8fa;cursor:pointer;user-select:none;white-space:nowrap}
+th[data-dir=asc]::after{content:" ▲"}th[data-dir=desc]::after{content:" ▼"}
+td.v{font-family:ui-monospace,SFMono-Regular,Menlo,monospace;word-break:break-all}
+.sev{display:inline-block;border-radius:10px;padding:0 8px;font-size:12px;font-weight:600;color:#fff;background:#6e7781}
+.sev-high{background:#cf222e}.sev-medium{background:#bc4c00}.sev-low{background:#9a6700}.sev-info{background:#0969da}
+pre{background:#0d1117;color:#c9d1d9;padding:8px;border-radius:6px;overflow:auto;font-size:12px;margin:6px 0 0}
+pre .kw{color:#ff7b72}pre .str{color:#a5d6ff}pre .num{color:#79c0ff}pre .com{color:#8b949e}pre .lit{color:#d2a8ff}
+pre mark{background:#e3b341;color:#0d1117;font-weight:700}
+details summary{cursor:pointer;color:#0969da}
+dl{display:grid;grid-template-columns:max-content 1fr;gap:2px 12px;margin:4px 0}dt{color:#57606a}dd{margin:0}
+.empty{color:#57606a;font-style:italic}
+</style>
+</head>
+<body>
+<header>
+<h1>JSMiner scan report</h1>
+<div class="meta">{{if .Version}}JSMiner {{.Version}} · {{end}}scan started {{.ScanTime}} · checksum {{.Checksum}}</div>
+</header>
+<section>
+<h2>Summary</h2>
+<div class="cards">
+<div class="card">total<b>{{.Total}}</b></div>
+{{- range .Severities}}
+<div class="card"><span class="sev sev-{{.Severity}}">{{.Severity}}</span><b>{{.Count}}</b></div>
+{{- end}}
+{{- if .Gathered}}
+<div class="card">gathered URLs<b>{{len .Gathered}}</b></div>
+{{- end}}
+</div>
+{{- if .Partial}}
+<div class="notice">The scan was interrupted or hit its runtime limit; these results cover only what was reached.</div>
+{{- end}}
+{{- with .Suppressed}}
+<div class="notice">{{.Total}} finding(s) suppressed: {{.Triage}} by triage file{{if .ByStatus}} ({{statuses .ByStatus}}){{end}}, {{.Inline}} by jsminer:ignore comment(s).{{if .ExpiredEntries}} {{.ExpiredEntries}} triage decision(s) expired; their findings are reported again.{{end}}</div>
+{{- end}}
+</section>
+
+<section>
+<h2>Findings ({{len .Matches}})</h2>
+{{- if .Matches}}
+<div class="controls"><input type="search" placeholder="Filter findings…" data-filter="matches"><select data-severity="matches"><option value="">all severities</option><option>high</option><option>medium</option><option>low</option><option>info</option></select></div>
+<table id="matches">
+<thead><tr><th>Severity</th><th>Confidence</th><th>Pattern</th><th>Value</th>{{if .ShowSource}}<th>Source</th>{{end}}{{if .ShowAssets}}<th>Asset</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Matches}}
+<tr data-severity="{{lower .Severity}}" title="fingerprint {{.Fingerprint}}"><td data-sort="{{rank .Severity}}"><span class="sev sev-{{lower .Severity}}">{{.Severity}}</span></td><td data-sort="{{.Confidence}}">{{if .Confidence}}{{.Confidence}}{{else}}–{{end}}</td><td>{{.Pattern}}</td><td class="v">{{.Value}}{{if .Params}}<div>params: {{.Params}}</div>{{end}}{{with .Library}}{{if .Vulnerabilities}}<div>{{range $i, $id := .VulnIDs}}{{if $i}}, {{end}}{{$id}}{{end}}{{with .FixedIn}} · fixed in {{.}}{{end}}</div>{{end}}{{end}}{{if .Snippet}}<details><summary>snippet</summary><pre>{{.Snippet}}</pre></details>{{end}}</td>{{if $.ShowSource}}<td class="v">{{.Source}}</td>{{end}}{{if $.ShowAssets}}<td class="v">{{with .Asset}}{{.Host}}{{if .Tags}}<div>{{tags .Tags}}</div>{{end}}{{if .IP}}<div>{{.IP}}{{if .ASN}} · AS{{.ASN}}{{with .ASOrg}} {{.}}{{end}}{{end}}</div>{{end}}{{with .Server}}<div>{{.}}</div>{{end}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- else}}
+<p class="empty">No findings.</p>
+{{- end}}
+</section>
+{{- if .Gathered}}
+
+<section>
+<h2>Gathered URLs ({{len .Gathered}})</h2>
+<div class="controls"><input type="search" placeholder="Filter URLs…" data-filter="gathered"></div>
+<table id="gathered">
+<thead><tr><th>URL</th><th>Details</th>{{if .ShowSource}}<th>Source</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Gathered}}
+<tr data-severity="{{lower .Severity}}"><td class="v">{{.Value}}</td><td class="v">{{.Params}}</td>{{if $.ShowSource}}<td class="v">{{.Source}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+</section>
+{{- end}}
+{{- if or .DOM .DOMSummary}}
+
+<section>
+<h2>DOM findings ({{len .DOM}})</h2>
+{{- with .DOMSummary}}
+<p>{{if .TimedOut}}Timed out{{else if .Partial}}Partial{{else}}Complete{{end}}: {{.PagesScanned}} page(s) scanned, {{.PagesFailed}} failed, {{.ProbesSent}} probe(s) sent (limit {{.ProbesLimit}}), mode {{.Mode}}, {{.DurationMS}} ms.{{if .SuppressedMessages}} {{.SuppressedMessages}} web-message chatter finding(s) suppressed.{{end}}{{if .SourceHints}} {{.SourceHints}} source hint(s), {{.HintProbesSent}} hint probe(s).{{end}}</p>
+{{- end}}
+{{- if .DOM}}
+<div class="controls"><input type="search" placeholder="Filter DOM findings…" data-filter="dom"><select data-severity="dom"><option value="">all severities</option><option>high</option><option>medium</option><option>low</option><option>info</option></select></div>
+<table id="dom">
+<thead><tr><th>Severity</th><th>Confidence</th><th>Type</th><th>Flow</th><th>Page</th><th>Evidence</th></tr></thead>
+<tbody>
+{{- range .DOM}}
+<tr data-severity="{{lower .Severity}}"><td data-sort="{{rank .Severity}}"><span class="sev sev-{{lower .Severity}}">{{.Severity}}</span></td><td>{{.Confidence}}{{if .Confirmed}} · confirmed{{end}}</td><td>{{.Type}}</td><td class="v">{{with .Source}}{{.Kind}}{{if .Name}}[{{.Name}}]{{end}} → {{end}}{{with .Sink}}{{.Name}}(arg {{.Argument}}){{end}}{{if .Context}}<div>context: {{.Context}}</div>{{end}}</td><td class="v">{{.PageURL}}{{if gt .SeenOnPages 1}}<div>+{{.SeenOnPages}} page(s)</div>{{end}}{{if .FrameURL}}<div>frame: {{.FrameURL}}</div>{{end}}</td><td>
+{{- if .ValuePreview}}<div class="v">value: {{.ValuePreview}}</div>{{end}}
+{{- with .Triage}}<div>triage: {{.Verdict}} — {{.Reason}}</div>{{end}}
+{{- with .URL}}<details><summary>URL evidence</summary><dl><dt>resolved</dt><dd>{{.Resolved}}</dd>{{if .Scheme}}<dt>scheme</dt><dd>{{.Scheme}}</dd>{{end}}{{if .DestinationOrigin}}<dt>destination</dt><dd>{{.DestinationOrigin}}</dd>{{end}}<dt>same origin</dt><dd>{{.SameOrigin}}</dd>{{if .CanaryComponent}}<dt>canary in</dt><dd>{{.CanaryComponent}}</dd>{{end}}{{if .InputKind}}<dt>input</dt><dd>{{.InputKind}}</dd>{{end}}<dt>executable scheme</dt><dd>{{.ExecutableScheme}}</dd><dt>canary leading</dt><dd>{{.CanaryLeading}}</dd>{{if .Control}}<dt>control</dt><dd>{{.Control}}</dd>{{end}}</dl></details>{{end}}
+{{- if .Stack}}<details><summary>stack ({{len .Stack}} frame(s))</summary><ol class="v">{{range .Stack}}<li>{{if .Function}}{{.Function}} {{end}}{{.URL}}{{if .Line}}:{{.Line}}:{{.Column}}{{end}}</li>{{end}}</ol></details>{{end}}
+{{- if .Notes}}<div>note: {{.Notes}}</div>{{end}}
+</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</section>
+{{- end}}
+{{- if or .Reflections .ReflectionSummary}}
+
+<section>
+<h2>Reflections ({{len .Reflections}})</h2>
+{{- with .ReflectionSummary}}
+<p>{{if .Partial}}Partial{{else}}Complete{{end}}: {{.URLsScanned}} URL(s) scanned, {{.URLsFailed}} failed, {{.ParamsTested}} parameter(s) tested, {{.ProbesSent}} probe(s) sent (limit {{.ProbesLimit}}), {{.DurationMS}} ms.{{if .SuppressedEchoes}} {{.SuppressedEchoes}} whole-query echo(es) suppressed.{{end}}</p>
+{{- end}}
+{{- if .Reflections}}
+<div class="controls"><input type="search" placeholder="Filter reflections…" data-filter="reflections"><select data-severity="reflections"><option value="">all severities</option><option>high</option><option>medium</option><option>low</option><option>info</option></select></div>
+<table id="reflections">
+<thead><tr><th>Severity</th><th>Confidence</th><th>Type</th><th>Parameter</th><th>Context</th><th>Page</th><th>Evidence</th></tr></thead>
+<tbody>
+{{- range .Reflections}}
+<tr data-severity="{{lower .Severity}}"><td data-sort="{{rank .Severity}}"><span class="sev sev-{{lower .Severity}}">{{.Severity}}</span></td><td>{{.Confidence}}</td><td>{{.Type}}</td><td class="v">{{.Method}} {{.Parameter}}</td><td>{{.Context}}</td><td class="v">{{.PageURL}}{{if gt .SeenOnRoutes 1}}<div>+{{.SeenOnRoutes}} route(s)</div>{{end}}</td><td>
+{{- if .Unfiltered}}<div>unfiltered: <span class="v">{{join .Unfiltered " "}}</span></div>{{end}}
+{{- if .Occurrences}}<div>occurrences: {{.Occurrences}}</div>{{end}}
+{{- if .ValuePreview}}<div class="v">value: {{.ValuePreview}}</div>{{end}}
+{{- if .DiscoveredBy}}<div>discovered by: {{join .DiscoveredBy ", "}}</div>{{end}}
+{{- with .Triage}}<div>triage: {{.Verdict}} — {{.Reason}}</div>{{end}}
+</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</section>
+{{- end}}
+{{- if .Crawls}}
+
+<section>
+<h2>Crawl statistics</h2>
+<table id="crawls">
+<thead><tr><th>Target</th><th>Pages fetched</th><th>Errors</th><th>Targets found</th><th>Enqueued</th><th>Matches</th><th>Passive</th><th>Permutations</th><th>Duration</th></tr></thead>
+<tbody>
+{{- range .Crawls}}
+<tr><td class="v">{{.Target}}{{if .Stats.Partial}} (partial){{end}}</td><td>{{.Stats.PagesFetched}}</td><td>{{.Stats.PagesErrored}}</td><td>{{.Stats.TargetsFound}}</td><td>{{.Stats.Enqueued}}</td><td>{{.Stats.Matches}}</td><td data-sort="{{.Stats.PassiveValidated}}">{{if .Stats.PassiveFound}}{{.Stats.PassiveValidated}}/{{.Stats.PassiveFound}} validated{{else}}–{{end}}</td><td data-sort="{{.Stats.PermuteFetched}}">{{if .Stats.PermuteConsidered}}{{.Stats.PermuteEnqueued}}/{{.Stats.PermuteConsidered}} enqueued, {{.Stats.PermuteYielded}} yielded{{else}}–{{end}}</td><td data-sort="{{ms .Stats.Duration}}">{{.Stats.Duration}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</section>
+{{- end}}
+
+<script>
+(function(){
+  function apply(id){
+    var t=document.getElementById(id);if(!t)return;
+    var q=document.querySelector('[data-filter="'+id+'"]'),s=document.querySelector('[data-severity="'+id+'"]');
+    var text=q?q.value.toLowerCase():'',sev=s?s.value:'';
+    t.tBodies[0].querySelectorAll('tr').forEach(function(r){
+      r.hidden=(text&&r.textContent.toLowerCase().indexOf(text)<0)||(sev&&r.getAttribute('data-severity')!==sev);
+    });
+  }
+  document.querySelectorAll('[data-filter],[data-severity]').forEach(function(el){
+    if(el.tagName==='TR')return;
+    var id=el.getAttribute('data-filter')||el.getAttribute('data-severity');
+    el.addEventListener('input',function(){apply(id)});
+  });
+  function key(cell){
+    var v=cell.getAttribute('data-sort');if(v===null)v=cell.textContent.trim();
+    var n=parseFloat(v);return isNaN(n)?v.toLowerCase():n;
+  }
+  document.querySelectorAll('th').forEach(function(th){
+    th.addEventListener('click',function(){
+      var table=th.closest('table'),col=th.cellIndex,body=table.tBodies[0];
+      var dir=th.getAttribute('data-dir')==='desc'?'asc':'desc';
+      table.querySelectorAll('th').forEach(function(h){h.removeAttribute('data-dir')});
+      th.setAttribute('data-dir',dir);
+      var rows=Array.prototype.slice.call(body.rows);
+      rows.sort(function(a,b){
+        var x=key(a.cells[col]),y=key(b.cells[col]);
+        var c=typeof x===typeof y?(x<y?-1:x>y?1:0):(typeof x==='number'?-1:1);
+        return dir==='asc'?c:-c;
+      });
+      rows.forEach(function(r){body.appendChild(r)});
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+func TestPrintReportHTMLIsSelfContained(t *testing.T) {
+	r := sampleReport()
+	r.Matches = append(r.Matches,
+		scan.Match{Source: "https://app.test/app.js", Pattern: "stripe_key", Value: `sk_live_<script>alert(1)</script>`, Severity: scan.SeverityHigh, Confidence: 88,
+			Snippet: `var a=1;const k="sk_live_<script>alert(1)</script>";`,
+			Asset:   &scan.Asset{Host: "app.test", IP: "192.0.2.7", ASN: 64500, ASOrg: "EXAMPLE-NET", Tags: map[string]string{"team": "payments"}}},
+		scan.Match{Source: "https://app.test/app.js", Pattern: scan.GatheredURLPattern, Value: "https://api.app.test/v1/users", Severity: scan.SeverityInfo},
+	)
+	r.DOM[0].Stack = []scan.DOMStackFrame{{Function: "render", URL: "https://app.test/main.js", Line: 12, Column: 7}}
+	r.DOM[0].URL = &scan.DOMURLEvidence{Resolved: true, Scheme: "javascript", ExecutableScheme: true}
+	r.Reflections = []scan.ReflectionFinding{{
+		Type: "reflection", Target: "https://app.test", PageURL: "https://app.test/search?q=x", Parameter: "q",
+		Method: "GET", Context: "html_text", Unfiltered: []string{"<", ">"}, Severity: scan.SeverityMedium,
+	}}
+	r.Crawls = []CrawlRun{{Target: "https://app.test", Stats: scan.CrawlStats{PagesFetched: 42, Duration: 3 * time.Second}}}
+
+	var buf bytes.Buffer
+	if err := NewPrinter("html", false, true, true, "v1.2.3").PrintReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "<!DOCTYPE html>") {
+		t.Fatalf("report does not start with a doctype: %.60q", out)
+	}
+	// Nothing may load from the network: the file must open offline.
+	if ext := regexp.MustCompile(`(?i)<(script|link|img)[^>]+(src|href)=`).FindString(out); ext != "" {
+		t.Errorf("report references an external asset: %s", ext)
+	}
+	for _, want := range []string{
+		"Findings (2)", "Gathered URLs (1)", "DOM findings (2)", "Reflections (1)", "Crawl statistics",
+		"https://api.app.test/v1/users", "render https://app.test/main.js:12:7", "URL evidence",
+		"<td>42</td>", "v1.2.3", `<span class="kw">`, "<mark>",
+		"<th>Asset</th>", "<div>team=payments</div>", "192.0.2.7 · AS64500 EXAMPLE-NET",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>alert(1)") {
+		t.Error("finding value was not escaped")
+	}
+}
+
+func TestHighlightSnippetHTMLEscapes(t *testing.T) {
+	got := string(highlightSnippetHTML(`if(a<b){x="tok_1"}`, "tok_1"))
+	if !strings.Contains(got, "&lt;") || strings.Contains(got, "a<b") {
+		t.Errorf("snippet markup not escaped: %s", got)
+	}
+	if !strings.Contains(got, "<mark>tok_1</mark>") {
+		t.Errorf("value not emphasised: %s", got)
+	}
+}
@@ -20,16 +20,21 @@ type Printer struct {
 	showSource    bool
 	snippet       bool
 	version       string
+	failOn        string
+	redact        *Redactor
 	printedBanner bool
 }
 
 type outMatch struct {
-	Source   string `json:"source,omitempty"`
-	Pattern  string `json:"pattern"`
-	Value    string `json:"value"`
-	Params   string `json:"params,omitempty"`
-	Severity string `json:"severity"`
-	Snippet  string `json:"snippet,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Pattern    string            `json:"pattern"`
+	Value      string            `json:"value"`
+	Params     string            `json:"params,omitempty"`
+	Severity   string            `json:"severity"`
+	Confidence int               `json:"confidence,omitempty"`
+	Snippet    string            `json:"snippet,omitempty"`
+	Asset      *scan.Asset       `json:"asset,omitempty"`
+	Library    *scan.LibraryInfo `json:"library,omitempty"`
 }
 
 type scanOutput struct {
@@ -121,6 +126,9 @@ func (p *Printer) PrintScan(w io.Writer, matches []scan.Match, scanTime time.Tim
 	}
 	scanTimeText := scanTime.UTC().Format(time.RFC3339Nano)
 	checksum := ResultsChecksum(matches)
+	// The checksum identifies the real results; everything shown below it is
+	// masked.
+	matches = p.redact.Matches(matches)
 
 	// Gathered-URL findings are shown as their own segment beneath the normal
 	// JavaScript findings, so split them out while preserving relative order.
@@ -172,7 +180,7 @@ func (p *Printer) PrintScan(w io.Writer, matches []scan.Match, scanTime time.Tim
 			}
 			params = strings.TrimSpace(params)
 		}
-		om := outMatch{Pattern: m.Pattern, Value: m.Value, Params: params, Severity: m.Severity}
+		om := outMatch{Pattern: m.Pattern, Value: m.Value, Params: params, Severity: m.Severity, Confidence: m.Confidence, Asset: m.Asset, Library: m.Library}
 		if p.showSource {
 			om.Source = m.Source
 		}
@@ -192,10 +200,14 @@ func (p *Printer) PrintScan(w io.Writer, matches []scan.Match, scanTime time.Tim
 // printPretty renders a single match in the pretty (human) format, including its
 // parameters and, when enabled, a source-code snippet.
 func (p *Printer) printPretty(w io.Writer, m scan.Match, useColor bool) {
+	rank := m.Severity
+	if m.Confidence > 0 {
+		rank = fmt.Sprintf("%s, confidence %d", m.Severity, m.Confidence)
+	}
 	if p.showSource {
-		fmt.Fprintf(w, "%s: [%s] (%s) %s", m.Source, m.Pattern, m.Severity, m.Value)
+		fmt.Fprintf(w, "%s: [%s] (%s) %s", m.Source, m.Pattern, rank, m.Value)
 	} else {
-		fmt.Fprintf(w, "[%s] (%s) %s", m.Pattern, m.Severity, m.Value)
+		fmt.Fprintf(w, "[%s] (%s) %s", m.Pattern, rank, m.Value)
 	}
 	if m.Params != "" {
 		params := m.Params
@@ -212,6 +224,12 @@ func (p *Printer) printPretty(w io.Writer, m scan.Match, useColor bool) {
 		}
 		fmt.Fprintf(w, " params=%s", strings.TrimSpace(params))
 	}
+	if ids := m.Library.VulnIDs(); len(ids) > 0 {
+		fmt.Fprintf(w, " vulns=%s", strings.Join(ids, ","))
+		if fixed := m.Library.FixedIn(); fixed != "" {
+			fmt.Fprintf(w, " fixed_in=%s", fixed)
+		}
+	}
 	fmt.Fprintln(w)
 	if p.snippet && m.Snippet != "" {
 		fmt.Fprint(w, RenderSnippet(m.Snippet, m.Value, useColor))
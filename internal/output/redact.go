@@ -0,0 +1,142 @@
+package output
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// Redaction modes for secret values in output.
+const (
+	// RedactNone prints values in full.
+	RedactNone = "none"
+	// RedactPartial keeps the first and last four characters of a value; a
+	// value too short to hide at least eight is shown as the mask alone.
+	RedactPartial = "partial"
+	// RedactHash replaces a value with a salted SHA-256 digest, so the same
+	// secret can be recognised across findings without being shown.
+	RedactHash = "hash"
+)
+
+// redactMask stands in for the hidden part of a partially redacted value.
+const redactMask = "****"
+
+// redactPartialKeep is how many characters partial redaction shows on each
+// side, and redactPartialMin the shortest value it shows them for.
+const (
+	redactPartialKeep = 4
+	redactPartialMin  = 2*redactPartialKeep + 8
+)
+
+// Redactor masks candidate secret values for display. Only the rendering is
+// masked: fingerprints and the results checksum are computed from the real
+// values, so baselines and triage files line up whatever the mode.
+type Redactor struct {
+	mode string
+	salt string
+}
+
+// NewRedactor returns a Redactor for mode. salt keys the hash mode; when it is
+// empty a random one is drawn, so digests correlate findings within one run
+// only.
+func NewRedactor(mode, salt string) (*Redactor, error) {
+	switch mode {
+	case "", RedactNone:
+		mode = RedactNone
+	case RedactPartial:
+	case RedactHash:
+		if salt == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return nil, err
+			}
+			salt = hex.EncodeToString(b)
+		}
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q (want none, partial or hash)", mode)
+	}
+	return &Redactor{mode: mode, salt: salt}, nil
+}
+
+// Value masks v according to the mode.
+func (r *Redactor) Value(v string) string {
+	if r == nil || v == "" {
+		return v
+	}
+	switch r.mode {
+	case RedactPartial:
+		runes := []rune(v)
+		// Short values would be mostly revealed by four characters a side.
+		if len(runes) < redactPartialMin {
+			return redactMask
+		}
+		return string(runes[:redactPartialKeep]) + redactMask + string(runes[len(runes)-redactPartialKeep:])
+	case RedactHash:
+		sum := sha256.Sum256([]byte(r.salt + "\x00" + v))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return v
+}
+
+// Match returns m with its secret masked in its value and its snippet.
+func (r *Redactor) Match(m scan.Match) scan.Match {
+	return r.Matches([]scan.Match{m})[0]
+}
+
+// Matches returns copies of ms, in order, with every candidate secret masked:
+// in its own value, and in every snippet, so a secret never shows through the
+// source excerpt of a neighbouring finding. Only the credential of a
+// "api_key: value" match is masked, so one secret reads the same whichever
+// rule reported it. Matches not marked Secret — endpoints, gathered URLs,
+// emails, GraphQL endpoints and the like — keep their values.
+func (r *Redactor) Matches(ms []scan.Match) []scan.Match {
+	if r == nil || r.mode == RedactNone {
+		return ms
+	}
+	var pairs []string
+	seen := make(map[string]bool)
+	for _, m := range ms {
+		if m.Value == "" || !m.Secret {
+			continue
+		}
+		if secret := scan.SecretValue(m.Value); !seen[secret] {
+			seen[secret] = true
+			pairs = append(pairs, secret, r.Value(secret))
+		}
+	}
+	if len(pairs) == 0 {
+		return ms
+	}
+	// At each position the replacer takes the first secret listed that
+	// matches, so the longer of two overlapping secrets must come first.
+	idx := make([]int, len(pairs)/2)
+	for i := range idx {
+		idx[i] = i * 2
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return len(pairs[idx[a]]) > len(pairs[idx[b]]) })
+	ordered := make([]string, 0, len(pairs))
+	for _, i := range idx {
+		ordered = append(ordered, pairs[i], pairs[i+1])
+	}
+	mask := strings.NewReplacer(ordered...)
+
+	out := make([]scan.Match, len(ms))
+	for i, m := range ms {
+		if m.Snippet != "" {
+			m.Snippet = mask.Replace(m.Snippet)
+		}
+		if m.Value != "" && m.Secret {
+			m.Value = mask.Replace(m.Value)
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// SetRedactor makes the printer mask secret values with r in every format.
+func (p *Printer) SetRedactor(r *Redactor) { p.redact = r }
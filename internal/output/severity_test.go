@@ -74,3 +74,60 @@ func TestPrintRanksBySeverityPretty(t *testing.T) {
 		t.Fatalf("high finding should print before info finding:\n%s", out)
 	}
 }
+
+// TestPrintShowsConfidence verifies a scored match carries its confidence in
+// every format, while an unscored one prints exactly as before.
+func TestPrintShowsConfidence(t *testing.T) {
+	matches := []scan.Match{
+		{Pattern: "github_token", Value: "ghp_x", Severity: scan.SeverityHigh, Confidence: 87},
+		{Pattern: "endpoint_path", Value: "/api", Severity: scan.SeverityInfo},
+	}
+	var pretty bytes.Buffer
+	if err := NewPrinter("pretty", false, false, false, "test").Print(&pretty, matches); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[github_token] (high, confidence 87) ghp_x", "[endpoint_path] (info) /api"} {
+		if !strings.Contains(pretty.String(), want) {
+			t.Errorf("pretty output missing %q:\n%s", want, pretty.String())
+		}
+	}
+
+	for _, format := range []string{"json", "jsonl"} {
+		var buf bytes.Buffer
+		if err := NewPrinter(format, false, false, false, "test").Print(&buf, matches); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"confidence":87`) {
+			t.Errorf("%s output missing the confidence: %s", format, buf.String())
+		}
+		if strings.Count(buf.String(), `"confidence"`) != 1 {
+			t.Errorf("%s output scored the unscored endpoint: %s", format, buf.String())
+		}
+	}
+}
+
+func TestPrintShowsLibraryVulnerabilities(t *testing.T) {
+	matches := []scan.Match{{
+		Pattern: scan.VulnerableLibraryPattern, Value: "jquery@1.8.3", Severity: scan.SeverityMedium,
+		Library: &scan.LibraryInfo{Name: "jquery", Version: "1.8.3", Detection: scan.DetectionBanner, Vulnerabilities: []scan.LibraryVuln{
+			{CVEs: []string{"CVE-2012-6708"}, Severity: scan.SeverityMedium, FixedIn: "1.9.0b1"},
+			{CVEs: []string{"CVE-2020-11022"}, Severity: scan.SeverityMedium, FixedIn: "3.5.0"},
+		}},
+	}}
+	var pretty bytes.Buffer
+	if err := NewPrinter("pretty", false, false, false, "test").Print(&pretty, matches); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[vulnerable_library] (medium) jquery@1.8.3 vulns=CVE-2012-6708,CVE-2020-11022 fixed_in=3.5.0"; !strings.Contains(pretty.String(), want) {
+		t.Errorf("pretty output missing %q:\n%s", want, pretty.String())
+	}
+	for _, format := range []string{"json", "jsonl"} {
+		var buf bytes.Buffer
+		if err := NewPrinter(format, false, false, false, "test").Print(&buf, matches); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"library":{"name":"jquery","version":"1.8.3","detection":"banner","vulnerabilities":[{"cves":["CVE-2012-6708"]`) {
+			t.Errorf("%s output missing the library: %s", format, buf.String())
+		}
+	}
+}
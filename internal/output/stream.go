@@ -0,0 +1,167 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// JSONLStream writes the jsonl format incrementally: the scan_meta record when
+// it is opened, each finding as the scanner hands it over, and the
+// scan_summary when it is closed. It lets a long crawl report findings while
+// it is still running instead of only when it returns. A JSONLStream is safe
+// for concurrent use.
+type JSONLStream struct {
+	p *Printer
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+	// Identities already written, so Close only adds what the stream missed.
+	matches map[string]struct{}
+	dom     map[string]struct{}
+	refl    map[string]struct{}
+	// valueRank is the highest severity written for each match value, mirroring
+	// scan.DedupMatchesByValueKeepSeverity as far as a stream can.
+	valueRank map[string]int
+}
+
+// StartJSONL opens a jsonl stream on w and writes its leading scan_meta record.
+// scanTime is when the scan began; the zero time means now.
+func (p *Printer) StartJSONL(w io.Writer, scanTime time.Time) (*JSONLStream, error) {
+	if scanTime.IsZero() {
+		scanTime = time.Now()
+	}
+	s := &JSONLStream{
+		p:         p,
+		enc:       json.NewEncoder(w),
+		matches:   make(map[string]struct{}),
+		dom:       make(map[string]struct{}),
+		refl:      make(map[string]struct{}),
+		valueRank: make(map[string]int),
+	}
+	meta := map[string]any{
+		"type":           recordTypeMeta,
+		"schema_version": JSONLSchemaVersion,
+		"scan_time":      scanTime.UTC().Format(time.RFC3339Nano),
+	}
+	if err := s.enc.Encode(meta); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Match writes m unless the stream already holds it, or already holds its
+// value at a higher severity.
+func (s *JSONLStream) Match(m scan.Match) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeMatch(m)
+}
+
+// DOMFinding writes f unless a finding with its fingerprint was written.
+func (s *JSONLStream) DOMFinding(f scan.DOMFinding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeDOM(f)
+}
+
+// ReflectionFinding writes f unless a finding with its fingerprint was written.
+func (s *JSONLStream) ReflectionFinding(f scan.ReflectionFinding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeReflection(f)
+}
+
+// Close writes, in severity order, the findings in r the stream has not
+// already carried, then the scan_summary describing r. r is the final result,
+// so the summary counts it rather than what was streamed: a value streamed at
+// a low severity before a stronger rule matched it is not counted. Close
+// returns the first write error the stream met.
+func (s *JSONLStream) Close(r Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := append([]scan.Match(nil), r.Matches...)
+	scan.SortBySeverity(matches)
+	for _, m := range matches {
+		s.writeMatch(m)
+	}
+	dom := append([]scan.DOMFinding(nil), r.DOM...)
+	scan.SortDOMFindings(dom)
+	for _, f := range dom {
+		s.writeDOM(f)
+	}
+	refl := append([]scan.ReflectionFinding(nil), r.Reflections...)
+	scan.SortReflectionFindings(refl)
+	for _, f := range refl {
+		s.writeReflection(f)
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return s.enc.Encode(s.p.summaryRecord(r, matches, dom, refl))
+}
+
+func (s *JSONLStream) writeMatch(m scan.Match) {
+	key := m.Pattern + "|" + m.Value + "|" + m.Params
+	if _, ok := s.matches[key]; ok {
+		return
+	}
+	rank := scan.SeverityRank(m.Severity)
+	if m.Value != "" && s.valueRank[m.Value] > rank {
+		return
+	}
+	s.matches[key] = struct{}{}
+	if m.Value != "" {
+		s.valueRank[m.Value] = rank
+	}
+	rec := jsonlMatch{
+		Type:        recordTypeMatch,
+		Pattern:     m.Pattern,
+		Value:       s.p.redact.Match(m).Value,
+		Params:      normalizeParams(m.Params),
+		Severity:    m.Severity,
+		Confidence:  m.Confidence,
+		Fingerprint: scan.MatchFingerprint(m),
+		Asset:       m.Asset,
+		Library:     m.Library,
+	}
+	if s.p.showSource {
+		rec.Source = m.Source
+	}
+	s.encode(rec)
+}
+
+func (s *JSONLStream) writeDOM(f scan.DOMFinding) {
+	if f.Fingerprint == "" {
+		f.Fingerprint = domFindingFingerprint(f)
+	}
+	if _, ok := s.dom[f.Fingerprint]; ok {
+		return
+	}
+	s.dom[f.Fingerprint] = struct{}{}
+	s.encode(f)
+}
+
+func (s *JSONLStream) writeReflection(f scan.ReflectionFinding) {
+	// Reflection findings carry their own "reflection" type, so each line is a
+	// self-describing NDJSON record alongside the match and DOM records.
+	if _, ok := s.refl[f.Fingerprint]; ok && f.Fingerprint != "" {
+		return
+	}
+	s.refl[f.Fingerprint] = struct{}{}
+	s.encode(f)
+}
+
+// encode writes one record, remembering the first error so later writes are
+// skipped and Close can report it.
+func (s *JSONLStream) encode(v any) {
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.Encode(v)
+}
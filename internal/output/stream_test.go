@@ -0,0 +1,56 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// TestJSONLStreamWritesAsFound proves findings reach the writer before the
+// stream closes, that Close adds only what the stream has not carried, and
+// that the summary closing it describes the final report.
+func TestJSONLStreamWritesAsFound(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinter("jsonl", false, true, false, "0.01v")
+	s, err := p.StartJSONL(&buf, sampleReport().ScanTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := sampleReport()
+	s.Match(r.Matches[0])
+	s.DOMFinding(r.DOM[0])
+	if got := len(splitNonEmpty(buf.String())); got != 3 {
+		t.Fatalf("expected meta + 2 streamed records before close, got %d:\n%s", got, buf.String())
+	}
+
+	// Repeats are dropped, as is a value already streamed at a higher severity.
+	s.Match(r.Matches[0])
+	s.DOMFinding(r.DOM[0])
+	s.Match(scan.Match{Pattern: "generic_secret", Value: r.Matches[0].Value, Severity: scan.SeverityLow})
+	if got := len(splitNonEmpty(buf.String())); got != 3 {
+		t.Fatalf("duplicates were streamed:\n%s", buf.String())
+	}
+
+	if err := s.Close(r); err != nil {
+		t.Fatal(err)
+	}
+	lines := splitNonEmpty(buf.String())
+	// meta + 1 match + 2 dom + summary: the second DOM finding came from Close.
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 records, got %d:\n%s", len(lines), buf.String())
+	}
+	var meta, summary map[string]any
+	_ = json.Unmarshal([]byte(lines[0]), &meta)
+	_ = json.Unmarshal([]byte(lines[len(lines)-1]), &summary)
+	if meta["type"] != recordTypeMeta {
+		t.Errorf("first record must be scan_meta, got %v", meta["type"])
+	}
+	if summary["type"] != recordTypeSummary || summary["checksum"] != ResultsChecksum(r.Matches) {
+		t.Errorf("summary must close the stream with the results checksum, got %v", summary)
+	}
+	if summary["total_findings"] != float64(3) {
+		t.Errorf("summary total_findings = %v, want 3", summary["total_findings"])
+	}
+}
@@ -33,25 +33,40 @@ func scanPrefix(body io.ReadCloser, limit int64) ([]byte, io.ReadCloser, error)
 // Run starts an HTTP proxy server that scans all HTTP responses using the
 // provided Extractor. Matches are printed live with the given Printer.
 func Run(ctx context.Context, addr string, ext *scan.Extractor, printer *output.Printer, out io.Writer, endpoints bool) error {
+	return RunReporting(ctx, addr, ext, endpoints, func(ms []scan.Match, startedAt time.Time) error {
+		return printer.PrintScan(out, ms, startedAt)
+	})
+}
+
+// Reporter receives the matches of one proxied response and the time its scan
+// started. Calls are serialized.
+type Reporter func(ms []scan.Match, startedAt time.Time) error
+
+// RunReporting is Run with the matches of each response handed to report
+// instead of one printer, so they can be filtered or written to several
+// destinations.
+func RunReporting(ctx context.Context, addr string, ext *scan.Extractor, endpoints bool, report Reporter) error {
 	prx := goproxy.NewProxyHttpServer()
 	prx.Verbose = false
 	// Enable MITM for HTTPS so response bodies can be inspected.
 	prx.OnRequest().HandleConnect(goproxy.AlwaysMitm)
 
 	// goproxy serves each proxied connection in its own goroutine, so this
-	// OnResponse handler runs concurrently for simultaneous requests. The Printer
+	// OnResponse handler runs concurrently for simultaneous requests. A Printer
 	// is stateful (it tracks whether the banner was emitted) and issues several
 	// writes per match, so concurrent Print calls would race on that state and
-	// interleave each other's output on the shared writer. Serialize the print so
-	// each result block is emitted atomically. Scanning itself is left outside the
+	// interleave each other's output on the shared writer. Serialize the report
+	// so each result block is emitted atomically. Scanning itself is left outside the
 	// lock: the Extractor is safe for concurrent read use, so responses are still
 	// scanned in parallel.
 	var printMu sync.Mutex
+	metrics := ext.Client().Config().Metrics
 
-	prx.OnResponse().DoFunc(func(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+	prx.OnResponse().DoFunc(func(resp *http.Response, _ *goproxy.ProxyCtx) *http.Response {
 		if resp == nil || resp.Body == nil || resp.Request == nil {
 			return resp
 		}
+		ext.Client().ObserveOrigin(resp)
 		scanData, replayedBody, err := scanPrefix(resp.Body, scan.MaxResponseBodyBytes)
 		resp.Body = replayedBody
 		if err != nil {
@@ -59,13 +74,18 @@ func Run(ctx context.Context, addr string, ext *scan.Extractor, printer *output.
 		}
 
 		scanStartedAt := time.Now().UTC()
-		ms, err := ext.ScanReaderWithEndpoints(resp.Request.URL.String(), bytes.NewReader(scanData))
+		ms, err := ext.ScanReaderWithEndpoints(ctx, resp.Request.URL.String(), bytes.NewReader(scanData))
+		metrics.ProxyResponseScanned()
 		if err == nil {
+			// Each response is scanned on its own, so a value repeated across
+			// responses counts once per response.
+			metrics.ObserveMatches(scan.UniqueMatches(ms)...)
 			if endpoints {
 				ms = scan.FilterEndpointMatches(ms)
 			}
+			ms = ext.Enricher().Matches(ctx, ms)
 			printMu.Lock()
-			err := printer.PrintScan(out, ms, scanStartedAt)
+			err := report(ms, scanStartedAt)
 			printMu.Unlock()
 			if err != nil {
 				log.Printf("printer error: %v", err)
@@ -151,3 +151,54 @@ func TestProxyConcurrentResponses(t *testing.T) {
 		t.Fatalf("proxy run error: %v", err)
 	}
 }
+
+// TestProxyRunReportingHandsOverMatches checks RunReporting passes each
+// response's matches to the reporter rather than printing them itself.
+func TestProxyRunReportingHandsOverMatches(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "token eyJabc.def.ghi")
+	}))
+	defer backend.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to get listener: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	var mu sync.Mutex
+	var got []scan.Match
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunReporting(ctx, addr, scan.NewExtractor(false, false), false, func(ms []scan.Match, _ time.Time) error {
+			mu.Lock()
+			got = append(got, ms...)
+			mu.Unlock()
+			return nil
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	proxyURL, _ := url.Parse("http://" + addr)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Get(backend.URL)
+	if err != nil {
+		t.Fatalf("client get failed: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("proxy run error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, m := range got {
+		if m.Pattern == "jwt" {
+			return
+		}
+	}
+	t.Fatalf("reporter got no jwt match: %+v", got)
+}
@@ -0,0 +1,260 @@
+package scan
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ASNDB maps IP addresses to the autonomous system announcing them, from an
+// offline IP-to-ASN table. It is safe for concurrent use once loaded.
+type ASNDB struct {
+	ranges []asnRange
+}
+
+type asnRange struct {
+	start, end netip.Addr
+	asn        int
+	country    string
+	org        string
+}
+
+// ASNInfo is what an ASNDB knows about one address.
+type ASNInfo struct {
+	ASN     int
+	Org     string
+	Country string
+}
+
+// LoadASNDB reads an IP-to-ASN table in the iptoasn.com TSV layout, plain or
+// gzip-compressed:
+//
+//	range_start	range_end	AS_number	country_code	AS_description
+//
+// IPv4 and IPv6 ranges may be mixed. Blank lines and lines starting with #
+// are skipped, as are ranges announced by no AS (AS_number 0).
+func LoadASNDB(file string) (*ASNDB, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(strings.ToLower(file), ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	db, err := ReadASNDB(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return db, nil
+}
+
+// ReadASNDB reads an IP-to-ASN table from r; see LoadASNDB for the format.
+func ReadASNDB(r io.Reader) (*ASNDB, error) {
+	db := &ASNDB{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimRight(sc.Text(), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, "\t", 5)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: want range_start, range_end and AS_number separated by tabs", line)
+		}
+		start, err1 := netip.ParseAddr(fields[0])
+		end, err2 := netip.ParseAddr(fields[1])
+		asn, err3 := strconv.Atoi(fields[2])
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: bad range %s-%s", line, start, end)
+		}
+		if asn == 0 {
+			continue
+		}
+		rg := asnRange{start: start.Unmap(), end: end.Unmap(), asn: asn}
+		if len(fields) > 3 && fields[3] != "None" {
+			rg.country = fields[3]
+		}
+		if len(fields) > 4 && fields[4] != "Not routed" {
+			rg.org = fields[4]
+		}
+		db.ranges = append(db.ranges, rg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(db.ranges, func(i, j int) bool { return db.ranges[i].start.Less(db.ranges[j].start) })
+	return db, nil
+}
+
+// Len returns the number of ranges loaded.
+func (db *ASNDB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
+
+// Lookup returns the AS announcing ip. Ranges are expected not to overlap, as
+// in the published tables; with overlaps the one starting closest below ip
+// wins.
+func (db *ASNDB) Lookup(ip string) (ASNInfo, bool) {
+	if db == nil {
+		return ASNInfo{}, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ASNInfo{}, false
+	}
+	addr = addr.Unmap()
+	// The last range starting at or below addr.
+	i := sort.Search(len(db.ranges), func(i int) bool { return addr.Less(db.ranges[i].start) }) - 1
+	if i < 0 {
+		return ASNInfo{}, false
+	}
+	rg := db.ranges[i]
+	if rg.start.Is4() != addr.Is4() || rg.end.Less(addr) {
+		return ASNInfo{}, false
+	}
+	return ASNInfo{ASN: rg.asn, Org: rg.org, Country: rg.country}, true
+}
+
+// AssetTags maps hosts to user-supplied tags such as the owning team, from a
+// CSV file. It is safe for concurrent use once loaded.
+type AssetTags struct {
+	names []string
+	rules []assetTagRule
+}
+
+type assetTagRule struct {
+	pattern string
+	tags    map[string]string
+}
+
+// LoadAssetTags reads a CSV mapping hosts to tags. The header row names the
+// columns: the first is the host and every other one a tag, for example
+//
+//	host,team,owner
+//	api.example.com,payments,alice@example.com
+//	*.cdn.example.com,platform,
+//
+// A host may be a path.Match glob; "*.example.com" also covers example.com
+// itself. An exact host wins over a glob, and a longer glob over a shorter one.
+// Empty cells leave that tag unset.
+func LoadAssetTags(file string) (*AssetTags, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := ReadAssetTags(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return t, nil
+}
+
+// ReadAssetTags reads an asset tag CSV from r; see LoadAssetTags.
+func ReadAssetTags(r io.Reader) (*AssetTags, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file; want a header row such as host,team,owner")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) < 2 {
+		return nil, errors.New("header needs a host column and at least one tag column")
+	}
+	t := &AssetTags{names: make([]string, len(header)-1)}
+	for i, h := range header[1:] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			return nil, fmt.Errorf("header column %d has no name", i+2)
+		}
+		t.names[i] = h
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		pattern := strings.ToLower(strings.TrimSpace(rec[0]))
+		if pattern == "" {
+			return nil, fmt.Errorf("line %d: empty host", line)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: bad host pattern %q: %w", line, pattern, err)
+		}
+		rule := assetTagRule{pattern: pattern, tags: make(map[string]string)}
+		for i, v := range rec[1:] {
+			if v = strings.TrimSpace(v); v != "" {
+				rule.tags[t.names[i]] = v
+			}
+		}
+		t.rules = append(t.rules, rule)
+	}
+	// Most specific first: exact hosts, then longer globs.
+	sort.SliceStable(t.rules, func(i, j int) bool {
+		gi, gj := isGlob(t.rules[i].pattern), isGlob(t.rules[j].pattern)
+		if gi != gj {
+			return !gi
+		}
+		return len(t.rules[i].pattern) > len(t.rules[j].pattern)
+	})
+	return t, nil
+}
+
+func isGlob(p string) bool { return strings.ContainsAny(p, "*?[") }
+
+// Lookup returns the tags of the most specific rule matching host, nil when
+// none does. The map is a copy.
+func (t *AssetTags) Lookup(host string) map[string]string {
+	if t == nil || host == "" {
+		return nil
+	}
+	host = strings.ToLower(host)
+	for _, rule := range t.rules {
+		ok := rule.pattern == host
+		if !ok && isGlob(rule.pattern) {
+			ok, _ = path.Match(rule.pattern, host)
+			if !ok && strings.HasPrefix(rule.pattern, "*.") {
+				ok = rule.pattern[2:] == host
+			}
+		}
+		if ok {
+			tags := make(map[string]string, len(rule.tags))
+			for k, v := range rule.tags {
+				tags[k] = v
+			}
+			return tags
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package scan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestASNDBLookup(t *testing.T) {
+	db, err := ReadASNDB(strings.NewReader(strings.Join([]string{
+		"# range_start\trange_end\tAS_number\tcountry_code\tAS_description",
+		"1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET",
+		"1.0.1.0\t1.0.3.255\t0\tNone\tNot routed",
+		"8.8.8.0\t8.8.8.255\t15169\tUS\tGOOGLE",
+		"2606:4700::\t2606:4700:ffff:ffff:ffff:ffff:ffff:ffff\t13335\tUS\tCLOUDFLARENET",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 3 {
+		t.Errorf("Len = %d, want 3 (unrouted range skipped)", db.Len())
+	}
+	for ip, want := range map[string]int{
+		"1.0.0.1": 13335, "8.8.8.8": 15169, "2606:4700::1111": 13335, "::ffff:8.8.8.8": 15169,
+		"1.0.2.1": 0, "9.9.9.9": 0, "0.0.0.1": 0, "not-an-ip": 0,
+	} {
+		got, ok := db.Lookup(ip)
+		if ok != (want != 0) || got.ASN != want {
+			t.Errorf("Lookup(%s) = %+v, %v; want AS%d", ip, got, ok, want)
+		}
+	}
+	if got, _ := db.Lookup("8.8.8.8"); got.Org != "GOOGLE" || got.Country != "US" {
+		t.Errorf("Lookup(8.8.8.8) = %+v", got)
+	}
+	if _, err := ReadASNDB(strings.NewReader("1.0.0.0\t1.0.0.255\tAS13335\n")); err == nil {
+		t.Error("non-numeric AS number accepted")
+	}
+}
+
+func TestAssetTagsPrefersMostSpecificHost(t *testing.T) {
+	tags, err := ReadAssetTags(strings.NewReader(`Host,Team,Owner
+# platform owns everything under the apex by default
+*.example.com,platform,ops@example.com
+*.pay.example.com,payments,
+api.pay.example.com,payments-api,api@example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, want := range map[string]map[string]string{
+		"API.pay.example.com": {"team": "payments-api", "owner": "api@example.com"},
+		"cdn.pay.example.com": {"team": "payments"},
+		"pay.example.com":     {"team": "payments"},
+		"www.example.com":     {"team": "platform", "owner": "ops@example.com"},
+		"example.com":         {"team": "platform", "owner": "ops@example.com"},
+		"example.org":         nil,
+	} {
+		if got := tags.Lookup(host); !reflect.DeepEqual(got, want) {
+			t.Errorf("Lookup(%s) = %v, want %v", host, got, want)
+		}
+	}
+	for name, csv := range map[string]string{
+		"empty":       "",
+		"host only":   "host\nexample.com\n",
+		"bad glob":    "host,team\n[x,a\n",
+		"empty host":  "host,team\n,a\n",
+		"short row":   "host,team,owner\nexample.com,a\n",
+		"unnamed tag": "host,,owner\nexample.com,a,b\n",
+	} {
+		if _, err := ReadAssetTags(strings.NewReader(csv)); err == nil {
+			t.Errorf("%s: tag file accepted", name)
+		}
+	}
+}
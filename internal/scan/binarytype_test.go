@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -36,11 +37,6 @@ func TestIsBinaryContentType(t *testing.T) {
 // served with a binary Content-Type, even when its bytes would otherwise match a
 // rule — an extensionless URL that returns an image must not be mined as text.
 func TestScanURLSkipsBinaryContentType(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-	ResetThrottle()
-
 	// A body that clearly matches an endpoint rule, so a non-skip would show up.
 	const body = `{"api":"https://api.example.com/v1/users","k":"secret"}`
 
@@ -60,7 +56,7 @@ func TestScanURLSkipsBinaryContentType(t *testing.T) {
 
 	// Use a .js path so endpoint extraction runs; the skip is driven by the binary
 	// Content-Type, not the URL, so the .js extension does not save the binary case.
-	got, err := e.ScanURL(binary.URL+"/app.js", true, false, false)
+	got, err := e.ScanURL(context.Background(), binary.URL+"/app.js", true, false, false)
 	if err != nil {
 		t.Fatalf("scan of binary response errored: %v", err)
 	}
@@ -70,7 +66,7 @@ func TestScanURLSkipsBinaryContentType(t *testing.T) {
 
 	// Control: the identical body under a JS content-type is scanned, proving the
 	// skip — not some other filter — is what suppressed the binary case.
-	ctrl, err := e.ScanURL(textual.URL+"/app.js", true, false, false)
+	ctrl, err := e.ScanURL(context.Background(), textual.URL+"/app.js", true, false, false)
 	if err != nil {
 		t.Fatalf("scan of JS response errored: %v", err)
 	}
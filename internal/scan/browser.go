@@ -70,7 +70,7 @@ func SetAutoDownloadBrowser(on bool) { AutoDownloadBrowser = on }
 var (
 	resolvedBrowser     string
 	resolveBrowserOnce  sync.Once
-	provisionHTTPClient = func() *http.Client { return newHTTPClient() }
+	provisionHTTPClient = func() *http.Client { return NewClient(DefaultClientConfig()).newHTTPClient() }
 )
 
 // resolvedBrowserPath returns the browser to launch, resolving (and provisioning)
@@ -91,8 +91,8 @@ func resetResolvedBrowser() {
 }
 
 // ResolveBrowser returns the path to a Chrome/Chromium executable to render with,
-// provisioning one if necessary. An explicit -chrome-path / $JSMINER_CHROME
-// override always wins. Otherwise, when downloads are enabled (the default), it
+// provisioning one if necessary. It is consulted only when a scan's ClientConfig
+// names no explicit ChromePath. When downloads are enabled (the default), it
 // always provisions the latest stable Chrome-for-Testing build — reusing that
 // version if already cached, downloading it if not — so renders use an up-to-date
 // browser. When downloads are disabled or fail (e.g. offline) it falls back to any
@@ -100,10 +100,6 @@ func resetResolvedBrowser() {
 // browser on PATH. It returns "" only when every option is exhausted, leaving
 // chromedp to try its own detection so behaviour is never worse than before.
 func ResolveBrowser() string {
-	if ChromePath != "" {
-		return ChromePath
-	}
-
 	if AutoDownloadBrowser {
 		version := chromeVersion()
 		root := managedBrowserDir()
@@ -160,7 +156,7 @@ func latestChromeVersion() string {
 	if err != nil {
 		return ""
 	}
-	applyHeaders(req)
+	req.Header.Set("User-Agent", defaultUserAgent)
 	client := provisionHTTPClient()
 	client.Timeout = 15 * time.Second
 	resp, err := client.Do(req)
@@ -406,14 +402,15 @@ func fetchAndExtractBrowser(version, extractDir, exePath string) (string, error)
 	return exePath, nil
 }
 
-// fetchToFile downloads url into w through the shared (throttled, header-applying)
-// HTTP path, streaming the body so a large archive does not buffer in memory.
+// fetchToFile downloads url into w, streaming the body so a large archive does
+// not buffer in memory. Provisioning is not part of any scan, so it sends only the
+// default User-Agent and none of a scan's extra headers.
 func fetchToFile(url string, w io.Writer) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
 	}
-	applyHeaders(req)
+	req.Header.Set("User-Agent", defaultUserAgent)
 	client := provisionHTTPClient()
 	// A browser archive is ~150MB; give it far longer than a normal request.
 	client.Timeout = 10 * time.Minute
@@ -257,7 +257,6 @@ func TestResolveBrowserDownloadsLatest(t *testing.T) {
 	BrowserDownloadBaseURL = dlSrv.URL
 	AutoDownloadBrowser = true
 	os.Setenv("XDG_CACHE_HOME", t.TempDir()) // isolate the managed cache
-	SetChromePath("")
 	defer func() {
 		BrowserVersionURL, BrowserDownloadBaseURL, AutoDownloadBrowser = savedVer, savedBase, savedAuto
 		os.Setenv("XDG_CACHE_HOME", savedCache)
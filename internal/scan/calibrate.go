@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"hash/fnv"
@@ -34,6 +35,12 @@ import (
 // level it probes that level with random paths and learns its own catch-all
 // fingerprint, then skips pages under that level that match it.
 type autoCalibrator struct {
+	// client issues the calibration probes with the scan's headers and pacing,
+	// and ctx is the crawl's context, which every probe is bound to. A
+	// calibrator lives exactly as long as the crawl that built it.
+	client *Client
+	ctx    context.Context
+
 	// mu guards every map below. A concurrent crawl consults one shared calibrator
 	// from all of its workers — skipPage on each fetched page, methodCatchAll on
 	// each method probe — so the maps must be synchronised. The HTTP probes that
@@ -95,8 +102,10 @@ const (
 	pageSkipDuplicate
 )
 
-func newAutoCalibrator() *autoCalibrator {
+func newAutoCalibrator(ctx context.Context, client *Client) *autoCalibrator {
 	return &autoCalibrator{
+		client:          client,
+		ctx:             withTraceKind(ctx, TraceCalibration),
 		wildcard:        make(map[string]struct{}),
 		levelWild:       make(map[string]map[string]struct{}),
 		levelDone:       make(map[string]struct{}),
@@ -157,7 +166,7 @@ func (c *autoCalibrator) calibrate(seedURL string) int {
 	c.levelDone["/"] = struct{}{}
 	counts := make(map[string]int)
 	for _, p := range calibrationProbePaths() {
-		resp, err := fetchURLResponseMethodSameScope(base+p, http.MethodGet, "")
+		resp, err := c.client.fetchURLResponseMethodSameScope(c.ctx, base+p, http.MethodGet, "")
 		if err != nil {
 			continue
 		}
@@ -367,7 +376,7 @@ func (c *autoCalibrator) ensureLevel(lvl string) {
 
 	counts := make(map[string]int)
 	for _, p := range levelProbePaths(lvl) {
-		resp, err := fetchURLResponseMethodSameScope(c.base+p, http.MethodGet, "")
+		resp, err := c.client.fetchURLResponseMethodSameScope(c.ctx, c.base+p, http.MethodGet, "")
 		if err != nil {
 			continue
 		}
@@ -461,7 +470,7 @@ func (c *autoCalibrator) ensureLevelShape(pageURL string) {
 
 	counts := make(map[string]int)
 	for _, p := range levelShapeProbePaths(pageURL) {
-		resp, err := fetchURLResponseMethodSameScope(c.base+p, http.MethodGet, "")
+		resp, err := c.client.fetchURLResponseMethodSameScope(c.ctx, c.base+p, http.MethodGet, "")
 		if err != nil {
 			continue
 		}
@@ -535,7 +544,7 @@ func (c *autoCalibrator) ensureMethodLevel(method, lvl string) {
 
 	counts := make(map[string]int)
 	for _, p := range levelProbePaths(lvl) {
-		resp, err := fetchURLResponseMethodSameScope(c.base+p, method, "")
+		resp, err := c.client.fetchURLResponseMethodSameScope(c.ctx, c.base+p, method, "")
 		if err != nil {
 			continue
 		}
@@ -586,7 +595,7 @@ func (c *autoCalibrator) ensureMethodShape(method, pageURL string) {
 
 	counts := make(map[string]int)
 	for _, p := range levelShapeProbePaths(pageURL) {
-		resp, err := fetchURLResponseMethodSameScope(c.base+p, method, "")
+		resp, err := c.client.fetchURLResponseMethodSameScope(c.ctx, c.base+p, method, "")
 		if err != nil {
 			continue
 		}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -28,7 +29,7 @@ func TestPageSig(t *testing.T) {
 }
 
 func TestAutoCalibratorSkipPage(t *testing.T) {
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	c.wildcard[pageSig(200, []byte("cannot find this page here now"))] = struct{}{}
 
 	// The first page (seed) is always accepted, even if it looks like anything.
@@ -52,7 +53,7 @@ func TestAutoCalibratorSkipPage(t *testing.T) {
 }
 
 func TestAutoCalibratorExemptsConfiguredSeedAfterOtherPages(t *testing.T) {
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	c.setBase("http://x/account/login?session=1")
 	catchAll := []byte("cannot find this page here now")
 	c.wildcard[pageSig(404, catchAll)] = struct{}{}
@@ -87,7 +88,7 @@ func TestAutoCalibratorWaitsForConcurrentLevelCalibration(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	c.setBase(srv.URL + "/")
 
 	first := make(chan bool, 1)
@@ -144,7 +145,7 @@ func TestAutoCalibratorWaitsForConcurrentMethodCalibration(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	c.setBase(srv.URL + "/")
 
 	first := make(chan bool, 1)
@@ -194,7 +195,7 @@ func TestGETMethodCalibrationReusesPageCalibration(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	c.setBase(srv.URL + "/")
 	c.ensureLevel("/section/")
 	if got := hits.Load(); got != 3 {
@@ -235,7 +236,7 @@ func TestScanURLCrawlAutoCalibrate(t *testing.T) {
 
 	e := NewExtractor(true, false)
 
-	plain, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, CrawlOptions{MaxDepth: 2, SameScopeOnly: true})
+	plain, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, CrawlOptions{MaxDepth: 2, SameScopeOnly: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -246,7 +247,7 @@ func TestScanURLCrawlAutoCalibrate(t *testing.T) {
 	var learned int
 	acOpts := CrawlOptions{MaxDepth: 2, SameScopeOnly: true, AutoCalibrate: true,
 		OnCalibrated: func(n int) { learned = n }}
-	calibrated, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, acOpts)
+	calibrated, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, acOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -297,7 +298,7 @@ func TestScanURLCrawlAutoCalibratePerLevel(t *testing.T) {
 
 	e := NewExtractor(true, false)
 
-	plain, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, CrawlOptions{MaxDepth: 2, SameScopeOnly: true})
+	plain, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, CrawlOptions{MaxDepth: 2, SameScopeOnly: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -306,7 +307,7 @@ func TestScanURLCrawlAutoCalibratePerLevel(t *testing.T) {
 	}
 
 	acOpts := CrawlOptions{MaxDepth: 2, SameScopeOnly: true, AutoCalibrate: true}
-	calibrated, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, acOpts)
+	calibrated, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, acOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
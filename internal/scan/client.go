@@ -0,0 +1,267 @@
+package scan
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// Defaults for the ClientConfig fields that a non-positive value falls back to.
+const (
+	defaultHTTPTimeout      = 10 * time.Second
+	defaultFetchRetries     = 2
+	defaultRenderTimeout    = 15 * time.Second
+	defaultRenderSleep      = 8 * time.Second
+	defaultMaxExploreStates = 12
+	defaultExploreSettle    = 1500 * time.Millisecond
+)
+
+// ClientConfig is the network and rendering configuration of one scan: what
+// every request carries, how requests are paced, retried and redirected, and how
+// pages are rendered. It is turned into a Client, which an Extractor threads
+// through every fetch, crawl, render, DOM and reflection scan it runs, so two
+// scans in one process never see each other's settings.
+type ClientConfig struct {
+	// Headers are added to every HTTP request and render. A User-Agent here
+	// replaces the default one.
+	Headers http.Header
+
+	// Timeout bounds each HTTP request (page and script fetches, calibration
+	// and method probes, sitemaps). Enterprise crawls of large bundles over slow
+	// links need it raised; interactive scans of flaky hosts may want it lowered
+	// so a single stalled request cannot hold up the whole crawl. A
+	// non-positive value uses the 10 second default.
+	Timeout time.Duration
+
+	// Retries is how many extra attempts a request makes when it fails with a
+	// transport error — a connection reset, DNS blip or timeout, the kind of
+	// transient failure an enterprise crawl of thousands of requests hits
+	// routinely. Only safe, bodyless GET/HEAD/OPTIONS requests are retried, so
+	// active mutation probes and discovered parameter replays are never
+	// double-submitted against a target. Zero or less disables retries.
+	Retries int
+
+	// SkipTLSVerification accepts invalid HTTPS certificates, for both fetches
+	// and renders.
+	SkipTLSVerification bool
+
+	// FollowRedirects controls whether HTTP clients follow 3xx responses. It is
+	// deliberately independent from external-resource discovery: -external
+	// decides which page-referenced scripts/imports are selected, while this
+	// setting decides whether any selected URL may follow a redirect. When off,
+	// the redirect response itself is returned without sending the next request,
+	// so scanning a target never silently moves elsewhere.
+	FollowRedirects bool
+
+	// RateLimit is the maximum number of requests per second per host. Zero or
+	// less disables proactive spacing, leaving only adaptive backoff and
+	// budget-aware pre-emption active (see requestThrottle).
+	RateLimit float64
+
+	// RateLimitJitter is the fraction (e.g. 0.2 for ±20%) by which each paced
+	// gap is randomised. Values are clamped to [0, 1].
+	RateLimitJitter float64
+
+	// MaxBackoff caps the adaptive gap and any honoured Retry-After or reset
+	// hold. A non-positive value uses the 30 second default.
+	MaxBackoff time.Duration
+
+	// ChromePath, when set, is the explicit path to the Chrome/Chromium
+	// executable used for rendering. Setting it lets JSMiner render where Chrome
+	// is installed at a known location that is not on PATH — common in CI images
+	// and containers — where provisioning and auto-detection would otherwise
+	// fail and rendering would silently fall back to a static fetch. When empty,
+	// a browser is resolved with ResolveBrowser.
+	ChromePath string
+
+	// RenderTimeout bounds a page render, not counting exploration.
+	// A non-positive value uses the 15 second default.
+	RenderTimeout time.Duration
+
+	// RenderSleep is the wait for dynamic content to load after navigation. It
+	// also sizes the DOM scan's initial settle window.
+	RenderSleep time.Duration
+
+	// MaxExploreStates bounds how many additional application states the
+	// renderer reaches by interacting with a page — clicking client-side
+	// navigation controls and filling and submitting forms — beyond the initial
+	// load. A single-page app hides most of its surface behind event handlers, so
+	// without this the crawler sees only the shell it first rendered. Zero or
+	// less disables interaction and renders each page exactly once.
+	MaxExploreStates int
+
+	// ExploreSettle is the longest wait after each interaction for the resulting
+	// state to render before it is snapshotted. It is deliberately much shorter
+	// than RenderSleep, which is paid once for the initial load. A non-positive
+	// value uses the 1.5 second default.
+	ExploreSettle time.Duration
+
+	// Archive, when set, records every HTTP exchange the client makes — each
+	// fetch, probe and redirect hop, source-map fetches, and the network
+	// traffic of rendered pages — so the scan can be replayed later.
+	Archive *WARCWriter
+
+	// Replay, when set, answers every HTTP request from a recorded archive
+	// instead of the network, so an improved rule can be re-run over an old
+	// crawl without touching the target. Requests the archive has no answer
+	// for fail with ErrNotArchived, and renders are refused: a browser would
+	// fetch from the network.
+	Replay *WARCArchive
+
+	// Metrics, when set, receives live telemetry from every crawl, request,
+	// throttle, render and DOM scan run through the client.
+	Metrics *Metrics
+
+	// RecordOrigins notes, per host, the address the client connected to and
+	// the first response's certificate, Server header and technology
+	// fingerprint, for an Enricher to attach to findings.
+	RecordOrigins bool
+
+	// Libraries, when set, fingerprints the JavaScript libraries every
+	// scanned script, source map and rendered page reveals, and flags the
+	// versions it lists as vulnerable.
+	Libraries *LibraryDB
+}
+
+// DefaultClientConfig returns the configuration the CLI uses when no flags are
+// given: no extra headers, two retries, invalid certificates accepted,
+// redirects not followed, no proactive rate limit, and up to twelve explored
+// states per rendered page.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		Timeout:             defaultHTTPTimeout,
+		Retries:             defaultFetchRetries,
+		SkipTLSVerification: true,
+		RenderTimeout:       defaultRenderTimeout,
+		RenderSleep:         defaultRenderSleep,
+		MaxExploreStates:    defaultMaxExploreStates,
+		ExploreSettle:       defaultExploreSettle,
+	}
+}
+
+// Client carries a scan's configuration together with the state built from
+// it: the keep-alive HTTP client every fetch shares, the per-host request
+// throttle, and the slots bounding concurrent source-map fetches. It is safe
+// for concurrent use; the configuration is fixed once the Client is built.
+type Client struct {
+	cfg ClientConfig
+
+	// http is shared by every request so its transport's keep-alive pool is
+	// reused across a crawl instead of re-handshaking each time.
+	http *http.Client
+
+	// throttle paces every request and render issued through this client.
+	throttle *requestThrottle
+
+	// Source maps frequently omit sourcesContent while listing hundreds of
+	// original files. Those independent files are fetched concurrently, but
+	// the fan-out is capped per client so several crawl workers recovering maps
+	// at once cannot create an unbounded request burst.
+	sourceMapSlots chan struct{}
+
+	// origins is what RecordOrigins has noted of each host so far.
+	origins origins
+}
+
+// NewClient builds a Client from cfg, filling unset durations with their
+// defaults. The headers are copied, so cfg may be reused afterwards.
+func NewClient(cfg ClientConfig) *Client {
+	h := make(http.Header)
+	for k, vals := range cfg.Headers {
+		for _, v := range vals {
+			h.Add(k, v)
+		}
+	}
+	cfg.Headers = h
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHTTPTimeout
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.RenderTimeout <= 0 {
+		cfg.RenderTimeout = defaultRenderTimeout
+	}
+	if cfg.RenderSleep < 0 {
+		cfg.RenderSleep = 0
+	}
+	if cfg.MaxExploreStates < 0 {
+		cfg.MaxExploreStates = 0
+	}
+	if cfg.ExploreSettle <= 0 {
+		cfg.ExploreSettle = defaultExploreSettle
+	}
+	if cfg.Replay != nil {
+		// An archive has no transient failures to retry and no server to
+		// pace requests for.
+		cfg.Retries = 0
+		cfg.RateLimit = 0
+	}
+
+	c := &Client{
+		cfg:            cfg,
+		throttle:       newRequestThrottle(),
+		sourceMapSlots: make(chan struct{}, maxSourceMapWorkers),
+	}
+	c.http = c.newHTTPClient()
+	c.throttle.setRateLimit(cfg.RateLimit)
+	c.throttle.setJitter(cfg.RateLimitJitter)
+	c.throttle.setMaxBackoff(cfg.MaxBackoff)
+	cfg.Metrics.watchThrottle(c.throttle)
+	return c
+}
+
+// metrics returns the client's Metrics, nil when there are none.
+func (c *Client) metrics() *Metrics {
+	if c == nil {
+		return nil
+	}
+	return c.cfg.Metrics
+}
+
+// Config returns the configuration the client was built with, defaults
+// applied.
+func (c *Client) Config() ClientConfig {
+	cfg := c.cfg
+	cfg.Headers = cfg.Headers.Clone()
+	return cfg
+}
+
+// newHTTPClient builds an HTTP client for the URL fetch helpers: a cloned default
+// transport that optionally skips TLS verification, a request timeout and a
+// redirect cap. The transport keeps a generous per-host idle-connection pool so a
+// crawl reuses keep-alive connections instead of opening a fresh TCP+TLS
+// connection for every one of its hundreds of requests to the same host.
+func (c *Client) newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The stdlib default is only 2 idle connections per host, which throttles
+	// connection reuse the moment a crawl issues requests back to back (and, once
+	// crawling is parallelised, forces extra handshakes). Raise it so the pool
+	// spans a crawl's working set of hosts.
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 16
+	if c.cfg.SkipTLSVerification {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		} else {
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+	}
+	var rt http.RoundTripper = transport
+	switch {
+	case c.cfg.Replay != nil:
+		rt = c.cfg.Replay
+	case c.cfg.Archive != nil:
+		rt = &warcRecorder{base: transport, w: c.cfg.Archive}
+	}
+	return &http.Client{
+		Transport: rt,
+		Timeout:   c.cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MaxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
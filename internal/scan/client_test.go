@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClient builds a client from the default configuration, adjusted by each
+// option in turn.
+func testClient(opts ...func(*ClientConfig)) *Client {
+	cfg := DefaultClientConfig()
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return NewClient(cfg)
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	h := http.Header{"X-Test": {"a"}}
+	c := NewClient(ClientConfig{Headers: h, Retries: -1, MaxExploreStates: -3})
+	h.Set("X-Test", "b")
+
+	cfg := c.Config()
+	if cfg.Headers.Get("X-Test") != "a" {
+		t.Fatalf("headers were not copied: %v", cfg.Headers)
+	}
+	if cfg.Timeout != defaultHTTPTimeout || cfg.RenderTimeout != defaultRenderTimeout || cfg.ExploreSettle != defaultExploreSettle {
+		t.Fatalf("unset durations should take their defaults: %+v", cfg)
+	}
+	if cfg.Retries != 0 || cfg.MaxExploreStates != 0 {
+		t.Fatalf("negative counts should clamp to zero: %+v", cfg)
+	}
+	if cfg.SkipTLSVerification || cfg.FollowRedirects {
+		t.Fatalf("a zero config should verify TLS and not follow redirects: %+v", cfg)
+	}
+}
+
+// TestClientsAreIsolated runs two extractors with different headers and rate
+// limits concurrently in one process; each must only ever send its own
+// settings, and one's pacing must not slow the other.
+func TestClientsAreIsolated(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string][]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := strings.Trim(r.URL.Path, "/")
+		mu.Lock()
+		seen[tenant] = append(seen[tenant], r.Header.Get("X-Tenant"))
+		mu.Unlock()
+		io.WriteString(w, "<html></html>")
+	}))
+	defer ts.Close()
+
+	slow := NewExtractor(false, false)
+	slow.SetClient(testClient(func(c *ClientConfig) {
+		c.Headers = http.Header{"X-Tenant": {"slow"}}
+		c.RateLimit = 4 // 250ms between requests to the test host
+	}))
+	fast := NewExtractor(false, false)
+	fast.SetClient(testClient(func(c *ClientConfig) {
+		c.Headers = http.Header{"X-Tenant": {"fast"}}
+	}))
+
+	var wg sync.WaitGroup
+	var fastTook time.Duration
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 4; i++ {
+			slow.ScanURL(context.Background(), ts.URL+"/slow", false, false, false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			fast.ScanURL(context.Background(), ts.URL+"/fast", false, false, false)
+		}
+		fastTook = time.Since(start)
+	}()
+	wg.Wait()
+
+	for tenant, got := range seen {
+		for _, h := range got {
+			if h != tenant {
+				t.Fatalf("%s scan sent X-Tenant %q", tenant, h)
+			}
+		}
+	}
+	if len(seen["slow"]) != 4 || len(seen["fast"]) != 4 {
+		t.Fatalf("unexpected request counts: %v", seen)
+	}
+	if fastTook > 500*time.Millisecond {
+		t.Fatalf("the unthrottled scan took %v; another client's rate limit leaked into it", fastTook)
+	}
+}
@@ -0,0 +1,213 @@
+package scan
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Confidence scores run from 1 (almost certainly noise) to 100 (almost
+// certainly a live secret). A Match with Confidence 0 was not scored: it is
+// intelligence such as an endpoint or email rather than a candidate secret.
+const (
+	MinConfidence = 1
+	MaxConfidence = 100
+)
+
+// confidenceWindow is how many source bytes on each side of a value are
+// searched for secret-naming keywords and public-key context.
+const confidenceWindow = 80
+
+// SecretValue returns the credential a candidate secret's value holds: what
+// follows the keyword and separator of a "api_key: value" match, or the whole
+// value when it is the credential itself.
+func SecretValue(value string) string {
+	if v := credentialValue(value); len(v) >= 6 {
+		return v
+	}
+	return value
+}
+
+// secretContextWords are the names code gives the variables and fields that
+// hold credentials. One of them just before a value makes it likelier to be
+// the real thing.
+var secretContextWords = []string{
+	"key", "secret", "token", "passw", "pwd", "auth", "credential", "bearer", "private",
+}
+
+// placeholderMarkers appear in documentation and template values that stand
+// in for a credential: "your_api_key", "xxxxxxxx", "EXAMPLE" and the like.
+var placeholderMarkers = []string{
+	"xxxx", "your_", "your-", "yourapi", "yourkey", "yoursecret", "example", "sample",
+	"dummy", "placeholder", "changeme", "change_me", "replace_me", "replaceme",
+	"redacted", "insert_", "<", ">", "****", "1234567890", "abcdefgh",
+}
+
+// testPathMarkers identify fixtures, tests and docs, where keys are usually
+// fake or deliberately published.
+var testPathMarkers = []string{
+	"/test/", "/tests/", "__tests__", "/spec/", ".test.", ".spec.", "_test.",
+	"/fixtures/", "/fixture/", "/mocks/", "/mock/", "/examples/", "/example/", "/docs/",
+}
+
+// docExts are prose formats; a key quoted in one is usually an illustration.
+var docExts = map[string]bool{".md": true, ".markdown": true, ".rst": true, ".txt": true, ".adoc": true}
+
+// attachConfidence scores each candidate secret in matches (see Match.Secret),
+// using data — the source they were found in — for context. Matches already
+// scored, and ones that are not candidate secrets, are left alone.
+func attachConfidence(source string, data []byte, matches []Match, isJS bool) {
+	for i := range matches {
+		m := &matches[i]
+		if m.Confidence != 0 || !m.Secret {
+			continue
+		}
+		var before, around []byte
+		if idx := bytes.Index(data, []byte(m.Value)); idx >= 0 && m.Value != "" {
+			start := max(idx-confidenceWindow, 0)
+			end := min(idx+len(m.Value)+confidenceWindow, len(data))
+			before, around = data[start:idx], data[start:end]
+		}
+		m.Confidence = scoreConfidence(*m, source, before, around, isJS)
+	}
+}
+
+// scoreConfidence combines the signals that separate a live credential from
+// look-alikes into one score. The rule's severity sets the starting point;
+// the value's entropy and character mix, a secret-naming keyword just before
+// it, placeholder and test markers, the kind of file it sits in and whether it
+// is a key that is public by design move it from there. before is the source
+// just ahead of the value and around the window enclosing it; both are empty
+// when the value was not found verbatim.
+func scoreConfidence(m Match, source string, before, around []byte, isJS bool) int {
+	score := 30
+	switch strings.ToLower(m.Severity) {
+	case SeverityHigh:
+		score = 70
+	case SeverityMedium:
+		score = 50
+	case SeverityLow:
+		score = 35
+	}
+
+	// Keyword-anchored rules match "api_key: value"; judge the value alone.
+	core := SecretValue(m.Value)
+	lower := strings.ToLower(core)
+
+	if len(core) >= 8 {
+		switch h := shannonEntropy(core); {
+		case h >= 4.5:
+			score += 15
+		case h >= 3.8:
+			score += 8
+		case h < 3.0:
+			score -= 15
+		}
+		switch charClasses(core) {
+		case 3, 4:
+			score += 5
+		case 1:
+			score -= 10
+		}
+	}
+	if containsAnyFold(before, secretContextWords) {
+		score += 10
+	}
+	if isPlaceholderValue(lower) {
+		score -= 50
+	} else if strings.Contains(lower, "test") {
+		score -= 20
+	}
+
+	src := strings.ToLower(source)
+	for _, marker := range testPathMarkers {
+		if strings.Contains(src, marker) {
+			score -= 20
+			break
+		}
+	}
+	if docExts[filepath.Ext(src)] {
+		score -= 15
+	} else if isJS {
+		score += 5
+	}
+
+	// Keys that are public by design are worth knowing about, never alarming.
+	if isPublicKey(m, around) {
+		score = min(score, 20)
+	}
+	return min(max(score, MinConfidence), MaxConfidence)
+}
+
+// charClasses counts how many of lowercase letters, uppercase letters, digits
+// and other characters s uses.
+func charClasses(s string) int {
+	var lower, upper, digit, other int
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z':
+			lower = 1
+		case c >= 'A' && c <= 'Z':
+			upper = 1
+		case c >= '0' && c <= '9':
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// isPlaceholderValue reports whether lower, a lowercased value, is a template
+// stand-in rather than a credential: it carries a placeholder marker or is one
+// character repeated.
+func isPlaceholderValue(lower string) bool {
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	run := 1
+	for i := 1; i < len(lower); i++ {
+		if lower[i] != lower[i-1] {
+			run = 1
+			continue
+		}
+		if run++; run >= 8 {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublicKey reports whether m is a key its provider designs to ship in the
+// browser: a Stripe publishable key, a Mapbox public token, or a Google API
+// key inside a Firebase web config. around is the source enclosing the value.
+func isPublicKey(m Match, around []byte) bool {
+	v := m.Value
+	switch {
+	case strings.HasPrefix(v, "pk_live_"), strings.HasPrefix(v, "pk_test_"):
+		return true
+	case strings.HasPrefix(v, "pk.eyJ"):
+		return true
+	case strings.HasPrefix(v, "AIza"):
+		return containsAnyFold(around, []string{"firebase", "authdomain", "messagingsenderid"})
+	}
+	return false
+}
+
+// FilterMinConfidence drops the scored matches whose confidence is below
+// threshold. Unscored matches — endpoints, emails and other intelligence — are
+// kept.
+func FilterMinConfidence(ms []Match, threshold int) []Match {
+	if threshold <= 0 {
+		return ms
+	}
+	out := ms[:0:0]
+	for _, m := range ms {
+		if m.Confidence == 0 || m.Confidence >= threshold {
+			out = append(out, m)
+		}
+	}
+	return out
+}
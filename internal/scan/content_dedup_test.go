@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,7 +12,7 @@ import (
 // body is admitted, an identical body afterwards is skipped, and a different body
 // is admitted.
 func TestSkipContentDedup(t *testing.T) {
-	c := newAutoCalibrator()
+	c := newAutoCalibrator(context.Background(), testClient())
 	bundle := []byte("var t='secret';\nfunction f(){return 1}")
 	if c.skipContent(bundle) {
 		t.Fatal("first sighting of a body should be scanned, not skipped")
@@ -72,7 +73,7 @@ func TestScanURLCrawlContentDedupKeepsReachability(t *testing.T) {
 	e := NewExtractor(true, false)
 	// AutoCalibrate on so the calibrator (which backs content dedup) is installed.
 	opts := CrawlOptions{MaxDepth: 2, MaxPages: 50, SameScopeOnly: true, AutoCalibrate: true}
-	ms, err := e.ScanURLCrawl(ts.URL, false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
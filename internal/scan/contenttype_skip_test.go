@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -36,7 +37,7 @@ func TestScanSkipsBinaryContentType(t *testing.T) {
 	e := NewExtractor(false, false)
 
 	hasSecret := func(u string) bool {
-		ms, err := e.ScanURL(u, false, false, false)
+		ms, err := e.ScanURL(context.Background(), u, false, false, false)
 		if err != nil {
 			t.Fatalf("scan %s: %v", u, err)
 		}
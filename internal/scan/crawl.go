@@ -1,6 +1,9 @@
 package scan
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"net/url"
 	"path"
 	"strings"
@@ -164,6 +167,13 @@ type CrawlOptions struct {
 	// the number of wildcard signatures learned.
 	OnCalibrated func(wildcardSigs int)
 
+	// OnMatch, when non-nil, receives each unique match as soon as the crawl
+	// confirms it, so a long crawl can report findings while it is still running
+	// rather than only when it returns. It is called from the crawl's
+	// coordinating goroutine, never concurrently, and each match at most once;
+	// the returned slice still holds every match.
+	OnMatch func(Match)
+
 	// OnComplete, when non-nil, is invoked once when the crawl finishes with a
 	// summary of what it did (see CrawlStats). It lets the CLI print an
 	// end-of-run report — pages fetched, targets discovered, errors, duration —
@@ -220,6 +230,10 @@ type CrawlStats struct {
 
 	// Duration is the wall-clock time the crawl took.
 	Duration time.Duration
+
+	// Partial reports that the crawl's context ended before the frontier was
+	// exhausted, so the matches cover only the pages finished by then.
+	Partial bool
 }
 
 // defaultCrawlConcurrency is how many pages a default interactive crawl fetches
@@ -260,6 +274,20 @@ type crawlTarget struct {
 	permuted      bool
 	passiveSource string
 	seed          bool
+	parent        string // the page it was discovered on, for the verbose log
+}
+
+// crawlPageAttrs are the structured fields of a crawled page's verbose record,
+// which join against findings by url. targets is left out when negative.
+func crawlPageAttrs(t crawlTarget, matches, targets int) []slog.Attr {
+	attrs := []slog.Attr{slog.String("url", t.url), slog.Int("depth", t.depth), slog.Int("matches", matches)}
+	if t.parent != "" {
+		attrs = append(attrs, slog.String("parent", t.parent))
+	}
+	if targets >= 0 {
+		attrs = append(attrs, slog.Int("targets", targets))
+	}
+	return attrs
 }
 
 // ScanURLCrawl scans urlStr and then crawls the in-scope endpoints it
@@ -267,30 +295,36 @@ type crawlTarget struct {
 // true only endpoint matches are produced (as with ScanURL). external controls
 // whether off-scope script/import references are followed while scanning an
 // individual page; the page-to-page crawl itself is governed by opts.
-func (e *Extractor) ScanURLCrawl(urlStr string, endpoints, external, render bool, opts CrawlOptions) ([]Match, error) {
-	scanPage := func(u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error) {
-		return e.scanURLWithValidationDetailed(u, baseHost, endpoints, visited, external, render, validator)
+//
+// When ctx ends first, the crawl stops dispatching pages, lets those in flight
+// wind down and returns the matches gathered so far together with ctx's error.
+// A configured ResumeFile is then kept, with the unfinished pages re-queued, so
+// the crawl can be resumed.
+func (e *Extractor) ScanURLCrawl(ctx context.Context, urlStr string, endpoints, external, render bool, opts CrawlOptions) ([]Match, error) {
+	scanPage := func(ctx context.Context, u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error) {
+		return e.scanURLWithValidationDetailed(ctx, u, baseHost, endpoints, visited, external, render, validator)
 	}
-	return e.crawlBFS(urlStr, opts, scanPage)
+	return e.crawlBFS(ctx, urlStr, opts, scanPage)
 }
 
 // ScanURLPostsCrawl behaves like ScanURLCrawl but scans each page for HTTP POST
 // request endpoints, following the discovered endpoints to reach deeper pages.
-func (e *Extractor) ScanURLPostsCrawl(urlStr string, external, render bool, opts CrawlOptions) ([]Match, error) {
-	scanPage := func(u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error) {
-		return e.scanURLPostsWithValidationDetailed(u, baseHost, visited, external, render, validator)
+func (e *Extractor) ScanURLPostsCrawl(ctx context.Context, urlStr string, external, render bool, opts CrawlOptions) ([]Match, error) {
+	scanPage := func(ctx context.Context, u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error) {
+		return e.scanURLPostsWithValidationDetailed(ctx, u, baseHost, visited, external, render, validator)
 	}
-	return e.crawlBFS(urlStr, opts, scanPage)
+	return e.crawlBFS(ctx, urlStr, opts, scanPage)
 }
 
 // crawlBFS drives the breadth-first crawl. scanPage scans a single page (and,
-// via scanURL/scanURLPosts, its script/import graph) and returns its matches;
-// crawlBFS harvests fresh in-scope targets from those matches and keeps going.
+// via scanURL/scanURLPosts, its script/import graph) under a context scoped to
+// that page and returns its matches; crawlBFS harvests fresh in-scope targets
+// from those matches and keeps going.
 //
 // The visited map is shared across every scanPage call so a JS bundle
 // referenced from many pages is fetched and scanned only once. The enqueued map
 // tracks page-level targets so each page is crawled at most once.
-func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error)) ([]Match, error) {
+func (e *Extractor) crawlBFS(ctx context.Context, seedURL string, opts CrawlOptions, scanPage func(ctx context.Context, u, baseHost string, visited *visitedSet, validator *autoCalibrator) (scanURLResult, error)) ([]Match, error) {
 	seed, err := url.Parse(seedURL)
 	if err != nil {
 		return nil, err
@@ -301,6 +335,8 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 
 	crawlStart := time.Now()
 	var stats CrawlStats
+	progress := e.client.metrics().startCrawl(seedURL)
+	defer progress.finish()
 
 	var perm *permuter
 	if opts.Permute {
@@ -312,7 +348,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 	// is created whenever either feature is active.
 	var cal *autoCalibrator
 	if opts.AutoCalibrate || opts.ProbeMethods || opts.DiscoverPassive {
-		cal = newAutoCalibrator()
+		cal = newAutoCalibrator(ctx, e.client)
 		cal.setBase(seed.String())
 	}
 	if opts.AutoCalibrate {
@@ -353,6 +389,24 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 	frontier := newCrawlFrontier()
 
 	var all []Match
+	// keep records matches as the crawl confirms them and hands each one not
+	// seen before to OnMatch, so findings stream out while the crawl runs.
+	streamed := make(map[string]struct{})
+	keep := func(ms ...Match) {
+		all = append(all, ms...)
+		progress.found(ms)
+		if opts.OnMatch == nil {
+			return
+		}
+		for _, m := range ms {
+			key := matchKey(m)
+			if _, ok := streamed[key]; ok {
+				continue
+			}
+			streamed[key] = struct{}{}
+			opts.OnMatch(m)
+		}
+	}
 	pages := 0
 	// crawlDelayForCheckpoint is the robots.txt Crawl-delay (as a per-host floor)
 	// carried through the checkpoint, so a resumed run re-establishes the same
@@ -387,13 +441,13 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			// its options; otherwise a pending historical hint could silently turn
 			// into a trusted ordinary page after restart.
 			if hasPendingPassive && cal == nil {
-				cal = newAutoCalibrator()
+				cal = newAutoCalibrator(ctx, e.client)
 				cal.setBase(seed.String())
 			}
 			if perm != nil {
 				perm.restore(cp.Permuter)
 			}
-			all = cp.Matches
+			keep(cp.Matches...)
 			pages = cp.Pages
 			stats.PassiveFound = cp.Passive.Found
 			stats.PassiveEnqueued = cp.Passive.Enqueued
@@ -401,7 +455,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			stats.PassiveRejected = cp.Passive.Rejected
 			crawlDelayForCheckpoint = time.Duration(cp.CrawlDelayMS) * time.Millisecond
 			if crawlDelayForCheckpoint > 0 {
-				SetHostRateFloor(baseHost, crawlDelayForCheckpoint)
+				e.client.throttle.setHostFloor(baseHost, crawlDelayForCheckpoint)
 			}
 			resumed = true
 			vlog(1, "[crawl] resumed from %s: %d page(s) done, %d queued, %d visited, %d match(es)",
@@ -419,7 +473,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		// reaches server-published paths that nothing links to. They enter at depth 0,
 		// like the seed, so their own discovered links get the full depth budget.
 		if opts.DiscoverWellKnown {
-			wkURLs, crawlDelay := discoverWellKnownURLs(origin)
+			wkURLs, crawlDelay := discoverWellKnownURLs(ctx, e.client, origin)
 			crawlDelayForCheckpoint = crawlDelay
 			// Honour the site's robots.txt Crawl-delay as a per-host pacing floor, so
 			// the crawl never requests faster than the site asked for. It is combined
@@ -427,7 +481,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			// own logic, staying under the limit protects secret recall by keeping
 			// pages from coming back as 429 shells.
 			if crawlDelay > 0 {
-				SetHostRateFloor(baseHost, crawlDelay)
+				e.client.throttle.setHostFloor(baseHost, crawlDelay)
 				vlog(1, "[crawl] honouring robots.txt Crawl-delay %s for %s", crawlDelay, baseHost)
 			}
 			for _, raw := range wkURLs {
@@ -462,7 +516,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		// The page scanner will reject stale/error/catch-all responses before they
 		// can produce findings, next hops, method probes or permutation input.
 		if opts.DiscoverPassive {
-			passive := discoverPassiveURLs(seed, opts.PassiveSources, opts.PassiveMax)
+			passive := discoverPassiveURLs(ctx, e.client, seed, opts.PassiveSources, opts.PassiveMax)
 			if opts.OnDOMSourceHints != nil {
 				var hints []DOMSourceHint
 				for _, candidate := range passive {
@@ -547,7 +601,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			if !accepted {
 				continue
 			}
-			frontier.push(crawlTarget{url: candidate.URL, depth: t.depth + 1, permuted: true})
+			frontier.push(crawlTarget{url: candidate.URL, depth: t.depth + 1, permuted: true, parent: t.url})
 			vlog(3, "[crawl] enqueue permuted depth %d score %d %s", t.depth+1, candidate.Score, candidate.URL)
 		}
 	}
@@ -616,6 +670,10 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		}
 	}
 
+	// interrupted collects the pages whose scan was cut short by cancellation.
+	var interrupted []crawlTarget
+	progress.queue(frontier)
+
 	if opts.Concurrency > 1 {
 		// Concurrent crawl. A pool of workers fetches and scans pages — and probes
 		// their methods and parameter replays — in parallel, while this goroutine
@@ -662,7 +720,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				defer wg.Done()
 				for job := range jobCh {
 					if job.isReplay {
-						worked := probeParamReplayMethods(cal, job.replay.url, methods, job.replay.params)
+						worked := probeParamReplayMethods(withTraceKind(ctx, TraceProbe), e.client, cal, job.replay.url, methods, job.replay.params)
 						var ms []Match
 						if gm, ok := gatheredMatch(job.replay.url, worked, job.replay.params); ok {
 							vlog(3, "[crawl] param-replay %s params=%s -> methods %s", job.replay.url, job.replay.params, strings.Join(worked, ","))
@@ -676,7 +734,8 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 					if t.passiveSource != "" {
 						validator = cal
 					}
-					pageResult, err := scanPage(t.url, baseHost, visited, validator)
+					pctx := withPageTrace(ctx, t.url, t.parent)
+					pageResult, err := scanPage(pctx, t.url, baseHost, visited, validator)
 					if err != nil {
 						resultCh <- crawlResult{job: job, err: err}
 						continue
@@ -690,7 +749,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 					// Report which request methods this page accepts, judged against
 					// the per-method error logic learned for its level.
 					if opts.ProbeMethods && !pageResult.skipped {
-						if worked := probeURLMethodsWithBaseline(cal, t.url, methods, "", pageResult.baseline); worked != nil {
+						if worked := probeURLMethodsWithBaseline(withTraceKind(pctx, TraceProbe), e.client, cal, t.url, methods, "", pageResult.baseline); worked != nil {
 							vlog(3, "[crawl] probe %s -> methods %s", t.url, strings.Join(worked, ","))
 							if gm, ok := gatheredMatch(t.url, worked, ""); ok {
 								if t.passiveSource != "" {
@@ -702,7 +761,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 						// A GraphQL endpoint: confirm it and map its schema surface by
 						// sending an introspection query.
 						if isGraphQLEndpoint(t.url) {
-							if gm, ok := probeGraphQLIntrospection(t.url); ok {
+							if gm, ok := probeGraphQLIntrospection(withTraceKind(pctx, TraceProbe), e.client, t.url); ok {
 								vlog(1, "[crawl] graphql introspection enabled at %s", t.url)
 								out = append(out, gm)
 							}
@@ -740,6 +799,8 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				haveJob bool
 			)
 			switch {
+			case ctx.Err() != nil:
+				// Cancelled: dispatch nothing more and only drain what is in flight.
 			case len(replayQueue) > 0:
 				job = crawlJob{isReplay: true, replay: replayQueue[0]}
 				haveJob = true
@@ -768,6 +829,8 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				} else {
 					frontier.pop()
 					inFlight[job.page.url] = job.page
+					progress.dispatch(job.page)
+					progress.queue(frontier)
 					pages++
 					if opts.Progress != nil {
 						opts.Progress(job.page.url, job.page.depth, pages)
@@ -777,11 +840,18 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			case res := <-resultCh:
 				pending--
 				if res.job.isReplay {
-					all = append(all, res.matches...)
+					keep(res.matches...)
 					continue
 				}
 				t := res.job.page
 				delete(inFlight, t.url)
+				progress.done(t, res.err, res.err != nil && ctx.Err() != nil)
+				if res.err != nil && ctx.Err() != nil {
+					// Cut short by cancellation rather than failed: keep it for the
+					// checkpoint so a resumed crawl fetches it again.
+					interrupted = append(interrupted, t)
+					continue
+				}
 				if res.err != nil {
 					if t.passiveSource != "" {
 						stats.PassiveRejected++
@@ -802,12 +872,13 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 					classer.admit(t.url)
 					vlog(1, "[crawl] validated passive path (%s) %s", t.passiveSource, t.url)
 				}
-				all = append(all, res.matches...)
+				keep(res.matches...)
 				stats.TargetsFound += len(res.targets)
 				if perm != nil && t.permuted {
 					perm.recordFetch(len(res.matches) > 0 || len(res.targets) > 0)
 				}
-				vlog(1, "[crawl] %s -> %d match(es), %d in-scope target(s)", t.url, len(res.matches), len(res.targets))
+				vlogAttrs(1, fmt.Sprintf("[crawl] %s -> %d match(es), %d in-scope target(s)", t.url, len(res.matches), len(res.targets)),
+					crawlPageAttrs(t, len(res.matches), len(res.targets))...)
 
 				// Replay this page's parameters against every level seen so far, and
 				// its levels against every parameter seen so far; the actual probing
@@ -820,13 +891,14 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				}
 				for _, next := range res.targets {
 					if admit(next) {
-						frontier.push(crawlTarget{url: next, depth: t.depth + 1})
+						frontier.push(crawlTarget{url: next, depth: t.depth + 1, parent: t.url})
 						vlog(3, "[crawl] enqueue depth %d %s", t.depth+1, next)
 					}
 				}
 				if res.skipReason != pageSkipWildcard {
 					enqueuePermutations(t)
 				}
+				progress.queue(frontier)
 				// Checkpoint periodically, re-queuing the still-in-flight pages so the
 				// snapshot is self-consistent.
 				if sinceCheckpoint++; opts.ResumeFile != "" && sinceCheckpoint >= crawlCheckpointInterval {
@@ -842,12 +914,14 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		close(jobCh)
 		wg.Wait()
 	} else {
-		for frontier.len() > 0 {
+		for frontier.len() > 0 && ctx.Err() == nil {
 			if opts.MaxPages > 0 && pages >= opts.MaxPages {
 				break
 			}
 			t := frontier.pop()
 			pages++
+			progress.dispatch(t)
+			progress.queue(frontier)
 			if opts.Progress != nil {
 				opts.Progress(t.url, t.depth, pages)
 			}
@@ -857,7 +931,13 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 			if t.passiveSource != "" {
 				validator = cal
 			}
-			pageResult, err := scanPage(t.url, baseHost, visited, validator)
+			pctx := withPageTrace(ctx, t.url, t.parent)
+			pageResult, err := scanPage(pctx, t.url, baseHost, visited, validator)
+			progress.done(t, err, err != nil && ctx.Err() != nil)
+			if err != nil && ctx.Err() != nil {
+				interrupted = append(interrupted, t)
+				break
+			}
 			if err != nil {
 				if t.passiveSource != "" {
 					stats.PassiveRejected++
@@ -880,30 +960,30 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				classer.admit(t.url)
 				vlog(1, "[crawl] validated passive path (%s) %s", t.passiveSource, t.url)
 			}
-			all = append(all, ms...)
+			keep(ms...)
 			if perm != nil && t.permuted {
 				perm.recordFetch(len(ms) > 0)
 			}
-			vlog(1, "[crawl] (%d) %s -> %d match(es)", pages, t.url, len(ms))
+			vlogAttrs(1, fmt.Sprintf("[crawl] (%d) %s -> %d match(es)", pages, t.url, len(ms)), crawlPageAttrs(t, len(ms), -1)...)
 
 			// Report which request methods this page accepts, judged against the
 			// per-method error logic learned for its level.
 			if opts.ProbeMethods && !pageResult.skipped {
-				if worked := probeURLMethodsWithBaseline(cal, t.url, methods, "", pageResult.baseline); worked != nil {
+				if worked := probeURLMethodsWithBaseline(withTraceKind(pctx, TraceProbe), e.client, cal, t.url, methods, "", pageResult.baseline); worked != nil {
 					vlog(3, "[crawl] probe %s -> methods %s", t.url, strings.Join(worked, ","))
 					if gm, ok := gatheredMatch(t.url, worked, ""); ok {
 						if t.passiveSource != "" {
 							gm.Params += " passive_source=" + t.passiveSource
 						}
-						all = append(all, gm)
+						keep(gm)
 					}
 				}
 				// A GraphQL endpoint: confirm it and map its schema surface by sending
 				// an introspection query.
 				if isGraphQLEndpoint(t.url) {
-					if gm, ok := probeGraphQLIntrospection(t.url); ok {
+					if gm, ok := probeGraphQLIntrospection(withTraceKind(pctx, TraceProbe), e.client, t.url); ok {
 						vlog(1, "[crawl] graphql introspection enabled at %s", t.url)
-						all = append(all, gm)
+						keep(gm)
 					}
 				}
 			}
@@ -927,22 +1007,23 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 				levelURLs = append(levelURLs, targets...)
 				levelURLs = append(levelURLs, t.url)
 				for _, rt := range replay.observe(paramsFromMatches(ms), levelURLs) {
-					worked := probeParamReplayMethods(cal, rt.url, methods, rt.params)
+					worked := probeParamReplayMethods(withTraceKind(ctx, TraceProbe), e.client, cal, rt.url, methods, rt.params)
 					if gm, ok := gatheredMatch(rt.url, worked, rt.params); ok {
 						vlog(3, "[crawl] param-replay %s params=%s -> methods %s", rt.url, rt.params, strings.Join(worked, ","))
-						all = append(all, gm)
+						keep(gm)
 					}
 				}
 			}
 			for _, next := range targets {
 				if admit(next) {
-					frontier.push(crawlTarget{url: next, depth: t.depth + 1})
+					frontier.push(crawlTarget{url: next, depth: t.depth + 1, parent: t.url})
 					vlog(3, "[crawl] enqueue depth %d %s", t.depth+1, next)
 				}
 			}
 			if pageResult.skipReason != pageSkipWildcard {
 				enqueuePermutations(t)
 			}
+			progress.queue(frontier)
 			// Checkpoint periodically. The serial loop fully processes each page
 			// before the next, so there are no in-flight pages to re-queue.
 			if opts.ResumeFile != "" && pages%crawlCheckpointInterval == 0 {
@@ -951,10 +1032,16 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		}
 	}
 
-	// The crawl reached this point without being killed, so it is complete: there
-	// is nothing left to resume, and leaving the checkpoint behind would make the
-	// next run wrongly resume a finished crawl. Remove it.
-	if opts.ResumeFile != "" {
+	if ctx.Err() != nil {
+		// Cancelled: the crawl is not complete, so persist where it stopped —
+		// re-queuing the pages the cancellation cut short — for a later resume.
+		stats.Partial = true
+		vlog(1, "[crawl] stopped early (%v) after %d page(s); %d left queued", ctx.Err(), pages-len(interrupted), frontier.len()+len(interrupted))
+		saveCheckpoint(interrupted)
+	} else if opts.ResumeFile != "" {
+		// The crawl reached this point without being killed, so it is complete:
+		// there is nothing left to resume, and leaving the checkpoint behind would
+		// make the next run wrongly resume a finished crawl. Remove it.
 		removeCheckpoint(opts.ResumeFile)
 	}
 
@@ -974,7 +1061,7 @@ func (e *Extractor) crawlBFS(seedURL string, opts CrawlOptions, scanPage func(u,
 		stats.Duration = time.Since(crawlStart)
 		opts.OnComplete(stats)
 	}
-	return out, nil
+	return out, ctx.Err()
 }
 
 // crawlTargetsFromMatches derives the next set of crawlable page URLs from the
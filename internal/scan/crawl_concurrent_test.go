@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -89,7 +90,7 @@ func TestScanURLCrawlConcurrentReachesAll(t *testing.T) {
 
 	serialOpts := base
 	serialOpts.Concurrency = 1
-	serial, err := e.ScanURLCrawl(ts.URL, false, false, false, serialOpts)
+	serial, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, serialOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -100,7 +101,7 @@ func TestScanURLCrawlConcurrentReachesAll(t *testing.T) {
 
 	concOpts := base
 	concOpts.Concurrency = 8
-	concurrent, err := e.ScanURLCrawl(ts.URL, false, false, false, concOpts)
+	concurrent, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, concOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -142,7 +143,7 @@ func TestScanURLCrawlConcurrentMaxPages(t *testing.T) {
 	e := NewExtractor(true, false)
 	// Isolate page counting: no probing/calibration/well-known/dedup extra requests.
 	opts := CrawlOptions{MaxDepth: 5, MaxPages: 5, SameScopeOnly: true, Concurrency: 8}
-	if _, err := e.ScanURLCrawl(ts.URL, true, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL, true, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 	if got := pages.count(); got != 5 {
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -34,11 +35,6 @@ func TestCrawlLiveComplexSPA(t *testing.T) {
 	srv := newComplexSPAServer()
 	defer srv.Close()
 
-	// Keep the render fast and bounded for a test while still exploring state.
-	restore := tuneForLiveTest()
-	defer restore()
-	ResetThrottle()
-
 	// Use whatever browser is already available (cache/PATH) without a network
 	// version lookup or download, so the crawl test stays hermetic.
 	savedAuto := AutoDownloadBrowser
@@ -47,11 +43,13 @@ func TestCrawlLiveComplexSPA(t *testing.T) {
 	defer func() { AutoDownloadBrowser = savedAuto; resetResolvedBrowser() }()
 
 	e := NewExtractor(false, false)
+	// Keep the render fast and bounded for a test while still exploring state.
+	e.SetClient(liveTestClient())
 	opts := DefaultCrawlOptions()
 	opts.MaxDepth = 3
 	opts.MaxPages = 40
 
-	matches, err := e.ScanURLCrawl(srv.URL, false, true, true, opts)
+	matches, err := e.ScanURLCrawl(context.Background(), srv.URL, false, true, true, opts)
 	if err != nil {
 		t.Fatalf("crawl error: %v", err)
 	}
@@ -87,17 +85,15 @@ func TestCrawlLiveComplexSPA(t *testing.T) {
 		srv.total(), goReqs, srv.total()-goReqs, srv.throttled())
 }
 
-// tuneForLiveTest shortens the render waits so the live test finishes quickly,
-// returning a function that restores the previous values.
-func tuneForLiveTest() func() {
-	savedSleep, savedTimeout, savedSettle, savedStates := RenderSleepDuration, RenderTimeout, ExploreSettleDuration, MaxExploreStates
-	RenderSleepDuration = 700 * time.Millisecond
-	RenderTimeout = 12 * time.Second
-	ExploreSettleDuration = 500 * time.Millisecond
-	MaxExploreStates = 8
-	return func() {
-		RenderSleepDuration, RenderTimeout, ExploreSettleDuration, MaxExploreStates = savedSleep, savedTimeout, savedSettle, savedStates
-	}
+// liveTestClient returns a client with shortened render waits so the live test
+// finishes quickly.
+func liveTestClient() *Client {
+	return testClient(func(c *ClientConfig) {
+		c.RenderSleep = 700 * time.Millisecond
+		c.RenderTimeout = 12 * time.Second
+		c.ExploreSettle = 500 * time.Millisecond
+		c.MaxExploreStates = 8
+	})
 }
 
 func chromeAvailable() bool {
@@ -126,7 +122,7 @@ type complexSPAServer struct {
 // isBrowserRequest reports whether r was issued by headless Chrome rather than
 // the throttled Go HTTP client. Chrome always sends Sec-Fetch-* (and, on modern
 // builds, sec-ch-ua) request headers; the Go client sends neither. Only the Go
-// path flows through globalThrottle, so backoff can only be asserted on it.
+// path flows through the client's throttle, so backoff can only be asserted on it.
 func isBrowserRequest(r *http.Request) bool {
 	return r.Header.Get("Sec-Fetch-Mode") != "" || r.Header.Get("Sec-Fetch-Dest") != "" || r.Header.Get("Sec-Ch-Ua") != ""
 }
@@ -261,7 +257,7 @@ func (s *complexSPAServer) goRequests() int {
 // assertBackoffHonoured checks that after any 429 answered to the throttled Go
 // client, the client's *next* request arrived at least minGap later — i.e. the
 // throttle honoured Retry-After instead of hammering through the limit. Only the
-// Go path flows through globalThrottle, so headless-Chrome's own render requests
+// Go path flows through the client's throttle, so headless-Chrome's own render requests
 // (which the throttle does not govern) are excluded from the sequence.
 func (s *complexSPAServer) assertBackoffHonoured(t *testing.T, minGap time.Duration) {
 	s.mu.Lock()
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -60,7 +61,7 @@ func TestCrawlPrioritisesHighYieldUnderCap(t *testing.T) {
 	// Serial (Concurrency unset), no probing/calibration/dedup so only the frontier
 	// order decides what the 3-page budget (seed + 2) is spent on.
 	opts := CrawlOptions{MaxDepth: 2, MaxPages: 3, SameScopeOnly: true}
-	if _, err := e.ScanURLCrawl(ts.URL, true, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL, true, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 
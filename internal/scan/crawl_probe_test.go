@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -94,14 +95,13 @@ func (s *probeCountServer) snapshot() (map[string]int, int) {
 func TestCrawlProbeBudget(t *testing.T) {
 	srv := newProbeCountServer()
 	defer srv.Close()
-	ResetThrottle()
 
 	e := NewExtractor(false, false)
 	opts := DefaultCrawlOptions()
 	opts.MaxDepth = 2
 	opts.MaxPages = 40
 
-	matches, err := e.ScanURLCrawl(srv.URL, false, false /*external*/, false /*render*/, opts)
+	matches, err := e.ScanURLCrawl(context.Background(), srv.URL, false, false /*external*/, false /*render*/, opts)
 	if err != nil {
 		t.Fatalf("crawl error: %v", err)
 	}
@@ -158,7 +158,7 @@ func TestCrawlDoesNotProbeSkippedDuplicatePage(t *testing.T) {
 		AutoCalibrate: true, ProbeMethods: true,
 		RequestMethods: []string{"GET", "POST"}, Concurrency: 1,
 	}
-	if _, err := e.ScanURLCrawl(srv.URL, false, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), srv.URL, false, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 
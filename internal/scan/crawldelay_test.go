@@ -48,17 +48,15 @@ func TestParseRobotsNoCrawlDelay(t *testing.T) {
 // TestSetHostRateFloor verifies the throttle applies a per-host floor and that
 // adaptive decay never eases the gap below it.
 func TestSetHostRateFloor(t *testing.T) {
-	ResetThrottle()
-	defer ResetThrottle()
-
+	th := newRequestThrottle()
 	const host = "floor.example"
-	SetHostRateFloor(host, 750*time.Millisecond)
+	th.setHostFloor(host, 750*time.Millisecond)
 
-	globalThrottle.mu.Lock()
-	hs := globalThrottle.host(host)
+	th.mu.Lock()
+	hs := th.host(host)
 	got := hs.curGap
-	base := globalThrottle.baseGapFor(host)
-	globalThrottle.mu.Unlock()
+	base := th.baseGapFor(host)
+	th.mu.Unlock()
 
 	if got < 750*time.Millisecond {
 		t.Fatalf("host gap = %v, want >= 750ms floor", got)
@@ -69,11 +67,11 @@ func TestSetHostRateFloor(t *testing.T) {
 
 	// Drive many clean responses; decay must not drop below the floor.
 	for i := 0; i < 50; i++ {
-		globalThrottle.decay(host)
+		th.decay(host)
 	}
-	globalThrottle.mu.Lock()
-	after := globalThrottle.host(host).curGap
-	globalThrottle.mu.Unlock()
+	th.mu.Lock()
+	after := th.host(host).curGap
+	th.mu.Unlock()
 	if after < 750*time.Millisecond {
 		t.Fatalf("gap decayed to %v, below the 750ms floor", after)
 	}
@@ -82,15 +80,13 @@ func TestSetHostRateFloor(t *testing.T) {
 // TestSetHostRateFloorKeepsStricterUserLimit verifies a larger user base gap is
 // not lowered by a smaller robots.txt floor.
 func TestSetHostRateFloorKeepsStricterUserLimit(t *testing.T) {
-	ResetThrottle()
-	defer func() { SetRateLimit(0); ResetThrottle() }()
-
-	SetRateLimit(1) // 1 req/s => 1s base gap for every host
-	SetHostRateFloor("h.example", 200*time.Millisecond)
+	th := newRequestThrottle()
+	th.setRateLimit(1) // 1 req/s => 1s base gap for every host
+	th.setHostFloor("h.example", 200*time.Millisecond)
 
-	globalThrottle.mu.Lock()
-	base := globalThrottle.baseGapFor("h.example")
-	globalThrottle.mu.Unlock()
+	th.mu.Lock()
+	base := th.baseGapFor("h.example")
+	th.mu.Unlock()
 	if base != time.Second {
 		t.Fatalf("effective base gap = %v, want the stricter 1s user limit", base)
 	}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -52,6 +53,22 @@ func (f *crawlFrontier) snapshot() []crawlTarget {
 	return out
 }
 
+// head returns up to n targets in the order they will be dequeued, without
+// removing them.
+func (f *crawlFrontier) head(n int) []crawlTarget {
+	items := append([]frontierItem(nil), f.h.items...)
+	h := &targetHeap{items: items}
+	sort.Slice(h.items, h.Less)
+	if len(h.items) > n {
+		h.items = h.items[:n]
+	}
+	out := make([]crawlTarget, len(h.items))
+	for i, it := range h.items {
+		out[i] = it.target
+	}
+	return out
+}
+
 // frontierItem is a heap entry: the target, its precomputed yield score and the
 // monotonic insertion sequence used as a stable final tiebreak.
 type frontierItem struct {
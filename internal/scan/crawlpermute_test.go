@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -239,7 +240,7 @@ func TestScanURLCrawlPermute(t *testing.T) {
 	base := CrawlOptions{MaxDepth: 3, MaxPages: 50, SameScopeOnly: true}
 
 	// Without permutation the crawler never asks for /api/admin/config.js.
-	plain, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, base)
+	plain, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, base)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -256,7 +257,7 @@ func TestScanURLCrawlPermute(t *testing.T) {
 	permOpts.Concurrency = 4
 	var stats CrawlStats
 	permOpts.OnComplete = func(s CrawlStats) { stats = s }
-	permuted, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, permOpts)
+	permuted, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, permOpts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -289,7 +290,7 @@ func TestScanURLCrawlPermuteHonorsDepthConcurrent(t *testing.T) {
 		MaxDepth: 0, MaxPages: 20, SameScopeOnly: true,
 		Permute: true, PermuteMax: 20, Concurrency: 4,
 	}
-	if _, err := e.ScanURLCrawl(ts.URL+"/api/status", false, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL+"/api/status", false, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 	if seedHits != 1 || deeperHits != 0 {
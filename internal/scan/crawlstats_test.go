@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -47,7 +48,7 @@ func TestCrawlStatsReported(t *testing.T) {
 		got = s
 	}
 
-	if _, err := e.ScanURLCrawl(ts.URL, false, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 
@@ -98,12 +99,12 @@ func TestCrawlStatsCountsErrors(t *testing.T) {
 	opts.AutoCalibrate = false
 	opts.ProbeMethods = false
 	opts.DiscoverWellKnown = false
-	SetFetchRetries(0) // don't let retries mask the forced failure
-	defer SetFetchRetries(2)
+	// Don't let retries mask the forced failure.
+	e.SetClient(testClient(func(c *ClientConfig) { c.Retries = 0 }))
 
 	var got CrawlStats
 	opts.OnComplete = func(s CrawlStats) { got = s }
-	if _, err := e.ScanURLCrawl(ts.URL, false, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 
@@ -1,5 +1,11 @@
 package scan
 
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
 // UniqueMatches returns a new slice containing only the first occurrence of each
 // pattern/value pair from ms. The original order is preserved for the first
 // occurrence.
@@ -7,7 +13,7 @@ func UniqueMatches(ms []Match) []Match {
 	seen := make(map[string]struct{})
 	out := make([]Match, 0, len(ms))
 	for _, m := range ms {
-		key := m.Pattern + "|" + m.Value + "|" + m.Params
+		key := matchKey(m)
 		if _, ok := seen[key]; ok {
 			continue
 		}
@@ -17,6 +23,20 @@ func UniqueMatches(ms []Match) []Match {
 	return out
 }
 
+// MatchFingerprint is the deterministic identity of an ordinary finding: its
+// pattern, value, params, severity and source, hashed. Transient output
+// concerns (snippets, display order) do not enter it. Machine-readable output
+// carries it and triage files key reviewed findings by it.
+func MatchFingerprint(m Match) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{m.Pattern, m.Value, m.Params, m.Severity, m.Source}, "\x1f")))
+	return fmt.Sprintf("%x", h[:16])
+}
+
+// matchKey is the identity UniqueMatches deduplicates on.
+func matchKey(m Match) string {
+	return m.Pattern + "|" + m.Value + "|" + m.Params
+}
+
 // DedupMatchesByValueKeepSeverity removes lower-severity duplicates of a value.
 // When the same value is reported by more than one signal, only the finding(s)
 // at the highest severity seen for that value are kept, so a real credential
@@ -44,3 +64,32 @@ func DedupMatchesByValueKeepSeverity(ms []Match) []Match {
 	}
 	return out
 }
+
+// StreamDedup applies DedupMatchesByValueKeepSeverity to findings one at a
+// time, as they are streamed: a value is passed on the first time it is seen
+// and again only when a later finding reports it at a severity at least as
+// high, so a stream never repeats a value at a lower severity. A finding
+// already passed on cannot be withdrawn, so when a value's higher-severity
+// finding comes later the stream holds both where the final result keeps one.
+// It is not safe for concurrent use.
+type StreamDedup struct {
+	maxRank map[string]int
+}
+
+// NewStreamDedup returns a StreamDedup that has seen nothing.
+func NewStreamDedup() *StreamDedup {
+	return &StreamDedup{maxRank: make(map[string]int)}
+}
+
+// Keep reports whether m should be streamed, and records its severity.
+func (d *StreamDedup) Keep(m Match) bool {
+	if m.Value == "" {
+		return true
+	}
+	r := severityRank(m.Severity)
+	if seen, ok := d.maxRank[m.Value]; ok && r < seen {
+		return false
+	}
+	d.maxRank[m.Value] = r
+	return true
+}
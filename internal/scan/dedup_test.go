@@ -58,3 +58,47 @@ func TestDedupMatchesByValuePreservesOrder(t *testing.T) {
 		t.Fatalf("unexpected order/content: %+v", out)
 	}
 }
+
+// TestMatchFingerprintStable proves identical findings hash identically and
+// differing ones do not.
+func TestMatchFingerprintStable(t *testing.T) {
+	a := Match{Pattern: "p", Value: "v", Severity: "high", Source: "s"}
+	b := Match{Pattern: "p", Value: "v", Severity: "high", Source: "s"}
+	if MatchFingerprint(a) != MatchFingerprint(b) {
+		t.Error("identical matches must share a fingerprint")
+	}
+	c := Match{Pattern: "p", Value: "w", Severity: "high", Source: "s"}
+	if MatchFingerprint(a) == MatchFingerprint(c) {
+		t.Error("differing matches must not share a fingerprint")
+	}
+}
+
+// TestStreamDedupDropsLowerSeverityRepeats streams the findings one by one and
+// expects a value's lower-severity echoes after its high finding to be held
+// back, while ties and severity upgrades still pass.
+func TestStreamDedupDropsLowerSeverityRepeats(t *testing.T) {
+	in := []Match{
+		{Pattern: "generic_api_key", Value: "k", Severity: SeverityMedium},
+		{Pattern: "aws_access_key", Value: "k", Severity: SeverityHigh},      // upgrade -> passed
+		{Pattern: "high_entropy_string", Value: "k", Severity: SeverityInfo}, // echo -> dropped
+		{Pattern: "other_high", Value: "k", Severity: SeverityHigh},          // tie -> passed
+		{Pattern: "generic_api_key", Value: "k", Severity: SeverityMedium},   // echo -> dropped
+		{Pattern: "d", Value: "", Severity: SeverityLow},                     // empty -> passed
+	}
+	d := NewStreamDedup()
+	var got []string
+	for _, m := range in {
+		if d.Keep(m) {
+			got = append(got, m.Pattern)
+		}
+	}
+	want := []string{"generic_api_key", "aws_access_key", "other_high", "d"}
+	if len(got) != len(want) {
+		t.Fatalf("streamed %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("streamed %v, want %v", got, want)
+		}
+	}
+}
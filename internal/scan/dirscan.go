@@ -1,12 +1,14 @@
 package scan
 
 import (
+	"bytes"
 	"io"
 	"sync"
 )
 
 // ScanDir scans all supported files under root directory using workers
-// to limit concurrency.
+// to limit concurrency. The files are local code, so jsminer:ignore comments
+// in them are honoured (see ApplyInlineIgnores).
 func (e *Extractor) ScanDir(root string, workers int) ([]Match, error) {
 	files, err := WalkDir(root)
 	if err != nil {
@@ -35,11 +37,17 @@ func (e *Extractor) ScanDir(root string, workers int) ([]Match, error) {
 			defer func() { <-sem }()
 			defer rc.Close()
 
-			ms, err := e.ScanReader(n, rc)
+			data, err := io.ReadAll(rc)
 			if err != nil {
 				errCh <- err
 				return
 			}
+			ms, err := e.ScanReader(n, bytes.NewReader(data))
+			if err != nil {
+				errCh <- err
+				return
+			}
+			ms, _ = ApplyInlineIgnores(data, ms)
 			if len(ms) > 0 {
 				matchesCh <- ms
 			}
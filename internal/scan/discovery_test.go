@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -40,7 +41,7 @@ func TestParseJSEndpointsWebSocketEventSource(t *testing.T) {
 func TestScanReaderExtractsEndpointsFromJSON(t *testing.T) {
 	e := NewExtractor(false, false)
 	body := `{"data":[{"id":1}],"_links":{"self":{"href":"/api/orders"},"next":{"href":"/api/orders?page=2"}}}`
-	ms, err := e.ScanReaderWithEndpoints("https://api.example.com/api/orders", strings.NewReader(body))
+	ms, err := e.ScanReaderWithEndpoints(context.Background(), "https://api.example.com/api/orders", strings.NewReader(body))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,7 +87,7 @@ func TestScanURLCrawlFollowsJSONHypermedia(t *testing.T) {
 
 	e := NewExtractor(false, false)
 	opts := CrawlOptions{MaxDepth: 3, MaxPages: 20, SameScopeOnly: true}
-	ms, err := e.ScanURLCrawl(ts.URL, false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -38,7 +38,9 @@ type domAgentConfig struct {
 	Canaries []domCanary     `json:"canaries"`
 	Limits   domAgentLimits  `json:"limits"`
 	Messages bool            `json:"messages"`
-	Sinks    map[string]bool `json:"sinks"` // nil = all sink families enabled
+	Sockets  bool            `json:"sockets"`  // track WebSocket/EventSource for replay
+	Navigate bool            `json:"navigate"` // let location changes to a probe value happen
+	Sinks    map[string]bool `json:"sinks"`    // nil = all sink families enabled
 }
 
 // domRawFrame mirrors DOMStackFrame for decoding the agent's captured stacks.
@@ -73,6 +75,7 @@ type domRawURL struct {
 	CanaryComponent   string `json:"canaryComponent"`
 	InputKind         string `json:"inputKind"`
 	ExecutableScheme  bool   `json:"executableScheme"`
+	CanaryLeading     bool   `json:"canaryLeading"`
 }
 
 // domRawFinding is one record the in-page agent produced, decoded on the Go
@@ -130,6 +133,9 @@ func buildDOMAgent(cfg domAgentConfig) string {
 //   - correlates the recorded value against the known canaries so a sink hit is
 //     attributed to the exact source that controlled it (a flow), rather than
 //     merely noting that a dangerous API ran;
+//   - records the Trusted Types policies the page creates, for CSP evidence;
+//   - tracks WebSocket/EventSource connections and replays their observed
+//     messages locally with canaries injected, as the websocket_message source;
 //   - analyses postMessage: listeners (and whether they appear to inspect
 //     origin/source), observed messages, and cross-origin sends of URL-derived
 //     data;
@@ -218,7 +224,7 @@ const domAgentScript = `
   function analyseURL(value, token) {
     var out = {
       resolved: false, scheme: '', destinationOrigin: '', sameOrigin: false,
-      canaryComponent: '', inputKind: '', executableScheme: false
+      canaryComponent: '', inputKind: '', executableScheme: false, canaryLeading: false
     };
     try {
       var trimmed = (value || '').trim();
@@ -229,6 +235,14 @@ const domAgentScript = `
       else if (trimmed.indexOf('#') === 0) out.inputKind = 'fragment_relative';
       else out.inputKind = 'path_relative';
 
+      // A marker at the very start means the input supplies the whole URL, so
+      // its scheme and origin are both attacker-chosen.
+      if (token) {
+        var lead = trimmed;
+        try { lead = decodeURIComponent(trimmed); } catch (e) {}
+        out.canaryLeading = trimmed.indexOf(token) === 0 || lead.indexOf(token) === 0;
+      }
+
       var u = new URL(trimmed, location.href);
       out.resolved = true;
       out.scheme = (u.protocol || '').replace(/:$/, '').toLowerCase();
@@ -342,6 +356,7 @@ const domAgentScript = `
   }
 
   // ---- the shared sink recorder -------------------------------------------
+  // It returns true when the value carried a canary.
   function __jsmdomRecord(sink, arg, rawValue, ctx) {
     try {
       var value = toStr(rawValue);
@@ -350,10 +365,17 @@ const domAgentScript = `
         var stack = captureStack();
         for (var i = 0; i < matched.length; i++) {
           var mc = matched[i];
+          var probeId = mc.id, sourceName = mc.name;
+          // The generic socket canary is named after the endpoint and message
+          // field it was replayed into, so each field is its own source.
+          if (mc.kind === 'websocket_message' && !mc.name && agent.activeSocket) {
+            sourceName = agent.activeSocket;
+            probeId = mc.kind + ':' + sourceName;
+          }
           emit({
             kind: 'flow', sink: sink, argument: arg, context: ctx,
-            value: preview(value, mc.token), probeId: mc.id,
-            sourceKind: mc.kind, sourceName: mc.name, discoveredBy: mc.discoveredBy,
+            value: preview(value, mc.token), probeId: probeId,
+            sourceKind: mc.kind, sourceName: sourceName, discoveredBy: mc.discoveredBy,
             transform: mc.transform,
             stack: stack, url: ctx === 'url' ? analyseURL(value, mc.token) : null
           });
@@ -379,12 +401,14 @@ const domAgentScript = `
             } catch (e) { noteErr(); }
           }
         }
+        return true;
       } else if (MODE === 'observe' && value) {
         var stack = captureStack();
         emit({ kind: 'sink', sink: sink, argument: arg, context: ctx,
                value: preview(value, null), stack: stack });
       }
     } catch (e) { noteErr(); }
+    return false;
   }
   // Register a canary discovered after the initial navigation (a form input, a
   // web message) so it is correlated without a state-losing reload.
@@ -475,8 +499,23 @@ const domAgentScript = `
 
   // Navigation sinks
   hookMethod(window, 'open', 'window.open', 'url', 0, 'navigation');
-  try { hookMethod(Location.prototype, 'assign', 'location.assign', 'url', 0, 'navigation'); } catch (e) {}
-  try { hookMethod(Location.prototype, 'replace', 'location.replace', 'url', 0, 'navigation'); } catch (e) {}
+  // A location change to a probe value is held back: following it would unload
+  // the page, and the recorded flow with it. Redirect confirmation sets
+  // CONFIG.navigate so the stand-in can see the navigation arrive.
+  ['assign', 'replace'].forEach(function (name) {
+    if (!sinkEnabled('navigation')) return;
+    try {
+      var orig = Location.prototype[name];
+      if (typeof orig !== 'function' || orig.__jsmdom) return;
+      var wrap = function (u) {
+        var hit = __jsmdomRecord('location.' + name, 0, u, 'url');
+        if (hit && !CONFIG.navigate) return;
+        return orig.apply(this, arguments);
+      };
+      wrap.__jsmdom = true;
+      Location.prototype[name] = wrap;
+    } catch (e) { noteErr(); }
+  });
   try { hookProp(HTMLAnchorElement.prototype, 'href', 'HTMLAnchorElement.href', 'url', 'navigation'); } catch (e) {}
   try { hookProp(HTMLIFrameElement.prototype, 'src', 'HTMLIFrameElement.src', 'url', 'navigation'); } catch (e) {}
   try { hookProp(HTMLFormElement.prototype, 'action', 'HTMLFormElement.action', 'url', 'navigation'); } catch (e) {}
@@ -506,6 +545,139 @@ const domAgentScript = `
     } catch (e) { noteErr(); }
   })();
 
+  // Trusted Types: record the policy names the page creates, so the CSP
+  // evidence can tell whether a permissive "default" policy stands in front of
+  // the sinks. Creation is passed straight through.
+  (function () {
+    try {
+      var tt = window.trustedTypes;
+      if (!tt || typeof tt.createPolicy !== 'function' || tt.createPolicy.__jsmdom) return;
+      var orig = tt.createPolicy;
+      var wrap = function (name) {
+        try {
+          agent.ttPolicies = agent.ttPolicies || [];
+          if (agent.ttPolicies.length < 32) agent.ttPolicies.push(String(name));
+        } catch (e) { noteErr(); }
+        return orig.apply(this, arguments);
+      };
+      wrap.__jsmdom = true;
+      tt.createPolicy = wrap;
+    } catch (e) { noteErr(); }
+  })();
+
+  // ---- WebSocket / EventSource message sources ------------------------------
+  // Wrap the constructors so every connection the page opens is tracked with
+  // the first message it receives. __jsmdomReplaySockets re-dispatches that
+  // message on the same object with the canary in one string field at a time,
+  // so the page's own onmessage/addEventListener handlers process it exactly as
+  // real server data — without anything being sent to the server.
+  if (CONFIG.sockets) {
+    (function () {
+      var sockets = agent.sockets = agent.sockets || [];
+      function endpointOf(u) {
+        try { var p = new URL(String(u), location.href); return p.origin + p.pathname; }
+        catch (e) { return String(u).split('?')[0]; }
+      }
+      function track(sock, u) {
+        try {
+          if (sockets.length >= 16) return;
+          var rec = { sock: sock, endpoint: endpointOf(u), sample: undefined };
+          sockets.push(rec);
+          NATIVE_ADD.call(sock, 'message', function (ev) {
+            try { if (!agent.replayingSocket && rec.sample === undefined) rec.sample = ev.data; } catch (e) {}
+          });
+        } catch (e) { noteErr(); }
+      }
+      function wrapCtor(name) {
+        try {
+          var Orig = window[name];
+          if (typeof Orig !== 'function' || Orig.__jsmdom) return;
+          var Wrapped = function (u, opts) {
+            var sock = arguments.length > 1 ? new Orig(u, opts) : new Orig(u);
+            track(sock, u);
+            return sock;
+          };
+          Wrapped.prototype = Orig.prototype;
+          ['CONNECTING', 'OPEN', 'CLOSING', 'CLOSED'].forEach(function (k) {
+            try { if (k in Orig) Wrapped[k] = Orig[k]; } catch (e) {}
+          });
+          Wrapped.__jsmdom = true;
+          window[name] = Wrapped;
+        } catch (e) { noteErr(); }
+      }
+      wrapCtor('WebSocket');
+      wrapCtor('EventSource');
+
+      // stringPaths lists up to 8 string leaves of a decoded message as dotted
+      // paths, the fields a replay injects into.
+      function stringPaths(o, prefix, out, depth) {
+        if (out.length >= 8 || depth > 4 || !o || typeof o !== 'object') return out;
+        var keys = Object.keys(o).slice(0, 50);
+        for (var i = 0; i < keys.length && out.length < 8; i++) {
+          var p = prefix ? prefix + '.' + keys[i] : keys[i];
+          if (typeof o[keys[i]] === 'string') out.push(p);
+          else stringPaths(o[keys[i]], p, out, depth + 1);
+        }
+        return out;
+      }
+      function withField(o, path, v) {
+        var copy = JSON.parse(JSON.stringify(o)), cur = copy, parts = path.split('.');
+        for (var i = 0; i < parts.length - 1; i++) cur = cur[parts[i]];
+        cur[parts[parts.length - 1]] = v;
+        return copy;
+      }
+      // variants shapes the canary like the observed message: each string
+      // field of a JSON message replaced in turn (keeping type/cmd fields that
+      // route the message intact for the others), or the bare value when the
+      // message was plain text, binary or never seen.
+      function variants(sample, v) {
+        var obj = null, text = false;
+        if (typeof sample === 'string') {
+          try { obj = JSON.parse(sample); text = true; } catch (e) {}
+        } else if (sample && typeof sample === 'object' && !(sample instanceof ArrayBuffer) &&
+                   !(typeof Blob !== 'undefined' && sample instanceof Blob)) {
+          obj = sample;
+        }
+        if (!obj || typeof obj !== 'object') return [{ path: '', data: v }];
+        var paths = stringPaths(obj, '', [], 0);
+        if (!paths.length) return [{ path: '', data: v }];
+        return paths.map(function (p) {
+          var d = withField(obj, p, v);
+          return { path: p, data: text ? JSON.stringify(d) : d };
+        });
+      }
+      window.__jsmdomReplaySockets = function (v) {
+        var n = 0;
+        for (var i = 0; i < sockets.length; i++) {
+          var rec = sockets[i], vs;
+          try { vs = variants(rec.sample, v); } catch (e) { noteErr(); continue; }
+          for (var j = 0; j < vs.length; j++) {
+            var label = rec.endpoint + (vs[j].path ? '#' + vs[j].path : '');
+            agent.replayingSocket = true;
+            agent.activeSocket = label;
+            try {
+              var origin = '';
+              try { origin = new URL(rec.endpoint).origin.replace(/^ws/, 'http'); } catch (e) {}
+              rec.sock.dispatchEvent(new MessageEvent('message', { data: vs[j].data, origin: origin }));
+              n++;
+            } catch (e) { noteErr(); }
+            agent.replayingSocket = false;
+          }
+          // Keep the attribution through handler-created microtasks, then clear
+          // it before unrelated later activity can be misattributed.
+          (function (label) {
+            try {
+              NATIVE_SET_TIMEOUT.call(window, function () {
+                if (agent.activeSocket === label) agent.activeSocket = null;
+              }, 0);
+            } catch (e) {}
+          })(label);
+        }
+        return n;
+      };
+    })();
+  }
+
   // ---- postMessage analysis ------------------------------------------------
   if (CONFIG.messages) {
     // Detect message listeners and whether they inspect origin/source.
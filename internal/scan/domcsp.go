@@ -0,0 +1,499 @@
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// CSP strength labels summarise how far a page's enforced policy constrains
+// injected script. They are stable output values.
+const (
+	// CSPStrengthNone means no enforced policy restricts script sources.
+	CSPStrengthNone = "none"
+	// CSPStrengthWeak means a policy exists but is bypassable: inline script is
+	// allowed, a wildcard or scheme source admits arbitrary hosts, or an
+	// allowlisted host serves JSONP/AngularJS gadgets.
+	CSPStrengthWeak = "weak"
+	// CSPStrengthModerate is a host allowlist with no known bypass, or a
+	// nonce/hash policy left open by 'unsafe-eval' or a missing object-src.
+	CSPStrengthModerate = "moderate"
+	// CSPStrengthStrict is a nonce- or hash-based policy with object-src
+	// restricted and no inline or eval allowance.
+	CSPStrengthStrict = "strict"
+)
+
+// cspBypassHosts are script hosts known to serve JSONP endpoints or AngularJS
+// builds that turn a host-allowlist script-src into arbitrary script execution.
+// Wildcard sources are matched against them, so *.google.com is caught too.
+var cspBypassHosts = []string{
+	"accounts.google.com", "ajax.googleapis.com", "apis.google.com",
+	"cdn.jsdelivr.net", "cdnjs.cloudflare.com", "code.angularjs.org",
+	"ssl.google-analytics.com", "ssl.gstatic.com", "unpkg.com",
+	"www.google-analytics.com", "www.google.com", "www.googleapis.com",
+	"www.googletagmanager.com", "www.gstatic.com",
+}
+
+// cspPolicy is one policy as delivered to the page, before evaluation.
+type cspPolicy struct {
+	Text       string
+	Delivery   string
+	ReportOnly bool
+}
+
+// parseCSP splits a serialized policy into its directives. Directive names are
+// case-insensitive and the first occurrence of a repeated directive wins, as
+// the CSP specification requires.
+func parseCSP(policy string) map[string][]string {
+	out := make(map[string][]string)
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, ok := out[name]; ok {
+			continue
+		}
+		out[name] = fields[1:]
+	}
+	return out
+}
+
+// splitCSPHeader splits a Content-Security-Policy header value into its
+// individual policies. CDP joins repeated headers with newlines and a single
+// header may carry several comma-separated policies.
+func splitCSPHeader(v string) []string {
+	var out []string
+	for _, line := range strings.Split(v, "\n") {
+		for _, p := range strings.Split(line, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// assessCSPDirectives evaluates the script-related properties of one enforced
+// policy, returning partial evidence with Strength and Issues filled in.
+func assessCSPDirectives(d map[string][]string) DOMCSPEvidence {
+	var ev DOMCSPEvidence
+	script, ok := d["script-src"]
+	if !ok {
+		script, ok = d["default-src"]
+	}
+	if !ok {
+		ev.Strength = CSPStrengthNone
+		ev.Issues = append(ev.Issues, "no script-src or default-src restricts scripts")
+		if _, obj := d["object-src"]; !obj {
+			ev.MissingObjectSrc = true
+		}
+		return ev
+	}
+
+	var inline bool
+	var hosts []string
+	for _, tok := range script {
+		lt := strings.ToLower(tok)
+		switch {
+		case strings.HasPrefix(lt, "'nonce-"), strings.HasPrefix(lt, "'sha256-"),
+			strings.HasPrefix(lt, "'sha384-"), strings.HasPrefix(lt, "'sha512-"):
+			ev.NonceOrHash = true
+		case lt == "'strict-dynamic'":
+			ev.StrictDynamic = true
+		case lt == "'unsafe-inline'":
+			inline = true
+		case lt == "'unsafe-eval'":
+			ev.UnsafeEval = true
+		case strings.HasPrefix(lt, "'"):
+			// 'self', 'none', 'report-sample', 'wasm-unsafe-eval' and the like.
+		case lt == "*", lt == "http:", lt == "https:", lt == "data:", lt == "blob:":
+			ev.WildcardSources = append(ev.WildcardSources, lt)
+		default:
+			hosts = append(hosts, lt)
+		}
+	}
+	// A nonce or hash disables 'unsafe-inline' in every CSP2+ browser, and
+	// 'strict-dynamic' alongside one makes the host and scheme allowlist inert.
+	ev.UnsafeInline = inline && !ev.NonceOrHash
+	if !(ev.StrictDynamic && ev.NonceOrHash) {
+		for _, h := range hosts {
+			host := cspSourceHost(h)
+			if strings.HasPrefix(host, "*") {
+				ev.WildcardSources = append(ev.WildcardSources, h)
+			}
+			for _, b := range cspBypassHosts {
+				if cspHostCovers(host, b) {
+					ev.BypassHosts = append(ev.BypassHosts, b)
+				}
+			}
+		}
+	} else {
+		ev.WildcardSources = nil
+	}
+	ev.WildcardSources = uniqueSortedStrings(ev.WildcardSources)
+	ev.BypassHosts = uniqueSortedStrings(ev.BypassHosts)
+	if _, obj := d["object-src"]; !obj {
+		if _, def := d["default-src"]; !def {
+			ev.MissingObjectSrc = true
+		}
+	}
+
+	if ev.UnsafeInline {
+		ev.Issues = append(ev.Issues, "script-src allows 'unsafe-inline'")
+	}
+	if ev.UnsafeEval {
+		ev.Issues = append(ev.Issues, "script-src allows 'unsafe-eval'")
+	}
+	if len(ev.WildcardSources) > 0 {
+		ev.Issues = append(ev.Issues, "script-src allows wildcard sources: "+strings.Join(ev.WildcardSources, " "))
+	}
+	if len(ev.BypassHosts) > 0 {
+		ev.Issues = append(ev.Issues, "script-src allowlists JSONP/AngularJS-capable hosts: "+strings.Join(ev.BypassHosts, " "))
+	}
+	if ev.MissingObjectSrc {
+		ev.Issues = append(ev.Issues, "no object-src or default-src restricts plugins")
+	}
+
+	switch {
+	case ev.UnsafeInline || len(ev.WildcardSources) > 0 || len(ev.BypassHosts) > 0:
+		ev.Strength = CSPStrengthWeak
+	case ev.NonceOrHash && !ev.UnsafeEval && !ev.MissingObjectSrc:
+		ev.Strength = CSPStrengthStrict
+	default:
+		ev.Strength = CSPStrengthModerate
+	}
+	return ev
+}
+
+// cspSourceHost reduces a host-source expression (scheme, port and path
+// optional) to its lower-case host pattern.
+func cspSourceHost(src string) string {
+	if i := strings.Index(src, "://"); i >= 0 {
+		src = src[i+3:]
+	}
+	if i := strings.IndexByte(src, '/'); i >= 0 {
+		src = src[:i]
+	}
+	if i := strings.LastIndexByte(src, ':'); i >= 0 {
+		src = src[:i]
+	}
+	return strings.ToLower(src)
+}
+
+// cspHostCovers reports whether a host-source pattern admits host.
+func cspHostCovers(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	return strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])
+}
+
+// evaluateCSP combines every policy delivered to a page, plus the Trusted Types
+// policies the page created at runtime, into one evidence record. A resource
+// must satisfy every enforced policy, so the strongest policy decides the
+// page's strength and its issues are the ones reported.
+func evaluateCSP(policies []cspPolicy, ttPolicies []string, ttDefault bool) *DOMCSPEvidence {
+	ev := &DOMCSPEvidence{Present: len(policies) > 0, Strength: CSPStrengthNone}
+	var best *DOMCSPEvidence
+	var ttNames []string
+	for _, p := range policies {
+		ev.Delivery = append(ev.Delivery, p.Delivery)
+		if p.ReportOnly {
+			continue
+		}
+		ev.Enforced = true
+		d := parseCSP(p.Text)
+		for _, tok := range d["require-trusted-types-for"] {
+			if strings.EqualFold(tok, "'script'") {
+				ev.TrustedTypesRequired = true
+			}
+		}
+		for _, tok := range d["trusted-types"] {
+			if !strings.HasPrefix(tok, "'") && tok != "*" {
+				ttNames = append(ttNames, tok)
+			}
+		}
+		a := assessCSPDirectives(d)
+		if best == nil || cspStrengthRank(a.Strength) > cspStrengthRank(best.Strength) {
+			best = &a
+		}
+	}
+	ev.Delivery = uniqueSortedStrings(ev.Delivery)
+	ev.TrustedTypesPolicies = uniqueSortedStrings(append(ttNames, ttPolicies...))
+	ev.TrustedTypesDefaultPolicy = ttDefault
+
+	switch {
+	case best != nil:
+		ev.Strength = best.Strength
+		ev.NonceOrHash, ev.StrictDynamic = best.NonceOrHash, best.StrictDynamic
+		ev.UnsafeInline, ev.UnsafeEval = best.UnsafeInline, best.UnsafeEval
+		ev.WildcardSources, ev.BypassHosts = best.WildcardSources, best.BypassHosts
+		ev.MissingObjectSrc = best.MissingObjectSrc
+		ev.Issues = best.Issues
+	case ev.Present:
+		ev.Issues = []string{"policy is report-only and is not enforced"}
+	default:
+		ev.Issues = []string{"no Content-Security-Policy"}
+	}
+	if ev.TrustedTypesRequired && ev.TrustedTypesDefaultPolicy {
+		ev.Issues = append(ev.Issues, "a Trusted Types default policy converts plain strings at every sink")
+	}
+	return ev
+}
+
+// cspStrengthRank orders strength labels; higher is more protective.
+func cspStrengthRank(s string) int {
+	switch s {
+	case CSPStrengthStrict:
+		return 3
+	case CSPStrengthModerate:
+		return 2
+	case CSPStrengthWeak:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// cspProtectionRank orders evidence records by how much they protect a flow,
+// counting Trusted Types enforcement (without a default policy) above the same
+// script-src on its own.
+func cspProtectionRank(ev *DOMCSPEvidence) int {
+	if ev == nil || !ev.Enforced {
+		return 0
+	}
+	r := cspStrengthRank(ev.Strength) * 2
+	if ev.TrustedTypesRequired && !ev.TrustedTypesDefaultPolicy {
+		r++
+	}
+	return r
+}
+
+// cspIdentity is the stable identity of a page's policy evaluation, used to
+// collapse weak_csp findings for a policy shared across a site. It is built from
+// the evaluated properties, never the raw text, because nonces change on every
+// response.
+func cspIdentity(ev *DOMCSPEvidence) string {
+	return ev.Strength + "|" + strings.Join(ev.Delivery, "+") + "|" + strings.Join(ev.Issues, "|")
+}
+
+// cspMitigation reports how the page's CSP affects an unconfirmed flow. blocked
+// means the browser rejects the controllable value at this sink outright;
+// otherwise a non-empty reason means the policy limits, but does not remove,
+// what injection can achieve.
+func cspMitigation(f DOMFinding) (reason string, blocked bool) {
+	if f.Confirmed || f.Type != DOMTypeFlow || f.CSP == nil || !f.CSP.Enforced {
+		return "", false
+	}
+	ev := f.CSP
+	scriptURL := f.Sink != nil && f.Sink.Name == "HTMLScriptElement.src"
+	ttSink := f.Context == "html" || f.Context == "js" || scriptURL
+	if ttSink && ev.TrustedTypesRequired && !ev.TrustedTypesDefaultPolicy {
+		return "Trusted Types are enforced without a default policy, so a plain string is rejected at this sink unless application code passes it through a policy", true
+	}
+	if f.Context == "js" && ev.Strength != CSPStrengthNone && !ev.UnsafeEval {
+		return "the enforced CSP does not allow 'unsafe-eval', so string evaluation at this sink is blocked", true
+	}
+	if ev.Strength != CSPStrengthStrict {
+		return "", false
+	}
+	switch {
+	case f.Context == "html" || f.Context == "attribute":
+		return "a strict nonce/hash-based CSP blocks injected inline script; markup injection may still allow non-script attacks", false
+	case scriptURL && !ev.StrictDynamic:
+		return "a strict CSP without 'strict-dynamic' only loads nonce- or hash-approved scripts", false
+	}
+	return "", false
+}
+
+// cspAdjustedConfidence lowers a flow's confidence when the page's CSP blocks
+// or limits the sink. Severity is impact and is left alone.
+func cspAdjustedConfidence(f DOMFinding) string {
+	reason, blocked := cspMitigation(f)
+	switch {
+	case blocked:
+		return ConfidenceLow
+	case reason != "" && f.Confidence == ConfidenceHigh:
+		return ConfidenceMedium
+	}
+	return f.Confidence
+}
+
+// cspTriage returns the triage hint for a flow the page's CSP blocks or limits,
+// or nil when the policy does not change the assessment.
+func cspTriage(f DOMFinding) *DOMTriage {
+	reason, blocked := cspMitigation(f)
+	switch {
+	case blocked:
+		return &DOMTriage{Verdict: DOMTriageLikelyBenign, Reason: reason}
+	case reason != "":
+		return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: reason}
+	}
+	return nil
+}
+
+// weakCSPFinding builds the standalone weak_csp finding for a page, or reports
+// false when its policy is strict or has nothing worth raising. An enforced but
+// bypassable policy is low severity; a missing or report-only one is info.
+func weakCSPFinding(target, pageURL string, ev *DOMCSPEvidence) (DOMFinding, bool) {
+	if ev == nil || len(ev.Issues) == 0 || ev.Strength == CSPStrengthStrict {
+		return DOMFinding{}, false
+	}
+	f := DOMFinding{
+		Type:       DOMTypeWeakCSP,
+		Target:     target,
+		PageURL:    pageURL,
+		FrameURL:   pageURL,
+		Trigger:    TriggerPageLoad,
+		Phase:      PhaseInitialLoad,
+		Severity:   SeverityInfo,
+		Confidence: ConfidenceHigh,
+		CSP:        ev,
+	}
+	if ev.Enforced && ev.Strength == CSPStrengthWeak {
+		f.Severity = SeverityLow
+	}
+	f.Triage = assessDOMFinding(f)
+	f.Fingerprint = f.computeFingerprint()
+	return f, true
+}
+
+// domCSPCapture collects the policies delivered to one scanned page: the
+// headers of its document response (from the network listener) and, once the
+// page has loaded, its <meta> policies and runtime Trusted Types policies.
+type domCSPCapture struct {
+	mu         sync.Mutex
+	document   bool
+	loaded     bool
+	policies   []cspPolicy
+	ttPolicies []string
+	ttDefault  bool
+}
+
+// response records the CSP headers of the first document response. The top
+// document commits before any frame it embeds, and later confirmation reloads
+// serve the same policy, so only the first is kept.
+func (c *domCSPCapture) response(resourceType network.ResourceType, headers network.Headers) {
+	if c == nil || resourceType != network.ResourceTypeDocument {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.document {
+		return
+	}
+	c.document = true
+	for k, v := range headers {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var delivery string
+		switch strings.ToLower(k) {
+		case "content-security-policy":
+			delivery = "header"
+		case "content-security-policy-report-only":
+			delivery = "header-report-only"
+		default:
+			continue
+		}
+		for _, p := range splitCSPHeader(s) {
+			c.policies = append(c.policies, cspPolicy{Text: p, Delivery: delivery, ReportOnly: delivery == "header-report-only"})
+		}
+	}
+}
+
+// cspDocumentExpr reads the page's <meta http-equiv> policies and the Trusted
+// Types state the agent recorded.
+const cspDocumentExpr = `JSON.stringify({
+	meta: Array.prototype.slice.call(document.querySelectorAll('meta[http-equiv]')).filter(function(m) {
+		return (m.httpEquiv || '').toLowerCase() === 'content-security-policy';
+	}).map(function(m) { return m.content || ''; }),
+	policies: (window.__jsmdom && window.__jsmdom.ttPolicies) || [],
+	defaultPolicy: !!(window.trustedTypes && window.trustedTypes.defaultPolicy)
+})`
+
+// readDocument reads the loaded page's meta policies and Trusted Types state.
+// It runs once, right after the first navigation, before exploration can move
+// the tab elsewhere.
+func (c *domCSPCapture) readDocument(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	done := c.loaded
+	c.mu.Unlock()
+	if done {
+		return
+	}
+	var raw string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(cspDocumentExpr, &raw)); err != nil {
+		return
+	}
+	var doc struct {
+		Meta          []string `json:"meta"`
+		Policies      []string `json:"policies"`
+		DefaultPolicy bool     `json:"defaultPolicy"`
+	}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = true
+	for _, m := range doc.Meta {
+		if m = strings.TrimSpace(m); m != "" {
+			c.policies = append(c.policies, cspPolicy{Text: m, Delivery: "meta"})
+		}
+	}
+	c.ttPolicies = doc.Policies
+	c.ttDefault = doc.DefaultPolicy
+}
+
+// evaluate returns the page's CSP evidence, or nil when the page never loaded
+// far enough for its policies to be read.
+func (c *domCSPCapture) evaluate() *DOMCSPEvidence {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		return nil
+	}
+	policies := append([]cspPolicy(nil), c.policies...)
+	sort.SliceStable(policies, func(i, j int) bool { return policies[i].Delivery < policies[j].Delivery })
+	return evaluateCSP(policies, c.ttPolicies, c.ttDefault)
+}
+
+// applyCSP attaches a page's CSP evidence to the flows recorded for it,
+// lowering confidence and re-assessing triage where the policy blocks or limits
+// the sink, and records the standalone weak_csp finding when the policy is weak.
+func (s *domScanner) applyCSP(baseHost, pageURL string, ev *DOMCSPEvidence) {
+	if ev == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.findings {
+		f := &s.findings[i]
+		if f.PageURL != pageURL || f.Type != DOMTypeFlow || f.CSP != nil {
+			continue
+		}
+		f.CSP = ev
+		f.Confidence = cspAdjustedConfidence(*f)
+		f.Triage = assessDOMFinding(*f)
+	}
+	target := (&url.URL{Scheme: schemeOf(pageURL), Host: baseHost}).String()
+	if wf, ok := weakCSPFinding(target, pageURL, ev); ok {
+		s.findings = append(s.findings, wf)
+	}
+}
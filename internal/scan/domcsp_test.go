@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestEvaluateCSPStrength(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"strict-nonce", "script-src 'nonce-abc' 'strict-dynamic'; object-src 'none'; base-uri 'none'", CSPStrengthStrict},
+		{"nonce-neutralises-inline", "script-src 'nonce-abc' 'unsafe-inline'; object-src 'none'", CSPStrengthStrict},
+		{"unsafe-inline", "default-src 'self' 'unsafe-inline'", CSPStrengthWeak},
+		{"wildcard", "script-src 'self' https:; object-src 'none'", CSPStrengthWeak},
+		{"bypass-host", "script-src 'self' https://www.google.com; object-src 'none'", CSPStrengthWeak},
+		{"wildcard-covers-bypass", "script-src *.googleapis.com; object-src 'none'", CSPStrengthWeak},
+		{"allowlist", "script-src 'self' https://static.app.test; object-src 'none'", CSPStrengthModerate},
+		{"nonce-missing-object-src", "script-src 'nonce-abc'", CSPStrengthModerate},
+		{"no-script-directive", "img-src 'self'", CSPStrengthNone},
+	}
+	for _, c := range cases {
+		ev := evaluateCSP([]cspPolicy{{Text: c.policy, Delivery: "header"}}, nil, false)
+		if ev.Strength != c.want {
+			t.Errorf("%s: strength = %s, want %s (issues %v)", c.name, ev.Strength, c.want, ev.Issues)
+		}
+	}
+}
+
+func TestEvaluateCSPStrictDynamicIgnoresAllowlist(t *testing.T) {
+	ev := evaluateCSP([]cspPolicy{{Text: "script-src 'nonce-x' 'strict-dynamic' https: www.google.com; object-src 'none'", Delivery: "header"}}, nil, false)
+	if len(ev.WildcardSources) != 0 || len(ev.BypassHosts) != 0 {
+		t.Errorf("allowlist should be inert under strict-dynamic: %+v", ev)
+	}
+}
+
+func TestEvaluateCSPReportOnlyAndMissing(t *testing.T) {
+	ro := evaluateCSP([]cspPolicy{{Text: "script-src 'nonce-x'; object-src 'none'", Delivery: "header-report-only", ReportOnly: true}}, nil, false)
+	if !ro.Present || ro.Enforced || ro.Strength != CSPStrengthNone {
+		t.Errorf("report-only policy evaluated as %+v", ro)
+	}
+	none := evaluateCSP(nil, nil, false)
+	if none.Present || none.Strength != CSPStrengthNone || len(none.Issues) == 0 {
+		t.Errorf("missing policy evaluated as %+v", none)
+	}
+}
+
+// TestEvaluateCSPStrongestPolicyWins checks that with several enforced policies
+// the strongest decides the page, since a script must satisfy all of them.
+func TestEvaluateCSPStrongestPolicyWins(t *testing.T) {
+	ev := evaluateCSP([]cspPolicy{
+		{Text: "script-src 'self' 'unsafe-inline'", Delivery: "header"},
+		{Text: "script-src 'nonce-x'; object-src 'none'; require-trusted-types-for 'script'; trusted-types app", Delivery: "meta"},
+	}, []string{"lit"}, false)
+	if ev.Strength != CSPStrengthStrict || !ev.TrustedTypesRequired {
+		t.Errorf("combined evidence = %+v", ev)
+	}
+	if want := []string{"header", "meta"}; !reflect.DeepEqual(ev.Delivery, want) {
+		t.Errorf("delivery = %v, want %v", ev.Delivery, want)
+	}
+	if want := []string{"app", "lit"}; !reflect.DeepEqual(ev.TrustedTypesPolicies, want) {
+		t.Errorf("trusted types policies = %v, want %v", ev.TrustedTypesPolicies, want)
+	}
+}
+
+func TestCSPMitigationAdjustsFlows(t *testing.T) {
+	strictTT := evaluateCSP([]cspPolicy{{Text: "script-src 'nonce-x'; object-src 'none'; require-trusted-types-for 'script'", Delivery: "header"}}, nil, false)
+	strict := evaluateCSP([]cspPolicy{{Text: "script-src 'nonce-x'; object-src 'none'", Delivery: "header"}}, nil, false)
+	weak := evaluateCSP([]cspPolicy{{Text: "script-src 'self' 'unsafe-inline' 'unsafe-eval'", Delivery: "header"}}, nil, false)
+
+	scriptSrc := mkFlow("u", "HTMLScriptElement.src", "url", TriggerPageLoad, "jsmdomc")
+	scriptSrc.CSP = strictTT
+	if got := cspAdjustedConfidence(scriptSrc); got != ConfidenceLow {
+		t.Errorf("script.src under Trusted Types: confidence %s, want low", got)
+	}
+	if got := assessDOMFinding(scriptSrc); got.Verdict != DOMTriageLikelyBenign {
+		t.Errorf("script.src under Trusted Types: triage %+v", got)
+	}
+
+	html := mkFlow("q", "Element.innerHTML", "html", TriggerPageLoad, "jsmdomc")
+	html.CSP = strict
+	if got := cspAdjustedConfidence(html); got != ConfidenceMedium {
+		t.Errorf("html under strict CSP: confidence %s, want medium", got)
+	}
+	if got := assessDOMFinding(html); got.Verdict != DOMTriageWorthReview || !strings.Contains(got.Reason, "nonce") {
+		t.Errorf("html under strict CSP: triage %+v", got)
+	}
+
+	eval := mkFlow("q", "eval", "js", TriggerPageLoad, "jsmdomc")
+	eval.CSP = strict
+	if _, blocked := cspMitigation(eval); !blocked {
+		t.Error("eval without 'unsafe-eval' should be blocked")
+	}
+	eval.CSP = weak
+	if got := cspAdjustedConfidence(eval); got != ConfidenceHigh {
+		t.Errorf("eval under a weak CSP: confidence %s, want unchanged high", got)
+	}
+
+	confirmed := html
+	confirmed.Confirmed = true
+	if reason, _ := cspMitigation(confirmed); reason != "" {
+		t.Errorf("confirmed execution must not be discounted: %q", reason)
+	}
+}
+
+func TestWeakCSPFindingCollapsesAcrossPages(t *testing.T) {
+	policy := "default-src 'self' 'unsafe-inline'"
+	a, ok := weakCSPFinding("https://app.test", "https://app.test/a", evaluateCSP([]cspPolicy{{Text: policy, Delivery: "header"}}, nil, false))
+	if !ok || a.Severity != SeverityLow || a.Triage.Verdict != DOMTriageWorthReview {
+		t.Fatalf("weak enforced policy finding = %+v ok=%t", a, ok)
+	}
+	b, _ := weakCSPFinding("https://app.test", "https://app.test/b", evaluateCSP([]cspPolicy{{Text: policy, Delivery: "header"}}, nil, false))
+	got := DedupDOMFindings([]DOMFinding{a, b})
+	if len(got) != 1 || got[0].SeenOnPages != 2 {
+		t.Fatalf("expected one weak_csp finding seen on 2 pages, got %+v", got)
+	}
+
+	if _, ok := weakCSPFinding("https://app.test", "https://app.test/", evaluateCSP([]cspPolicy{{Text: "script-src 'nonce-x'; object-src 'none'", Delivery: "header"}}, nil, false)); ok {
+		t.Error("strict policy should not produce a weak_csp finding")
+	}
+	if f, ok := weakCSPFinding("https://app.test", "https://app.test/", evaluateCSP(nil, nil, false)); !ok || f.Severity != SeverityInfo {
+		t.Errorf("missing policy finding = %+v ok=%t, want info", f, ok)
+	}
+}
+
+func TestDOMCSPCaptureKeepsFirstDocument(t *testing.T) {
+	c := &domCSPCapture{}
+	c.response(network.ResourceTypeScript, network.Headers{"Content-Security-Policy": "script-src *"})
+	c.response(network.ResourceTypeDocument, network.Headers{
+		"content-security-policy":             "script-src 'nonce-a'; object-src 'none', default-src 'self'",
+		"Content-Security-Policy-Report-Only": "script-src 'none'",
+	})
+	c.response(network.ResourceTypeDocument, network.Headers{"Content-Security-Policy": "script-src *"})
+	if c.evaluate() != nil {
+		t.Fatal("evidence before the document was read should be nil")
+	}
+	c.loaded = true
+	ev := c.evaluate()
+	if ev.Strength != CSPStrengthStrict {
+		t.Errorf("strength = %s, want strict (issues %v)", ev.Strength, ev.Issues)
+	}
+	if want := []string{"header", "header-report-only"}; !reflect.DeepEqual(ev.Delivery, want) {
+		t.Errorf("delivery = %v, want %v", ev.Delivery, want)
+	}
+}
@@ -13,7 +13,7 @@ import (
 // structured output so downstream consumers can detect an incompatible change.
 // Bump the minor version when adding fields, the major version when changing or
 // removing an existing field's meaning.
-const DOMSchemaVersion = "dom.1.2"
+const DOMSchemaVersion = "dom.1.4"
 
 // DOM finding types. These strings are stable public identifiers: automated
 // triage keys off them, so their spellings must not change. New analyses
@@ -34,6 +34,16 @@ const (
 	// data reached a sink.
 	DOMTypeWebMessage = "web_message"
 
+	// DOMTypeOpenRedirect is a source-to-sink flow into a navigation sink where
+	// the input decides the destination origin (or the whole URL), so the page
+	// can be made to send its visitor elsewhere.
+	DOMTypeOpenRedirect = "open_redirect"
+
+	// DOMTypeWeakCSP reports a page whose Content-Security-Policy (or lack of
+	// one) would not stop injected script from running. It describes the
+	// page's defences, not a flow, so it is informational or low severity.
+	DOMTypeWeakCSP = "weak_csp"
+
 	// DOMTypeSummary is the final scan-summary record emitted once per scan in
 	// streaming output.
 	DOMTypeSummary = "scan_summary"
@@ -58,9 +68,10 @@ const (
 // as evidence in the finding and, folded to a category, as part of dedup so the
 // same flow reached several ways collapses to one record with combined triggers.
 const (
-	TriggerPageLoad    = "page_load"
-	TriggerInteraction = "interaction"
-	TriggerPostMessage = "post_message"
+	TriggerPageLoad      = "page_load"
+	TriggerInteraction   = "interaction"
+	TriggerPostMessage   = "post_message"
+	TriggerSocketMessage = "socket_message"
 )
 
 // Scan phase distinguishes a flow seen during the initial page load from one
@@ -154,6 +165,46 @@ type DOMURLEvidence struct {
 	CanaryComponent   string `json:"canary_component,omitempty"`
 	InputKind         string `json:"input_kind,omitempty"`
 	ExecutableScheme  bool   `json:"executable_scheme"`
+
+	// CanaryLeading reports that the marker started the sink value, i.e. the
+	// input supplied the whole URL rather than a piece of it.
+	CanaryLeading bool `json:"canary_leading"`
+
+	// Control is the part of the navigation the input decides; see the
+	// NavControl* values.
+	Control string `json:"control,omitempty"`
+}
+
+// DOMCSPEvidence summarises the Content-Security-Policy and Trusted Types
+// configuration of the page a finding was observed on. Policies are reduced to
+// the properties that decide whether injected script would run; raw policy
+// text is not kept because it carries per-response nonces.
+type DOMCSPEvidence struct {
+	Present  bool     `json:"present"`
+	Delivery []string `json:"delivery,omitempty"` // header, header-report-only, meta
+	Enforced bool     `json:"enforced"`
+
+	// Strength is none, weak, moderate or strict; see the CSPStrength* values.
+	Strength string `json:"strength"`
+
+	NonceOrHash      bool     `json:"nonce_or_hash"`
+	StrictDynamic    bool     `json:"strict_dynamic"`
+	UnsafeInline     bool     `json:"unsafe_inline"`
+	UnsafeEval       bool     `json:"unsafe_eval"`
+	WildcardSources  []string `json:"wildcard_sources,omitempty"`
+	BypassHosts      []string `json:"bypass_hosts,omitempty"`
+	MissingObjectSrc bool     `json:"missing_object_src"`
+
+	// TrustedTypesRequired is set when an enforced policy carries
+	// require-trusted-types-for 'script'. TrustedTypesPolicies lists the policy
+	// names the trusted-types directive allows plus those the page created at
+	// runtime; TrustedTypesDefaultPolicy reports a "default" policy, which
+	// converts plain strings at every sink and so weakens enforcement.
+	TrustedTypesRequired      bool     `json:"trusted_types_required"`
+	TrustedTypesPolicies      []string `json:"trusted_types_policies,omitempty"`
+	TrustedTypesDefaultPolicy bool     `json:"trusted_types_default_policy"`
+
+	Issues []string `json:"issues,omitempty"`
 }
 
 // DOMTriage is a conservative, evidence-backed hint for deciding whether a
@@ -204,7 +255,7 @@ type DOMFinding struct {
 	Stack []DOMStackFrame `json:"stack,omitempty"`
 
 	// Trigger is the primary trigger category (page_load, interaction,
-	// post_message). Triggers holds every distinct trigger a deduplicated flow was
+	// post_message, socket_message). Triggers holds every distinct trigger a deduplicated flow was
 	// observed through.
 	Trigger  string   `json:"trigger,omitempty"`
 	Triggers []string `json:"triggers,omitempty"`
@@ -231,6 +282,10 @@ type DOMFinding struct {
 	// URL carries structural destination evidence for URL/navigation sinks.
 	URL *DOMURLEvidence `json:"url,omitempty"`
 
+	// CSP describes the page's Content-Security-Policy and Trusted Types
+	// configuration, for flows and weak_csp findings.
+	CSP *DOMCSPEvidence `json:"csp,omitempty"`
+
 	// Triage explains, in plain terms, how much attention the current evidence
 	// deserves. It never upgrades severity and does not replace manual review.
 	Triage *DOMTriage `json:"triage,omitempty"`
@@ -242,6 +297,10 @@ type DOMFinding struct {
 	// Notes carries any diagnostic annotations (e.g. that instrumentation appeared
 	// to break page execution). It is advisory context, not part of identity.
 	Notes string `json:"notes,omitempty"`
+
+	// Asset describes the page's host when enrichment is enabled (see
+	// Enricher). It is not part of identity.
+	Asset *Asset `json:"asset,omitempty"`
 }
 
 // originOf returns scheme://host for a URL, the stable "target origin" component
@@ -289,6 +348,9 @@ func (f *DOMFinding) scriptLocation() string {
 // not merged. Note that inline scripts carry the document URL in their location,
 // so a genuinely per-page inline bug keeps a page-specific identity naturally.
 func (f *DOMFinding) codeIdentity() string {
+	if f.Type == DOMTypeWeakCSP && f.CSP != nil {
+		return "csp:" + cspIdentity(f.CSP)
+	}
 	if f.Message != nil {
 		if k := messageListenerKey(f.Message.ListenerLocations); k != "" {
 			return "listeners:" + k
@@ -328,6 +390,9 @@ func (f *DOMFinding) computeFingerprint() string {
 	if f.Message != nil {
 		write("msg", messageIdentityKey(f.Message))
 	}
+	if f.Type == DOMTypeWeakCSP && f.CSP != nil {
+		write("csp", cspIdentity(f.CSP))
+	}
 	sum := sha256.Sum256([]byte(b.String()))
 	return fmt.Sprintf("%x", sum[:16])
 }
@@ -492,6 +557,11 @@ func DedupDOMFindings(findings []DOMFinding) []DOMFinding {
 		if e.f.URL == nil {
 			e.f.URL = f.URL
 		}
+		// A flow shared across pages is as exploitable as its least protected
+		// page, so keep the weakest policy seen.
+		if f.CSP != nil && (e.f.CSP == nil || cspProtectionRank(f.CSP) < cspProtectionRank(e.f.CSP)) {
+			e.f.CSP = f.CSP
+		}
 	}
 
 	out := make([]DOMFinding, 0, len(byFP))
@@ -541,7 +611,7 @@ func mergeTriggers(acc []string, add ...string) []string {
 }
 
 // primaryTriggerOf picks the most specific trigger category present, preferring
-// an active interaction/post-message over a passive page load.
+// an active post-message, socket replay or interaction over a passive page load.
 func primaryTriggerOf(triggers []string) string {
 	has := func(t string) bool {
 		for _, x := range triggers {
@@ -554,6 +624,8 @@ func primaryTriggerOf(triggers []string) string {
 	switch {
 	case has(TriggerPostMessage):
 		return TriggerPostMessage
+	case has(TriggerSocketMessage):
+		return TriggerSocketMessage
 	case has(TriggerInteraction):
 		return TriggerInteraction
 	case has(TriggerPageLoad):
@@ -0,0 +1,286 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Navigation control labels say which part of a navigation URL the input
+// decides. They are stable output values.
+const (
+	// NavControlURL means the input supplied the whole URL: both the scheme
+	// (javascript:) and the destination origin are attacker-chosen.
+	NavControlURL = "url"
+	// NavControlOrigin means the input lands in the host or credentials, so it
+	// picks the destination origin but not the scheme.
+	NavControlOrigin = "origin"
+	// NavControlScheme means the input lands in the scheme or inside an
+	// executable javascript:/data: URL.
+	NavControlScheme = "scheme"
+	// NavControlPath, NavControlQuery and NavControlFragment keep the
+	// destination fixed; the input only shapes part of it.
+	NavControlPath     = "path"
+	NavControlQuery    = "query"
+	NavControlFragment = "fragment"
+	// NavControlOpaque is a marker the browser's URL parser left outside every
+	// component (an unusual scheme, say).
+	NavControlOpaque = "opaque"
+)
+
+// navigationControl classifies the URL evidence of a flow by the component its
+// marker landed in.
+func navigationControl(u *DOMURLEvidence) string {
+	if u == nil || !u.Resolved {
+		return ""
+	}
+	switch {
+	case u.CanaryLeading:
+		return NavControlURL
+	case u.CanaryComponent == "scheme":
+		return NavControlScheme
+	case u.ExecutableScheme && u.CanaryComponent != "":
+		// Everything after javascript: is code, whichever URL part the parser
+		// assigned the marker to.
+		return NavControlScheme
+	case u.CanaryComponent == "authority" || u.CanaryComponent == "credentials":
+		// A value placed in the userinfo can end it early ("evil.test/#"), so it
+		// decides the host as surely as a value placed there directly.
+		return NavControlOrigin
+	}
+	return u.CanaryComponent
+}
+
+// Navigation sink kinds. An automatic sink navigates as soon as it is called;
+// a user sink only prepares a link or form the visitor must follow; a frame
+// sink loads the URL into an iframe.
+const (
+	navSinkAutomatic = "automatic"
+	navSinkUser      = "user"
+	navSinkFrame     = "frame"
+)
+
+// navigationSinkKind returns the kind of a navigation sink, or "" for sinks that
+// do not navigate (script.src, and setAttribute(src), which cannot be told apart
+// from a script element's).
+func navigationSinkKind(sink string) string {
+	switch sink {
+	case "location.assign", "location.replace", "window.open":
+		return navSinkAutomatic
+	case "HTMLAnchorElement.href", "HTMLFormElement.action",
+		"Element.setAttribute(href)", "Element.setAttribute(action)":
+		return navSinkUser
+	case "HTMLIFrameElement.src":
+		return navSinkFrame
+	}
+	return ""
+}
+
+// classifyNavigation settles the type and severity of a url-context flow from
+// the component its marker landed in. Input that decides the destination
+// origin of a top-level navigation is an open redirect; one that also decides
+// the scheme, or reaches a javascript: URL, is script execution once followed.
+// Flows whose input only shapes a fixed destination keep the plain flow rating.
+func classifyNavigation(f *DOMFinding) {
+	if f.Sink == nil || f.URL == nil {
+		return
+	}
+	f.URL.Control = navigationControl(f.URL)
+	kind := navigationSinkKind(f.Sink.Name)
+	if kind == "" {
+		return
+	}
+	switch f.URL.Control {
+	case NavControlURL:
+		if kind == navSinkFrame {
+			f.Severity = SeverityHigh
+			return
+		}
+		f.Type = DOMTypeOpenRedirect
+		f.Severity = SeverityHigh
+		if kind == navSinkUser {
+			f.Severity = SeverityMedium
+		}
+	case NavControlOrigin:
+		if kind == navSinkFrame {
+			return
+		}
+		f.Type = DOMTypeOpenRedirect
+		f.Severity = SeverityMedium
+		if kind == navSinkUser {
+			f.Severity = SeverityLow
+		}
+	case NavControlScheme:
+		f.Severity = SeverityHigh
+		if kind == navSinkUser {
+			f.Severity = SeverityMedium
+		}
+	}
+}
+
+// redirectTriage explains an open_redirect finding.
+func redirectTriage(f DOMFinding) *DOMTriage {
+	if f.Confirmed {
+		return &DOMTriage{Verdict: DOMTriageConfirmed, Reason: "the page navigated to a cross-origin stand-in URL supplied through this source"}
+	}
+	reason := "controllable data decides the navigation's destination origin"
+	if f.URL != nil && f.URL.Control == NavControlURL {
+		reason = "controllable data supplies the whole navigation URL, so it can name another origin or a javascript: URL"
+	}
+	if f.Sink != nil && navigationSinkKind(f.Sink.Name) == navSinkUser {
+		reason += "; a user must follow the link or submit the form"
+	}
+	return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: reason}
+}
+
+// domRedirectStandIn is a loopback HTTP server that stands in for an
+// attacker's site during redirect confirmation. It is cross-origin to every
+// target, serves an empty page and records which probe paths were requested,
+// so a hit proves the browser really navigated there.
+type domRedirectStandIn struct {
+	base string
+	srv  *http.Server
+
+	mu   sync.Mutex
+	hits map[string]bool
+}
+
+// domRedirectPath prefixes every stand-in probe path.
+const domRedirectPath = "/jsmredir/"
+
+func newDOMRedirectStandIn() (*domRedirectStandIn, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	d := &domRedirectStandIn{base: "http://" + ln.Addr().String(), hits: make(map[string]bool)}
+	d.srv = &http.Server{Handler: http.HandlerFunc(d.serve), ReadHeaderTimeout: 5 * time.Second}
+	go d.srv.Serve(ln)
+	return d, nil
+}
+
+func (d *domRedirectStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	if id, ok := strings.CutPrefix(r.URL.Path, domRedirectPath); ok && id != "" {
+		d.mu.Lock()
+		d.hits[id] = true
+		d.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte("<!doctype html><title>jsminer</title>"))
+}
+
+// url returns the stand-in URL for a probe marker.
+func (d *domRedirectStandIn) url(marker string) string {
+	return d.base + domRedirectPath + marker
+}
+
+// hit reports whether the stand-in URL for marker was requested.
+func (d *domRedirectStandIn) hit(marker string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.hits[marker]
+}
+
+func (d *domRedirectStandIn) close() {
+	if d != nil {
+		_ = d.srv.Close()
+	}
+}
+
+// redirectStandIn starts the scan's stand-in on first use. A failure to listen
+// is recorded once and leaves redirects unconfirmed.
+func (s *domScanner) redirectStandIn() *domRedirectStandIn {
+	s.standInOnce.Do(func() {
+		d, err := newDOMRedirectStandIn()
+		if err != nil {
+			s.addErr("redirect stand-in: " + err.Error())
+			return
+		}
+		s.standIn = d
+	})
+	return s.standIn
+}
+
+// confirmRedirects replays each source that supplied a whole navigation URL on
+// this page with the stand-in URL as its value, one page load per source since
+// the first redirect that fires leaves the page. A source the stand-in then
+// hears from is marked confirmed. Origin-only control is not replayed: the
+// surrounding scheme and path the page adds decide whether a loopback URL can
+// be formed at all.
+func (s *domScanner) confirmRedirects(ctx context.Context, pageURL, relay string) {
+	type sourceKey struct{ kind, name string }
+	var keys []sourceKey
+	probeIDs := make(map[sourceKey]string)
+	s.mu.Lock()
+	for _, f := range s.findings {
+		if f.PageURL != pageURL || f.Type != DOMTypeOpenRedirect || f.Source == nil ||
+			f.URL == nil || f.URL.Control != NavControlURL {
+			continue
+		}
+		k := sourceKey{f.Source.Kind, f.Source.Name}
+		if _, ok := probeIDs[k]; !ok {
+			keys = append(keys, k)
+			probeIDs[k] = f.ProbeID
+		}
+	}
+	s.mu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+	standIn := s.redirectStandIn()
+	if standIn == nil {
+		return
+	}
+	for _, key := range keys {
+		if ctx.Err() != nil || !s.reserveProbes(1) {
+			return
+		}
+		pid := probeIDs[key]
+		confirmID := pid + "|redirect"
+		marker := confirmMarker(confirmID)
+		c := domCanary{ID: confirmID, Token: marker, Kind: key.kind, Name: key.name, Value: standIn.url(marker)}
+		cfg := s.agentConfig(DOMModeConfirm, relay, []domCanary{c})
+		cfg.Navigate = true
+		agentSrc := buildDOMAgent(cfg)
+		injected := s.injectSourceURL(pageURL, c)
+		referrer := ""
+		if c.Kind == SourceReferrer {
+			referrer = confirmReferrer(pageURL, c.Value)
+		}
+		// The page may already be gone when the redirect fires during load, so a
+		// load error is not a reason to skip the remaining steps.
+		_ = s.loadPage(ctx, agentSrc, injected, referrer)
+		switch c.Kind {
+		case SourceFormInput:
+			s.fillFormCanaries(ctx, []domCanary{c})
+		case SourceWebMessage:
+			s.sendWebMessage(ctx, c.Value)
+		case SourceSocketMessage:
+			s.replaySocketMessages(ctx, c.Value)
+		}
+		if !standIn.hit(marker) {
+			s.clickExplore(ctx)
+		}
+		if standIn.hit(marker) {
+			s.confirmRedirect(pageURL, pid)
+		}
+	}
+}
+
+// confirmRedirect marks the open_redirect findings bearing probe id pid as
+// confirmed. Severity keeps its sink-based rating; only the evidence changes.
+func (s *domScanner) confirmRedirect(pageURL, pid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.findings {
+		f := &s.findings[i]
+		if f.PageURL == pageURL && f.Type == DOMTypeOpenRedirect && f.ProbeID == pid {
+			f.Confirmed = true
+			f.Confidence = ConfidenceCertain
+			f.Triage = assessDOMFinding(*f)
+		}
+	}
+}
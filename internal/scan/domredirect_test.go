@@ -0,0 +1,107 @@
+package scan
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNavigationControl(t *testing.T) {
+	cases := []struct {
+		name string
+		url  DOMURLEvidence
+		want string
+	}{
+		{"whole-url", DOMURLEvidence{Resolved: true, CanaryComponent: "path", CanaryLeading: true}, NavControlURL},
+		{"host", DOMURLEvidence{Resolved: true, CanaryComponent: "authority"}, NavControlOrigin},
+		{"userinfo", DOMURLEvidence{Resolved: true, CanaryComponent: "credentials"}, NavControlOrigin},
+		{"javascript-body", DOMURLEvidence{Resolved: true, CanaryComponent: "path", ExecutableScheme: true}, NavControlScheme},
+		{"scheme", DOMURLEvidence{Resolved: true, CanaryComponent: "scheme"}, NavControlScheme},
+		{"query", DOMURLEvidence{Resolved: true, CanaryComponent: "query"}, NavControlQuery},
+		{"unresolved", DOMURLEvidence{CanaryLeading: true}, ""},
+	}
+	for _, c := range cases {
+		if got := navigationControl(&c.url); got != c.want {
+			t.Errorf("%s: control = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMapFindingClassifiesNavigation(t *testing.T) {
+	s := &domScanner{cfg: DefaultDOMScanConfig()}
+	raw := func(sink string, u domRawURL) domRawFinding {
+		u.Resolved = true
+		return domRawFinding{Kind: "flow", Sink: sink, Context: "url", ProbeID: "url_query:next",
+			SourceKind: SourceURLQuery, SourceName: "next", URL: &u}
+	}
+	cases := []struct {
+		name     string
+		rf       domRawFinding
+		wantType string
+		wantSev  string
+	}{
+		{"assign-whole-url", raw("location.assign", domRawURL{CanaryComponent: "path", CanaryLeading: true}), DOMTypeOpenRedirect, SeverityHigh},
+		{"assign-host", raw("location.replace", domRawURL{CanaryComponent: "authority"}), DOMTypeOpenRedirect, SeverityMedium},
+		{"link-host", raw("HTMLAnchorElement.href", domRawURL{CanaryComponent: "authority"}), DOMTypeOpenRedirect, SeverityLow},
+		{"iframe-host", raw("HTMLIFrameElement.src", domRawURL{CanaryComponent: "authority"}), DOMTypeFlow, SeverityLow},
+		{"assign-javascript", raw("location.assign", domRawURL{CanaryComponent: "path", ExecutableScheme: true}), DOMTypeFlow, SeverityHigh},
+		{"assign-query", raw("location.assign", domRawURL{CanaryComponent: "query", SameOrigin: true}), DOMTypeFlow, SeverityLow},
+		{"script-src", raw("HTMLScriptElement.src", domRawURL{CanaryComponent: "path", CanaryLeading: true}), DOMTypeFlow, SeverityLow},
+	}
+	for _, c := range cases {
+		f := s.mapFinding("https://app.test", "https://app.test/", c.rf, PhaseInitialLoad, TriggerPageLoad, "", nil)
+		if f.Type != c.wantType || f.Severity != c.wantSev {
+			t.Errorf("%s: type=%s severity=%s, want %s/%s", c.name, f.Type, f.Severity, c.wantType, c.wantSev)
+		}
+		if f.URL.Control == "" {
+			t.Errorf("%s: control was not recorded", c.name)
+		}
+	}
+}
+
+func TestConfirmRedirectMarksOnlyRedirects(t *testing.T) {
+	redirect := mkFlow("next", "location.assign", "url", TriggerPageLoad, "jsmdomc")
+	redirect.Type = DOMTypeOpenRedirect
+	redirect.URL = &DOMURLEvidence{Resolved: true, CanaryLeading: true, Control: NavControlURL}
+	flow := mkFlow("next", "Element.innerHTML", "html", TriggerPageLoad, "jsmdomc")
+	s := &domScanner{findings: []DOMFinding{redirect, flow}}
+
+	s.confirmRedirect(redirect.PageURL, redirect.ProbeID)
+	if got := s.findings[0]; !got.Confirmed || got.Confidence != ConfidenceCertain || got.Triage.Verdict != DOMTriageConfirmed {
+		t.Errorf("redirect after confirmation = %+v", got)
+	}
+	if s.findings[1].Confirmed {
+		t.Error("a flow sharing the probe id must not be confirmed by a redirect")
+	}
+}
+
+func TestDOMRedirectStandInRecordsHits(t *testing.T) {
+	d, err := newDOMRedirectStandIn()
+	if err != nil {
+		t.Skipf("loopback listener unavailable: %v", err)
+	}
+	defer d.close()
+	resp, err := http.Get(d.url("jsmdomk1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !d.hit("jsmdomk1234") || d.hit("jsmdomk9999") {
+		t.Errorf("hits = %v", d.hits)
+	}
+}
+
+func TestRedirectTriageExplainsControl(t *testing.T) {
+	f := mkFlow("next", "HTMLAnchorElement.href", "url", TriggerPageLoad, "jsmdomc")
+	f.Type = DOMTypeOpenRedirect
+	f.URL = &DOMURLEvidence{Resolved: true, CanaryComponent: "authority", Control: NavControlOrigin}
+	got := assessDOMFinding(f)
+	if got.Verdict != DOMTriageWorthReview || !strings.Contains(got.Reason, "destination origin") || !strings.Contains(got.Reason, "user must follow") {
+		t.Errorf("origin-controlled link triage = %+v", got)
+	}
+	f.Sink.Name = "location.assign"
+	f.URL.Control = NavControlURL
+	if got := assessDOMFinding(f); !strings.Contains(got.Reason, "javascript:") {
+		t.Errorf("whole-URL redirect triage = %+v", got)
+	}
+}
@@ -39,6 +39,7 @@ const (
 	SourceLocalStorage   = "local_storage"
 	SourceSessionStorage = "session_storage"
 	SourceWebMessage     = "web_message"
+	SourceSocketMessage  = "websocket_message"
 )
 
 // allSourceFamilies lists every source the scanner directly seeds. url_full and
@@ -47,7 +48,7 @@ const (
 var allSourceFamilies = []string{
 	SourceURLQuery, SourceURLFragment, SourceReferrer,
 	SourceWindowName, SourceFormInput, SourceCookie, SourceLocalStorage,
-	SourceSessionStorage, SourceWebMessage,
+	SourceSessionStorage, SourceWebMessage, SourceSocketMessage,
 }
 
 // allSinkFamilies lists every sink family the agent can hook, used for
@@ -118,6 +119,12 @@ type DOMScanConfig struct {
 
 	// Progress, when set, receives short human-readable status lines (stderr).
 	Progress func(msg string)
+	// OnFinding, when set, receives each DOM finding as the scan confirms it,
+	// ahead of the final result. Findings are handed over once every page of a
+	// crawl level has finished, deduplicated by fingerprint, so each one is seen
+	// at most once; the result's merged record remains authoritative. Calls are
+	// never concurrent.
+	OnFinding func(DOMFinding)
 }
 
 // DefaultDOMScanConfig returns the default DOM scan configuration: canary mode,
@@ -182,6 +189,7 @@ type domRenderedPage struct {
 	Requests   []HTTPRequest
 	XHRURLs    []string
 	Resources  []domRenderedResource
+	Storage    RenderedStorage
 }
 
 type domRenderedResource struct {
@@ -203,6 +211,7 @@ type domPageCapture struct {
 	requests  map[network.RequestID]*HTTPRequest
 	resources map[network.RequestID]*domRenderedResource
 	bodyBytes int
+	storage   RenderedStorage
 }
 
 const (
@@ -344,6 +353,18 @@ func (c *domPageCapture) addState(data []byte) {
 	c.states = append(c.states, append([]byte(nil), data...))
 }
 
+// addStorage merges a dump of the page's storage and cookies. The stores are
+// dumped with every captured state and once more, with service workers and
+// caches, after every pass; storage is not subject to freezing.
+func (c *domPageCapture) addStorage(st RenderedStorage) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.storage.merge(st)
+	c.mu.Unlock()
+}
+
 func (c *domPageCapture) freeze() {
 	if c == nil {
 		return
@@ -360,7 +381,7 @@ func (c *domPageCapture) snapshot(pageURL, baseHost string) domRenderedPage {
 	c.mu.Lock()
 	c.frozen = true
 	defer c.mu.Unlock()
-	p := domRenderedPage{PageURL: pageURL, BaseHost: baseHost}
+	p := domRenderedPage{PageURL: pageURL, BaseHost: baseHost, Storage: c.storage}
 	for _, state := range c.states {
 		p.HTMLStates = append(p.HTMLStates, append([]byte(nil), state...))
 	}
@@ -395,6 +416,7 @@ func (c *domPageCapture) snapshot(pageURL, baseHost string) domRenderedPage {
 // synchronised) across its worker goroutines.
 type domScanner struct {
 	cfg        DOMScanConfig
+	client     *Client
 	browserCtx context.Context
 
 	mu           sync.Mutex
@@ -409,10 +431,18 @@ type domScanner struct {
 	visited      map[string]struct{}
 	rendered     []domRenderedPage
 	hintCache    map[string][]DOMSourceHint
+
+	// streamedUpTo and streamed track which findings OnFinding has been handed.
+	// Only the scanSeed goroutine touches them.
+	streamedUpTo int
+	streamed     map[string]struct{}
+
+	standInOnce sync.Once
+	standIn     *domRedirectStandIn
 }
 
 // ScanDOM runs the opt-in DOM vulnerability scan over the given URL targets. It
-// reuses the package's browser provisioning, headers/cookies, TLS, redirect,
+// reuses the extractor's Client: its browser, headers/cookies, TLS, redirect,
 // throttle and timeout configuration. It never changes any behaviour unless
 // called, and returns findings plus a summary. A single failed page does not
 // discard findings from successful pages; context cancellation stops active
@@ -429,8 +459,8 @@ func (e *Extractor) ScanDOM(ctx context.Context, targets []string, cfg DOMScanCo
 		cfg.PageTimeout = 25 * time.Second
 	}
 	s := &domScanner{
-		cfg: cfg, visited: make(map[string]struct{}),
-		hintCache: make(map[string][]DOMSourceHint),
+		cfg: cfg, client: e.client, visited: make(map[string]struct{}),
+		hintCache: make(map[string][]DOMSourceHint), streamed: make(map[string]struct{}),
 	}
 	validTargets := make([]*url.URL, 0, len(targets))
 	for _, target := range targets {
@@ -450,7 +480,7 @@ func (e *Extractor) ScanDOM(ctx context.Context, targets []string, cfg DOMScanCo
 	// One Chromium process owns the whole DOM scan. Each page below receives an
 	// isolated browser context/tab, so cookies and storage cannot bleed between
 	// workers while process startup, code pages and the network service are reused.
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, renderExecOptions()...)
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, s.client.renderExecOptions()...)
 	defer cancelAlloc()
 	browserCtx, cancelBrowser := newRenderContext(allocCtx)
 	defer cancelBrowser()
@@ -458,6 +488,7 @@ func (e *Extractor) ScanDOM(ctx context.Context, targets []string, cfg DOMScanCo
 		return DOMScanResult{}, fmt.Errorf("start browser: %w", err)
 	}
 	s.browserCtx = browserCtx
+	defer func() { s.standIn.close() }()
 
 	for _, u := range validTargets {
 		if ctx.Err() != nil {
@@ -469,7 +500,7 @@ func (e *Extractor) ScanDOM(ctx context.Context, targets []string, cfg DOMScanCo
 	kept, suppressedMessages := suppressUninterestingMessages(DedupDOMFindings(s.snapshotFindings()))
 	result := DOMScanResult{Findings: kept}
 	if cfg.CollectRenderedArtifacts {
-		matches, hints := e.scanDOMRenderedPages(s.snapshotRendered(), cfg)
+		matches, hints := e.scanDOMRenderedPages(ctx, s.snapshotRendered(), cfg)
 		result.Matches = UniqueMatches(matches)
 		result.SourceHints = hints
 	}
@@ -504,6 +535,7 @@ func (s *domScanner) scanSeed(ctx context.Context, seed *url.URL) {
 			return
 		}
 		next := s.processFrontier(ctx, baseHost, frontier)
+		s.streamFindings()
 		if s.budgetExhausted() {
 			return
 		}
@@ -565,9 +597,11 @@ func (s *domScanner) scanPage(ctx context.Context, baseHost, pageURL string) ([]
 		s.cfg.Progress(fmt.Sprintf("[dom] scanning %s (%s)", pageURL, s.cfg.Mode))
 	}
 
-	// Pace against the shared throttle before arming the per-page timeout, so a
+	// Pace against the scan's throttle before arming the per-page timeout, so a
 	// backoff sleep cannot consume the page budget.
-	globalThrottle.waitHost(hostOf(pageURL))
+	if err := s.client.throttle.waitHostContext(ctx, hostOf(pageURL)); err != nil {
+		return nil, err
+	}
 
 	// Reuse the scan-wide Chromium process, but isolate this page's cookies,
 	// storage and cache from every other worker.
@@ -575,18 +609,21 @@ func (s *domScanner) scanPage(ctx context.Context, baseHost, pageURL string) ([]
 	defer cancelCtx()
 	pctx, cancelTimeout := context.WithTimeout(pctx, s.cfg.PageTimeout)
 	defer cancelTimeout()
+	pctx = withPageTrace(pctx, pageURL, "")
 
 	var capture *domPageCapture
 	if s.cfg.CollectRenderedArtifacts {
 		capture = newDOMPageCapture()
 	}
+	csp := &domCSPCapture{}
 	// Note render-time rate-limit responses and, when this is the shared render,
 	// retain the same script/request evidence the ordinary renderer used to gather.
 	chromedp.ListenTarget(pctx, func(ev interface{}) {
 		switch e := ev.(type) {
 		case *network.EventResponseReceived:
-			noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
+			s.client.noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
 			capture.response(e.RequestID, e.Response.URL, e.Response.MimeType, e.Type, e.Response.Headers)
+			csp.response(e.Type, e.Response.Headers)
 		case *network.EventRequestWillBeSent:
 			if e.Request != nil {
 				capture.request(e.RequestID, e.Request.Method, e.Request.URL, e.Type)
@@ -602,11 +639,17 @@ func (s *domScanner) scanPage(ctx context.Context, baseHost, pageURL string) ([]
 	)
 	switch s.cfg.Mode {
 	case DOMModeObserve:
-		links, err = s.runObserve(pctx, baseHost, pageURL, relay, capture)
+		links, err = s.runObserve(pctx, baseHost, pageURL, relay, capture, csp)
 	default: // canary and confirm both start from a canary pass
-		links, err = s.runCanary(pctx, baseHost, pageURL, relay, capture)
+		links, err = s.runCanary(pctx, baseHost, pageURL, relay, capture, csp)
+	}
+	if err == nil {
+		s.applyCSP(baseHost, pageURL, csp.evaluate())
 	}
 	if capture != nil {
+		final := readRenderedStorage(pctx, true)
+		final.Libraries = s.client.readLibraryGlobals(pctx)
+		capture.addStorage(final)
 		capture.resolveRequestBodies(pctx)
 		capture.resolveResponseBodies(pctx)
 		rendered := capture.snapshot(pageURL, baseHost)
@@ -620,11 +663,12 @@ func (s *domScanner) scanPage(ctx context.Context, baseHost, pageURL string) ([]
 // runObserve loads the page with no input modification and records dangerous
 // sink activity only. It still explores state via clicks (which do not change
 // application inputs) so sinks reached after interaction are observed too.
-func (s *domScanner) runObserve(ctx context.Context, baseHost, pageURL, relay string, capture *domPageCapture) ([]string, error) {
+func (s *domScanner) runObserve(ctx context.Context, baseHost, pageURL, relay string, capture *domPageCapture, csp *domCSPCapture) ([]string, error) {
 	agentSrc := buildDOMAgent(s.agentConfig(DOMModeObserve, relay, nil))
 	if err := s.loadPage(ctx, agentSrc, pageURL, ""); err != nil {
 		return nil, err
 	}
+	csp.readDocument(ctx)
 	offset, st := s.readAndIngest(ctx, baseHost, pageURL, PhaseInitialLoad, TriggerPageLoad, "", 0)
 	s.captureRenderedState(ctx, capture)
 	capture.resolveResponseBodies(ctx)
@@ -648,15 +692,15 @@ func (s *domScanner) runObserve(ctx context.Context, baseHost, pageURL, relay st
 // source-to-sink flows they produce across the initial load, interaction and
 // postMessage passes, and — in confirm mode — follows up with controlled,
 // non-visible confirmation probes.
-func (s *domScanner) runCanary(ctx context.Context, baseHost, pageURL, relay string, capture *domPageCapture) ([]string, error) {
+func (s *domScanner) runCanary(ctx context.Context, baseHost, pageURL, relay string, capture *domPageCapture, csp *domCSPCapture) ([]string, error) {
 	canaries, injectedURL, referrer := s.buildCanaries(pageURL, s.probeCapacity())
 	if len(canaries) == 0 {
-		return s.runObserve(ctx, baseHost, pageURL, relay, capture)
+		return s.runObserve(ctx, baseHost, pageURL, relay, capture, csp)
 	}
 	if !s.reserveProbes(len(canaries)) {
 		// Probe budget exhausted before this page: fall back to a pure observation
 		// so the page is still not wasted, but inject nothing.
-		return s.runObserve(ctx, baseHost, pageURL, relay, capture)
+		return s.runObserve(ctx, baseHost, pageURL, relay, capture, csp)
 	}
 	s.noteHintProbes(canaries)
 
@@ -664,6 +708,7 @@ func (s *domScanner) runCanary(ctx context.Context, baseHost, pageURL, relay str
 	if err := s.loadPage(ctx, agentSrc, injectedURL, referrer); err != nil {
 		return nil, err
 	}
+	csp.readDocument(ctx)
 
 	// Scope guard: if a client-side redirect took the page off-scope, do not keep
 	// probing or enqueue its links.
@@ -706,6 +751,17 @@ func (s *domScanner) runCanary(ctx context.Context, baseHost, pageURL, relay str
 		offset, _ = s.readAndIngest(ctx, baseHost, pageURL, PhaseStateExploration, TriggerPostMessage, "postMessage", offset)
 		s.captureRenderedState(ctx, capture)
 	}
+
+	// Socket pass: replay each WebSocket/EventSource connection's observed
+	// message with a canary in its string fields. Only pages that opened a
+	// socket spend a probe on it.
+	if s.sourceEnabled(SourceSocketMessage) && s.socketCount(ctx) > 0 && s.reserveProbes(1) {
+		sc := s.socketMessageCanary()
+		s.addCanaryToAgent(ctx, sc)
+		s.replaySocketMessages(ctx, sc.Value)
+		offset, _ = s.readAndIngest(ctx, baseHost, pageURL, PhaseStateExploration, TriggerSocketMessage, "socket replay", offset)
+		s.captureRenderedState(ctx, capture)
+	}
 	_ = offset
 	preLinks, _ := s.collectLinks(ctx, baseHost)
 	s.exploreRenderedStates(ctx, capture, agentSrc)
@@ -722,6 +778,7 @@ func (s *domScanner) runCanary(ctx context.Context, baseHost, pageURL, relay str
 	capture.freeze()
 	if s.cfg.Mode == DOMModeConfirm {
 		s.runConfirm(ctx, baseHost, pageURL, relay)
+		s.confirmRedirects(ctx, pageURL, relay)
 	}
 
 	stFinal, _ := readAgentState(ctx)
@@ -795,10 +852,7 @@ func (s *domScanner) runConfirm(ctx context.Context, baseHost, pageURL, relay st
 			originalIDs[confirmID] = pid
 			injected = s.injectSourceURL(injected, c)
 			if key.kind == SourceReferrer {
-				if u, err := url.Parse(pageURL); err == nil {
-					q := url.Values{"jsmref": []string{payload}}
-					referrer = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/", RawQuery: q.Encode()}).String()
-				}
+				referrer = confirmReferrer(pageURL, payload)
 			}
 		}
 		if len(canaries) == 0 {
@@ -814,6 +868,8 @@ func (s *domScanner) runConfirm(ctx context.Context, baseHost, pageURL, relay st
 				formCanaries = append(formCanaries, c)
 			} else if c.Kind == SourceWebMessage {
 				s.sendWebMessage(ctx, c.Value)
+			} else if c.Kind == SourceSocketMessage {
+				s.replaySocketMessages(ctx, c.Value)
 			}
 		}
 		if len(formCanaries) > 0 {
@@ -829,6 +885,17 @@ func (s *domScanner) runConfirm(ctx context.Context, baseHost, pageURL, relay st
 	}
 }
 
+// confirmReferrer builds a same-origin referrer URL carrying payload, for
+// replaying a referrer source.
+func confirmReferrer(pageURL, payload string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	q := url.Values{"jsmref": []string{payload}}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/", RawQuery: q.Encode()}).String()
+}
+
 // ---- browser action helpers ------------------------------------------------
 
 // loadPage installs the agent (so it runs before page scripts, in every frame),
@@ -837,7 +904,7 @@ func (s *domScanner) runConfirm(ctx context.Context, baseHost, pageURL, relay st
 func (s *domScanner) loadPage(ctx context.Context, agentSrc, urlStr, referrer string) error {
 	var scriptID page.ScriptIdentifier
 	actions := []chromedp.Action{network.Enable().WithMaxPostDataSize(MaxPostDataSize)}
-	actions = append(actions, headerActions(renderHeaders())...)
+	actions = append(actions, headerActions(s.client.renderHeaders())...)
 	actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
 		var err error
 		scriptID, err = page.AddScriptToEvaluateOnNewDocument(agentSrc).Do(ctx)
@@ -859,9 +926,18 @@ func (s *domScanner) loadPage(ctx context.Context, agentSrc, urlStr, referrer st
 	}))
 	actions = append(actions,
 		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.ActionFunc(waitForDOMQuiet),
+		chromedp.ActionFunc(s.waitForDOMQuiet),
 	)
+	tev := startTrace(ctx, TraceDOMProbe, http.MethodGet, urlStr)
 	err := chromedp.Run(ctx, actions...)
+	if vEnabled(2) {
+		if err != nil {
+			tev.log(2, fmt.Sprintf("[dom] load %s -> error: %v", urlStr, err), 0, -1, err)
+		} else {
+			nav := readNavigationTiming(ctx)
+			tev.log(2, fmt.Sprintf("[dom] load %s -> %d (%d byte(s))", urlStr, nav.Status, nav.Bytes), nav.Status, nav.Bytes, nil)
+		}
+	}
 	// The registration is only for this navigation. Keeping it would make every
 	// confirmation reload parse and execute all prior agents; the idempotence
 	// guard would also let the oldest configuration shadow the current probe.
@@ -873,6 +949,25 @@ func (s *domScanner) loadPage(ctx context.Context, agentSrc, urlStr, referrer st
 	return err
 }
 
+// navigationTiming is the loaded document's status and decoded size, as the
+// page's Navigation Timing entry reports them.
+type navigationTiming struct {
+	Status int   `json:"status"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// readNavigationTiming reads the current document's navigationTiming; the
+// zero value (and -1 bytes) when the browser does not report it.
+func readNavigationTiming(ctx context.Context) navigationTiming {
+	var raw string
+	expr := `JSON.stringify((function(){var n=(performance.getEntriesByType('navigation')||[])[0]||{};return{status:n.responseStatus||0,bytes:typeof n.decodedBodySize==='number'?n.decodedBodySize:-1};})())`
+	nav := navigationTiming{Bytes: -1}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(expr, &raw)); err == nil {
+		_ = json.Unmarshal([]byte(raw), &nav)
+	}
+	return nav
+}
+
 // readAgentState reads the in-page agent state back as JSON and decodes it.
 func readAgentState(ctx context.Context) (domAgentState, error) {
 	var raw string
@@ -1029,6 +1124,25 @@ func (s *domScanner) sendWebMessage(ctx context.Context, value string) {
 	_ = chromedp.Run(ctx, chromedp.Evaluate(js, nil), chromedp.Sleep(domInteractionSettle()))
 }
 
+// socketCount reports how many WebSocket/EventSource connections the agent is
+// tracking on the current page.
+func (s *domScanner) socketCount(ctx context.Context) int {
+	var n int
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`(window.__jsmdom&&window.__jsmdom.sockets)?window.__jsmdom.sockets.length:0`, &n)); err != nil {
+		return 0
+	}
+	return n
+}
+
+// replaySocketMessages asks the agent to re-dispatch every tracked socket's
+// observed message with value injected, one string field at a time, then waits
+// for the handlers to settle. The events are delivered locally on the page's own
+// socket objects; nothing is sent to the server.
+func (s *domScanner) replaySocketMessages(ctx context.Context, value string) {
+	js := `(function(v){try{return window.__jsmdomReplaySockets?window.__jsmdomReplaySockets(v):0;}catch(e){return 0;}})(` + jsString(value) + `)`
+	_ = chromedp.Run(ctx, chromedp.Evaluate(js, nil), chromedp.Sleep(domInteractionSettle()))
+}
+
 // inScopeNow reports whether the page's current origin is still within baseHost
 // scope (or external probing is allowed).
 func (s *domScanner) inScopeNow(ctx context.Context, baseHost string) bool {
@@ -1089,6 +1203,7 @@ func (s *domScanner) captureRenderedState(ctx context.Context, capture *domPageC
 	if err := chromedp.Run(ctx, chromedp.OuterHTML("html", &html, chromedp.ByQuery)); err == nil {
 		capture.addState([]byte(html))
 	}
+	capture.addStorage(readRenderedStorage(ctx, false))
 }
 
 // exploreRenderedStates preserves the ordinary renderer's bounded form,
@@ -1096,7 +1211,7 @@ func (s *domScanner) captureRenderedState(ctx context.Context, capture *domPageC
 // snapshots each structurally distinct state without launching a second baseline
 // render or Chromium process.
 func (s *domScanner) exploreRenderedStates(ctx context.Context, capture *domPageCapture, agentSrc string) {
-	if capture == nil || MaxExploreStates <= 0 || ctx.Err() != nil {
+	if capture == nil || s.client.cfg.MaxExploreStates <= 0 || ctx.Err() != nil {
 		return
 	}
 	// Forms and route controls may create a fresh document. Keep the current
@@ -1123,17 +1238,19 @@ func (s *domScanner) exploreRenderedStates(ctx context.Context, capture *domPage
 	}
 	states := [][]byte{[]byte(html)}
 	seen := map[string]struct{}{structuralSig([]byte(html)): {}}
-	states, _ = exploreStates(ctx, states, seen, nil)
+	var storage RenderedStorage
+	states, _ = s.client.exploreStates(ctx, states, seen, nil, &storage)
 	for _, state := range states {
 		capture.addState(state)
 	}
+	capture.addStorage(storage)
 }
 
 // scanDOMRenderedPages turns artifacts from the shared instrumented navigation
 // into the same ordinary match stream previously produced by
 // RenderURLWithStates. Browser work is finished before these HTTP/script scans
 // begin, so it cannot consume a page's DOM timeout.
-func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanConfig) ([]Match, []DOMSourceHint) {
+func (e *Extractor) scanDOMRenderedPages(ctx context.Context, pages []domRenderedPage, cfg DOMScanConfig) ([]Match, []DOMSourceHint) {
 	visitedByHost := make(map[string]*visitedSet)
 	var matches []Match
 	var hints []DOMSourceHint
@@ -1174,7 +1291,7 @@ func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanCon
 		// Mark the complete captured set before following imports so a bundle cannot
 		// refetch a later captured chunk merely because it sorts after its importer.
 		for _, resource := range capturedResources {
-			matches = append(matches, e.scanDOMRenderedResource(resource, p.BaseHost, visited, cfg)...)
+			matches = append(matches, e.scanDOMRenderedResource(ctx, resource, p.BaseHost, visited, cfg)...)
 		}
 		for i, state := range p.HTMLStates {
 			var dynamic []string
@@ -1182,14 +1299,14 @@ func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanCon
 				dynamic = p.Scripts
 			}
 			if cfg.ArtifactPosts {
-				matches = append(matches, e.scanHTMLStatePosts(
+				matches = append(matches, e.scanHTMLStatePosts(ctx, 
 					p.PageURL, p.BaseHost, state, dynamic, visited, cfg.ArtifactExternal, false,
 				)...)
 				if cfg.Crawl {
 					matches = append(matches, extractHTMLLinkMatches(state, p.PageURL)...)
 				}
 			} else {
-				matches = append(matches, e.scanHTMLState(
+				matches = append(matches, e.scanHTMLState(ctx, 
 					p.PageURL, p.BaseHost, state, dynamic, cfg.ArtifactEndpoints,
 					visited, cfg.ArtifactExternal, false,
 				)...)
@@ -1205,6 +1322,11 @@ func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanCon
 				Params: request.Body, Severity: SeverityInfo,
 			})
 		}
+		if !p.Storage.empty() {
+			matches = append(matches, e.scanRenderedStorage(ctx, 
+				p.PageURL, p.BaseHost, p.Storage, cfg.ArtifactEndpoints, cfg.ArtifactPosts, visited, cfg.ArtifactExternal,
+			)...)
+		}
 		if cfg.ArtifactPosts {
 			drainHints(p.BaseHost)
 			continue
@@ -1217,7 +1339,7 @@ func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanCon
 			if err != nil || (!cfg.ArtifactExternal && !sameScope(p.BaseHost, u.Hostname())) {
 				continue
 			}
-			if ms, err := e.scanURL(xhrURL, p.BaseHost, cfg.ArtifactEndpoints, visited, cfg.ArtifactExternal, false); err == nil {
+			if ms, err := e.scanURL(ctx, xhrURL, p.BaseHost, cfg.ArtifactEndpoints, visited, cfg.ArtifactExternal, false); err == nil {
 				matches = append(matches, ms...)
 			}
 		}
@@ -1230,7 +1352,7 @@ func (e *Extractor) scanDOMRenderedPages(pages []domRenderedPage, cfg DOMScanCon
 // received by Chrome. This is the request-level counterpart to sharing the page
 // render: dynamic resources are not downloaded a second time merely so the Go
 // scanner can inspect their bytes.
-func (e *Extractor) scanDOMRenderedResource(resource domRenderedResource, baseHost string, visited *visitedSet, cfg DOMScanConfig) []Match {
+func (e *Extractor) scanDOMRenderedResource(ctx context.Context, resource domRenderedResource, baseHost string, visited *visitedSet, cfg DOMScanConfig) []Match {
 	isJS := resource.Script || isJavaScriptContentType(resource.MimeType)
 	var matches []Match
 	if cfg.ArtifactPosts {
@@ -1242,11 +1364,11 @@ func (e *Extractor) scanDOMRenderedResource(resource domRenderedResource, baseHo
 			matches = append(matches, ms...)
 		}
 		matches = append(matches, e.recoverSourceMap(
-			resource.URL, resource.Body, resource.Header, baseHost,
+			ctx, resource.URL, resource.Body, resource.Header, baseHost,
 			cfg.ArtifactExternal, visited, true,
 		)...)
 		for _, imp := range inScopeJSImports(resource.Body, resource.URL, baseHost, cfg.ArtifactExternal) {
-			if ms, err := e.scanURLPosts(imp, baseHost, visited, cfg.ArtifactExternal, false); err == nil {
+			if ms, err := e.scanURLPosts(ctx, imp, baseHost, visited, cfg.ArtifactExternal, false); err == nil {
 				matches = append(matches, ms...)
 			}
 		}
@@ -1262,7 +1384,7 @@ func (e *Extractor) scanDOMRenderedResource(resource domRenderedResource, baseHo
 	}
 	if isJS {
 		recovered := e.recoverSourceMap(
-			resource.URL, resource.Body, resource.Header, baseHost,
+			ctx, resource.URL, resource.Body, resource.Header, baseHost,
 			cfg.ArtifactExternal, visited, false,
 		)
 		if cfg.ArtifactEndpoints {
@@ -1270,7 +1392,7 @@ func (e *Extractor) scanDOMRenderedResource(resource domRenderedResource, baseHo
 		}
 		matches = append(matches, recovered...)
 		for _, imp := range inScopeJSImports(resource.Body, resource.URL, baseHost, cfg.ArtifactExternal) {
-			if imported, err := e.scanURL(imp, baseHost, cfg.ArtifactEndpoints, visited, cfg.ArtifactExternal, false); err == nil {
+			if imported, err := e.scanURL(ctx, imp, baseHost, cfg.ArtifactEndpoints, visited, cfg.ArtifactExternal, false); err == nil {
 				matches = append(matches, imported...)
 			}
 		}
@@ -1535,6 +1657,14 @@ func (s *domScanner) webMessageCanary() domCanary {
 	return domCanary{ID: SourceWebMessage + ":message", Token: tok, Kind: SourceWebMessage, Name: "message", Value: tok}
 }
 
+// socketMessageCanary returns the canary replayed into socket messages. Its name
+// is left empty: the agent names each flow after the socket endpoint and message
+// field the marker was injected into.
+func (s *domScanner) socketMessageCanary() domCanary {
+	tok := randomCanary()
+	return domCanary{ID: SourceSocketMessage + ":", Token: tok, Kind: SourceSocketMessage, Value: tok}
+}
+
 // ---- ingest & classification ----------------------------------------------
 
 // readAndIngest reads the agent state and ingests only the findings recorded
@@ -1603,6 +1733,7 @@ func (s *domScanner) mapFinding(target, pageURL string, rf domRawFinding, phase,
 				CanaryComponent:   rf.URL.CanaryComponent,
 				InputKind:         rf.URL.InputKind,
 				ExecutableScheme:  rf.URL.ExecutableScheme,
+				CanaryLeading:     rf.URL.CanaryLeading,
 			}
 		}
 		if interaction != "" && trigger != TriggerPageLoad {
@@ -1611,6 +1742,9 @@ func (s *domScanner) mapFinding(target, pageURL string, rf domRawFinding, phase,
 		confirmed := rf.ProbeID != "" && confirms[rf.ProbeID]
 		f.Confirmed = confirmed
 		f.Severity, f.Confidence = classifyFlow(rf.Context, confirmed)
+		if rf.Context == "url" {
+			classifyNavigation(&f)
+		}
 	case "sink":
 		f.Type = DOMTypeSink
 		f.Sink = &DOMSink{Name: rf.Sink, Argument: rf.Argument}
@@ -1663,6 +1797,9 @@ func (s *domScanner) mapFinding(target, pageURL string, rf domRawFinding, phase,
 // conservative triage hint. It deliberately avoids claiming exploitability:
 // only controlled execution is labelled confirmed.
 func assessDOMFinding(f DOMFinding) *DOMTriage {
+	if f.Type == DOMTypeOpenRedirect {
+		return redirectTriage(f)
+	}
 	if f.Confirmed {
 		return &DOMTriage{Verdict: DOMTriageConfirmed, Reason: "controlled execution was confirmed"}
 	}
@@ -1680,7 +1817,20 @@ func assessDOMFinding(f DOMFinding) *DOMTriage {
 			return &DOMTriage{Verdict: DOMTriageInfo, Reason: "message activity was observed without a security-sensitive effect"}
 		}
 	}
+	if f.Type == DOMTypeWeakCSP && f.CSP != nil {
+		issue := "no Content-Security-Policy"
+		if len(f.CSP.Issues) > 0 {
+			issue = f.CSP.Issues[0]
+		}
+		if f.CSP.Enforced && f.CSP.Strength == CSPStrengthWeak {
+			return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: "the page's CSP would not stop injected script: " + issue}
+		}
+		return &DOMTriage{Verdict: DOMTriageInfo, Reason: "the page has no effective script restriction: " + issue}
+	}
 	if f.Type == DOMTypeFlow {
+		if t := cspTriage(f); t != nil {
+			return t
+		}
 		switch f.Context {
 		case "js", "script-url":
 			return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: "controllable data reached a JavaScript execution context"}
@@ -1693,6 +1843,9 @@ func assessDOMFinding(f DOMFinding) *DOMTriage {
 			if f.URL.ExecutableScheme {
 				return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: "navigation resolved to an executable URL scheme; execution was not confirmed"}
 			}
+			if f.URL.Control == NavControlScheme {
+				return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: "controllable data decides the navigation URL's scheme, so a javascript: URL may be possible"}
+			}
 			if !f.URL.SameOrigin {
 				return &DOMTriage{Verdict: DOMTriageWorthReview, Reason: "controllable data reached a cross-origin navigation target"}
 			}
@@ -1782,6 +1935,7 @@ func (s *domScanner) agentConfig(mode, relay string, canaries []domCanary) domAg
 		Token:    relay,
 		Canaries: canaries,
 		Messages: s.cfg.Messages,
+		Sockets:  s.sourceEnabled(SourceSocketMessage),
 		Sinks:    s.cfg.Sinks,
 		Limits:   domAgentLimits{MaxFindings: 300, PreviewMax: 120, StackDepth: 8},
 	}
@@ -1821,6 +1975,7 @@ func (s *domScanner) reserveProbes(n int) bool {
 		return false
 	}
 	s.probes += n
+	s.client.metrics().observeDOMProbes(s.probes, s.cfg.MaxProbes)
 	return true
 }
 
@@ -1903,6 +2058,29 @@ func (s *domScanner) note(pageURL, msg string) {
 	}
 }
 
+// streamFindings hands the findings recorded since its last call to
+// cfg.OnFinding. It runs between crawl levels, when no page is in flight and
+// every page's CSP evidence has been applied, so each finding goes out in its
+// settled per-page form. Message chatter the final result suppresses, and
+// findings sharing a fingerprint with one already streamed, are skipped.
+func (s *domScanner) streamFindings() {
+	if s.cfg.OnFinding == nil {
+		return
+	}
+	s.mu.Lock()
+	fresh := append([]DOMFinding(nil), s.findings[s.streamedUpTo:]...)
+	s.streamedUpTo = len(s.findings)
+	s.mu.Unlock()
+	kept, _ := suppressUninterestingMessages(DedupDOMFindings(fresh))
+	for _, f := range kept {
+		if _, ok := s.streamed[f.Fingerprint]; ok {
+			continue
+		}
+		s.streamed[f.Fingerprint] = struct{}{}
+		s.cfg.OnFinding(f)
+	}
+}
+
 func (s *domScanner) snapshotFindings() []DOMFinding {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -1957,8 +2135,8 @@ func (s *domScanner) buildSummary(cfg DOMScanConfig, findingCount int, findings
 // domSettle is the initial per-page settle wait, reusing the render sleep so the
 // -timeout flag governs it, but capped so a long render sleep does not blow the
 // per-page DOM budget.
-func domSettle() time.Duration {
-	d := RenderSleepDuration
+func (s *domScanner) domSettle() time.Duration {
+	d := s.client.cfg.RenderSleep
 	if d > 6*time.Second {
 		d = 6 * time.Second
 	}
@@ -1972,8 +2150,8 @@ func domSettle() time.Duration {
 // based wait. Navigation has already reached the load event; this retains a
 // bounded window for SPA mounts and late resources but lets an idle page proceed
 // as soon as both the DOM and resource timeline have been quiet for 300ms.
-func waitForDOMQuiet(ctx context.Context) error {
-	deadline := time.Now().Add(domSettle())
+func (s *domScanner) waitForDOMQuiet(ctx context.Context) error {
+	deadline := time.Now().Add(s.domSettle())
 	quietMS := interactionQuietPeriod.Milliseconds()
 	expr := fmt.Sprintf(`(function(){
 		try {
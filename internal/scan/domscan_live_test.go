@@ -21,22 +21,17 @@ import (
 // They tune the render waits down and pin browser resolution to a
 // cached/PATH build so no network access is required.
 
-// domTestSetup shortens render waits, pins the browser and resets the throttle,
-// returning a restore func. It skips the test when no browser is present.
+// domTestSetup pins the browser to a cached/PATH build, returning a restore
+// func. It skips the test when no browser is present.
 func domTestSetup(t *testing.T) func() {
 	t.Helper()
 	if !chromeAvailable() {
 		t.Skip("DOM live test: no Chrome/Chromium binary found on PATH")
 	}
-	savedSleep, savedTimeout := RenderSleepDuration, RenderTimeout
-	RenderSleepDuration = 500 * time.Millisecond
-	RenderTimeout = 15 * time.Second
 	savedAuto := AutoDownloadBrowser
 	AutoDownloadBrowser = false
 	resetResolvedBrowser()
-	ResetThrottle()
 	return func() {
-		RenderSleepDuration, RenderTimeout = savedSleep, savedTimeout
 		AutoDownloadBrowser = savedAuto
 		resetResolvedBrowser()
 	}
@@ -113,6 +108,13 @@ func vulnServer() *httptest.Server {
 	mux.HandleFunc("/href", page(`<a id=out>current</a>
 <script>document.getElementById('out').href = location.href;</script>`))
 
+	// query parameter -> location.assign: the whole destination is the input,
+	// a classic client-side open redirect.
+	mux.HandleFunc("/redirect", page(`<script>
+  var n = new URLSearchParams(location.search).get('next');
+  if (n) location.assign(n);
+</script>`))
+
 	// A parameter name that exists only in JavaScript. The seed URL has no query,
 	// so this flow is reachable only when static source hints feed the DOM pass.
 	mux.HandleFunc("/hinted-eval", page(`<script>
@@ -165,6 +167,10 @@ func runDOM(t *testing.T, target string, mut func(*DOMScanConfig)) DOMScanResult
 		mut(&cfg)
 	}
 	e := NewExtractor(false, false)
+	e.SetClient(testClient(func(c *ClientConfig) {
+		c.RenderSleep = 500 * time.Millisecond
+		c.RenderTimeout = 15 * time.Second
+	}))
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	res, err := e.ScanDOM(ctx, []string{target}, cfg)
@@ -490,6 +496,29 @@ func TestDOMConfirmModeConfirmsExecution(t *testing.T) {
 	}
 }
 
+func TestDOMConfirmModeConfirmsOpenRedirect(t *testing.T) {
+	defer domTestSetup(t)()
+	srv := vulnServer()
+	defer srv.Close()
+
+	res := runDOM(t, srv.URL+"/redirect?next=/home", func(c *DOMScanConfig) { c.Mode = DOMModeConfirm })
+	var f *DOMFinding
+	for i := range res.Findings {
+		if res.Findings[i].Type == DOMTypeOpenRedirect && res.Findings[i].Source != nil && res.Findings[i].Source.Name == "next" {
+			f = &res.Findings[i]
+		}
+	}
+	if f == nil {
+		t.Fatalf("no open_redirect finding for next; findings=%s", summarize(res))
+	}
+	if f.URL == nil || f.URL.Control != NavControlURL || f.Severity != SeverityHigh {
+		t.Errorf("unexpected redirect classification: severity=%s url=%+v", f.Severity, f.URL)
+	}
+	if !f.Confirmed || f.Confidence != ConfidenceCertain {
+		t.Errorf("navigation to the stand-in was not confirmed: %+v", f)
+	}
+}
+
 func TestDOMObserveModeDoesNotInject(t *testing.T) {
 	defer domTestSetup(t)()
 	srv := vulnServer()
@@ -109,3 +109,35 @@ func TestMessageDedupKeepsDistinctListenersSeparate(t *testing.T) {
 		t.Fatalf("distinct listeners should stay separate, got %d findings", len(out))
 	}
 }
+
+// TestSocketSourceWiring checks the websocket_message family is selectable, that
+// the agent only wraps sockets when it is enabled, and that a socket replay
+// outranks an interaction as the primary trigger.
+func TestSocketSourceWiring(t *testing.T) {
+	found := false
+	for _, f := range DOMSourceFamilies() {
+		if f == SourceSocketMessage {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("websocket_message missing from source families")
+	}
+
+	on := &domScanner{}
+	if !on.agentConfig(DOMModeCanary, "r", nil).Sockets {
+		t.Error("socket tracking should be on when all sources are enabled")
+	}
+	off := &domScanner{cfg: DOMScanConfig{Sources: map[string]bool{SourceURLQuery: true}}}
+	if off.agentConfig(DOMModeCanary, "r", nil).Sockets {
+		t.Error("socket tracking should be off when websocket_message is not selected")
+	}
+
+	c := on.socketMessageCanary()
+	if c.Kind != SourceSocketMessage || c.Name != "" || !strings.HasPrefix(c.Token, "jsmdom") {
+		t.Errorf("socket canary = %+v; the agent names it after the endpoint", c)
+	}
+	if got := primaryTriggerOf([]string{TriggerInteraction, TriggerSocketMessage, TriggerPageLoad}); got != TriggerSocketMessage {
+		t.Errorf("primary trigger = %q, want %q", got, TriggerSocketMessage)
+	}
+}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net/url"
 	"reflect"
 	"strings"
@@ -49,14 +50,14 @@ Cookies.get("session_hint");
 func TestExtractorCollectsDOMHintsOnlyWhenEnabled(t *testing.T) {
 	e := NewExtractor(false, false)
 	js := `const p=new URLSearchParams(location.search); p.get("dom_only");`
-	if _, err := e.ScanReaderWithEndpoints("app.js", strings.NewReader(js)); err != nil {
+	if _, err := e.ScanReaderWithEndpoints(context.Background(), "app.js", strings.NewReader(js)); err != nil {
 		t.Fatal(err)
 	}
 	if got := e.TakeDOMSourceHints(); len(got) != 0 {
 		t.Fatalf("disabled collector returned hints: %+v", got)
 	}
 	e.SetCollectDOMSourceHints(true)
-	if _, err := e.ScanReaderWithEndpoints("app.js", strings.NewReader(js)); err != nil {
+	if _, err := e.ScanReaderWithEndpoints(context.Background(), "app.js", strings.NewReader(js)); err != nil {
 		t.Fatal(err)
 	}
 	got := e.TakeDOMSourceHints()
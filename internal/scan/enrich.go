@@ -0,0 +1,396 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Asset is what enrichment knows about the host a finding came from: where it
+// resolves, who announces that address, what answers there and who owns it.
+type Asset struct {
+	Host       string            `json:"host"`
+	IP         string            `json:"ip,omitempty"`
+	ASN        int               `json:"asn,omitempty"`
+	ASOrg      string            `json:"as_org,omitempty"`
+	Country    string            `json:"country,omitempty"`
+	TLSSubject string            `json:"tls_subject,omitempty"`
+	TLSIssuer  string            `json:"tls_issuer,omitempty"`
+	Server     string            `json:"server,omitempty"`
+	Tech       []string          `json:"tech,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// MatchesTags reports whether a carries every tag in filter with a value
+// matching its path.Match glob. An empty filter matches anything, a nil Asset
+// only an empty filter.
+func (a *Asset) MatchesTags(filter map[string]string) bool {
+	for name, glob := range filter {
+		var value string
+		if a != nil {
+			value = a.Tags[name]
+		}
+		if ok, _ := path.Match(glob, value); value == "" || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// originInfo is what the client saw of a host while scanning it: the address
+// it connected to and the first response's certificate and headers.
+type originInfo struct {
+	ip         string
+	tlsSubject string
+	tlsIssuer  string
+	server     string
+	tech       []string
+	seen       bool
+}
+
+// origins records originInfo per host for enrichment. The zero value is ready
+// to use.
+type origins struct {
+	mu    sync.Mutex
+	hosts map[string]*originInfo
+}
+
+func (o *origins) get(host string) *originInfo {
+	if o.hosts == nil {
+		o.hosts = make(map[string]*originInfo)
+	}
+	info := o.hosts[host]
+	if info == nil {
+		info = &originInfo{}
+		o.hosts[host] = info
+	}
+	return info
+}
+
+func (o *origins) snapshot(host string) (originInfo, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	info, ok := o.hosts[host]
+	if !ok {
+		return originInfo{}, false
+	}
+	return *info, true
+}
+
+// needsIP reports whether no address has been recorded for host yet.
+func (o *origins) needsIP(host string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	info, ok := o.hosts[host]
+	return !ok || info.ip == ""
+}
+
+func (o *origins) noteIP(host, ip string) {
+	if host == "" || ip == "" {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if info := o.get(strings.ToLower(host)); info.ip == "" {
+		info.ip = ip
+	}
+}
+
+// traceOrigin arranges for the address each connection of req reaches to be
+// recorded against its host. It is skipped once the host's address is known,
+// and when the request goes through a proxy, whose address is not the host's.
+func (c *Client) traceOrigin(req *http.Request) *http.Request {
+	if !c.cfg.RecordOrigins || c.cfg.Replay != nil || !c.origins.needsIP(strings.ToLower(req.URL.Hostname())) {
+		return req
+	}
+	if p, err := http.ProxyFromEnvironment(req); err != nil || p != nil {
+		return req
+	}
+	var mu sync.Mutex
+	var host string
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			h, _, err := net.SplitHostPort(hostPort)
+			if err != nil {
+				h = hostPort
+			}
+			mu.Lock()
+			host = h
+			mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr)
+			if !ok {
+				return
+			}
+			mu.Lock()
+			h := host
+			mu.Unlock()
+			c.origins.noteIP(h, addr.IP.String())
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// ObserveOrigin records the certificate, Server header and technology
+// fingerprint of resp's host the first time the host answers. Scans call it
+// for every response when RecordOrigins is set; the proxy calls it for the
+// responses it relays.
+func (c *Client) ObserveOrigin(resp *http.Response) {
+	if c == nil || !c.cfg.RecordOrigins || resp == nil || resp.Request == nil || resp.Request.URL == nil {
+		return
+	}
+	host := strings.ToLower(resp.Request.URL.Hostname())
+	if host == "" {
+		return
+	}
+	c.origins.mu.Lock()
+	defer c.origins.mu.Unlock()
+	info := c.origins.get(host)
+	if info.seen {
+		return
+	}
+	info.seen = true
+	info.server = resp.Header.Get("Server")
+	info.tech = fingerprintTech(resp.Header)
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		info.tlsSubject = cert.Subject.String()
+		info.tlsIssuer = cert.Issuer.String()
+	}
+}
+
+// techHeaders names the technology a response header's presence reveals.
+var techHeaders = map[string]string{
+	"X-Aspnet-Version":              "ASP.NET",
+	"X-Aspnetmvc-Version":           "ASP.NET MVC",
+	"X-Drupal-Cache":                "Drupal",
+	"X-Drupal-Dynamic-Cache":        "Drupal",
+	"X-Shopify-Stage":               "Shopify",
+	"X-Vercel-Id":                   "Vercel",
+	"X-Nf-Request-Id":               "Netlify",
+	"X-Github-Request-Id":           "GitHub Pages",
+	"X-Amz-Cf-Id":                   "Amazon CloudFront",
+	"X-Amz-Request-Id":              "Amazon S3",
+	"Cf-Ray":                        "Cloudflare",
+	"X-Akamai-Transformed":          "Akamai",
+	"X-Served-By":                   "Fastly",
+	"X-Envoy-Upstream-Service-Time": "Envoy",
+	"X-Kong-Upstream-Latency":       "Kong",
+	"X-Azure-Ref":                   "Azure Front Door",
+	"X-Goog-Generation":             "Google Cloud Storage",
+}
+
+// techCookies names the technology a session cookie's name reveals.
+var techCookies = map[string]string{
+	"phpsessid":         "PHP",
+	"jsessionid":        "Java",
+	"asp.net_sessionid": "ASP.NET",
+	"laravel_session":   "Laravel",
+	"connect.sid":       "Express",
+	"_rails_session":    "Ruby on Rails",
+	"csrftoken":         "Django",
+	"ci_session":        "CodeIgniter",
+	"__cfduid":          "Cloudflare",
+	"awsalb":            "AWS ALB",
+	"bigipserver":       "F5 BIG-IP",
+}
+
+// fingerprintTech names the technologies a response's headers reveal: its
+// Server and X-Powered-By products, telltale vendor headers and session
+// cookie names. The result is sorted and free of duplicates.
+func fingerprintTech(h http.Header) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name = strings.TrimSpace(name); name != "" {
+			seen[name] = true
+		}
+	}
+	for _, field := range []string{"Server", "X-Powered-By", "X-Generator"} {
+		for _, v := range h.Values(field) {
+			// "nginx/1.25.3" and "PHP/8.2.1, ASP.NET" name products; the
+			// version is kept on Server itself.
+			for _, part := range strings.Split(v, ",") {
+				product, _, _ := strings.Cut(strings.TrimSpace(part), " ")
+				product, _, _ = strings.Cut(product, "/")
+				add(product)
+			}
+		}
+	}
+	for header, tech := range techHeaders {
+		if h.Get(header) != "" {
+			add(tech)
+		}
+	}
+	for _, cookie := range h.Values("Set-Cookie") {
+		name, _, _ := strings.Cut(cookie, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if tech, ok := techCookies[name]; ok {
+			add(tech)
+		} else if strings.HasPrefix(name, "wp-") || strings.HasPrefix(name, "wordpress_") {
+			add("WordPress")
+		} else if strings.HasPrefix(name, "bigipserver") {
+			add("F5 BIG-IP")
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// EnrichConfig selects what an Enricher attaches beyond what the client saw
+// of each host.
+type EnrichConfig struct {
+	// ASNDB maps resolved addresses to their AS; nil leaves ASN fields empty.
+	ASNDB *ASNDB
+	// Tags maps hosts to owner tags; nil leaves Tags empty.
+	Tags *AssetTags
+	// Resolve looks up the address of hosts the scan never connected to
+	// itself, such as those only a browser or the proxy's client reached.
+	Resolve bool
+}
+
+// enrichResolveTimeout bounds the DNS lookup of one host.
+const enrichResolveTimeout = 3 * time.Second
+
+// Enricher attaches Asset metadata to findings, from what the client recorded
+// of each origin during the scan (see ClientConfig.RecordOrigins) and the
+// configured offline data. It is safe for concurrent use.
+type Enricher struct {
+	client *Client
+	cfg    EnrichConfig
+
+	mu       sync.Mutex
+	resolved map[string]string
+}
+
+// NewEnricher returns an Enricher over what client records.
+func NewEnricher(client *Client, cfg EnrichConfig) *Enricher {
+	return &Enricher{client: client, cfg: cfg, resolved: make(map[string]string)}
+}
+
+// AssetFor returns the Asset of the host rawURL names, nil when it names no
+// host (a local file, stdin).
+func (e *Enricher) AssetFor(ctx context.Context, rawURL string) *Asset {
+	if e == nil {
+		return nil
+	}
+	host := findingHost(rawURL)
+	if host == "" {
+		return nil
+	}
+	a := &Asset{Host: host}
+	var info originInfo
+	if e.client != nil {
+		info, _ = e.client.origins.snapshot(host)
+	}
+	a.IP, a.TLSSubject, a.TLSIssuer, a.Server = info.ip, info.tlsSubject, info.tlsIssuer, info.server
+	a.Tech = append([]string(nil), info.tech...)
+	if a.IP == "" && e.cfg.Resolve {
+		a.IP = e.resolve(ctx, host)
+	}
+	if as, ok := e.cfg.ASNDB.Lookup(a.IP); ok {
+		a.ASN, a.ASOrg, a.Country = as.ASN, as.Org, as.Country
+	}
+	a.Tags = e.cfg.Tags.Lookup(host)
+	return a
+}
+
+// resolve looks host up once, remembering failures too.
+func (e *Enricher) resolve(ctx context.Context, host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	e.mu.Lock()
+	ip, ok := e.resolved[host]
+	e.mu.Unlock()
+	if ok {
+		return ip
+	}
+	ctx, cancel := context.WithTimeout(ctx, enrichResolveTimeout)
+	defer cancel()
+	if addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host); err == nil && len(addrs) > 0 {
+		ip = addrs[0].IP.String()
+		for _, a := range addrs {
+			if a.IP.To4() != nil {
+				ip = a.IP.String()
+				break
+			}
+		}
+	}
+	e.mu.Lock()
+	e.resolved[host] = ip
+	e.mu.Unlock()
+	return ip
+}
+
+// Matches returns ms with each match's Asset set from its Source.
+func (e *Enricher) Matches(ctx context.Context, ms []Match) []Match {
+	if e == nil || len(ms) == 0 {
+		return ms
+	}
+	out := make([]Match, len(ms))
+	for i, m := range ms {
+		m.Asset = e.AssetFor(ctx, m.Source)
+		out[i] = m
+	}
+	return out
+}
+
+// DOMFindings returns fs with each finding's Asset set from its page.
+func (e *Enricher) DOMFindings(ctx context.Context, fs []DOMFinding) []DOMFinding {
+	if e == nil || len(fs) == 0 {
+		return fs
+	}
+	out := make([]DOMFinding, len(fs))
+	for i, f := range fs {
+		f.Asset = e.AssetFor(ctx, firstNonEmpty(f.PageURL, f.Target))
+		out[i] = f
+	}
+	return out
+}
+
+// ReflectionFindings returns fs with each finding's Asset set from its target.
+func (e *Enricher) ReflectionFindings(ctx context.Context, fs []ReflectionFinding) []ReflectionFinding {
+	if e == nil || len(fs) == 0 {
+		return fs
+	}
+	out := make([]ReflectionFinding, len(fs))
+	for i, f := range fs {
+		f.Asset = e.AssetFor(ctx, firstNonEmpty(f.Target, f.PageURL))
+		out[i] = f
+	}
+	return out
+}
+
+func firstNonEmpty(ss ...string) string {
+	for _, s := range ss {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// findingHost returns the lower-cased host of an http(s) URL, "" for
+// anything else.
+func findingHost(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestEnricherAttachesOriginMetadata scans a TLS origin and expects its
+// findings to carry the address, certificate, Server header, technology
+// fingerprint, AS and owner tags of that origin.
+func TestEnricherAttachesOriginMetadata(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.1")
+		w.Header().Add("Set-Cookie", "PHPSESSID=abc; Path=/")
+		w.Header().Set("CF-Ray", "8a1b2c3d4e5f-AMS")
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	c := testClient(func(c *ClientConfig) { c.RecordOrigins = true })
+	rc, err := c.FetchURL(context.Background(), ts.URL+"/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+
+	db, err := ReadASNDB(strings.NewReader("127.0.0.0\t127.255.255.255\t64500\tZZ\tLOOPBACK-NET\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := ReadAssetTags(strings.NewReader("host,team\n127.0.0.1,payments\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	en := NewEnricher(c, EnrichConfig{ASNDB: db, Tags: tags})
+	ms := en.Matches(context.Background(), []Match{
+		{Source: ts.URL + "/app.js", Pattern: "aws_key", Value: "AKIA"},
+		{Source: "bundle.js", Pattern: "aws_key", Value: "AKIA"},
+	})
+	a := ms[0].Asset
+	if a == nil {
+		t.Fatal("match from the TLS origin has no asset")
+	}
+	want := Asset{
+		Host: "127.0.0.1", IP: "127.0.0.1", ASN: 64500, ASOrg: "LOOPBACK-NET", Country: "ZZ",
+		TLSSubject: "O=Acme Co", TLSIssuer: "O=Acme Co", Server: "nginx/1.25.3",
+		Tech: []string{"Cloudflare", "PHP", "nginx"}, Tags: map[string]string{"team": "payments"},
+	}
+	if !reflect.DeepEqual(*a, want) {
+		t.Errorf("asset = %+v\nwant    %+v", *a, want)
+	}
+	if ms[1].Asset != nil {
+		t.Errorf("local file match got asset %+v", ms[1].Asset)
+	}
+	if !a.MatchesTags(map[string]string{"team": "pay*"}) || a.MatchesTags(map[string]string{"owner": "*"}) {
+		t.Error("MatchesTags disagrees with the asset's tags")
+	}
+
+	var nilEnricher *Enricher
+	if got := nilEnricher.Matches(context.Background(), ms[1:]); got[0].Asset != nil {
+		t.Error("nil Enricher attached an asset")
+	}
+}
+
+func TestFingerprintTech(t *testing.T) {
+	h := http.Header{}
+	h.Set("Server", "Microsoft-IIS/10.0")
+	h.Set("X-Powered-By", "ASP.NET")
+	h.Set("X-AspNet-Version", "4.0.30319")
+	h.Add("Set-Cookie", "ASP.NET_SessionId=x; path=/")
+	h.Add("Set-Cookie", "wordpress_logged_in_abc=y")
+	want := []string{"ASP.NET", "Microsoft-IIS", "WordPress"}
+	if got := fingerprintTech(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("fingerprintTech = %v, want %v", got, want)
+	}
+	if got := fingerprintTech(http.Header{}); got != nil {
+		t.Errorf("bare response fingerprinted as %v", got)
+	}
+}
@@ -3,13 +3,13 @@ package scan
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -24,6 +24,21 @@ type Match struct {
 	Value    string `json:"value"`
 	Params   string `json:"params,omitempty"`
 	Severity string `json:"severity"`
+	// Confidence scores a candidate secret from MinConfidence to MaxConfidence
+	// by how likely it is to be live; zero means the match was not scored.
+	Confidence int `json:"confidence,omitempty"`
+	// Secret marks a candidate secret: the value is a credential rather than
+	// intelligence such as an endpoint, email or header. Only secrets are
+	// scored for confidence and masked by redaction.
+	Secret bool `json:"secret,omitempty"`
+
+	// Asset describes the host the match was found on; it is set only when
+	// enrichment is enabled (see Enricher).
+	Asset *Asset `json:"asset,omitempty"`
+
+	// Library describes the fingerprinted library of a library or
+	// vulnerable_library match.
+	Library *LibraryInfo `json:"library,omitempty"`
 
 	// Snippet holds a raw source window surrounding the matched value. It is
 	// only populated when snippet capture is enabled (see SetSnippet) and is
@@ -41,6 +56,17 @@ type Extractor struct {
 	snippet           bool
 	calibrator        *autoCalibrator
 	recoverSourceMaps bool
+	// prefilter decides in one pass over an input which of rules can match
+	// it; it is rebuilt whenever rules changes.
+	prefilter *rulePrefilter
+
+	// client carries the scan's network and rendering configuration; every
+	// fetch, render, crawl, DOM and reflection scan goes through it.
+	client *Client
+
+	// enricher, when set, is how the CLI and the proxy attach asset metadata
+	// to what the extractor finds.
+	enricher *Enricher
 
 	// DOM source hints are hidden cross-phase intelligence: when enabled by the
 	// CLI's -dom/-full modes, concurrent JS scans accumulate parameter/storage
@@ -57,6 +83,21 @@ type Extractor struct {
 // all source-map fetching and decoding.
 func (e *Extractor) SetRecoverSourceMaps(on bool) { e.recoverSourceMaps = on }
 
+// SetClient replaces the client the extractor fetches and renders with.
+// NewExtractor installs one built from DefaultClientConfig.
+func (e *Extractor) SetClient(c *Client) { e.client = c }
+
+// Client returns the client the extractor fetches and renders with.
+func (e *Extractor) Client() *Client { return e.client }
+
+// SetEnricher installs the Enricher findings are annotated with; nil (the
+// default) leaves them bare.
+func (e *Extractor) SetEnricher(en *Enricher) { e.enricher = en }
+
+// Enricher returns the installed Enricher, nil when there is none. Its methods
+// are nil-safe.
+func (e *Extractor) Enricher() *Enricher { return e.enricher }
+
 // SetCalibrator installs (or clears, when nil) an auto-calibrator used during
 // crawls to skip catch-all/soft-404 and duplicate pages. It is nil by default,
 // leaving non-crawl scans unaffected.
@@ -376,7 +417,11 @@ var defaultFilters = map[string]func(string) bool{
 	"password": credentialValueFilter,
 }
 
-// powerPatterns provide additional regexes enabled by default.
+// intelligencePatterns are the default rules whose matches are not secrets.
+var intelligencePatterns = map[string]bool{"email": true}
+
+// powerPatterns provide additional regexes enabled by default. None of them
+// matches secrets.
 var powerPatterns = map[string]string{
 	"phone": `\d{3}-\d{3}-\d{4}`,
 	// Require at least two colons so bare "a:b" object literals are ignored;
@@ -403,7 +448,7 @@ var powerContextFilters = map[string]func(data []byte, start, end int) bool{
 
 // NewExtractor creates an Extractor
 func NewExtractor(safe bool, longSecret bool) *Extractor {
-	e := &Extractor{safeMode: safe, jsRules: make(map[string]bool), recoverSourceMaps: true}
+	e := &Extractor{safeMode: safe, jsRules: make(map[string]bool), recoverSourceMaps: true, client: NewClient(DefaultClientConfig())}
 	for k, v := range baseJSRules {
 		e.jsRules[k] = v
 	}
@@ -412,6 +457,7 @@ func NewExtractor(safe bool, longSecret bool) *Extractor {
 			continue
 		}
 		r := newRegexRule(name, pat, severityFor(name))
+		r.Secret = !intelligencePatterns[name]
 		if f, ok := defaultFilters[name]; ok {
 			r.Filter = f
 		}
@@ -436,28 +482,32 @@ func NewExtractor(safe bool, longSecret bool) *Extractor {
 	// literals and CSS declarations that share the `name: value` shape.
 	e.rules = append(e.rules, newHTTPHeaderRule())
 	e.rules = append(e.rules, getRegisteredRules()...)
+	e.prefilter = newRulePrefilter(e.rules)
 	return e
 }
 
-// LoadRulesFile loads additional regex patterns from a YAML file
+// LoadRulesFile loads additional rules from a rule file or a directory of
+// them: versioned rule files (see rulefile.go), gitleaks configs, nuclei
+// templates, or the legacy flat YAML mapping of rule names to regular
+// expressions, whose rules all report at info severity.
 func (e *Extractor) LoadRulesFile(path string) error {
-	data, err := os.ReadFile(path)
+	specs, err := readRulePath(path)
 	if err != nil {
 		return err
 	}
-
-	rules, err := parseSimpleYAML(data)
-	if err != nil {
-		return err
-	}
-
-	for name, pat := range rules {
-		r, err := regexp.Compile(pat)
+	rules := make([]Rule, 0, len(specs))
+	for _, spec := range specs {
+		r, err := spec.compile()
 		if err != nil {
 			return err
 		}
-		e.rules = append(e.rules, RegexRule{Name: strings.TrimSpace(name), RE: r, Severity: "info"})
+		rules = append(rules, r)
+		if spec.JS {
+			e.jsRules[spec.ID] = true
+		}
 	}
+	e.rules = append(e.rules, rules...)
+	e.prefilter = newRulePrefilter(e.rules)
 	return nil
 }
 
@@ -553,40 +603,45 @@ func (e *Extractor) scanReader(source string, r io.Reader, isJS bool) ([]Match,
 	buf.Buffer(make([]byte, 0, InitialBufferSize), MaxBufferSize)
 	for buf.Scan() {
 		line := []byte(buf.Text())
-		matches = append(matches, e.scanRulesSelected(source, line, e.rules, true)...)
+		ms := e.scanRulesSelected(source, line, e.rules, true)
+		attachConfidence(source, line, ms, isJS)
+		matches = append(matches, ms...)
 	}
 	return matches, buf.Err()
 }
 
 // rulesForData removes rules that provably cannot match data because one of
-// their required literal prefilters is absent. The check is performed once for
-// an already-buffered response instead of once per rule per line. Rules without
-// a provable literal requirement, including arbitrary plugin rules, always stay
-// eligible.
-func (e *Extractor) rulesForData(data []byte) []Rule {
+// their required literal prefilters, or every one of their keywords, is
+// absent. A single pass of the prefilter automaton decides this for all rules
+// at once. When whole is set the rules will run over exactly data, so the
+// returned copies drop the checks already proven instead of repeating them on
+// every Find. Rules without a provable literal requirement, including arbitrary
+// plugin rules, always stay eligible.
+func (e *Extractor) rulesForData(data []byte, whole bool) []Rule {
+	eligible := e.prefilter.eligible(data)
 	rules := make([]Rule, 0, len(e.rules))
-	for _, rule := range e.rules {
-		if e.safeMode && !e.isJSRule(rule.MatchName()) {
+	for i, rule := range e.rules {
+		if !eligible[i] || (e.safeMode && !e.isJSRule(rule.MatchName())) {
 			continue
 		}
-
-		var prefilters [][]byte
-		switch typed := rule.(type) {
-		case RegexRule:
-			prefilters = typed.prefilters
-		case *RegexRule:
-			prefilters = typed.prefilters
-		}
-		eligible := true
-		for _, prefilter := range prefilters {
-			if !bytes.Contains(data, prefilter) {
-				eligible = false
-				break
+		if whole {
+			switch typed := rule.(type) {
+			case RegexRule:
+				typed.prefilters = nil
+				if len(e.prefilter.keywords[i]) > 0 {
+					typed.Keywords = nil
+				}
+				rule = typed
+			case *RegexRule:
+				c := *typed
+				c.prefilters = nil
+				if len(e.prefilter.keywords[i]) > 0 {
+					c.Keywords = nil
+				}
+				rule = &c
 			}
 		}
-		if eligible {
-			rules = append(rules, rule)
-		}
+		rules = append(rules, rule)
 	}
 	return rules
 }
@@ -598,21 +653,19 @@ func (e *Extractor) scanBuffered(source string, data []byte, isJS bool) ([]Match
 	if e.isAllowed(source) || (e.safeMode && !isJS) {
 		return nil, nil
 	}
-	rules := e.rules
-	filterSafe := true
-	// A one-line minified bundle already checks each rule's prefilters once in
-	// Rule.Find; a file-level pass would duplicate that work. Eligibility pays
-	// off for multiline source, where it prevents the same impossible rules from
-	// being reconsidered on thousands of separate lines.
-	if bytes.Count(data, []byte{'\n'}) >= 32 {
-		rules = e.rulesForData(data)
-		filterSafe = false
-	}
+	// A one-line minified bundle is scanned as that one line, so the rules
+	// need not re-check what the prefilter pass proved. Multiline source keeps
+	// each rule's own checks, which skip the regex on lines that cannot match.
+	newlines := bytes.Count(data, []byte{'\n'})
+	whole := newlines == 0 || (newlines == 1 && data[len(data)-1] == '\n')
+	rules := e.rulesForData(data, whole)
 	var matches []Match
 	buf := bufio.NewScanner(bytes.NewReader(data))
 	buf.Buffer(make([]byte, 0, InitialBufferSize), MaxBufferSize)
 	for buf.Scan() {
-		matches = append(matches, e.scanRulesSelected(source, buf.Bytes(), rules, filterSafe)...)
+		ms := e.scanRulesSelected(source, buf.Bytes(), rules, false)
+		attachConfidence(source, buf.Bytes(), ms, isJS)
+		matches = append(matches, ms...)
 	}
 	return matches, buf.Err()
 }
@@ -686,14 +739,29 @@ func (e *Extractor) scanRulesSelected(source string, line []byte, rules []Rule,
 // ScanReaderWithEndpoints scans r like ScanReader and also extracts HTTP
 // endpoints from JavaScript sources. Endpoint matches use the pattern name
 // "endpoint_url" for absolute URLs and "endpoint_path" for relative paths.
-func (e *Extractor) ScanReaderWithEndpoints(source string, r io.Reader) ([]Match, error) {
-	data, err := io.ReadAll(r)
+// Reading r stops with ctx's error once ctx ends.
+func (e *Extractor) ScanReaderWithEndpoints(ctx context.Context, source string, r io.Reader) ([]Match, error) {
+	data, err := io.ReadAll(contextReader{ctx, r})
 	if err != nil {
 		return nil, err
 	}
 	return e.scanDataWithEndpoints(source, data, source == "stdin" || isJSFile(source))
 }
 
+// contextReader stops a read as soon as its context is done, so a slow or
+// endless reader (a pipe on stdin, say) cannot outlive a cancelled scan.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
 // scanDataWithEndpoints scans already-buffered data, using isJS as the
 // authoritative source classification. It combines raw regex scanning with the
 // lightweight AST/value pass so secrets assembled from adjacent string literals
@@ -712,8 +780,10 @@ func (e *Extractor) scanDataWithEndpoints(source string, data []byte, isJS bool)
 		// Raw regex scanning already covers values that appear contiguously in the
 		// source. Limit the additional AST pass to reconstructed values so a large
 		// bundle does not run hundreds of rules over every ordinary string twice.
-		matches = append(matches, e.scanASTData(source, data, true)...)
-		matches = UniqueMatches(matches)
+		reconstructed := e.scanASTData(source, data, true)
+		attachConfidence(source, data, reconstructed, true)
+		matches = UniqueMatches(append(matches, reconstructed...))
+		matches = append(matches, e.scanLibraries(source, data)...)
 	}
 
 	if isJS || looksLikeJSON(data) {
@@ -744,9 +814,9 @@ func (e *Extractor) scanDataWithEndpoints(source string, data []byte, isJS bool)
 // ScanReaderPostRequests extracts HTTP POST request endpoints from r. Matches
 // use the pattern name "post_url" for absolute URLs and "post_path" for
 // relative paths. Only JavaScript files are processed when safe mode is
-// enabled.
-func (e *Extractor) ScanReaderPostRequests(source string, r io.Reader) ([]Match, error) {
-	data, err := io.ReadAll(r)
+// enabled. Reading r stops with ctx's error once ctx ends.
+func (e *Extractor) ScanReaderPostRequests(ctx context.Context, source string, r io.Reader) ([]Match, error) {
+	data, err := io.ReadAll(contextReader{ctx, r})
 	if err != nil {
 		return nil, err
 	}
@@ -977,6 +1047,7 @@ func (e *Extractor) ScanReaderAST(source string, r io.Reader) ([]Match, error) {
 		return nil, err
 	}
 	matches := e.scanASTData(source, data, false)
+	attachConfidence(source, data, matches, true)
 	if e.snippet {
 		attachSnippets(data, matches)
 	}
@@ -2,6 +2,7 @@ package scan
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"reflect"
@@ -530,7 +531,7 @@ func BenchmarkScanReaderWithEndpointsMultiline(b *testing.B) {
 	b.SetBytes(int64(len(data)))
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if _, err := e.ScanReaderWithEndpoints("bundle.js", strings.NewReader(data)); err != nil {
+		if _, err := e.ScanReaderWithEndpoints(context.Background(), "bundle.js", strings.NewReader(data)); err != nil {
 			b.Fatal(err)
 		}
 	}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -16,8 +17,7 @@ func TestFetchURLSetsUserAgent(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	SetExtraHeaders(nil)
-	rc, err := FetchURL(ts.URL)
+	rc, err := testClient().FetchURL(context.Background(), ts.URL)
 	if err != nil {
 		t.Fatalf("FetchURL returned error: %v", err)
 	}
@@ -28,7 +28,7 @@ func TestFetchURLSetsUserAgent(t *testing.T) {
 	}
 }
 
-// Test that FetchURL uses extra headers provided via SetExtraHeaders
+// Test that FetchURL sends the extra headers configured on the client
 func TestFetchURLExtraHeaders(t *testing.T) {
 	var hv string
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -39,8 +39,7 @@ func TestFetchURLExtraHeaders(t *testing.T) {
 
 	h := http.Header{}
 	h.Set("X-Test", "yes")
-	SetExtraHeaders(h)
-	rc, err := FetchURL(ts.URL)
+	rc, err := testClient(func(c *ClientConfig) { c.Headers = h }).FetchURL(context.Background(), ts.URL)
 	if err != nil {
 		t.Fatalf("FetchURL returned error: %v", err)
 	}
@@ -58,16 +57,14 @@ func TestFetchURLSkipTLSVerify(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	SetSkipTLSVerification(false)
-	if _, err := FetchURL(ts.URL); err == nil {
+	strict := testClient(func(c *ClientConfig) { c.SkipTLSVerification = false })
+	if _, err := strict.FetchURL(context.Background(), ts.URL); err == nil {
 		t.Fatalf("expected TLS error when verification enabled")
 	}
 
-	SetSkipTLSVerification(true)
-	rc, err := FetchURL(ts.URL)
+	rc, err := testClient().FetchURL(context.Background(), ts.URL)
 	if err != nil {
 		t.Fatalf("FetchURL returned error with skip verify: %v", err)
 	}
 	rc.Close()
-	SetSkipTLSVerification(true)
 }
@@ -7,6 +7,7 @@ type FilterRegexRule struct {
 	Name     string
 	RE       *regexp.Regexp
 	Severity string
+	Secret   bool
 	Filter   func(string) bool
 }
 
@@ -19,7 +20,7 @@ func (r FilterRegexRule) Find(data []byte) []Match {
 		if r.Filter != nil && !r.Filter(s) {
 			continue
 		}
-		matches = append(matches, Match{Pattern: r.Name, Value: s, Severity: r.Severity})
+		matches = append(matches, Match{Pattern: r.Name, Value: s, Severity: r.Severity, Secret: r.Secret})
 	}
 	return matches
 }
@@ -0,0 +1,184 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ruleAllowlist is a gitleaks allowlist attached to an imported rule. It drops
+// a hit when one of its regexes matches the target (the secret, the whole
+// match, or the line holding it) or the secret contains one of its stopwords.
+// With matchAll (gitleaks' condition = "AND") both kinds must hit.
+type ruleAllowlist struct {
+	target    string
+	matchAll  bool
+	regexes   []string
+	stopwords []string
+}
+
+type compiledAllowlist struct {
+	target    string
+	matchAll  bool
+	res       []*regexp.Regexp
+	stopwords []string
+}
+
+func (a ruleAllowlist) compile() (compiledAllowlist, error) {
+	res, err := compileValueREs(a.regexes)
+	if err != nil {
+		return compiledAllowlist{}, err
+	}
+	c := compiledAllowlist{target: a.target, matchAll: a.matchAll, res: res}
+	for _, w := range a.stopwords {
+		if w = strings.ToLower(w); w != "" {
+			c.stopwords = append(c.stopwords, w)
+		}
+	}
+	return c, nil
+}
+
+func (a compiledAllowlist) excludes(data []byte, start, end int, value string) bool {
+	reHit := false
+	if len(a.res) > 0 {
+		subject := value
+		switch a.target {
+		case "match":
+			subject = string(data[start:end])
+		case "line":
+			subject = string(lineAround(data, start, end))
+		}
+		reHit = matchesAny(a.res, subject)
+	}
+	stopHit := false
+	if len(a.stopwords) > 0 {
+		lower := strings.ToLower(value)
+		for _, w := range a.stopwords {
+			if strings.Contains(lower, w) {
+				stopHit = true
+				break
+			}
+		}
+	}
+	if a.matchAll {
+		return (len(a.res) == 0 || reHit) && (len(a.stopwords) == 0 || stopHit)
+	}
+	return reHit || stopHit
+}
+
+// lineAround returns the line of data holding data[start:end].
+func lineAround(data []byte, start, end int) []byte {
+	from := bytes.LastIndexByte(data[:start], '\n') + 1
+	to := len(data)
+	if i := bytes.IndexByte(data[end:], '\n'); i >= 0 {
+		to = end + i
+	}
+	return data[from:to]
+}
+
+// readGitleaksConfig converts a gitleaks TOML config into rule specs. Each
+// [[rules]] entry keeps its regex, secretGroup, entropy and keywords, and its
+// allowlists plus the global ones. Gitleaks has no severities: rules whose id
+// names them generic are medium, the provider-specific rest high, and all are
+// JavaScript-relevant secrets. Rules scoped to file paths are skipped, since
+// path conditions have no meaning for crawled content, as are allowlists that
+// require a path or commit to match.
+func readGitleaksConfig(path string, data []byte) ([]ruleSpec, error) {
+	doc, err := parseTOMLSubset(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	global, err := gitleaksAllowlists(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: allowlist: %w", path, err)
+	}
+	list, _ := doc["rules"].([]any)
+	var specs []ruleSpec
+	for i, raw := range list {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: rule %d: expected a table", path, i+1)
+		}
+		id, _ := ruleString(m["id"])
+		if id == "" {
+			id = fmt.Sprintf("gitleaks-%d", i+1)
+		}
+		regex, err := ruleString(m["regex"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: regex: %w", path, id, err)
+		}
+		if p, _ := ruleString(m["path"]); regex == "" || p != "" {
+			vlog(1, "rules: %s: skipping gitleaks rule %s (path-scoped)", path, id)
+			continue
+		}
+		spec := ruleSpec{ID: id, Regex: regex, Severity: SeverityHigh, JS: true}
+		if strings.Contains(strings.ToLower(id), "generic") {
+			spec.Severity = SeverityMedium
+		}
+		spec.Description, _ = ruleString(m["description"])
+		if spec.Group, err = ruleInt(m["secretGroup"]); err != nil {
+			return nil, fmt.Errorf("%s: %s: secretGroup: %w", path, id, err)
+		}
+		if spec.Entropy, err = ruleFloat(m["entropy"]); err != nil {
+			return nil, fmt.Errorf("%s: %s: entropy: %w", path, id, err)
+		}
+		if spec.Keywords, err = ruleStrings(m["keywords"]); err != nil {
+			return nil, fmt.Errorf("%s: %s: keywords: %w", path, id, err)
+		}
+		own, err := gitleaksAllowlists(m)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: allowlist: %w", path, id, err)
+		}
+		spec.allowlists = append(own, global...)
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// gitleaksAllowlists reads the allowlists of a config or rule table: the
+// single [allowlist] table of older configs and the [[allowlists]] array of
+// newer ones.
+func gitleaksAllowlists(table map[string]any) ([]ruleAllowlist, error) {
+	var raws []any
+	if one, ok := table["allowlist"].(map[string]any); ok {
+		raws = append(raws, one)
+	}
+	if many, ok := table["allowlists"].([]any); ok {
+		raws = append(raws, many...)
+	}
+	var out []ruleAllowlist
+	for _, raw := range raws {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a table")
+		}
+		a := ruleAllowlist{target: "secret"}
+		if t, _ := ruleString(m["regexTarget"]); t != "" {
+			a.target = strings.ToLower(t)
+		}
+		if a.target != "secret" && a.target != "match" && a.target != "line" {
+			return nil, fmt.Errorf("unknown regexTarget %q", a.target)
+		}
+		cond, _ := ruleString(m["condition"])
+		a.matchAll = strings.EqualFold(cond, "AND")
+		var err error
+		if a.regexes, err = ruleStrings(m["regexes"]); err != nil {
+			return nil, err
+		}
+		if a.stopwords, err = ruleStrings(m["stopwords"]); err != nil {
+			return nil, err
+		}
+		paths, _ := ruleStrings(m["paths"])
+		commits, _ := ruleStrings(m["commits"])
+		if a.matchAll && (len(paths) > 0 || len(commits) > 0) {
+			// Every condition must hold, and a path or commit never does here.
+			continue
+		}
+		if len(a.regexes) == 0 && len(a.stopwords) == 0 {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
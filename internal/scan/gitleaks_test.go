@@ -0,0 +1,120 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+)
+
+const testGitleaksConfig = `title = "test config"
+
+[allowlist]
+description = "global"
+stopwords = ["example"]
+
+[[rules]]
+id = "acme-token"
+description = "ACME token"
+regex = '''acme_token\s*=\s*"(acme_[a-z0-9]{16})"'''
+secretGroup = 1
+entropy = 2.5
+keywords = ["acme_token"]
+
+[[rules.allowlists]]
+regexTarget = "line"
+regexes = ['''// fixture''']
+
+[[rules]]
+id = "generic-api-key"
+regex = '''apikey=([A-Za-z0-9]{20})'''
+secretGroup = 1
+
+[[rules]]
+id = "pkcs12-file"
+path = '''\.p12$'''
+`
+
+func TestLoadGitleaksConfig(t *testing.T) {
+	p := writeRuleFile(t, "gitleaks.toml", testGitleaksConfig)
+	e := NewExtractor(true, false)
+	before := len(e.rules)
+	if err := e.LoadRulesFile(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(e.rules) - before; got != 2 {
+		t.Fatalf("loaded %d rules, want 2 (path-scoped rule skipped)", got)
+	}
+	if !e.jsRules["acme-token"] || !e.jsRules["generic-api-key"] {
+		t.Fatal("gitleaks rules should run in safe mode")
+	}
+
+	cases := []struct {
+		line, want, sev string
+	}{
+		{`acme_token = "acme_k3j9x2m7q4w8z1p5"`, "acme_k3j9x2m7q4w8z1p5", SeverityHigh},
+		{`acme_token = "acme_k3j9x2m7q4w8z1p5" // fixture`, "", ""},
+		{`acme_token = "acme_aaaaaaaaaaaaaaaa"`, "", ""},
+		{`acme_token = "acme_example1234567a"`, "", ""},
+		{`acme_k3j9x2m7q4w8z1p5`, "", ""},
+		{`apikey=Zx81Lq0Wm3Nv5Tb7Yc9R`, "Zx81Lq0Wm3Nv5Tb7Yc9R", SeverityMedium},
+	}
+	for _, c := range cases {
+		var got []Match
+		for _, r := range e.rules[before:] {
+			got = append(got, r.Find([]byte(c.line))...)
+		}
+		if c.want == "" {
+			if len(got) != 0 {
+				t.Errorf("%q: unexpected match %+v", c.line, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Value != c.want || got[0].Severity != c.sev {
+			t.Errorf("%q: got %+v, want %s at %s", c.line, got, c.want, c.sev)
+		}
+	}
+}
+
+func TestGitleaksAllowlistConditions(t *testing.T) {
+	doc, err := parseTOMLSubset([]byte(`
+[[allowlists]]
+condition = "AND"
+regexes = ['''^test_''']
+stopwords = ["dummy"]
+
+[[allowlists]]
+condition = "AND"
+regexes = ['''.*''']
+paths = ['''.*''']
+
+[[allowlists]]
+description = "nothing to match on"
+commits = ["abc123"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists, err := gitleaksAllowlists(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 1 {
+		t.Fatalf("got %d allowlists, want only the AND list without paths", len(lists))
+	}
+	c, err := lists[0].compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for value, want := range map[string]bool{
+		"test_dummy_1": true,
+		"test_real_1":  false,
+		"prod_dummy_1": false,
+	} {
+		if got := c.excludes([]byte(value), 0, len(value), value); got != want {
+			t.Errorf("excludes(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	if _, err := gitleaksAllowlists(map[string]any{"allowlist": map[string]any{"regexTarget": "path"}}); err == nil || !strings.Contains(err.Error(), "regexTarget") {
+		t.Fatalf("expected a regexTarget error, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -60,8 +61,8 @@ type graphqlSchemaResponse struct {
 // and the count of user-defined types). ok is false when the endpoint does not
 // answer, is not GraphQL, or has introspection disabled — so the crawl reports the
 // finding only when it is real. It issues a single POST and reads a bounded body.
-func probeGraphQLIntrospection(endpointURL string) (Match, bool) {
-	resp, err := fetchURLResponseMethodSameScope(endpointURL, "POST", graphqlIntrospectionQuery)
+func probeGraphQLIntrospection(ctx context.Context, client *Client, endpointURL string) (Match, bool) {
+	resp, err := client.fetchURLResponseMethodSameScope(ctx, endpointURL, "POST", graphqlIntrospectionQuery)
 	if err != nil {
 		return Match{}, false
 	}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -59,7 +60,7 @@ func TestProbeGraphQLIntrospectionEnabled(t *testing.T) {
 	ts := graphqlServer(true)
 	defer ts.Close()
 
-	m, ok := probeGraphQLIntrospection(ts.URL + "/graphql")
+	m, ok := probeGraphQLIntrospection(context.Background(), testClient(), ts.URL+"/graphql")
 	if !ok {
 		t.Fatal("expected a finding when introspection is enabled")
 	}
@@ -80,7 +81,7 @@ func TestProbeGraphQLIntrospectionEnabled(t *testing.T) {
 func TestProbeGraphQLIntrospectionDisabled(t *testing.T) {
 	ts := graphqlServer(false)
 	defer ts.Close()
-	if _, ok := probeGraphQLIntrospection(ts.URL + "/graphql"); ok {
+	if _, ok := probeGraphQLIntrospection(context.Background(), testClient(), ts.URL+"/graphql"); ok {
 		t.Fatal("expected no finding when introspection is disabled")
 	}
 }
@@ -109,7 +110,7 @@ func TestScanURLCrawlProbesGraphQL(t *testing.T) {
 	e := NewExtractor(true, false)
 	// ProbeMethods on so active probing (including introspection) runs.
 	opts := CrawlOptions{MaxDepth: 2, MaxPages: 10, SameScopeOnly: true, ProbeMethods: true, RequestMethods: defaultRequestMethods()}
-	ms, err := e.ScanURLCrawl(ts.URL, false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
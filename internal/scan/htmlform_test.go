@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -68,7 +69,6 @@ func TestExtractHTMLFormMatches(t *testing.T) {
 // Without form harvesting the crawl would only ever replay parameters mined from
 // JavaScript, and this form (whose fields appear in no script) would be invisible.
 func TestCrawlReplaysHTMLFormParams(t *testing.T) {
-	ResetThrottle()
 
 	var sawFormPost int32
 	mux := http.NewServeMux()
@@ -108,7 +108,7 @@ func TestCrawlReplaysHTMLFormParams(t *testing.T) {
 	opts := DefaultCrawlOptions()
 	opts.Concurrency = 1
 	opts.DiscoverWellKnown = false // keep the test's request graph tight
-	if _, err := e.ScanURLCrawl(ts.URL, false, false, false, opts); err != nil {
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts); err != nil {
 		t.Fatal(err)
 	}
 	if atomic.LoadInt32(&sawFormPost) == 0 {
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -8,65 +9,45 @@ import (
 	"testing"
 )
 
-// resetSharedClient clears the cached fetch client so a test starts from a known
-// state regardless of what ran before it.
-func resetSharedClient() {
-	httpClientMu.Lock()
-	sharedClient = nil
-	sharedClientBuilt = false
-	httpClientMu.Unlock()
-}
-
-// TestSharedHTTPClientReused verifies the fetch path reuses one client (and thus
-// its keep-alive connection pool) across requests, rather than building a fresh
-// client — and transport — every time.
-func TestSharedHTTPClientReused(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-
-	a := sharedHTTPClient()
-	b := sharedHTTPClient()
-	if a != b {
-		t.Fatal("sharedHTTPClient built a new client when config was unchanged")
+// TestClientHTTPReused verifies a client builds its HTTP client (and thus its
+// keep-alive connection pool) once, rather than building a fresh client — and
+// transport — for every request.
+func TestClientHTTPReused(t *testing.T) {
+	c := testClient()
+	a := c.http
+	if a == nil {
+		t.Fatal("NewClient did not build an HTTP client")
+	}
+	if _, err := c.fetchURLResponse(context.Background(), "http://127.0.0.1:0/"); err == nil {
+		t.Fatal("expected a dial error for port 0")
+	}
+	if c.http != a {
+		t.Fatal("fetch replaced the client's HTTP client")
 	}
 }
 
-// TestSharedHTTPClientRebuiltOnConfigChange verifies the cached client is rebuilt
-// when a baked-in setting (TLS verification, timeout) changes, so configuration
-// updates are never silently ignored.
-func TestSharedHTTPClientRebuiltOnConfigChange(t *testing.T) {
-	resetSharedClient()
-	origTLS := SkipTLSVerification
-	origTimeout := HTTPClientTimeout
-	defer func() {
-		SkipTLSVerification = origTLS
-		HTTPClientTimeout = origTimeout
-		resetSharedClient()
-	}()
-
-	SetSkipTLSVerification(true)
-	first := sharedHTTPClient()
-
-	SetSkipTLSVerification(false)
-	if second := sharedHTTPClient(); second == first {
-		t.Fatal("client not rebuilt after SkipTLSVerification changed")
+// TestClientTLSSettingIsPerClient verifies two clients with different TLS
+// settings get separate transports, so one scan's -insecure never leaks into
+// another.
+func TestClientTLSSettingIsPerClient(t *testing.T) {
+	lax := testClient()
+	strict := testClient(func(c *ClientConfig) { c.SkipTLSVerification = false })
+	if lax.http.Transport == strict.http.Transport {
+		t.Fatal("clients with different TLS settings share a transport")
 	}
-
-	third := sharedHTTPClient()
-	SetHTTPTimeout(33)
-	if fourth := sharedHTTPClient(); fourth == third {
-		t.Fatal("client not rebuilt after HTTPClientTimeout changed")
+	tr := strict.http.Transport.(*http.Transport)
+	if tr.TLSClientConfig != nil && tr.TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("strict client skips TLS verification")
+	}
+	if !lax.http.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("lax client verifies TLS")
 	}
 }
 
-// TestSharedHTTPClientKeepAlive drives several requests through the shared client
-// against one server and asserts the server observed connection reuse — the
-// concrete payoff of not rebuilding the transport per request.
-func TestSharedHTTPClientKeepAlive(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-
+// TestClientKeepAlive drives several requests through one client against one
+// server and asserts the server observed connection reuse — the concrete payoff
+// of not rebuilding the transport per request.
+func TestClientKeepAlive(t *testing.T) {
 	var conns int64
 	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("ok"))
@@ -79,8 +60,9 @@ func TestSharedHTTPClientKeepAlive(t *testing.T) {
 	ts.Start()
 	defer ts.Close()
 
+	c := testClient()
 	for i := 0; i < 5; i++ {
-		resp, err := fetchURLResponse(ts.URL)
+		resp, err := c.fetchURLResponse(context.Background(), ts.URL)
 		if err != nil {
 			t.Fatalf("request %d failed: %v", i, err)
 		}
@@ -325,6 +325,7 @@ func (httpHeaderRule) Find(data []byte) []Match {
 				Pattern:  httpHeaderPattern,
 				Value:    pair,
 				Severity: SeverityLow,
+				Secret:   isCredentialHeader(strings.ToLower(name)),
 			})
 		}
 	}
@@ -398,6 +399,32 @@ func inAnonymousHeaderMap(data []byte, nameStart int) bool {
 	return false
 }
 
+// credentialHeaderMarkers identify custom header names that carry a credential:
+// X-Api-Key, X-Auth-Token, X-CSRF-Token and the like.
+var credentialHeaderMarkers = []string{
+	"api-key", "apikey", "auth", "credential", "csrf", "secret",
+	"signature", "token", "xsrf",
+}
+
+// isCredentialHeader reports whether a header named lower carries a
+// credential, which makes the pair a candidate secret rather than
+// intelligence about the API.
+func isCredentialHeader(lower string) bool {
+	switch lower {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	if !isCustomHeaderName(lower) {
+		return false
+	}
+	for _, marker := range credentialHeaderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // anonymousHeaderEvidence scores names conservatively when an object has no
 // explicit header anchor. Security-bearing names are sufficient alone. Provider
 // and protocol families contribute one point each, as do standard names with a
@@ -405,10 +432,7 @@ func inAnonymousHeaderMap(data []byte, nameStart int) bool {
 // contribute nothing.
 func anonymousHeaderEvidence(lower, rawValue, cleaned string) int {
 	if isCustomHeaderName(lower) {
-		for _, marker := range []string{
-			"api-key", "apikey", "auth", "credential", "csrf", "secret",
-			"signature", "token", "xsrf",
-		} {
+		for _, marker := range credentialHeaderMarkers {
 			if strings.Contains(lower, marker) {
 				return 2
 			}
@@ -5,28 +5,19 @@ import (
 	"time"
 )
 
-// TestSetHTTPTimeoutWiring verifies that SetHTTPTimeout is honoured by the shared
-// HTTP client — the timeout was previously hardcoded, leaving HTTPClientTimeout
-// dead — and that a non-positive value restores the default.
-func TestSetHTTPTimeoutWiring(t *testing.T) {
-	orig := HTTPClientTimeout
-	defer func() {
-		HTTPClientTimeout = orig
-	}()
-
-	SetHTTPTimeout(45)
-	if HTTPClientTimeout != 45*time.Second {
-		t.Fatalf("HTTPClientTimeout = %v, want 45s", HTTPClientTimeout)
-	}
-	if c := newHTTPClient(); c.Timeout != 45*time.Second {
-		t.Fatalf("client Timeout = %v, want 45s", c.Timeout)
+// TestClientTimeoutWiring verifies that ClientConfig.Timeout is honoured by the
+// client's HTTP client and that a non-positive value restores the default.
+func TestClientTimeoutWiring(t *testing.T) {
+	c := testClient(func(c *ClientConfig) { c.Timeout = 45 * time.Second })
+	if c.http.Timeout != 45*time.Second {
+		t.Fatalf("client Timeout = %v, want 45s", c.http.Timeout)
 	}
 
-	SetHTTPTimeout(0)
-	if HTTPClientTimeout != 10*time.Second {
-		t.Fatalf("SetHTTPTimeout(0) = %v, want default 10s", HTTPClientTimeout)
+	c = testClient(func(c *ClientConfig) { c.Timeout = 0 })
+	if c.Config().Timeout != 10*time.Second {
+		t.Fatalf("Timeout 0 = %v, want default 10s", c.Config().Timeout)
 	}
-	if c := newHTTPClient(); c.Timeout != 10*time.Second {
-		t.Fatalf("client Timeout after reset = %v, want 10s", c.Timeout)
+	if c.http.Timeout != 10*time.Second {
+		t.Fatalf("client Timeout after reset = %v, want 10s", c.http.Timeout)
 	}
 }
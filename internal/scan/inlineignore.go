@@ -0,0 +1,136 @@
+package scan
+
+import (
+	"bytes"
+	"strings"
+)
+
+// inlineIgnoreMarker starts an in-source suppression comment. A comment such
+// as
+//
+//	const key = "AIza..." // jsminer:ignore google_api
+//
+// silences the named rules (or, with no rule named, every rule) on its own
+// line. A directive alone on a comment line silences the line after it
+// instead, so it can sit above the code it covers. Rules are separated by
+// commas or spaces, and anything after "--" is a free-form reason.
+const inlineIgnoreMarker = "jsminer:ignore"
+
+// ApplyInlineIgnores drops the matches that jsminer:ignore comments in data
+// silence, where data is the source the matches were found in, and returns
+// the rest with the number dropped. A match is dropped only when every
+// occurrence of its value in data is silenced for its rule; a value that does
+// not appear verbatim (one reassembled from string fragments, say) cannot be
+// tied to a line and is kept.
+//
+// Only apply this to local code the user controls: honouring directives in
+// fetched content would let a scanned site hide its own secrets.
+func ApplyInlineIgnores(data []byte, ms []Match) ([]Match, int) {
+	if len(ms) == 0 || !bytes.Contains(data, []byte(inlineIgnoreMarker)) {
+		return ms, 0
+	}
+	lineStarts := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	// silenced maps a 0-based line to what is silenced on it.
+	type lineIgnore struct {
+		all   bool
+		rules map[string]bool
+	}
+	silenced := make(map[int]*lineIgnore)
+	for line, start := range lineStarts {
+		end := len(data)
+		if line+1 < len(lineStarts) {
+			end = lineStarts[line+1]
+		}
+		rules, standalone, ok := parseInlineIgnore(string(data[start:end]))
+		if !ok {
+			continue
+		}
+		target := line
+		if standalone {
+			target = line + 1
+		}
+		li := silenced[target]
+		if li == nil {
+			li = &lineIgnore{rules: make(map[string]bool)}
+			silenced[target] = li
+		}
+		li.all = li.all || len(rules) == 0
+		for _, r := range rules {
+			li.rules[r] = true
+		}
+	}
+
+	lineOf := func(off int) int {
+		lo, hi := 0, len(lineStarts)-1
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			if lineStarts[mid] <= off {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		return lo
+	}
+	isSilenced := func(m Match) bool {
+		if m.Value == "" {
+			return false
+		}
+		value := []byte(m.Value)
+		found := false
+		for off := 0; ; {
+			i := bytes.Index(data[off:], value)
+			if i < 0 {
+				break
+			}
+			found = true
+			li, ok := silenced[lineOf(off+i)]
+			if !ok || (!li.all && !li.rules[m.Pattern]) {
+				return false
+			}
+			off += i + len(value)
+		}
+		return found
+	}
+
+	kept := make([]Match, 0, len(ms))
+	for _, m := range ms {
+		if !isSilenced(m) {
+			kept = append(kept, m)
+		}
+	}
+	return kept, len(ms) - len(kept)
+}
+
+// parseInlineIgnore reports whether line carries a jsminer:ignore directive,
+// which rules it names (none means every rule) and whether the directive
+// stands alone on a comment line.
+func parseInlineIgnore(line string) (rules []string, standalone, ok bool) {
+	i := strings.Index(line, inlineIgnoreMarker)
+	if i < 0 {
+		return nil, false, false
+	}
+	rest := line[i+len(inlineIgnoreMarker):]
+	// The marker must end there: jsminer:ignored is not a directive.
+	if rest != "" && !strings.ContainsAny(rest[:1], " \t\r\n,*-") {
+		return nil, false, false
+	}
+	switch strings.TrimSpace(line[:i]) {
+	case "", "//", "#", "/*", "*", "<!--":
+		standalone = true
+	}
+	for _, end := range []string{"--", "*/"} {
+		if j := strings.Index(rest, end); j >= 0 {
+			rest = rest[:j]
+		}
+	}
+	rules = strings.FieldsFunc(rest, func(c rune) bool {
+		return c == ',' || c == ' ' || c == '\t' || c == '\r' || c == '\n'
+	})
+	return rules, standalone, true
+}
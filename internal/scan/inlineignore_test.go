@@ -0,0 +1,43 @@
+package scan
+
+import "testing"
+
+// TestApplyInlineIgnores verifies jsminer:ignore comments silence the named
+// rules on their own line, or on the next line when the comment stands alone,
+// that a bare directive silences every rule, and that a value still present on
+// an unsilenced line is kept.
+func TestApplyInlineIgnores(t *testing.T) {
+	src := []byte(`const a = "AIzaSAME"; // jsminer:ignore google_api
+// jsminer:ignore aws_key, jwt -- rotated in 2025
+const b = "AKIANEXT";
+const c = "AKIAWRONGRULE"; /* jsminer:ignore google_api */
+const d = "eyJBARE"; // jsminer:ignore
+const e = "AIzaTWICE"; // jsminer:ignore google_api
+const f = "AIzaTWICE";
+const g = "tokenignored"; // jsminer:ignored
+`)
+	ms := []Match{
+		{Pattern: "google_api", Value: "AIzaSAME"},
+		{Pattern: "aws_key", Value: "AKIANEXT"},
+		{Pattern: "aws_key", Value: "AKIAWRONGRULE"},
+		{Pattern: "jwt", Value: "eyJBARE"},
+		{Pattern: "google_api", Value: "AIzaTWICE"},
+		{Pattern: "generic", Value: "tokenignored"},
+		{Pattern: "jwt", Value: "eyJ" + "reassembled"},
+	}
+	kept, n := ApplyInlineIgnores(src, ms)
+	want := map[string]bool{"AKIAWRONGRULE": true, "AIzaTWICE": true, "tokenignored": true, "eyJreassembled": true}
+	if n != len(ms)-len(want) || len(kept) != len(want) {
+		t.Fatalf("kept %d (suppressed %d): %+v", len(kept), n, kept)
+	}
+	for _, m := range kept {
+		if !want[m.Value] {
+			t.Errorf("%s (%s) should have been silenced", m.Value, m.Pattern)
+		}
+	}
+
+	// Source without a directive is returned untouched.
+	if kept, n := ApplyInlineIgnores([]byte(`const a = "AIzaSAME";`), ms); n != 0 || len(kept) != len(ms) {
+		t.Errorf("matches dropped without a directive: %+v", kept)
+	}
+}
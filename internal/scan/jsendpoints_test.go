@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"strings"
 	"testing"
 )
@@ -40,7 +41,7 @@ func TestParseJSEndpoints(t *testing.T) {
 func TestScanReaderWithEndpoints(t *testing.T) {
 	js := `fetch("https://ex.com/api"); axios.get('/v2/data');`
 	e := NewExtractor(true, false)
-	matches, err := e.ScanReaderWithEndpoints("script.js", strings.NewReader(js))
+	matches, err := e.ScanReaderWithEndpoints(context.Background(), "script.js", strings.NewReader(js))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -61,7 +62,7 @@ func TestScanReaderWithEndpoints(t *testing.T) {
 func TestScanReaderWithEndpointsNonJS(t *testing.T) {
 	js := `fetch("/should/ignore")`
 	e := NewExtractor(true, false)
-	matches, err := e.ScanReaderWithEndpoints("file.txt", strings.NewReader(js))
+	matches, err := e.ScanReaderWithEndpoints(context.Background(), "file.txt", strings.NewReader(js))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,7 +76,7 @@ func TestScanReaderWithEndpointsNonJS(t *testing.T) {
 func TestScanReaderFiltersInvalidEndpoints(t *testing.T) {
 	js := `fetch("http://a"); fetch('/./'); fetch('//'); fetch('/$'); fetch('https://valid.com/api');`
 	e := NewExtractor(true, false)
-	matches, err := e.ScanReaderWithEndpoints("script.js", strings.NewReader(js))
+	matches, err := e.ScanReaderWithEndpoints(context.Background(), "script.js", strings.NewReader(js))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -44,7 +45,7 @@ func TestParseJSPostRequests(t *testing.T) {
 func TestScanReaderPostRequests(t *testing.T) {
 	js := `fetch("https://ex.com/api", {method:'POST', body:req}); axios.post('/submit', data);`
 	e := NewExtractor(true, false)
-	matches, err := e.ScanReaderPostRequests("script.js", strings.NewReader(js))
+	matches, err := e.ScanReaderPostRequests(context.Background(), "script.js", strings.NewReader(js))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -66,7 +67,7 @@ func TestScanReaderPostRequests(t *testing.T) {
 func TestScanReaderPostRequestsNonJS(t *testing.T) {
 	js := `fetch('/ignore', {method:'POST'})`
 	e := NewExtractor(true, false)
-	matches, err := e.ScanReaderPostRequests("file.txt", strings.NewReader(js))
+	matches, err := e.ScanReaderPostRequests(context.Background(), "file.txt", strings.NewReader(js))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,362 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Library finding patterns. A library match's Value is "name@version".
+const (
+	// LibraryPattern reports a fingerprinted library no known vulnerability
+	// affects.
+	LibraryPattern = "library"
+	// VulnerableLibraryPattern reports a library version the vulnerability
+	// database lists as affected.
+	VulnerableLibraryPattern = "vulnerable_library"
+)
+
+// How a library version was identified, the Detection of a LibraryInfo.
+const (
+	DetectionBanner    = "banner"     // a license or version banner in the script
+	DetectionHash      = "hash"       // the script's SHA-1 is a known release
+	DetectionFilename  = "filename"   // the script's URL names the version
+	DetectionGlobal    = "global"     // a global the rendered page defines
+	DetectionSourceMap = "source_map" // a package path or package.json in a source map
+)
+
+// LibraryInfo describes a fingerprinted library on a library or
+// vulnerable_library match.
+type LibraryInfo struct {
+	Name            string        `json:"name"`
+	Version         string        `json:"version"`
+	Detection       string        `json:"detection"`
+	Vulnerabilities []LibraryVuln `json:"vulnerabilities,omitempty"`
+}
+
+// LibraryVuln is one known vulnerability of a library version.
+type LibraryVuln struct {
+	CVEs       []string `json:"cves,omitempty"`
+	IDs        []string `json:"ids,omitempty"` // advisory IDs other than CVEs: GHSA, OSV, retire.js
+	Severity   string   `json:"severity"`
+	Summary    string   `json:"summary,omitempty"`
+	FixedIn    string   `json:"fixed_in,omitempty"`
+	References []string `json:"references,omitempty"`
+}
+
+// VulnIDs returns one identifier per vulnerability: its CVEs when it has
+// any, else its first advisory ID.
+func (l *LibraryInfo) VulnIDs() []string {
+	if l == nil {
+		return nil
+	}
+	var ids []string
+	for _, v := range l.Vulnerabilities {
+		switch {
+		case len(v.CVEs) > 0:
+			ids = append(ids, v.CVEs...)
+		case len(v.IDs) > 0:
+			ids = append(ids, v.IDs[0])
+		}
+	}
+	return ids
+}
+
+// FixedIn returns the lowest version that fixes every known vulnerability,
+// empty when none lists a fix.
+func (l *LibraryInfo) FixedIn() string {
+	if l == nil {
+		return ""
+	}
+	var fixed string
+	for _, v := range l.Vulnerabilities {
+		if v.FixedIn != "" && (fixed == "" || compareVersions(v.FixedIn, fixed) > 0) {
+			fixed = v.FixedIn
+		}
+	}
+	return fixed
+}
+
+// libraryHit is one library version found by one detection method.
+type libraryHit struct {
+	name, version, detection string
+}
+
+// versionPlaceholder stands for the version in retire.js-style extractor
+// patterns; versionExpr is what it expands to.
+const (
+	versionPlaceholder = "§§version§§"
+	versionExpr        = `[0-9][0-9.a-z_\-]+`
+)
+
+// compileExtractor expands the version placeholder of a retire.js-style
+// pattern and compiles it. The first capture group is the version.
+func compileExtractor(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(strings.ReplaceAll(pattern, versionPlaceholder, versionExpr))
+}
+
+// versionSuffixes are file-name parts a greedy version match takes along, as
+// in jquery-1.12.4.min.js.
+var versionSuffixes = []string{".min", ".slim", ".custom", ".prod", ".bundle", ".js"}
+
+// cleanVersion trims what a greedy version match can take from the text after
+// it: punctuation ("v1.8.3." at the end of a sentence) and file-name suffixes.
+func cleanVersion(v string) string {
+	for {
+		trimmed := strings.TrimRight(v, ".-_")
+		for _, s := range versionSuffixes {
+			trimmed = strings.TrimSuffix(trimmed, s)
+		}
+		if trimmed == v {
+			return v
+		}
+		v = trimmed
+	}
+}
+
+// findVersion returns the version the first matching extractor captures.
+func findVersion(res []*regexp.Regexp, data []byte) string {
+	for _, re := range res {
+		if m := re.FindSubmatch(data); len(m) > 1 {
+			if v := cleanVersion(string(m[1])); v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// fromContent fingerprints a script by its banners and, when the database
+// has release hashes, its SHA-1.
+func (db *LibraryDB) fromContent(data []byte) []libraryHit {
+	var hits []libraryHit
+	var sum string
+	if db.hashed {
+		h := sha1.Sum(data)
+		sum = hex.EncodeToString(h[:])
+	}
+	for _, name := range db.names {
+		lib := db.libs[name]
+		if v, ok := lib.hashes[sum]; ok && sum != "" {
+			hits = append(hits, libraryHit{lib.name, v, DetectionHash})
+			continue
+		}
+		if len(lib.content) == 0 || (lib.hint != "" && !bytes.Contains(data, []byte(lib.hint))) {
+			continue
+		}
+		if v := findVersion(lib.content, data); v != "" {
+			hits = append(hits, libraryHit{lib.name, v, DetectionBanner})
+		}
+	}
+	return hits
+}
+
+// fromURL fingerprints a script by the version its file name or path
+// carries, e.g. /js/jquery-1.8.3.min.js or /ajax/libs/jquery/1.8.3/jquery.js.
+func (db *LibraryDB) fromURL(u string) []libraryHit {
+	path := u
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	file := path[strings.LastIndex(path, "/")+1:]
+	var hits []libraryHit
+	for _, name := range db.names {
+		lib := db.libs[name]
+		if v := findVersion(lib.filename, []byte(file)); v != "" {
+			hits = append(hits, libraryHit{lib.name, v, DetectionFilename})
+		} else if v := findVersion(lib.uri, []byte(path)); v != "" {
+			hits = append(hits, libraryHit{lib.name, v, DetectionFilename})
+		}
+	}
+	return hits
+}
+
+// pnpmPathRe finds the package and version pnpm writes into every dependency
+// path: node_modules/.pnpm/lodash@4.17.15/... or .pnpm/@scope+pkg@1.2.3/...
+var pnpmPathRe = regexp.MustCompile(`node_modules/\.pnpm/((?:@[^/+@]+\+)?[^/+@]+)@([0-9][^/_(]*)`)
+
+// packageJSONPathRe finds a package's own package.json in a source map.
+var packageJSONPathRe = regexp.MustCompile(`node_modules/((?:@[^/]+/)?[^/.][^/]*)/package\.json$`)
+
+// fromSourceMap fingerprints the npm packages a bundle was built from, by
+// the package.json files its source map embeds and the versioned paths pnpm
+// gives dependencies. Only packages the database knows are reported.
+func (db *LibraryDB) fromSourceMap(sm sourceMap) []libraryHit {
+	var hits []libraryHit
+	seen := make(map[string]bool)
+	add := func(name, version string) {
+		name = strings.ToLower(name)
+		version = cleanVersion(version)
+		if version == "" || db.libs[name] == nil || seen[name+"@"+version] {
+			return
+		}
+		seen[name+"@"+version] = true
+		hits = append(hits, libraryHit{name, version, DetectionSourceMap})
+	}
+	for i, src := range sm.Sources {
+		if m := pnpmPathRe.FindStringSubmatch(src); m != nil {
+			add(strings.Replace(m[1], "+", "/", 1), m[2])
+			continue
+		}
+		m := packageJSONPathRe.FindStringSubmatch(src)
+		if m == nil || i >= len(sm.SourcesContent) {
+			continue
+		}
+		var pkg struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if json.Unmarshal([]byte(sm.SourcesContent[i]), &pkg) != nil {
+			continue
+		}
+		if pkg.Name == "" {
+			pkg.Name = m[1]
+		}
+		add(pkg.Name, pkg.Version)
+	}
+	return hits
+}
+
+// libraryMatches turns hits found in source into library and
+// vulnerable_library matches, one per library version.
+func (db *LibraryDB) libraryMatches(source string, hits []libraryHit) []Match {
+	var out []Match
+	seen := make(map[string]bool)
+	for _, h := range hits {
+		key := h.name + "@" + h.version
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		info := &LibraryInfo{Name: h.name, Version: h.version, Detection: h.detection}
+		m := Match{Source: source, Pattern: LibraryPattern, Value: key, Severity: SeverityInfo, Library: info}
+		if vulns := db.Vulnerabilities(h.name, h.version); len(vulns) > 0 {
+			info.Vulnerabilities = vulns
+			m.Pattern = VulnerableLibraryPattern
+			m.Severity = SeverityLow
+			for _, v := range vulns {
+				if SeverityRank(v.Severity) > SeverityRank(m.Severity) {
+					m.Severity = v.Severity
+				}
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// libraries returns the library database of the extractor's client, nil when
+// fingerprinting is off.
+func (e *Extractor) libraries() *LibraryDB {
+	if e.client == nil {
+		return nil
+	}
+	return e.client.cfg.Libraries
+}
+
+// scanLibraries fingerprints one JavaScript source by its URL and content.
+func (e *Extractor) scanLibraries(source string, data []byte) []Match {
+	db := e.libraries()
+	if db == nil {
+		return nil
+	}
+	return db.libraryMatches(source, append(db.fromContent(data), db.fromURL(source)...))
+}
+
+// scanSourceMapLibraries fingerprints the packages bundleURL was built from.
+func (e *Extractor) scanSourceMapLibraries(bundleURL string, sm sourceMap) []Match {
+	db := e.libraries()
+	if db == nil {
+		return nil
+	}
+	return db.libraryMatches(bundleURL, db.fromSourceMap(sm))
+}
+
+// RenderedLibrary is a library version a rendered page's globals reveal.
+type RenderedLibrary struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// globalsScript builds the page script that reads every library's version
+// globals, returning a JSON object of name to version. Each expression is
+// guarded on its own so one library's absence or error does not hide the
+// others.
+func (db *LibraryDB) globalsScript(names []string) string {
+	var b strings.Builder
+	b.WriteString("(() => {\n  const out = {};\n  const ok = v => (typeof v === 'string' || typeof v === 'number') && /^[0-9]/.test(String(v));\n")
+	for _, name := range names {
+		for _, expr := range db.libs[name].globals {
+			fmt.Fprintf(&b, "  if (!(%s in out)) { try { const v = (%s); if (ok(v)) out[%s] = String(v); } catch (e) {} }\n",
+				strconv.Quote(name), expr, strconv.Quote(name))
+		}
+	}
+	b.WriteString("  return JSON.stringify(out);\n})()")
+	return b.String()
+}
+
+// readLibraryGlobals reads the library versions the rendered page's globals
+// expose. The expressions come from the database and may not all parse; when
+// the combined script fails, each library is read on its own. A failed read
+// yields nothing; fingerprinting never fails a render.
+func (c *Client) readLibraryGlobals(ctx context.Context) []RenderedLibrary {
+	db := c.cfg.Libraries
+	if db == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range db.names {
+		if len(db.libs[name].globals) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	found := make(map[string]string)
+	read := func(names []string) error {
+		var raw string
+		if err := chromedp.Run(ctx, chromedp.Evaluate(db.globalsScript(names), &raw)); err != nil {
+			return err
+		}
+		return json.Unmarshal([]byte(raw), &found)
+	}
+	if err := read(names); err != nil {
+		vlog(2, "library globals read failed (%v); reading each library on its own", err)
+		for _, name := range names {
+			if ctx.Err() != nil {
+				break
+			}
+			_ = read([]string{name})
+		}
+	}
+	var out []RenderedLibrary
+	for _, name := range names {
+		if v := cleanVersion(found[name]); v != "" {
+			out = append(out, RenderedLibrary{Name: name, Version: v})
+		}
+	}
+	return out
+}
+
+// renderedLibraryMatches reports the libraries a rendered page's globals
+// revealed, attributed to the page.
+func (e *Extractor) renderedLibraryMatches(pageURL string, libs []RenderedLibrary) []Match {
+	db := e.libraries()
+	if db == nil || len(libs) == 0 {
+		return nil
+	}
+	hits := make([]libraryHit, len(libs))
+	for i, l := range libs {
+		hits[i] = libraryHit{strings.ToLower(l.Name), l.Version, DetectionGlobal}
+	}
+	return db.libraryMatches(pageURL, hits)
+}
@@ -0,0 +1,279 @@
+package scan
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// retireRepo is a retire.js repository in the shape of jsrepository.json.
+const retireRepo = `{
+  "retire-example": {"vulnerabilities": [], "extractors": {"func": ["'dont check'"]}},
+  "jquery": {
+    "npmname": "jquery",
+    "vulnerabilities": [
+      {"below": "1.9.0b1", "severity": "medium", "identifiers": {"CVE": ["CVE-2012-6708"], "summary": "Selector interpreted as HTML"}, "info": ["https://bugs.jquery.com/ticket/11290"]},
+      {"atOrAbove": "1.2", "below": "3.5.0", "severity": "medium", "identifiers": {"CVE": ["CVE-2020-11022"], "githubID": "GHSA-gxr4-xjj5-5px2"}},
+      {"below": "1.6.3", "severity": "high", "identifiers": {"CVE": ["CVE-2011-4969"]}}
+    ],
+    "extractors": {"filecontent": ["/\\*!? jQuery v(§§version§§)", "(?<=x)jQuery (§§version§§)"]}
+  },
+  "mylib": {
+    "vulnerabilities": [{"below": "10.0.0", "severity": "critical", "identifiers": {"summary": "Prototype pollution"}}],
+    "extractors": {"hashes": {"0000": "9.9.9"}}
+  }
+}`
+
+// osvAdvisory is a GitHub advisory as osv.dev exports it.
+const lodashAdvisory = `{
+  "id": "GHSA-35jh-r3h4-6jhm",
+  "aliases": ["CVE-2021-23337"],
+  "summary": "Command Injection in lodash",
+  "affected": [{
+    "package": {"ecosystem": "npm", "name": "lodash"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "4.17.21"}]}]
+  }],
+  "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H"}]
+}`
+
+func TestLibraryDBBannersAndFileNames(t *testing.T) {
+	db := NewLibraryDB()
+	cases := []struct {
+		name, data string
+		want       []libraryHit
+	}{
+		{"jquery banner", "/*! jQuery v1.8.3 jquery.com | jquery.org/license */\n(function(e,t){", []libraryHit{{"jquery", "1.8.3", DetectionBanner}}},
+		{"angular banner", "/*\n AngularJS v1.5.8\n (c) 2010-2016 Google, Inc.", []libraryHit{{"angular", "1.5.8", DetectionBanner}}},
+		{"lodash banner", "/**\n * @license\n * Lodash 4.17.15 <https://lodash.com/>", []libraryHit{{"lodash", "4.17.15", DetectionBanner}}},
+		{"two libraries", "/*! jQuery v3.7.1 | (c) OpenJS */\n/*! @license DOMPurify 2.0.7 | (c) Cure53 */", []libraryHit{{"dompurify", "2.0.7", DetectionBanner}, {"jquery", "3.7.1", DetectionBanner}}},
+		{"no library", "console.log('jQuery is great')", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := db.fromContent([]byte(tc.data)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fromContent = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	urls := map[string]libraryHit{
+		"https://app.test/js/jquery-1.12.4.min.js?v=3":                            {"jquery", "1.12.4", DetectionFilename},
+		"https://cdnjs.cloudflare.com/ajax/libs/lodash.js/4.17.11/lodash.min.js":  {"lodash", "4.17.11", DetectionFilename},
+		"https://app.test/js/jquery-ui-1.12.1.custom.min.js":                      {"jquery-ui", "1.12.1", DetectionFilename},
+		"https://cdn.jsdelivr.net/npm/bootstrap@4.3.1/dist/js/bootstrap-4.3.1.js": {"bootstrap", "4.3.1", DetectionFilename},
+	}
+	for u, want := range urls {
+		if got := db.fromURL(u); len(got) != 1 || got[0] != want {
+			t.Errorf("fromURL(%s) = %v, want %v", u, got, want)
+		}
+	}
+	if got := db.fromURL("https://app.test/static/main.3f2a.js"); got != nil {
+		t.Errorf("fromURL of an application bundle = %v", got)
+	}
+}
+
+func TestLibraryDBLoadsRetireRepository(t *testing.T) {
+	content := "window.mylib=function(){};"
+	sum := sha1.Sum([]byte(content))
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(retireRepo), &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["mylib"].(map[string]any)["extractors"] = map[string]any{"hashes": map[string]any{hex.EncodeToString(sum[:]): "9.9.9"}}
+	data, _ := json.Marshal(doc)
+	db := NewLibraryDB()
+	if err := db.LoadFile(writeRuleFile(t, "jsrepository.json", string(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := db.fromContent([]byte(content)); !reflect.DeepEqual(got, []libraryHit{{"mylib", "9.9.9", DetectionHash}}) {
+		t.Errorf("hash fingerprint = %v", got)
+	}
+
+	ms := db.libraryMatches("https://app.test/js/app.js", []libraryHit{{"jquery", "1.8.3", DetectionBanner}, {"jquery", "1.8.3", DetectionFilename}, {"mylib", "9.9.9", DetectionHash}, {"jquery", "3.5.0", DetectionFilename}})
+	if len(ms) != 3 {
+		t.Fatalf("got %d matches, want one per library version: %+v", len(ms), ms)
+	}
+	jq := ms[0]
+	if jq.Pattern != VulnerableLibraryPattern || jq.Value != "jquery@1.8.3" || jq.Severity != SeverityMedium {
+		t.Errorf("jquery 1.8.3 = %s %s %s", jq.Pattern, jq.Value, jq.Severity)
+	}
+	if ids := jq.Library.VulnIDs(); !reflect.DeepEqual(ids, []string{"CVE-2012-6708", "CVE-2020-11022"}) {
+		t.Errorf("jquery 1.8.3 vulns = %v", ids)
+	}
+	if fixed := jq.Library.FixedIn(); fixed != "3.5.0" {
+		t.Errorf("jquery 1.8.3 fixed in %q, want 3.5.0", fixed)
+	}
+	if v := jq.Library.Vulnerabilities[1]; !reflect.DeepEqual(v.IDs, []string{"GHSA-gxr4-xjj5-5px2"}) {
+		t.Errorf("GitHub advisory id = %v", v.IDs)
+	}
+	if m := ms[1]; m.Pattern != VulnerableLibraryPattern || m.Severity != SeverityHigh || m.Library.Vulnerabilities[0].Summary != "Prototype pollution" {
+		t.Errorf("mylib = %+v %+v", m, m.Library)
+	}
+	if m := ms[2]; m.Pattern != LibraryPattern || m.Severity != SeverityInfo || m.Library.Vulnerabilities != nil {
+		t.Errorf("fixed jquery 3.5.0 = %+v", m)
+	}
+
+	// The lookbehind extractor is dropped; the banner one still applies.
+	if got := db.fromContent([]byte("/*! jQuery v1.4.2 */")); !reflect.DeepEqual(got, []libraryHit{{"jquery", "1.4.2", DetectionBanner}}) {
+		t.Errorf("retire extractor = %v", got)
+	}
+}
+
+func TestLibraryDBLoadsOSVAdvisories(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"GHSA-35jh-r3h4-6jhm.json": lodashAdvisory,
+		"PYSEC-1.json":             `{"id": "PYSEC-1", "affected": [{"package": {"ecosystem": "PyPI", "name": "lodash"}, "versions": ["4.17.20"]}]}`,
+		"list.json": `[{
+	  "id": "GHSA-vvv", "aliases": ["CVE-2099-0001"], "details": "Handlebars template injection.\nMore.",
+	  "affected": [{"package": {"ecosystem": "npm", "name": "handlebars"},
+	    "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.0.12"}, {"introduced": "4.0.0"}, {"last_affected": "4.5.2"}]}]}],
+	  "database_specific": {"severity": "MODERATE"}
+	}]`,
+		"notes.txt": "not an advisory",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := NewLibraryDB()
+	if err := db.LoadFile(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	vulns := db.Vulnerabilities("lodash", "4.17.20")
+	want := []LibraryVuln{{CVEs: []string{"CVE-2021-23337"}, IDs: []string{"GHSA-35jh-r3h4-6jhm"}, Severity: SeverityHigh, Summary: "Command Injection in lodash", FixedIn: "4.17.21"}}
+	if !reflect.DeepEqual(vulns, want) {
+		t.Errorf("lodash 4.17.20 vulns = %+v\nwant %+v", vulns, want)
+	}
+	if v := db.Vulnerabilities("lodash", "4.17.21"); v != nil {
+		t.Errorf("fixed lodash has vulns %+v", v)
+	}
+	for version, affected := range map[string]bool{"0.9": false, "1.0.0": true, "1.0.11": true, "1.0.12": false, "3.0.0": false, "4.0.0": true, "4.5.2": true, "4.5.3": false} {
+		v := db.Vulnerabilities("handlebars", version)
+		if (len(v) > 0) != affected {
+			t.Errorf("handlebars %s affected = %v, want %v", version, len(v) > 0, affected)
+			continue
+		}
+		if affected && (v[0].Severity != SeverityMedium || v[0].Summary != "Handlebars template injection.") {
+			t.Errorf("handlebars %s vuln = %+v", version, v[0])
+		}
+	}
+
+	if err := db.LoadFile(writeRuleFile(t, "bad.json", `{"name": "x"}`)); err == nil {
+		t.Error("loading a JSON file that is neither format succeeded")
+	}
+}
+
+func TestLibraryDBFromSourceMap(t *testing.T) {
+	db := NewLibraryDB()
+	sm := sourceMap{
+		Sources: []string{
+			"webpack:///./node_modules/lodash/package.json",
+			"webpack:///./node_modules/.pnpm/jquery@3.4.1/node_modules/jquery/dist/jquery.js",
+			"webpack:///./node_modules/.pnpm/@scope+widget@1.0.0/node_modules/@scope/widget/index.js",
+			"webpack:///./node_modules/left-pad/package.json",
+			"webpack:///./src/app.js",
+		},
+		SourcesContent: []string{`{"name": "lodash", "version": "4.17.15"}`, "", "", `{"name": "left-pad", "version": "1.3.0"}`, ""},
+	}
+	want := []libraryHit{{"lodash", "4.17.15", DetectionSourceMap}, {"jquery", "3.4.1", DetectionSourceMap}}
+	if got := db.fromSourceMap(sm); !reflect.DeepEqual(got, want) {
+		t.Errorf("fromSourceMap = %v, want %v", got, want)
+	}
+}
+
+// TestScanURLReportsVulnerableLibraries scans a bundle with a jQuery banner
+// and an inline source map naming lodash's package.json, and expects both
+// libraries reported against the bundle.
+func TestScanURLReportsVulnerableLibraries(t *testing.T) {
+	sm, _ := json.Marshal(sourceMap{
+		Version:        3,
+		Sources:        []string{"webpack:///node_modules/lodash/package.json"},
+		SourcesContent: []string{`{"name":"lodash","version":"4.17.15"}`},
+	})
+	bundle := "/*! jQuery v1.8.3 jquery.com | jquery.org/license */\nvar x=1;\n//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(sm)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(w, bundle)
+	}))
+	defer srv.Close()
+
+	db := NewLibraryDB()
+	if err := db.LoadFile(writeRuleFile(t, "lodash.json", lodashAdvisory)); err != nil {
+		t.Fatal(err)
+	}
+	e := NewExtractor(false, false)
+	e.SetClient(testClient(func(c *ClientConfig) { c.Libraries = db }))
+	ms, err := e.ScanURL(context.Background(), srv.URL+"/static/app.js", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Match)
+	for _, m := range ms {
+		if m.Library != nil {
+			got[m.Value] = m
+		}
+	}
+	if m, ok := got["jquery@1.8.3"]; !ok || m.Pattern != LibraryPattern || m.Library.Detection != DetectionBanner || m.Source != srv.URL+"/static/app.js" {
+		t.Errorf("jquery match = %+v (found %v)", m, ok)
+	}
+	if m, ok := got["lodash@4.17.15"]; !ok || m.Pattern != VulnerableLibraryPattern || m.Severity != SeverityHigh || m.Library.Detection != DetectionSourceMap {
+		t.Errorf("lodash match = %+v (found %v)", m, ok)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d library matches, want 2: %v", len(got), got)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.8.3", "1.9.0b1", -1},
+		{"1.10.0", "1.9.9", 1},
+		{"2", "2.0.0", 0},
+		{"v3.5.0", "3.5.0", 0},
+		{"1.0.0-beta.2", "1.0.0", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+	}
+	for _, tc := range cases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := compareVersions(tc.b, tc.a); got != -tc.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tc.b, tc.a, got, -tc.want)
+		}
+	}
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+	cases := map[string]float64{
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H": 9.8,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N": 6.1,
+		"CVSS:3.1/AV:N/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H": 7.2,
+		"CVSS:3.0/AV:L/AC:H/PR:L/UI:R/S:U/C:L/I:N/A:N": 2.2,
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+	}
+	for vector, want := range cases {
+		if got, ok := cvss3BaseScore(vector); !ok || got != want {
+			t.Errorf("cvss3BaseScore(%s) = %v, %v; want %v", vector, got, ok, want)
+		}
+	}
+	if _, ok := cvss3BaseScore("CVSS:3.1/AV:N"); ok {
+		t.Error("incomplete vector scored")
+	}
+}
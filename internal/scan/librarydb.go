@@ -0,0 +1,675 @@
+package scan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LibraryDB identifies JavaScript libraries and their versions and knows
+// which versions are vulnerable. NewLibraryDB holds signatures for common
+// libraries; LoadFile adds a retire.js repository, whose extractors and
+// vulnerabilities both apply, or OSV advisories for npm packages. Libraries
+// are keyed by their npm package name. It is safe for concurrent use once
+// loaded.
+type LibraryDB struct {
+	libs   map[string]*librarySignature
+	names  []string // sorted keys of libs, for a stable match order
+	hashed bool     // some library has release hashes
+}
+
+// librarySignature is what identifies one library and which of its versions
+// are vulnerable.
+type librarySignature struct {
+	name     string
+	hint     string // a literal every content extractor needs, to skip the regexps cheaply
+	content  []*regexp.Regexp
+	filename []*regexp.Regexp
+	uri      []*regexp.Regexp
+	hashes   map[string]string // SHA-1 hex to version
+	globals  []string          // page expressions evaluating to the version
+	vulns    []libraryVulnRange
+}
+
+// libraryVulnRange is a vulnerability and the version ranges it affects.
+type libraryVulnRange struct {
+	ranges   []versionRange
+	versions []string // explicitly listed affected versions (OSV)
+	vuln     LibraryVuln
+}
+
+// versionRange is an affected version interval; empty bounds are open.
+type versionRange struct {
+	atOrAbove string // inclusive lower bound
+	below     string // exclusive upper bound
+	through   string // inclusive upper bound
+}
+
+func (r versionRange) contains(v string) bool {
+	return (r.atOrAbove == "" || compareVersions(v, r.atOrAbove) >= 0) &&
+		(r.below == "" || compareVersions(v, r.below) < 0) &&
+		(r.through == "" || compareVersions(v, r.through) <= 0)
+}
+
+// builtinLibrary is a built-in signature in retire.js pattern syntax.
+type builtinLibrary struct {
+	name              string
+	hint              string
+	content, filename []string
+	uri, globals      []string
+}
+
+// builtinLibraries fingerprint widely deployed libraries from their release
+// banners, conventional file names and globals. They carry no vulnerability
+// data; that comes from the database file.
+var builtinLibraries = []builtinLibrary{
+	{name: "jquery", hint: "jQuery",
+		content:  []string{`/\*!? jQuery v(§§version§§)`, `\* jQuery JavaScript Library v(§§version§§)`},
+		filename: []string{`^jquery-(§§version§§)(\.min|\.slim|\.slim\.min)?\.js$`},
+		uri:      []string{`/jquery/(§§version§§)/jquery(\.min|\.slim|\.slim\.min)?\.js$`},
+		globals:  []string{`window.jQuery && window.jQuery.fn && window.jQuery.fn.jquery`}},
+	{name: "jquery-ui", hint: "jQuery UI",
+		content:  []string{`/\*! jQuery UI - v(§§version§§)`, `\* jQuery UI (?:[A-Z][a-z]+ )?(§§version§§)`},
+		filename: []string{`^jquery-ui-(§§version§§)(\.custom)?(\.min)?\.js$`},
+		uri:      []string{`/jqueryui/(§§version§§)/jquery-ui(\.min)?\.js$`},
+		globals:  []string{`window.jQuery && window.jQuery.ui && window.jQuery.ui.version`}},
+	{name: "jquery-migrate", hint: "jQuery Migrate",
+		content:  []string{`/\*!? jQuery Migrate v(§§version§§)`},
+		filename: []string{`^jquery-migrate-(§§version§§)(\.min)?\.js$`},
+		globals:  []string{`window.jQuery && window.jQuery.migrateVersion`}},
+	{name: "lodash", hint: "odash",
+		content: []string{
+			`@license\s+(?:\*\s+)?(?:Lo-Dash|lodash|Lodash)\s+(§§version§§)`,
+			`var VERSION\s*=\s*['"](§§version§§)['"];\s*(?:/\*\* Used as the size to enable large array optimizations|var LARGE_ARRAY_SIZE)`,
+		},
+		uri:     []string{`/lodash\.js/(§§version§§)/lodash(\.min)?\.js$`},
+		globals: []string{`window._ && window._.runInContext && window._.VERSION`}},
+	{name: "underscore", hint: "Underscore",
+		content: []string{`//\s+Underscore\.js (§§version§§)`},
+		uri:     []string{`/underscore\.js/(§§version§§)/underscore(-min)?\.js$`},
+		globals: []string{`window._ && !window._.runInContext && window._.VERSION`}},
+	{name: "angular", hint: "AngularJS",
+		content: []string{`@license AngularJS v(§§version§§)`, `/\*\s+AngularJS v(§§version§§)`},
+		uri:     []string{`/angular\.js/(§§version§§)/angular(\.min)?\.js$`, `/angularjs/(§§version§§)/angular(\.min)?\.js$`},
+		globals: []string{`window.angular && window.angular.version && window.angular.version.full`}},
+	{name: "react", hint: "React",
+		content: []string{`@license React v(§§version§§)`},
+		uri:     []string{`/react/(§§version§§)/umd/react\.(production\.min|development)\.js$`},
+		globals: []string{`window.React && window.React.version`}},
+	{name: "vue", hint: "Vue.js",
+		content: []string{`/\*!?\s*\*?\s*Vue\.js v(§§version§§)`},
+		uri:     []string{`/vue/(§§version§§)/vue(\.runtime)?(\.global)?(\.prod|\.min)?\.js$`},
+		globals: []string{`window.Vue && window.Vue.version`}},
+	{name: "bootstrap", hint: "Bootstrap",
+		content:  []string{`/\*!?\s*\*?\s*Bootstrap v(§§version§§)`},
+		filename: []string{`^bootstrap-(§§version§§)(\.min)?\.js$`},
+		uri:      []string{`/bootstrap/(§§version§§)/js/bootstrap(\.bundle)?(\.min)?\.js$`},
+		globals: []string{
+			`window.bootstrap && window.bootstrap.Tooltip && window.bootstrap.Tooltip.VERSION`,
+			`window.jQuery && window.jQuery.fn.tooltip && window.jQuery.fn.tooltip.Constructor && window.jQuery.fn.tooltip.Constructor.VERSION`,
+		}},
+	{name: "moment", hint: "moment",
+		content: []string{`//! moment\.js\s*(?://! )?version : (§§version§§)`, `//! version : (§§version§§)\s*//! authors : Tim Wood`},
+		uri:     []string{`/moment\.js/(§§version§§)/moment(\.min)?\.js$`},
+		globals: []string{`window.moment && window.moment.version`}},
+	{name: "handlebars", hint: "andlebars",
+		content: []string{`(?i)handlebars v(§§version§§)`},
+		uri:     []string{`/handlebars\.js/(§§version§§)/handlebars(\.runtime)?(\.min)?\.js$`},
+		globals: []string{`window.Handlebars && window.Handlebars.VERSION`}},
+	{name: "dompurify", hint: "DOMPurify",
+		content: []string{`/\*! @license DOMPurify (§§version§§)`},
+		uri:     []string{`/dompurify/(§§version§§)/purify(\.min)?\.js$`},
+		globals: []string{`window.DOMPurify && window.DOMPurify.version`}},
+	{name: "knockout", hint: "Knockout",
+		content: []string{`Knockout JavaScript library v(§§version§§)`},
+		globals: []string{`window.ko && window.ko.version`}},
+	{name: "backbone", hint: "Backbone",
+		content: []string{`//\s+Backbone\.js (§§version§§)`},
+		globals: []string{`window.Backbone && window.Backbone.VERSION`}},
+	{name: "axios", hint: "Axios",
+		content: []string{`/\*!? Axios v(§§version§§)`},
+		globals: []string{`window.axios && window.axios.VERSION`}},
+}
+
+// NewLibraryDB returns a database with the built-in signatures and no
+// vulnerability data.
+func NewLibraryDB() *LibraryDB {
+	db := &LibraryDB{libs: make(map[string]*librarySignature)}
+	for _, b := range builtinLibraries {
+		lib := db.library(b.name)
+		lib.hint = b.hint
+		for _, list := range []struct {
+			patterns []string
+			into     *[]*regexp.Regexp
+		}{{b.content, &lib.content}, {b.filename, &lib.filename}, {b.uri, &lib.uri}} {
+			for _, p := range list.patterns {
+				*list.into = append(*list.into, regexp.MustCompile(strings.ReplaceAll(p, versionPlaceholder, versionExpr)))
+			}
+		}
+		lib.globals = append(lib.globals, b.globals...)
+	}
+	return db
+}
+
+// library returns the signature of name, creating it.
+func (db *LibraryDB) library(name string) *librarySignature {
+	name = strings.ToLower(strings.TrimSpace(name))
+	lib := db.libs[name]
+	if lib == nil {
+		lib = &librarySignature{name: name}
+		db.libs[name] = lib
+		i := sort.SearchStrings(db.names, name)
+		db.names = append(db.names, "")
+		copy(db.names[i+1:], db.names[i:])
+		db.names[i] = name
+	}
+	return lib
+}
+
+// Len returns the number of libraries the database knows.
+func (db *LibraryDB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.libs)
+}
+
+// LoadFile adds a vulnerability database: a retire.js repository
+// (jsrepository.json), a single OSV advisory, a JSON array of them, or a
+// directory of OSV advisory files such as an unpacked osv.dev npm export. OSV
+// advisories for ecosystems other than npm are ignored.
+func (db *LibraryDB) LoadFile(file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return filepath.WalkDir(file, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+				return err
+			}
+			return db.LoadFile(path)
+		})
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := db.load(data); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// load adds one JSON vulnerability document, telling the formats apart by
+// shape.
+func (db *LibraryDB) load(data []byte) error {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	switch t := doc.(type) {
+	case []any:
+		for i, raw := range t {
+			if err := db.loadOSVValue(raw); err != nil {
+				return fmt.Errorf("advisory %d: %w", i+1, err)
+			}
+		}
+		return nil
+	case map[string]any:
+		if _, ok := t["affected"]; ok {
+			return db.loadOSVValue(t)
+		}
+		if _, ok := t["id"]; ok {
+			return db.loadOSVValue(t)
+		}
+		return db.loadRetire(data)
+	}
+	return errors.New("want a retire.js repository or OSV advisories")
+}
+
+// retireLibrary is one library of a retire.js repository.
+type retireLibrary struct {
+	NPMName         string `json:"npmname"`
+	Vulnerabilities []struct {
+		AtOrAbove   string   `json:"atOrAbove"`
+		Below       string   `json:"below"`
+		Severity    string   `json:"severity"`
+		Info        []string `json:"info"`
+		Identifiers struct {
+			CVE      []string `json:"CVE"`
+			GithubID string   `json:"githubID"`
+			Summary  string   `json:"summary"`
+			Issue    string   `json:"issue"`
+			Bug      string   `json:"bug"`
+		} `json:"identifiers"`
+	} `json:"vulnerabilities"`
+	Extractors struct {
+		Func        []string          `json:"func"`
+		URI         []string          `json:"uri"`
+		Filename    []string          `json:"filename"`
+		FileContent []string          `json:"filecontent"`
+		Hashes      map[string]string `json:"hashes"`
+	} `json:"extractors"`
+}
+
+// loadRetire adds a retire.js repository: library name to extractors and
+// vulnerabilities.
+func (db *LibraryDB) loadRetire(data []byte) error {
+	var repo map[string]json.RawMessage
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(repo))
+	for k := range repo {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	loaded := 0
+	for _, key := range keys {
+		var rl retireLibrary
+		// Non-library members (retire's "dont check" list and the like) do
+		// not decode as one and are skipped.
+		if json.Unmarshal(repo[key], &rl) != nil || (len(rl.Vulnerabilities) == 0 && rl.Extractors.FileContent == nil &&
+			rl.Extractors.Filename == nil && rl.Extractors.URI == nil && rl.Extractors.Func == nil && rl.Extractors.Hashes == nil) {
+			continue
+		}
+		loaded++
+		lib := db.library(cmpOr(rl.NPMName, key))
+		for _, list := range []struct {
+			patterns []string
+			into     *[]*regexp.Regexp
+		}{{rl.Extractors.FileContent, &lib.content}, {rl.Extractors.Filename, &lib.filename}, {rl.Extractors.URI, &lib.uri}} {
+			for _, p := range list.patterns {
+				re, err := compileExtractor(p)
+				if err != nil || re.NumSubexp() < 1 {
+					// retire.js writes JavaScript regexps; the few using
+					// lookarounds have no RE2 equivalent and are left out.
+					continue
+				}
+				*list.into = append(*list.into, re)
+			}
+		}
+		if len(rl.Extractors.FileContent) > 0 {
+			// The built-in hint no longer covers every content pattern.
+			lib.hint = ""
+		}
+		lib.globals = append(lib.globals, rl.Extractors.Func...)
+		for hash, version := range rl.Extractors.Hashes {
+			if lib.hashes == nil {
+				lib.hashes = make(map[string]string)
+			}
+			lib.hashes[strings.ToLower(hash)] = version
+			db.hashed = true
+		}
+		for _, v := range rl.Vulnerabilities {
+			vr := libraryVulnRange{
+				ranges: []versionRange{{atOrAbove: v.AtOrAbove, below: v.Below}},
+				vuln: LibraryVuln{
+					CVEs: v.Identifiers.CVE, Severity: normalizeVulnSeverity(v.Severity),
+					Summary: strings.TrimSpace(v.Identifiers.Summary), FixedIn: v.Below, References: v.Info,
+				},
+			}
+			if v.Identifiers.GithubID != "" {
+				vr.vuln.IDs = append(vr.vuln.IDs, v.Identifiers.GithubID)
+			}
+			if vr.vuln.Summary == "" {
+				vr.vuln.Summary = cmpOr(v.Identifiers.Issue, v.Identifiers.Bug)
+			}
+			lib.vulns = append(lib.vulns, vr)
+		}
+	}
+	if loaded == 0 {
+		return errors.New("no libraries found; want a retire.js repository or OSV advisories")
+	}
+	return nil
+}
+
+// osvAdvisory is the part of an OSV advisory the database uses.
+type osvAdvisory struct {
+	ID        string   `json:"id"`
+	Aliases   []string `json:"aliases"`
+	Summary   string   `json:"summary"`
+	Details   string   `json:"details"`
+	Withdrawn string   `json:"withdrawn"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string              `json:"type"`
+			Events []map[string]string `json:"events"`
+		} `json:"ranges"`
+		Versions         []string       `json:"versions"`
+		DatabaseSpecific map[string]any `json:"database_specific"`
+	} `json:"affected"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+	DatabaseSpecific map[string]any `json:"database_specific"`
+}
+
+// maxVulnReferences bounds the links kept per vulnerability.
+const maxVulnReferences = 5
+
+func (db *LibraryDB) loadOSVValue(raw any) error {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	var adv osvAdvisory
+	if err := json.Unmarshal(data, &adv); err != nil {
+		return err
+	}
+	if adv.ID == "" {
+		return errors.New("OSV advisory without an id")
+	}
+	if adv.Withdrawn != "" {
+		return nil
+	}
+	vuln := LibraryVuln{Summary: strings.TrimSpace(adv.Summary), Severity: osvSeverity(adv)}
+	if vuln.Summary == "" {
+		vuln.Summary, _, _ = strings.Cut(strings.TrimSpace(adv.Details), "\n")
+	}
+	for _, id := range append([]string{adv.ID}, adv.Aliases...) {
+		if strings.HasPrefix(id, "CVE-") {
+			if !containsString(vuln.CVEs, id) {
+				vuln.CVEs = append(vuln.CVEs, id)
+			}
+		} else if !containsString(vuln.IDs, id) {
+			vuln.IDs = append(vuln.IDs, id)
+		}
+	}
+	for _, r := range adv.References {
+		if len(vuln.References) == maxVulnReferences {
+			break
+		}
+		vuln.References = append(vuln.References, r.URL)
+	}
+	for _, aff := range adv.Affected {
+		if !strings.EqualFold(aff.Package.Ecosystem, "npm") || aff.Package.Name == "" {
+			continue
+		}
+		vr := libraryVulnRange{versions: aff.Versions, vuln: vuln}
+		for _, rg := range aff.Ranges {
+			if rg.Type != "SEMVER" && rg.Type != "ECOSYSTEM" {
+				continue
+			}
+			ranges, fixed := osvRanges(rg.Events)
+			vr.ranges = append(vr.ranges, ranges...)
+			if fixed != "" {
+				vr.vuln.FixedIn = fixed
+			}
+		}
+		if len(vr.ranges) == 0 && len(vr.versions) == 0 {
+			continue
+		}
+		lib := db.library(aff.Package.Name)
+		lib.vulns = append(lib.vulns, vr)
+	}
+	return nil
+}
+
+// osvRanges turns a range's events into affected intervals: each
+// "introduced" opens one, closed by the next "fixed" (exclusive) or
+// "last_affected" (inclusive). fixed is the highest fixing version.
+func osvRanges(events []map[string]string) (ranges []versionRange, fixed string) {
+	var open *versionRange
+	for _, ev := range events {
+		switch {
+		case ev["introduced"] != "":
+			if open != nil {
+				ranges = append(ranges, *open)
+			}
+			open = &versionRange{}
+			if v := ev["introduced"]; v != "0" {
+				open.atOrAbove = v
+			}
+		case ev["fixed"] != "" && open != nil:
+			open.below = ev["fixed"]
+			fixed = ev["fixed"]
+			ranges = append(ranges, *open)
+			open = nil
+		case ev["last_affected"] != "" && open != nil:
+			open.through = ev["last_affected"]
+			ranges = append(ranges, *open)
+			open = nil
+		case ev["limit"] != "" && open != nil:
+			open.below = ev["limit"]
+			ranges = append(ranges, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		ranges = append(ranges, *open)
+	}
+	return ranges, fixed
+}
+
+// osvSeverity rates an advisory from its database severity (GitHub's LOW,
+// MODERATE, HIGH, CRITICAL) or, failing that, its CVSS v3 vector.
+func osvSeverity(adv osvAdvisory) string {
+	if s, ok := adv.DatabaseSpecific["severity"].(string); ok && s != "" {
+		return normalizeVulnSeverity(s)
+	}
+	for _, aff := range adv.Affected {
+		if s, ok := aff.DatabaseSpecific["severity"].(string); ok && s != "" {
+			return normalizeVulnSeverity(s)
+		}
+	}
+	for _, s := range adv.Severity {
+		if strings.HasPrefix(s.Type, "CVSS_V3") {
+			if score, ok := cvss3BaseScore(s.Score); ok {
+				return cvssSeverity(score)
+			}
+		}
+	}
+	return SeverityMedium
+}
+
+// normalizeVulnSeverity maps a database's severity word onto JSMiner's
+// levels; critical folds into high.
+func normalizeVulnSeverity(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "critical", "high":
+		return SeverityHigh
+	case "low":
+		return SeverityLow
+	case "none", "info":
+		return SeverityInfo
+	}
+	return SeverityMedium
+}
+
+// cvssSeverity rates a CVSS base score.
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityInfo
+}
+
+// cvss3Weights are the CVSS v3 base metric weights. Privileges Required
+// weighs more when the scope changes, which cvss3BaseScore applies.
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.x vector such as
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:U/C:L/I:L/A:N.
+func cvss3BaseScore(vector string) (float64, bool) {
+	metrics := make(map[string]string)
+	for _, part := range strings.Split(vector, "/") {
+		if k, v, ok := strings.Cut(part, ":"); ok {
+			metrics[k] = v
+		}
+	}
+	w := make(map[string]float64)
+	for k, values := range cvss3Weights {
+		v, ok := values[metrics[k]]
+		if !ok {
+			return 0, false
+		}
+		w[k] = v
+	}
+	changed := metrics["S"] == "C"
+	if !changed && metrics["S"] != "U" {
+		return 0, false
+	}
+	if changed {
+		switch metrics["PR"] {
+		case "L":
+			w["PR"] = 0.68
+		case "H":
+			w["PR"] = 0.5
+		}
+	}
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	score := impact + exploitability
+	if changed {
+		score *= 1.08
+	}
+	return cvssRoundUp(math.Min(score, 10)), true
+}
+
+// cvssRoundUp rounds up to one decimal as the CVSS v3.1 specification does,
+// avoiding floating-point artefacts.
+func cvssRoundUp(x float64) float64 {
+	i := int64(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
+
+// Vulnerabilities returns the known vulnerabilities of a library version,
+// one per advisory even when several sources list it.
+func (db *LibraryDB) Vulnerabilities(name, version string) []LibraryVuln {
+	if db == nil {
+		return nil
+	}
+	lib := db.libs[strings.ToLower(name)]
+	if lib == nil {
+		return nil
+	}
+	var out []LibraryVuln
+	seen := make(map[string]bool)
+	for _, vr := range lib.vulns {
+		if !vr.affects(version) {
+			continue
+		}
+		ids := append(append([]string(nil), vr.vuln.CVEs...), vr.vuln.IDs...)
+		dup := len(ids) > 0
+		for _, id := range ids {
+			dup = dup && seen[id]
+		}
+		if dup {
+			continue
+		}
+		for _, id := range ids {
+			seen[id] = true
+		}
+		out = append(out, vr.vuln)
+	}
+	return out
+}
+
+func (vr libraryVulnRange) affects(version string) bool {
+	if containsString(vr.versions, version) {
+		return true
+	}
+	for _, r := range vr.ranges {
+		if r.contains(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions orders two library versions the way retire.js does: the
+// dot- and dash-separated parts compare numerically when both are numbers
+// and as text otherwise, a missing part counts as 0, and a textual part
+// (a pre-release such as "beta") sorts below a numeric one, so 1.0.0-beta
+// comes before 1.0.0.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(strings.TrimSpace(v), "v"), func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errx := strconv.ParseUint(x, 10, 64)
+		ny, erry := strconv.ParseUint(y, 10, 64)
+		switch {
+		case errx == nil && erry == nil:
+			if nx != ny {
+				if nx < ny {
+					return -1
+				}
+				return 1
+			}
+		case errx == nil:
+			return 1
+		case erry == nil:
+			return -1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func cmpOr(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -75,7 +76,7 @@ func TestScanURLCrawlFollowsLinkHeader(t *testing.T) {
 
 	e := NewExtractor(false, false)
 	opts := CrawlOptions{MaxDepth: 3, MaxPages: 20, SameScopeOnly: true}
-	ms, err := e.ScanURLCrawl(ts.URL, false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
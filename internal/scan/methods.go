@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"sort"
 	"strings"
@@ -108,7 +109,7 @@ type methodProbeBaseline struct {
 	body   []byte
 }
 
-func probeURLMethodsWithBaseline(cal *autoCalibrator, pageURL string, methods []string, body string, baseline *methodProbeBaseline) []string {
+func probeURLMethodsWithBaseline(ctx context.Context, client *Client, cal *autoCalibrator, pageURL string, methods []string, body string, baseline *methodProbeBaseline) []string {
 	probes := make([]probeResult, 0, len(methods))
 	for _, m := range methods {
 		reqBody := ""
@@ -123,7 +124,7 @@ func probeURLMethodsWithBaseline(cal *autoCalibrator, pageURL string, methods []
 			})
 			continue
 		}
-		resp, err := fetchURLResponseMethodSameScope(pageURL, m, reqBody)
+		resp, err := client.fetchURLResponseMethodSameScope(ctx, pageURL, m, reqBody)
 		if err != nil {
 			continue
 		}
@@ -167,8 +168,8 @@ func probeURLMethodsWithBaseline(cal *autoCalibrator, pageURL string, methods []
 	return worked
 }
 
-func probeURLMethods(cal *autoCalibrator, pageURL string, methods []string, body string) []string {
-	return probeURLMethodsWithBaseline(cal, pageURL, methods, body, nil)
+func probeURLMethods(ctx context.Context, client *Client, cal *autoCalibrator, pageURL string, methods []string, body string) []string {
+	return probeURLMethodsWithBaseline(ctx, client, cal, pageURL, methods, body, nil)
 }
 
 // paramReplayChanged reports whether sending the parameter body produced a
@@ -199,7 +200,7 @@ func paramReplayChanged(paramStatus int, paramBody []byte, baseStatus int, baseB
 // The baseline request is issued only after the parameter response already looks
 // like a hit, so an endpoint that rejects the verb outright costs a single probe,
 // not two.
-func probeParamReplayMethods(cal *autoCalibrator, pageURL string, methods []string, body string) []string {
+func probeParamReplayMethods(ctx context.Context, client *Client, cal *autoCalibrator, pageURL string, methods []string, body string) []string {
 	if strings.TrimSpace(body) == "" {
 		return nil
 	}
@@ -208,7 +209,7 @@ func probeParamReplayMethods(cal *autoCalibrator, pageURL string, methods []stri
 		if !bodyMethods[m] {
 			continue
 		}
-		presp, err := fetchURLResponseMethodSameScope(pageURL, m, body)
+		presp, err := client.fetchURLResponseMethodSameScope(ctx, pageURL, m, body)
 		if err != nil {
 			continue
 		}
@@ -217,7 +218,7 @@ func probeParamReplayMethods(cal *autoCalibrator, pageURL string, methods []stri
 		if !methodWorks(cal, m, pageURL, presp.StatusCode, pdata) {
 			continue
 		}
-		bresp, err := fetchURLResponseMethodSameScope(pageURL, m, "")
+		bresp, err := client.fetchURLResponseMethodSameScope(ctx, pageURL, m, "")
 		if err != nil {
 			continue
 		}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -60,7 +61,7 @@ func TestProbeURLMethodsReusesFetchedGETBaseline(t *testing.T) {
 		status: http.StatusOK,
 		body:   []byte("the same stable response body for every request method"),
 	}
-	got := probeURLMethodsWithBaseline(nil, srv.URL, []string{"GET", "POST"}, "", baseline)
+	got := probeURLMethodsWithBaseline(context.Background(), testClient(), nil, srv.URL, []string{"GET", "POST"}, "", baseline)
 	if strings.Join(got, ",") != "GET" {
 		t.Fatalf("worked methods = %v, want GET", got)
 	}
@@ -106,7 +107,7 @@ func TestScanURLCrawlGathersMethods(t *testing.T) {
 	opts := DefaultCrawlOptions()
 	opts.AutoCalibrate = false // isolate method probing from page suppression
 	opts.ParamReplay = false
-	ms, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,7 +153,7 @@ func TestScanURLCrawlMethodCatchAll(t *testing.T) {
 	opts := DefaultCrawlOptions()
 	opts.AutoCalibrate = false
 	opts.ParamReplay = false
-	ms, err := e.ScanURLCrawl(ts.URL+"/", false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -193,12 +194,12 @@ func TestProbeURLMethodsRejectsShapeSpecificSoft404(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	cal := newAutoCalibrator()
+	cal := newAutoCalibrator(context.Background(), testClient())
 	cal.setBase(srv.URL)
-	if got := probeURLMethods(cal, srv.URL+"/new/firebase-messaging-sw.js", []string{"GET"}, ""); len(got) != 0 {
+	if got := probeURLMethods(context.Background(), testClient(), cal, srv.URL+"/new/firebase-messaging-sw.js", []string{"GET"}, ""); len(got) != 0 {
 		t.Fatalf("shape-specific soft-404 reported as gathered methods: %v", got)
 	}
-	if got := probeURLMethods(cal, srv.URL+"/new/real.js", []string{"GET"}, ""); !equalStrings(got, []string{"GET"}) {
+	if got := probeURLMethods(context.Background(), testClient(), cal, srv.URL+"/new/real.js", []string{"GET"}, ""); !equalStrings(got, []string{"GET"}) {
 		t.Fatalf("real bundle sharing the calibrated .js level was suppressed: %v", got)
 	}
 }
@@ -228,10 +229,10 @@ func TestProbeURLMethodsCollapsesMethodAgnostic(t *testing.T) {
 
 	methods := defaultRequestMethods()
 
-	if got := probeURLMethods(nil, ts.URL+"/asset.js", methods, ""); !equalStrings(got, []string{"GET"}) {
+	if got := probeURLMethods(context.Background(), testClient(), nil, ts.URL+"/asset.js", methods, ""); !equalStrings(got, []string{"GET"}) {
 		t.Fatalf("method-agnostic resource should collapse to GET only, got %v", got)
 	}
-	if got := probeURLMethods(nil, ts.URL+"/api/create", methods, ""); !equalStrings(got, []string{"POST"}) {
+	if got := probeURLMethods(context.Background(), testClient(), nil, ts.URL+"/api/create", methods, ""); !equalStrings(got, []string{"POST"}) {
 		t.Fatalf("POST-only endpoint should report POST only, got %v", got)
 	}
 }
@@ -277,16 +278,16 @@ func TestProbeParamReplayMethodsRequiresEffect(t *testing.T) {
 	methods := defaultRequestMethods()
 	body := "username=testuser&password=Password123!"
 
-	got := probeParamReplayMethods(nil, ts.URL+"/consumes", methods, body)
+	got := probeParamReplayMethods(context.Background(), testClient(), nil, ts.URL+"/consumes", methods, body)
 	if !equalStrings(got, []string{"POST", "PUT", "PATCH"}) {
 		t.Fatalf("endpoint that consumes the body should report only body verbs, got %v", got)
 	}
 
-	if got := probeParamReplayMethods(nil, ts.URL+"/ignores", methods, body); len(got) != 0 {
+	if got := probeParamReplayMethods(context.Background(), testClient(), nil, ts.URL+"/ignores", methods, body); len(got) != 0 {
 		t.Fatalf("endpoint that ignores the body should report nothing, got %v", got)
 	}
 
-	if got := probeParamReplayMethods(nil, ts.URL+"/consumes", methods, ""); got != nil {
+	if got := probeParamReplayMethods(context.Background(), testClient(), nil, ts.URL+"/consumes", methods, ""); got != nil {
 		t.Fatalf("empty params must not probe at all, got %v", got)
 	}
 }
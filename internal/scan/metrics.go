@@ -0,0 +1,370 @@
+package scan
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/metrics"
+)
+
+// statusFrontierPreview is how many of the next queued targets a crawl's
+// status lists.
+const statusFrontierPreview = 20
+
+// statusFrontierInterval bounds how often a crawl re-derives that preview: it
+// sorts the frontier, which a busy coordinator should not do per page.
+const statusFrontierInterval = time.Second
+
+// Metrics is the live telemetry of a long-running scan: the crawl's pages and
+// queue, each host's throttle, render durations, the DOM scan's probe budget
+// and the matches found, as Prometheus metrics, plus a Status view of the
+// crawls in progress. Set it as ClientConfig.Metrics; every method is safe
+// for concurrent use and does nothing on a nil Metrics.
+type Metrics struct {
+	started time.Time
+
+	pagesFetched *metrics.Counter
+	pagesErrored *metrics.Counter
+	requests     *metrics.CounterVec
+	backoffs     *metrics.CounterVec
+	throttleWait *metrics.HistogramVec
+	renders      *metrics.HistogramVec
+	domProbes    *metrics.Gauge
+	domBudget    *metrics.Gauge
+	matches      *metrics.CounterVec
+	proxyScanned *metrics.Counter
+
+	mu        sync.Mutex
+	crawls    []*crawlProgress
+	throttles []*requestThrottle
+}
+
+// NewMetrics registers JSMiner's metrics, all prefixed jsminer_, on reg.
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	m := &Metrics{
+		started:      time.Now(),
+		pagesFetched: reg.NewCounter("jsminer_crawl_pages_fetched_total", "Crawl pages fetched and scanned.").With(),
+		pagesErrored: reg.NewCounter("jsminer_crawl_pages_errored_total", "Crawl pages that failed to fetch or scan.").With(),
+		requests:     reg.NewCounter("jsminer_http_requests_total", "HTTP requests sent, by method and status code (\"error\" for transport failures).", "method", "code"),
+		backoffs:     reg.NewCounter("jsminer_throttle_backoffs_total", "Times a host's request gap was widened after a 429 or 503.", "host"),
+		throttleWait: reg.NewHistogram("jsminer_throttle_wait_seconds", "Time requests waited for the per-host throttle.", metrics.DefaultDurationBuckets, "host"),
+		renders:      reg.NewHistogram("jsminer_render_duration_seconds", "Headless-Chrome page render durations, by outcome.", metrics.DefaultDurationBuckets, "outcome"),
+		domProbes:    reg.NewGauge("jsminer_dom_probes_used", "Canary probes the DOM scan has sent.").With(),
+		domBudget:    reg.NewGauge("jsminer_dom_probes_budget", "The DOM scan's probe budget (0 = unlimited).").With(),
+		matches:      reg.NewCounter("jsminer_matches_total", "Distinct matches found, by severity.", "severity"),
+		proxyScanned: reg.NewCounter("jsminer_proxy_responses_scanned_total", "Responses scanned in proxy mode.").With(),
+	}
+	reg.NewGaugeFunc("jsminer_crawl_queue_depth", "Targets queued in running crawls, by seed.", []string{"seed"}, func() []metrics.Sample {
+		var out []metrics.Sample
+		for _, c := range m.Status().Crawls {
+			if c.Finished == nil {
+				out = append(out, metrics.Sample{Labels: []string{c.Seed}, Value: float64(c.QueueDepth)})
+			}
+		}
+		return out
+	})
+	reg.NewGaugeFunc("jsminer_crawl_workers_busy", "Crawl workers fetching or scanning a page, by seed.", []string{"seed"}, func() []metrics.Sample {
+		var out []metrics.Sample
+		for _, c := range m.Status().Crawls {
+			if c.Finished == nil {
+				out = append(out, metrics.Sample{Labels: []string{c.Seed}, Value: float64(len(c.Workers))})
+			}
+		}
+		return out
+	})
+	reg.NewGaugeFunc("jsminer_throttle_gap_seconds", "Each host's current gap between requests, adaptive backoff included.", []string{"host"}, func() []metrics.Sample {
+		var out []metrics.Sample
+		for _, h := range m.Status().Hosts {
+			out = append(out, metrics.Sample{Labels: []string{h.Host}, Value: h.GapSeconds})
+		}
+		return out
+	})
+	return m
+}
+
+// ObserveMatches counts matches by severity. Callers pass each distinct
+// match once.
+func (m *Metrics) ObserveMatches(ms ...Match) {
+	if m == nil {
+		return
+	}
+	for _, match := range ms {
+		sev := strings.ToLower(match.Severity)
+		if sev == "" {
+			sev = SeverityInfo
+		}
+		m.matches.With(sev).Inc()
+	}
+}
+
+// ProxyResponseScanned counts one response scanned in proxy mode.
+func (m *Metrics) ProxyResponseScanned() {
+	if m == nil {
+		return
+	}
+	m.proxyScanned.Inc()
+}
+
+func (m *Metrics) observeRequest(method string, status int, err error) {
+	if m == nil {
+		return
+	}
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(status)
+	}
+	m.requests.With(strings.ToUpper(method), code).Inc()
+}
+
+func (m *Metrics) observeRender(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	m.renders.With(outcome).Observe(time.Since(start).Seconds())
+}
+
+func (m *Metrics) observeDOMProbes(used, budget int) {
+	if m == nil {
+		return
+	}
+	m.domProbes.Set(float64(used))
+	m.domBudget.Set(float64(budget))
+}
+
+// watchThrottle reports t's per-host gaps, waits and backoffs.
+func (m *Metrics) watchThrottle(t *requestThrottle) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.throttles = append(m.throttles, t)
+	m.mu.Unlock()
+	t.mu.Lock()
+	t.onWait = func(host string, d time.Duration) { m.throttleWait.With(host).Observe(d.Seconds()) }
+	t.onBackoff = func(host string) { m.backoffs.With(host).Inc() }
+	t.mu.Unlock()
+}
+
+// Status is a point-in-time view of a scan in progress, served as JSON by
+// -metrics-addr's /status.
+type Status struct {
+	Started time.Time     `json:"started"`
+	Uptime  string        `json:"uptime"`
+	Crawls  []CrawlStatus `json:"crawls"`
+	Hosts   []HostStatus  `json:"hosts"`
+}
+
+// CrawlStatus is one crawl's progress: its counters, the head of its
+// frontier and what each busy worker is doing.
+type CrawlStatus struct {
+	Seed         string           `json:"seed"`
+	Started      time.Time        `json:"started"`
+	Finished     *time.Time       `json:"finished,omitempty"`
+	PagesFetched int              `json:"pages_fetched"`
+	PagesErrored int              `json:"pages_errored"`
+	Matches      int              `json:"matches"`
+	QueueDepth   int              `json:"queue_depth"`
+	Next         []StatusTarget   `json:"next"`
+	Workers      []StatusInFlight `json:"workers"`
+}
+
+// StatusTarget is a queued crawl target.
+type StatusTarget struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// StatusInFlight is a page a crawl worker is fetching or scanning.
+type StatusInFlight struct {
+	URL     string `json:"url"`
+	Depth   int    `json:"depth"`
+	Running string `json:"running"`
+}
+
+// HostStatus is a host's throttle state.
+type HostStatus struct {
+	Host       string  `json:"host"`
+	GapSeconds float64 `json:"gap_seconds"`
+}
+
+// Status returns the current view. Finished crawls stay listed so a run over
+// several targets shows what it has done.
+func (m *Metrics) Status() Status {
+	if m == nil {
+		return Status{}
+	}
+	m.mu.Lock()
+	crawls := append([]*crawlProgress(nil), m.crawls...)
+	throttles := append([]*requestThrottle(nil), m.throttles...)
+	m.mu.Unlock()
+
+	now := time.Now()
+	st := Status{Started: m.started, Uptime: now.Sub(m.started).Round(time.Second).String(), Crawls: []CrawlStatus{}, Hosts: []HostStatus{}}
+	for _, c := range crawls {
+		st.Crawls = append(st.Crawls, c.status(now))
+	}
+	gaps := make(map[string]time.Duration)
+	for _, t := range throttles {
+		t.mu.Lock()
+		for h, hs := range t.hosts {
+			if h != "" && hs.curGap > gaps[h] {
+				gaps[h] = hs.curGap
+			}
+		}
+		t.mu.Unlock()
+	}
+	for h, g := range gaps {
+		st.Hosts = append(st.Hosts, HostStatus{Host: h, GapSeconds: g.Seconds()})
+	}
+	sort.Slice(st.Hosts, func(i, j int) bool { return st.Hosts[i].Host < st.Hosts[j].Host })
+	return st
+}
+
+// crawlProgress is the live state of one crawl. The crawl's coordinator
+// updates it; Status reads it from the metrics server's goroutines.
+type crawlProgress struct {
+	m    *Metrics
+	seed string
+
+	mu          sync.Mutex
+	started     time.Time
+	finished    *time.Time
+	fetched     int
+	errored     int
+	matches     int
+	queued      int
+	next        []StatusTarget
+	nextAt      time.Time
+	inFlight    map[string]crawlInFlight
+	seenMatches map[string]struct{}
+}
+
+type crawlInFlight struct {
+	depth int
+	since time.Time
+}
+
+func (m *Metrics) startCrawl(seed string) *crawlProgress {
+	if m == nil {
+		return nil
+	}
+	p := &crawlProgress{
+		m: m, seed: seed, started: time.Now(),
+		inFlight: make(map[string]crawlInFlight), seenMatches: make(map[string]struct{}),
+	}
+	m.mu.Lock()
+	m.crawls = append(m.crawls, p)
+	m.mu.Unlock()
+	return p
+}
+
+func (p *crawlProgress) dispatch(t crawlTarget) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.inFlight[t.url] = crawlInFlight{depth: t.depth, since: time.Now()}
+	p.mu.Unlock()
+}
+
+// done records a finished page: fetched when err is nil. An interrupted page
+// is neither.
+func (p *crawlProgress) done(t crawlTarget, err error, interrupted bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	delete(p.inFlight, t.url)
+	switch {
+	case interrupted:
+	case err != nil:
+		p.errored++
+	default:
+		p.fetched++
+	}
+	p.mu.Unlock()
+	switch {
+	case interrupted:
+	case err != nil:
+		p.m.pagesErrored.Inc()
+	default:
+		p.m.pagesFetched.Inc()
+	}
+}
+
+// found counts the crawl's distinct matches.
+func (p *crawlProgress) found(ms []Match) {
+	if p == nil {
+		return
+	}
+	var fresh []Match
+	p.mu.Lock()
+	for _, m := range ms {
+		key := matchKey(m)
+		if _, ok := p.seenMatches[key]; ok {
+			continue
+		}
+		p.seenMatches[key] = struct{}{}
+		fresh = append(fresh, m)
+	}
+	p.matches += len(fresh)
+	p.mu.Unlock()
+	p.m.ObserveMatches(fresh...)
+}
+
+// queue records the frontier's size and, at most once per
+// statusFrontierInterval, the targets at its head.
+func (p *crawlProgress) queue(f *crawlFrontier) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.queued = f.len()
+	refresh := time.Since(p.nextAt) >= statusFrontierInterval
+	p.mu.Unlock()
+	if !refresh {
+		return
+	}
+	head := f.head(statusFrontierPreview)
+	next := make([]StatusTarget, len(head))
+	for i, t := range head {
+		next[i] = StatusTarget{URL: t.url, Depth: t.depth}
+	}
+	p.mu.Lock()
+	p.next, p.nextAt = next, time.Now()
+	p.mu.Unlock()
+}
+
+func (p *crawlProgress) finish() {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	p.mu.Lock()
+	p.finished = &now
+	p.queued, p.next = 0, nil
+	p.inFlight = make(map[string]crawlInFlight)
+	p.mu.Unlock()
+}
+
+func (p *crawlProgress) status(now time.Time) CrawlStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cs := CrawlStatus{
+		Seed: p.seed, Started: p.started, Finished: p.finished,
+		PagesFetched: p.fetched, PagesErrored: p.errored, Matches: p.matches,
+		QueueDepth: p.queued, Next: append([]StatusTarget{}, p.next...), Workers: []StatusInFlight{},
+	}
+	for u, f := range p.inFlight {
+		cs.Workers = append(cs.Workers, StatusInFlight{URL: u, Depth: f.depth, Running: now.Sub(f.since).Round(time.Millisecond).String()})
+	}
+	sort.Slice(cs.Workers, func(i, j int) bool { return cs.Workers[i].URL < cs.Workers[j].URL })
+	return cs
+}
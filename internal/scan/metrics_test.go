@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tavgar/JSMiner/internal/metrics"
+)
+
+// TestMetricsCrawlProgress crawls a small site with metrics attached and
+// expects the page counters to agree with the crawl's own summary, alongside
+// the request and match counters and a finished crawl status.
+func TestMetricsCrawlProgress(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><script>var t='eyJabc.def.ghi';fetch('/next');</script></html>`)
+	})
+	mux.HandleFunc("/next", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><body>next</body></html>`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	reg := metrics.NewRegistry()
+	m := NewMetrics(reg)
+	cfg := DefaultClientConfig()
+	cfg.Metrics = m
+	e := NewExtractor(true, false)
+	e.SetClient(NewClient(cfg))
+	opts := DefaultCrawlOptions()
+	opts.AutoCalibrate = false
+	var stats CrawlStats
+	opts.OnComplete = func(s CrawlStats) { stats = s }
+	ms, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasPattern(ms, "jwt") {
+		t.Fatalf("crawl found no jwt: %+v", ms)
+	}
+
+	st := m.Status()
+	if len(st.Crawls) != 1 {
+		t.Fatalf("status has %d crawl(s), want 1", len(st.Crawls))
+	}
+	c := st.Crawls[0]
+	if c.Finished == nil {
+		t.Error("crawl not marked finished")
+	}
+	if stats.PagesFetched < 2 || c.PagesFetched != stats.PagesFetched || c.PagesErrored != stats.PagesErrored {
+		t.Errorf("status pages fetched/errored = %d/%d, crawl stats %d/%d", c.PagesFetched, c.PagesErrored, stats.PagesFetched, stats.PagesErrored)
+	}
+	if c.Matches != len(UniqueMatches(ms)) {
+		t.Errorf("status counts %d match(es), crawl returned %d unique", c.Matches, len(UniqueMatches(ms)))
+	}
+	if c.QueueDepth != 0 || len(c.Workers) != 0 {
+		t.Errorf("finished crawl still has queue %d, workers %+v", c.QueueDepth, c.Workers)
+	}
+
+	var buf bytes.Buffer
+	if err := reg.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("jsminer_crawl_pages_fetched_total %d\n", stats.PagesFetched),
+		fmt.Sprintf("jsminer_crawl_pages_errored_total %d\n", stats.PagesErrored),
+		`jsminer_http_requests_total{method="GET",code="200"} `,
+		`jsminer_matches_total{severity="high"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics lack %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMetricsNilSafe(t *testing.T) {
+	var m *Metrics
+	m.ObserveMatches(Match{Pattern: "jwt"})
+	m.ProxyResponseScanned()
+	if st := m.Status(); len(st.Crawls) != 0 {
+		t.Errorf("nil metrics status = %+v", st)
+	}
+	p := m.startCrawl("https://a.test")
+	p.found([]Match{{Pattern: "jwt"}})
+	p.finish()
+}
@@ -834,6 +834,7 @@ func init() {
 		// Medium and require the value to look like a real secret.
 		severity := SeverityHigh
 		r := newRegexRule(name, pat, severity)
+		r.Secret = true
 		if isKeywordValuePattern(pat) {
 			r.Severity = SeverityMedium
 			r.Filter = credentialValueFilter
@@ -0,0 +1,107 @@
+package scan
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A nuclei template is recognised by its top-level id and info keys.
+var (
+	nucleiTemplateIDRe   = regexp.MustCompile(`(?m)^id\s*:`)
+	nucleiTemplateInfoRe = regexp.MustCompile(`(?m)^info\s*:`)
+)
+
+func isNucleiTemplate(data []byte) bool {
+	return nucleiTemplateIDRe.Match(data) && nucleiTemplateInfoRe.Match(data)
+}
+
+// readNucleiTemplate converts the regex extractors of a nuclei template's
+// file and http (or legacy requests) blocks into rule specs. Each regex
+// becomes a rule named after its extractor, or the template id when the
+// extractor is unnamed, keeping the extractor's group and case-insensitive
+// flag. Matchers and other extractor types have no counterpart and are
+// ignored. Severity comes from the template (critical maps to high); a
+// template without one is rated the way the bundled nuclei patterns are,
+// which also gives "keyword: value" patterns the credential value filter.
+func readNucleiTemplate(path string, data []byte) ([]ruleSpec, error) {
+	doc, err := parseYAMLSubset(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a nuclei template", path)
+	}
+	id, _ := ruleString(top["id"])
+	if id == "" {
+		return nil, fmt.Errorf("%s: template has no id", path)
+	}
+	severity := ""
+	if info, ok := top["info"].(map[string]any); ok {
+		s, _ := ruleString(info["severity"])
+		severity = nucleiSeverity(s)
+	}
+	var specs []ruleSpec
+	for _, proto := range []string{"file", "http", "requests"} {
+		blocks, _ := top[proto].([]any)
+		for _, block := range blocks {
+			b, ok := block.(map[string]any)
+			if !ok {
+				continue
+			}
+			extractors, _ := b["extractors"].([]any)
+			for i, raw := range extractors {
+				ex, ok := raw.(map[string]any)
+				if !ok {
+					continue
+				}
+				if t, _ := ruleString(ex["type"]); t != "regex" {
+					continue
+				}
+				name, _ := ruleString(ex["name"])
+				if name == "" {
+					name = id
+				}
+				group, err := ruleInt(ex["group"])
+				if err != nil {
+					return nil, fmt.Errorf("%s: extractor %d: group: %w", path, i+1, err)
+				}
+				fold, _ := ruleBool(ex["case-insensitive"])
+				pats, err := ruleStrings(ex["regex"])
+				if err != nil {
+					return nil, fmt.Errorf("%s: extractor %d: regex: %w", path, i+1, err)
+				}
+				for _, pat := range pats {
+					if fold {
+						pat = "(?i)" + pat
+					}
+					spec := ruleSpec{ID: name, Regex: pat, Severity: severity, Group: group, JS: true}
+					if isKeywordValuePattern(pat) {
+						spec.Filter = "credential"
+						if spec.Severity == "" {
+							spec.Severity = SeverityMedium
+						}
+					}
+					if spec.Severity == "" {
+						spec.Severity = SeverityHigh
+					}
+					specs = append(specs, spec)
+				}
+			}
+		}
+	}
+	return specs, nil
+}
+
+// nucleiSeverity maps a nuclei severity onto ours, or "" when the template
+// leaves it unknown.
+func nucleiSeverity(s string) string {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "critical", SeverityHigh:
+		return SeverityHigh
+	case SeverityMedium, SeverityLow, SeverityInfo:
+		return s
+	}
+	return ""
+}
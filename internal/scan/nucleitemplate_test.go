@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testNucleiTemplate = `id: acme-keys
+
+info:
+  name: ACME keys
+  author: someone
+  severity: critical
+  tags: file,keys
+
+file:
+  - extensions:
+      - all
+    extractors:
+      - type: regex
+        name: acme-secret
+        part: body
+        regex:
+          - 'ACME-[A-Z0-9]{12}'
+      - type: regex
+        group: 1
+        case-insensitive: true
+        regex:
+          - 'acme_region=([a-z]+-[0-9])'
+      - type: kval
+        kval:
+          - server
+`
+
+func TestLoadNucleiTemplate(t *testing.T) {
+	p := writeRuleFile(t, "acme.yaml", testNucleiTemplate)
+	specs, err := readRuleFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d specs, want 2 regex extractors", len(specs))
+	}
+	if specs[0].ID != "acme-secret" || specs[0].Severity != SeverityHigh {
+		t.Fatalf("unexpected first spec %+v", specs[0])
+	}
+	if specs[1].ID != "acme-keys" || specs[1].Group != 1 || specs[1].Regex != "(?i)acme_region=([a-z]+-[0-9])" {
+		t.Fatalf("unexpected second spec %+v", specs[1])
+	}
+	r, err := specs[1].compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := r.Find([]byte("ACME_REGION=eu-1")); len(ms) != 1 || ms[0].Value != "eu-1" {
+		t.Fatalf("group extraction failed: %+v", ms)
+	}
+}
+
+func TestNucleiTemplateSeverityFallback(t *testing.T) {
+	tmpl := `id: creds
+info:
+  name: creds
+http:
+  - method: GET
+    extractors:
+      - type: regex
+        regex:
+          - '(?i)secret_key[\s"'']*[:=][\s"'']*[\w-]+["'']?'
+          - 'tok_[a-f0-9]{32}'
+`
+	specs, err := readNucleiTemplate("creds.yaml", []byte(tmpl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("got %d specs, want 2", len(specs))
+	}
+	if specs[0].Severity != SeverityMedium || specs[0].Filter != "credential" {
+		t.Errorf("keyword-value pattern: got %+v", specs[0])
+	}
+	if specs[1].Severity != SeverityHigh || specs[1].Filter != "" {
+		t.Errorf("token pattern: got %+v", specs[1])
+	}
+}
+
+func TestLoadRulesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"acme.yaml":     testNucleiTemplate,
+		"gitleaks.toml": testGitleaksConfig,
+		"notes.txt":     "not a rule file",
+		"broken.yaml":   "id: x\ninfo:\n  a: 1\n   b: 2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specs, err := readRulePath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 4 {
+		t.Fatalf("got %d specs, want 4 (broken and non-rule files skipped)", len(specs))
+	}
+	if specs[0].ID != "acme-secret" || specs[2].ID != "acme-token" {
+		t.Fatalf("files should load in lexical order, got %s then %s", specs[0].ID, specs[2].ID)
+	}
+}
@@ -3,6 +3,7 @@ package scan
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -49,7 +50,7 @@ type passiveCandidate struct {
 // discoverPassiveURLs gathers, sanitizes, rebases, de-duplicates and ranks
 // historical URL hints. Provider failures are intentionally best-effort: a
 // public archive being unavailable must not abort an otherwise valid crawl.
-func discoverPassiveURLs(seed *url.URL, sourceNames []string, max int) []passiveCandidate {
+func discoverPassiveURLs(ctx context.Context, client *Client, seed *url.URL, sourceNames []string, max int) []passiveCandidate {
 	if seed == nil || seed.Hostname() == "" {
 		return nil
 	}
@@ -74,9 +75,9 @@ func discoverPassiveURLs(seed *url.URL, sourceNames []string, max int) []passive
 			defer wg.Done()
 			switch source {
 			case passiveSourceWayback:
-				results[i].rawURLs, results[i].err = gatherWaybackURLs(seed.Hostname(), max)
+				results[i].rawURLs, results[i].err = gatherWaybackURLs(ctx, client, seed.Hostname(), max)
 			case passiveSourceCommonCrawl:
-				results[i].rawURLs, results[i].err = gatherCommonCrawlURLs(seed.Hostname(), max)
+				results[i].rawURLs, results[i].err = gatherCommonCrawlURLs(ctx, client, seed.Hostname(), max)
 			}
 		}()
 	}
@@ -229,7 +230,7 @@ func passivePathLength(raw string) int {
 	return len(raw)
 }
 
-func gatherWaybackURLs(host string, limit int) ([]string, error) {
+func gatherWaybackURLs(ctx context.Context, client *Client, host string, limit int) ([]string, error) {
 	endpoint, err := url.Parse(waybackCDXEndpoint)
 	if err != nil {
 		return nil, err
@@ -244,7 +245,7 @@ func gatherWaybackURLs(host string, limit int) ([]string, error) {
 	q.Set("gzip", "false")
 	endpoint.RawQuery = q.Encode()
 
-	data, err := fetchPassiveProviderBody(endpoint.String())
+	data, err := fetchPassiveProviderBody(ctx, client, endpoint.String())
 	if err != nil {
 		return nil, err
 	}
@@ -278,8 +279,8 @@ type commonCrawlCollection struct {
 	CDXAPI string `json:"cdx-api"`
 }
 
-func gatherCommonCrawlURLs(host string, limit int) ([]string, error) {
-	data, err := fetchPassiveProviderBody(commonCrawlCollectionsURL)
+func gatherCommonCrawlURLs(ctx context.Context, client *Client, host string, limit int) ([]string, error) {
+	data, err := fetchPassiveProviderBody(ctx, client, commonCrawlCollectionsURL)
 	if err != nil {
 		return nil, err
 	}
@@ -310,7 +311,7 @@ func gatherCommonCrawlURLs(host string, limit int) ([]string, error) {
 	q.Set("limit", fmt.Sprintf("%d", limit))
 	endpoint.RawQuery = q.Encode()
 
-	data, err = fetchPassiveProviderBody(endpoint.String())
+	data, err = fetchPassiveProviderBody(ctx, client, endpoint.String())
 	if err != nil {
 		return nil, err
 	}
@@ -338,12 +339,15 @@ func gatherCommonCrawlURLs(host string, limit int) ([]string, error) {
 // applyHeaders. It sends only JSMiner's non-sensitive default User-Agent, keeps
 // normal TLS certificate verification enabled even when the target scan uses
 // -insecure, and confines redirects to the provider's exact origin.
-func fetchPassiveProviderBody(raw string) ([]byte, error) {
+func fetchPassiveProviderBody(ctx context.Context, c *Client, raw string) ([]byte, error) {
+	if c.cfg.Replay != nil {
+		return nil, fmt.Errorf("passive provider %s: %w", raw, ErrNotArchived)
+	}
 	parsed, err := url.Parse(raw)
 	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
 		return nil, fmt.Errorf("invalid passive provider URL %q", raw)
 	}
-	req, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -353,7 +357,7 @@ func fetchPassiveProviderBody(raw string) ([]byte, error) {
 	transport.MaxIdleConns = 10
 	transport.MaxIdleConnsPerHost = 4
 	defer transport.CloseIdleConnections()
-	client := http.Client{Transport: transport, Timeout: HTTPClientTimeout}
+	client := http.Client{Transport: transport, Timeout: c.cfg.Timeout}
 	providerHost := parsed.Hostname()
 	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		if len(via) >= MaxRedirects ||
@@ -364,9 +368,11 @@ func fetchPassiveProviderBody(raw string) ([]byte, error) {
 		return nil
 	}
 
-	globalThrottle.waitHost(providerHost)
+	if err := c.throttle.waitHostContext(ctx, providerHost); err != nil {
+		return nil, err
+	}
 	resp, err := client.Do(req)
-	globalThrottle.observeHost(providerHost, resp, err)
+	c.throttle.observeHost(providerHost, resp, err)
 	if err != nil {
 		return nil, err
 	}
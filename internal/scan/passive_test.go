@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -45,16 +46,16 @@ func TestPassivePathCandidateRebasesPathAndDropsHistoricalQuery(t *testing.T) {
 func TestDiscoverPassiveURLsUsesProvidersWithoutLeakingTargetHeaders(t *testing.T) {
 	oldWayback := waybackCDXEndpoint
 	oldCollections := commonCrawlCollectionsURL
-	oldHeaders := extraHeaders.Clone()
 	defer func() {
 		waybackCDXEndpoint = oldWayback
 		commonCrawlCollectionsURL = oldCollections
-		SetExtraHeaders(oldHeaders)
 	}()
 
-	SetExtraHeaders(http.Header{
-		"Authorization": []string{"Bearer target-only-secret"},
-		"Cookie":        []string{"session=target-only"},
+	client := testClient(func(c *ClientConfig) {
+		c.Headers = http.Header{
+			"Authorization": []string{"Bearer target-only-secret"},
+			"Cookie":        []string{"session=target-only"},
+		}
 	})
 
 	var provider *httptest.Server
@@ -90,7 +91,7 @@ func TestDiscoverPassiveURLsUsesProvidersWithoutLeakingTargetHeaders(t *testing.
 	commonCrawlCollectionsURL = provider.URL + "/collinfo.json"
 
 	seed, _ := url.Parse("https://target.example/")
-	got := discoverPassiveURLs(seed, nil, 10)
+	got := discoverPassiveURLs(context.Background(), client, seed, nil, 10)
 	if len(got) != 2 {
 		t.Fatalf("passive candidates = %+v, want two sanitized unique paths", got)
 	}
@@ -141,7 +142,7 @@ func TestPassiveProvidersAreQueriedConcurrently(t *testing.T) {
 	commonCrawlCollectionsURL = provider.URL + "/collinfo.json"
 
 	seed, _ := url.Parse("https://target.example/")
-	got := discoverPassiveURLs(seed, nil, 10)
+	got := discoverPassiveURLs(context.Background(), testClient(), seed, nil, 10)
 	if len(got) != 2 {
 		t.Fatalf("passive candidates = %+v, want two", got)
 	}
@@ -158,10 +159,10 @@ func TestPassiveSoft404IsRejectedBeforeScanning(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	cal := newAutoCalibrator()
+	cal := newAutoCalibrator(context.Background(), testClient())
 	cal.setBase(srv.URL)
 	e := NewExtractor(true, false)
-	ms, accepted, err := e.scanURLValidated(
+	ms, accepted, err := e.scanURLValidated(context.Background(),
 		srv.URL+"/ghost/dead.js", strings.TrimPrefix(srv.URL, "http://"),
 		false, newVisitedSet(), false, false, cal,
 	)
@@ -192,10 +193,10 @@ func TestPassiveShapeSpecificSoft404IsRejected(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	cal := newAutoCalibrator()
+	cal := newAutoCalibrator(context.Background(), testClient())
 	cal.setBase(srv.URL)
 	e := NewExtractor(true, false)
-	ms, accepted, err := e.scanURLValidated(
+	ms, accepted, err := e.scanURLValidated(context.Background(),
 		srv.URL+"/new/firebase-messaging-sw.js", strings.TrimPrefix(srv.URL, "http://"),
 		false, newVisitedSet(), false, false, cal,
 	)
@@ -269,7 +270,7 @@ func TestPassiveValidatedPathFeedsPermuterButRejectedPathDoesNot(t *testing.T) {
 	}
 	var stats CrawlStats
 	opts.OnComplete = func(s CrawlStats) { stats = s }
-	ms, err := e.ScanURLCrawl(target.URL, false, false, false, opts)
+	ms, err := e.ScanURLCrawl(context.Background(), target.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -331,7 +332,7 @@ func TestResumedPassiveTargetKeepsStrictValidation(t *testing.T) {
 	// must still force validation rather than silently trusting the pending hint.
 	var stats CrawlStats
 	e := NewExtractor(true, false)
-	ms, err := e.ScanURLCrawl(target.URL, false, false, false, CrawlOptions{
+	ms, err := e.ScanURLCrawl(context.Background(), target.URL, false, false, false, CrawlOptions{
 		MaxDepth: 1, MaxPages: 5, SameScopeOnly: true,
 		ResumeFile: checkpoint, OnComplete: func(s CrawlStats) { stats = s },
 	})
@@ -0,0 +1,244 @@
+package scan
+
+// This file decides, in a single pass over an input, which rules can possibly
+// match it. Every RegexRule carries literals that any match must contain (its
+// prefilters) and optionally keywords of which one must appear. Checking them
+// rule by rule costs one bytes.Contains scan per literal per rule — well over a
+// thousand passes over a multi-megabyte bundle once the nuclei patterns are
+// registered. Instead all the literals of all the rules are compiled into one
+// Aho-Corasick automaton, which finds every one of them in a single scan.
+
+// literalSet is an Aho-Corasick automaton over a fixed set of byte strings.
+// The automaton itself matches ASCII case-insensitively, which is what the
+// keywords need; a literal that must match exactly is confirmed against the
+// input whenever its case-folded form is found.
+type literalSet struct {
+	pats  [][]byte
+	exact []bool
+
+	// class maps an input byte to its column in next. Bytes that occur in no
+	// literal share class 0, which always leads back to the root; folding
+	// upper-case letters onto their lower-case class makes the automaton
+	// case-insensitive and keeps the table narrow.
+	class  [256]uint8
+	nclass int
+	// next is the complete transition table, nclass entries per state.
+	next []int32
+	// out lists, for each state, the literals that end there, including those
+	// reached through failure links.
+	out [][]int32
+}
+
+// newLiteralSet builds the automaton for pats. exact[i] reports whether
+// pats[i] must match byte for byte rather than ignoring ASCII case.
+func newLiteralSet(pats [][]byte, exact []bool) *literalSet {
+	s := &literalSet{pats: pats, exact: exact}
+	for _, p := range pats {
+		for _, b := range p {
+			b = lowerASCII(b)
+			if s.class[b] == 0 {
+				s.nclass++
+				s.class[b] = uint8(s.nclass)
+			}
+		}
+	}
+	for b := 'A'; b <= 'Z'; b++ {
+		s.class[b] = s.class[b+'a'-'A']
+	}
+	s.nclass++ // column 0 for bytes outside every literal
+
+	// Build the trie, leaving missing transitions as -1.
+	s.addState()
+	for id, p := range pats {
+		state := int32(0)
+		for _, b := range p {
+			i := int(state)*s.nclass + int(s.class[b])
+			if s.next[i] < 0 {
+				s.next[i] = s.addState()
+			}
+			state = s.next[i]
+		}
+		s.out[state] = append(s.out[state], int32(id))
+	}
+
+	// Breadth-first, turn the trie into a complete transition table: a missing
+	// transition follows the failure link, which for a state one level deeper
+	// than another is already final.
+	fail := make([]int32, len(s.out))
+	queue := make([]int32, 0, len(s.out))
+	for c := 0; c < s.nclass; c++ {
+		if t := s.next[c]; t > 0 {
+			queue = append(queue, t)
+		} else {
+			s.next[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		s.out[state] = append(s.out[state], s.out[fail[state]]...)
+		for c := 0; c < s.nclass; c++ {
+			i := int(state)*s.nclass + c
+			via := s.next[int(fail[state])*s.nclass+c]
+			if t := s.next[i]; t >= 0 {
+				fail[t] = via
+				queue = append(queue, t)
+			} else {
+				s.next[i] = via
+			}
+		}
+	}
+	return s
+}
+
+func (s *literalSet) addState() int32 {
+	for c := 0; c < s.nclass; c++ {
+		s.next = append(s.next, -1)
+	}
+	s.out = append(s.out, nil)
+	return int32(len(s.out) - 1)
+}
+
+// find reports which literals occur in data, indexed like the pats the set
+// was built from.
+func (s *literalSet) find(data []byte) []bool {
+	found := make([]bool, len(s.pats))
+	remaining := len(s.pats)
+	state := int32(0)
+	for i, b := range data {
+		state = s.next[int(state)*s.nclass+int(s.class[b])]
+		for _, id := range s.out[state] {
+			if found[id] {
+				continue
+			}
+			if s.exact[id] {
+				p := s.pats[id]
+				if string(data[i+1-len(p):i+1]) != string(p) {
+					continue
+				}
+			}
+			found[id] = true
+			if remaining--; remaining == 0 {
+				return found
+			}
+		}
+	}
+	return found
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// automatonLiteralLen is the shortest required literal the prefilter
+// automaton checks. Two-byte literals would rule out almost nothing.
+const automatonLiteralLen = 3
+
+// rulePrefilter records, for each rule of an Extractor, the literals that
+// decide whether it can match, as indexes into one shared literalSet.
+type rulePrefilter struct {
+	lits *literalSet
+	// required[i] lists the literals rule i needs all of; keywords[i] those
+	// it needs one of, or none when the rule has no keywords.
+	required [][]int32
+	keywords [][]int32
+}
+
+// newRulePrefilter compiles the prefilters and keywords of rules into one
+// automaton. Rules without either always stay eligible.
+func newRulePrefilter(rules []Rule) *rulePrefilter {
+	type key struct {
+		lit   string
+		exact bool
+	}
+	ids := make(map[key]int32)
+	var pats [][]byte
+	var exact []bool
+	intern := func(lit []byte, isExact bool) int32 {
+		k := key{string(lit), isExact}
+		if id, ok := ids[k]; ok {
+			return id
+		}
+		id := int32(len(pats))
+		ids[k] = id
+		pats = append(pats, lit)
+		exact = append(exact, isExact)
+		return id
+	}
+
+	p := &rulePrefilter{
+		required: make([][]int32, len(rules)),
+		keywords: make([][]int32, len(rules)),
+	}
+	for i, rule := range rules {
+		var r *RegexRule
+		switch typed := rule.(type) {
+		case RegexRule:
+			r = &typed
+		case *RegexRule:
+			r = typed
+		default:
+			continue
+		}
+		// The automaton finds short literals as cheaply as long ones, so it
+		// also checks those too common to be worth a bytes.Contains of their
+		// own.
+		lits := r.prefilters
+		if r.RE != nil {
+			lits = requiredLiteralsMin(r.RE.String(), automatonLiteralLen)
+		}
+		for _, lit := range lits {
+			p.required[i] = append(p.required[i], intern(lit, true))
+		}
+		for _, kw := range r.Keywords {
+			if kw == "" || !isASCII(kw) {
+				// The rule checks keywords the automaton cannot fold (or an
+				// empty one, which is in every input) itself.
+				p.keywords[i] = nil
+				break
+			}
+			p.keywords[i] = append(p.keywords[i], intern([]byte(kw), false))
+		}
+	}
+	p.lits = newLiteralSet(pats, exact)
+	return p
+}
+
+// eligible reports, for each rule, whether its literals allow it to match
+// data.
+func (p *rulePrefilter) eligible(data []byte) []bool {
+	found := p.lits.find(data)
+	ok := make([]bool, len(p.required))
+	for i := range ok {
+		ok[i] = true
+		for _, id := range p.required[i] {
+			if !found[id] {
+				ok[i] = false
+				break
+			}
+		}
+		if ok[i] && len(p.keywords[i]) > 0 {
+			hit := false
+			for _, id := range p.keywords[i] {
+				if found[id] {
+					hit = true
+					break
+				}
+			}
+			ok[i] = hit
+		}
+	}
+	return ok
+}
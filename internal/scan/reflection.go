@@ -56,8 +56,8 @@ var reflectionBreakoutChars = []struct {
 
 // ReflectionScanConfig configures a reflection scan. Its bounds are independent
 // of the DOM scan's so the two can be tuned separately, and it reuses the
-// package's shared HTTP client, headers, TLS, redirect, throttle and timeout
-// configuration.
+// extractor's Client: its HTTP connections, headers, TLS, redirect, throttle
+// and timeout configuration.
 type ReflectionScanConfig struct {
 	// MaxURLs bounds how many distinct routes are probed (0 = unlimited).
 	MaxURLs int
@@ -79,6 +79,10 @@ type ReflectionScanConfig struct {
 
 	// Progress, when set, receives short human-readable status lines (stderr).
 	Progress func(msg string)
+	// OnFinding, when set, receives each reflection as soon as it is confirmed,
+	// ahead of the final result, at most once per fingerprint. The result's
+	// merged record remains authoritative. Calls are never concurrent.
+	OnFinding func(ReflectionFinding)
 }
 
 // DefaultReflectionScanConfig returns conservative defaults mirroring the DOM
@@ -140,6 +144,10 @@ type ReflectionFinding struct {
 	// Notes carries advisory diagnostics (e.g. that the reflection boundary was
 	// altered so character filtering could not be determined).
 	Notes string `json:"notes,omitempty"`
+
+	// Asset describes the target's host when enrichment is enabled (see
+	// Enricher). It is not part of identity.
+	Asset *Asset `json:"asset,omitempty"`
 }
 
 // ReflectionScanSummary is the machine-readable end-of-scan record.
@@ -169,7 +177,8 @@ type ReflectionScanResult struct {
 
 // reflectionScanner holds the mutable, synchronised state of a running scan.
 type reflectionScanner struct {
-	cfg ReflectionScanConfig
+	cfg    ReflectionScanConfig
+	client *Client
 
 	mu           sync.Mutex
 	findings     []ReflectionFinding
@@ -180,6 +189,11 @@ type reflectionScanner struct {
 	suppressed   int
 	errs         []string
 	partial      bool
+
+	// streamMu serialises OnFinding and guards streamed, the fingerprints
+	// already handed to it.
+	streamMu sync.Mutex
+	streamed map[string]struct{}
 }
 
 // reflectionProbe is one parameter's marker pair injected into a request.
@@ -212,7 +226,7 @@ func (e *Extractor) ScanReflections(ctx context.Context, targets []string, cfg R
 	if cfg.MaxParams <= 0 {
 		cfg.MaxParams = DefaultReflectionScanConfig().MaxParams
 	}
-	s := &reflectionScanner{cfg: cfg}
+	s := &reflectionScanner{cfg: cfg, client: e.client}
 
 	routes := reflectionRoutes(targets, cfg.MaxURLs)
 	hintIndex := reflectionParamHints(cfg.ParamHints)
@@ -281,7 +295,10 @@ func (s *reflectionScanner) scanRoute(ctx context.Context, route string, hintInd
 		if !ok {
 			continue
 		}
-		body, err := s.fetchBody(reqURL, u.Hostname())
+		body, err := s.fetchBody(ctx, reqURL, u.Hostname())
+		if err != nil && ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			s.urlFailed(fmt.Sprintf("route %s: %v", route, err))
 			continue
@@ -424,15 +441,15 @@ func reflectionRequestURL(u *url.URL, batch []reflectionProbe) (string, bool) {
 
 // fetchBody retrieves a probe URL and returns its capped response body, keeping
 // redirects within the target's scope unless external probing is allowed.
-func (s *reflectionScanner) fetchBody(reqURL, baseHost string) ([]byte, error) {
+func (s *reflectionScanner) fetchBody(ctx context.Context, reqURL, baseHost string) ([]byte, error) {
 	var (
 		resp *http.Response
 		err  error
 	)
 	if s.cfg.AllowExternal {
-		resp, err = fetchURLResponse(reqURL)
+		resp, err = s.client.fetchURLResponse(ctx, reqURL)
 	} else {
-		resp, err = fetchURLResponseScoped(reqURL, baseHost)
+		resp, err = s.client.fetchURLResponseScoped(ctx, reqURL, baseHost)
 	}
 	if err != nil {
 		return nil, err
@@ -995,6 +1012,21 @@ func (s *reflectionScanner) addFinding(f ReflectionFinding) {
 	s.mu.Lock()
 	s.findings = append(s.findings, f)
 	s.mu.Unlock()
+	if s.cfg.OnFinding == nil {
+		return
+	}
+	f.Fingerprint = f.computeFingerprint()
+	f.Triage = reflectionTriageFor(f)
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	if _, ok := s.streamed[f.Fingerprint]; ok {
+		return
+	}
+	if s.streamed == nil {
+		s.streamed = make(map[string]struct{})
+	}
+	s.streamed[f.Fingerprint] = struct{}{}
+	s.cfg.OnFinding(f)
 }
 
 func (s *reflectionScanner) addParamsTested(n int) {
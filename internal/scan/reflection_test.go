@@ -453,3 +453,37 @@ func TestReflectionFingerprintDedup(t *testing.T) {
 		t.Errorf("severity = %q, want the stronger medium", out[0].Severity)
 	}
 }
+
+// TestScanReflectionsStreamsFindings verifies OnFinding receives each
+// reflection once, fingerprinted, before the scan returns its result.
+func TestScanReflectionsStreamsFindings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + r.URL.Query().Get("q") + " and " + r.URL.Query().Get("q") + "</body></html>"))
+	}))
+	defer srv.Close()
+
+	var streamed []ReflectionFinding
+	cfg := DefaultReflectionScanConfig()
+	cfg.OnFinding = func(f ReflectionFinding) { streamed = append(streamed, f) }
+	res := runReflection(t, srv.URL+"/search?q=test", cfg)
+
+	if len(res.Findings) == 0 {
+		t.Fatal("expected a reflection for q")
+	}
+	if len(streamed) != len(res.Findings) {
+		t.Fatalf("streamed %d finding(s), result has %d", len(streamed), len(res.Findings))
+	}
+	seen := make(map[string]bool)
+	for _, f := range streamed {
+		if f.Fingerprint == "" || seen[f.Fingerprint] {
+			t.Fatalf("streamed finding missing or repeating its fingerprint: %+v", f)
+		}
+		seen[f.Fingerprint] = true
+	}
+	for _, f := range res.Findings {
+		if !seen[f.Fingerprint] {
+			t.Errorf("result finding %s was never streamed", f.Parameter)
+		}
+	}
+}
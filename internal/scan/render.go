@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -52,22 +53,18 @@ func newRenderContext(parent context.Context) (context.Context, context.CancelFu
 	}))
 }
 
-// ChromePath, when set, is the explicit path to the Chrome/Chromium executable
-// used for rendering. It is empty by default, in which case chromedp auto-detects
-// a browser on PATH. Setting it lets JSMiner render in environments where Chrome
-// is installed at a known location that is not on PATH — common in CI images and
-// containers (Playwright/Puppeteer browser caches, custom installs) — where
-// auto-detection would otherwise fail and rendering would silently fall back to a
-// static fetch.
-var ChromePath string
-
-// SetChromePath configures an explicit Chrome/Chromium executable path for
-// rendering. An empty value restores chromedp's PATH-based auto-detection.
-func SetChromePath(path string) { ChromePath = path }
+// browserPath returns the browser to render with: the configured ChromePath,
+// or else one resolved (and, if needed, provisioned) once for the process.
+func (c *Client) browserPath() string {
+	if c.cfg.ChromePath != "" {
+		return c.cfg.ChromePath
+	}
+	return resolvedBrowserPath()
+}
 
 // renderExecOptions builds the headless-Chrome allocator options shared by every
 // render helper.
-func renderExecOptions() []chromedp.ExecAllocatorOption {
+func (c *Client) renderExecOptions() []chromedp.ExecAllocatorOption {
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", true),
 		chromedp.Flag("disable-gpu", true),
@@ -76,10 +73,10 @@ func renderExecOptions() []chromedp.ExecAllocatorOption {
 	// Resolve (and, if needed, provision) a browser: an explicit override, one
 	// bundled with jsminer, a cached download, or a fresh Chrome-for-Testing build.
 	// An empty result leaves chromedp to auto-detect on PATH as before.
-	if browser := resolvedBrowserPath(); browser != "" {
+	if browser := c.browserPath(); browser != "" {
 		opts = append(opts, chromedp.ExecPath(browser))
 	}
-	if SkipTLSVerification {
+	if c.cfg.SkipTLSVerification {
 		opts = append(opts, chromedp.Flag("ignore-certificate-errors", true))
 	}
 	return opts
@@ -88,12 +85,12 @@ func renderExecOptions() []chromedp.ExecAllocatorOption {
 // renderHeaders returns the request headers to apply during rendering: the
 // default (or user-overridden) User-Agent plus any extra headers, matching the
 // headers used by the plain HTTP fetch path.
-func renderHeaders() map[string]interface{} {
+func (c *Client) renderHeaders() map[string]interface{} {
 	headers := map[string]interface{}{"User-Agent": defaultUserAgent}
-	if vals := extraHeaders.Values("User-Agent"); len(vals) > 0 {
+	if vals := c.cfg.Headers.Values("User-Agent"); len(vals) > 0 {
 		headers["User-Agent"] = vals[len(vals)-1]
 	}
-	for k, vals := range extraHeaders {
+	for k, vals := range c.cfg.Headers {
 		if strings.EqualFold(k, "User-Agent") || len(vals) == 0 {
 			continue
 		}
@@ -128,35 +125,45 @@ func retryAfterFromHeaders(h network.Headers) string {
 	return ""
 }
 
-// noteRenderResponse feeds a response headless Chrome received into the shared
+// noteRenderResponse feeds a response headless Chrome received into the client's
 // throttle when it is a rate-limit/overload signal, so a 429/503 the browser hits
 // during a render backs off the whole scan even though Chrome's own fetches never
 // pass through the Go request path. respURL is the URL of the response, so the
 // backoff is applied against the host that actually rate-limited rather than
 // globally.
-func noteRenderResponse(respURL string, status int, h network.Headers) {
+func (c *Client) noteRenderResponse(respURL string, status int, h network.Headers) {
 	if isThrottleStatus(status) {
-		globalThrottle.noteThrottledHost(hostOf(respURL), status, retryAfterFromHeaders(h))
+		c.throttle.noteThrottledHost(hostOf(respURL), status, retryAfterFromHeaders(h))
 	}
 }
 
 // RenderURL loads the page at urlStr in headless Chrome and returns the
 // rendered HTML along with JavaScript URLs fetched during the page load.
-func RenderURL(urlStr string) ([]byte, []string, error) {
-	// Pace the render against the shared throttle before arming the timeout, so a
+// Cancelling ctx closes the browser and abandons the render.
+func (c *Client) RenderURL(ctx context.Context, urlStr string) ([]byte, []string, error) {
+	if c.cfg.Replay != nil {
+		states, err := c.cfg.Replay.renderStates(urlStr)
+		if err != nil {
+			return nil, nil, err
+		}
+		return states[0], nil, nil
+	}
+	// Pace the render against the client's throttle before arming the timeout, so a
 	// backoff sleep cannot consume the render budget (see renderStates).
-	globalThrottle.waitHost(hostOf(urlStr))
+	if err := c.throttle.waitHostContext(ctx, hostOf(urlStr)); err != nil {
+		return nil, nil, err
+	}
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), renderExecOptions()...)
+	allocCtx, cancel := chromedp.NewExecAllocator(ctx, c.renderExecOptions()...)
 	defer cancel()
 
 	ctx, cancelCtx := newRenderContext(allocCtx)
 	defer cancelCtx()
 
-	ctx, cancelTimeout := context.WithTimeout(ctx, RenderTimeout)
+	ctx, cancelTimeout := context.WithTimeout(ctx, c.cfg.RenderTimeout)
 	defer cancelTimeout()
 
-	headers := renderHeaders()
+	headers := c.renderHeaders()
 
 	// The listener runs on chromedp's event-loop goroutine, and network events
 	// keep arriving after chromedp.Run returns (the context is still live), so the
@@ -164,9 +171,14 @@ func RenderURL(urlStr string) ([]byte, []string, error) {
 	// otherwise a late response racing the read is a fatal concurrent map access.
 	var mu sync.Mutex
 	scriptSet := make(map[string]struct{})
+	var status documentStatus
+	archive := c.newRenderArchiver()
+	defer archive.flush(ctx)
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		archive.observe(ev)
 		if e, ok := ev.(*network.EventResponseReceived); ok {
-			noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
+			c.noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
+			status.note(e)
 			u := e.Response.URL
 			if strings.HasSuffix(strings.ToLower(u), ".js") ||
 				strings.Contains(e.Response.MimeType, "javascript") {
@@ -183,27 +195,31 @@ func RenderURL(urlStr string) ([]byte, []string, error) {
 	actions = append(actions,
 		chromedp.Navigate(urlStr),
 		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(RenderSleepDuration),
+		chromedp.Sleep(c.cfg.RenderSleep),
 		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
 	)
 	vlog(2, "render %s (plain)", urlStr)
+	start := time.Now()
+	tev := startTrace(ctx, TraceRender, http.MethodGet, urlStr)
 	err := chromedp.Run(ctx, actions...)
+	c.metrics().observeRender(start, err)
 	if err != nil {
-		vlog(2, "render %s -> error: %v", urlStr, err)
+		tev.log(2, fmt.Sprintf("render %s -> error: %v", urlStr, err), status.get(), -1, err)
 		return nil, nil, err
 	}
 
 	mu.Lock()
 	scripts := scriptKeys(scriptSet)
 	mu.Unlock()
-	vlog(2, "render %s -> %d byte(s), %d script(s)", urlStr, len(html), len(scripts))
+	tev.log(2, fmt.Sprintf("render %s -> %d byte(s), %d script(s)", urlStr, len(html), len(scripts)), status.get(), int64(len(html)), nil)
+	c.cfg.Archive.writeRenderStates(urlStr, [][]byte{[]byte(html)})
 	return []byte(html), scripts, nil
 }
 
 // RenderURLWithRequests loads the page and captures POST requests made during
 // rendering. It returns the rendered HTML, JavaScript URLs and POST requests.
-func RenderURLWithRequests(urlStr string) ([]byte, []string, []HTTPRequest, error) {
-	states, scripts, posts, _, err := renderStates(urlStr, false)
+func (c *Client) RenderURLWithRequests(ctx context.Context, urlStr string) ([]byte, []string, []HTTPRequest, error) {
+	states, scripts, posts, _, _, err := c.renderStates(ctx, urlStr, false)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -223,38 +239,47 @@ func RenderURLWithRequests(urlStr string) ([]byte, []string, []HTTPRequest, erro
 // made — so a single-page app whose surface lives behind event handlers is
 // scanned in every state, not just the shell it first renders, and the API
 // endpoints its dynamic navigation calls are discovered even when no bundle
-// mentions them literally.
+// mentions them literally. Once the page has settled it also returns the
+// service workers, Cache Storage and web storage the page left behind.
 //
-// Interaction is bounded by MaxExploreStates; when that is zero the result is a
-// single state and this behaves like RenderURLWithRequests.
-func RenderURLWithStates(urlStr string) ([][]byte, []string, []HTTPRequest, []string, error) {
-	return renderStates(urlStr, MaxExploreStates > 0)
+// Interaction is bounded by the client's MaxExploreStates; when that is zero the
+// result is a single state and this behaves like RenderURLWithRequests.
+func (c *Client) RenderURLWithStates(ctx context.Context, urlStr string) ([][]byte, []string, []HTTPRequest, []string, RenderedStorage, error) {
+	return c.renderStates(ctx, urlStr, c.cfg.MaxExploreStates > 0)
 }
 
 // renderStates performs the initial render and, when explore is set, drives
 // interaction-based exploration of further states. It is the shared engine
 // behind RenderURLWithRequests (explore off) and RenderURLWithStates.
-func renderStates(urlStr string, explore bool) ([][]byte, []string, []HTTPRequest, []string, error) {
-	// Respect the shared throttle's proactive spacing and any active backoff
+func (c *Client) renderStates(ctx context.Context, urlStr string, explore bool) ([][]byte, []string, []HTTPRequest, []string, RenderedStorage, error) {
+	if c.cfg.Replay != nil {
+		// Replay has the recorded states only: the requests, scripts and
+		// storage the page produced came from a live browser.
+		states, err := c.cfg.Replay.renderStates(urlStr)
+		return states, nil, nil, nil, RenderedStorage{}, err
+	}
+	// Respect the client throttle's proactive spacing and any active backoff
 	// before starting a render. This must happen before the render timeout is
 	// armed below, otherwise a backoff sleep would eat into the render budget and
 	// could expire the context before Chrome even navigates.
-	globalThrottle.waitHost(hostOf(urlStr))
+	if err := c.throttle.waitHostContext(ctx, hostOf(urlStr)); err != nil {
+		return nil, nil, nil, nil, RenderedStorage{}, err
+	}
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), renderExecOptions()...)
+	allocCtx, cancel := chromedp.NewExecAllocator(ctx, c.renderExecOptions()...)
 	defer cancel()
 
 	ctx, cancelCtx := newRenderContext(allocCtx)
 	defer cancelCtx()
 
-	timeout := RenderTimeout
+	timeout := c.cfg.RenderTimeout
 	if explore {
-		timeout += exploreBudget()
+		timeout += c.exploreBudget()
 	}
 	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
 	defer cancelTimeout()
 
-	headers := renderHeaders()
+	headers := c.renderHeaders()
 
 	// The listener runs on chromedp's event-loop goroutine and keeps firing after
 	// each chromedp.Run returns (the context stays live through exploration and the
@@ -265,10 +290,15 @@ func renderStates(urlStr string, explore bool) ([][]byte, []string, []HTTPReques
 	scriptSet := make(map[string]struct{})
 	xhrSet := make(map[string]struct{})
 	reqMap := make(map[network.RequestID]*HTTPRequest)
+	var status documentStatus
+	archive := c.newRenderArchiver()
+	defer archive.flush(ctx)
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		archive.observe(ev)
 		switch e := ev.(type) {
 		case *network.EventResponseReceived:
-			noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
+			c.noteRenderResponse(e.Response.URL, int(e.Response.Status), e.Response.Headers)
+			status.note(e)
 			u := e.Response.URL
 			if strings.HasSuffix(strings.ToLower(u), ".js") ||
 				strings.Contains(e.Response.MimeType, "javascript") {
@@ -319,25 +349,34 @@ func renderStates(urlStr string, explore bool) ([][]byte, []string, []HTTPReques
 		chromedp.Navigate(urlStr),
 		chromedp.Evaluate(interactionScript, nil),
 		chromedp.WaitReady("body", chromedp.ByQuery),
-		chromedp.Sleep(RenderSleepDuration),
+		chromedp.Sleep(c.cfg.RenderSleep),
 		chromedp.OuterHTML("html", &baseHTML, chromedp.ByQuery),
 	)
 	vlog(2, "render %s (explore=%t)", urlStr, explore)
+	start := time.Now()
+	tev := startTrace(ctx, TraceRender, http.MethodGet, urlStr)
 	if err := chromedp.Run(ctx, actions...); err != nil {
-		vlog(2, "render %s -> error: %v", urlStr, err)
-		return nil, nil, nil, nil, err
+		c.metrics().observeRender(start, err)
+		tev.log(2, fmt.Sprintf("render %s -> error: %v", urlStr, err), status.get(), -1, err)
+		return nil, nil, nil, nil, RenderedStorage{}, err
 	}
 
 	states := [][]byte{[]byte(baseHTML)}
 	seen := map[string]struct{}{structuralSig([]byte(baseHTML)): {}}
 	winPosts := readWindowPosts(ctx, nil)
+	storage := readRenderedStorage(ctx, false)
 
 	if explore {
 		before := len(states)
-		states, winPosts = exploreStates(ctx, states, seen, winPosts)
+		states, winPosts = c.exploreStates(ctx, states, seen, winPosts, &storage)
 		vlog(2, "render %s -> explored %d additional state(s)", urlStr, len(states)-before)
 	}
 
+	// Workers register and caches fill while the page settles and is explored,
+	// so they are read last, with a final pass over the stores.
+	storage.merge(readRenderedStorage(ctx, true))
+	storage.Libraries = c.readLibraryGlobals(ctx)
+
 	// Snapshot the listener-written maps under the lock, then read from the copies:
 	// mergePosts issues further CDP calls (which fire more listener events), so it
 	// must not run while holding the lock, and the live maps must not be touched
@@ -352,8 +391,11 @@ func renderStates(urlStr string, explore bool) ([][]byte, []string, []HTTPReques
 	mu.Unlock()
 
 	posts := mergePosts(ctx, reqSnapshot, winPosts)
-	vlog(2, "render %s -> %d state(s), %d script(s), %d xhr(s), %d post(s)", urlStr, len(states), len(scripts), len(xhrURLs), len(posts))
-	return states, scripts, posts, xhrURLs, nil
+	c.metrics().observeRender(start, nil)
+	tev.log(2, fmt.Sprintf("render %s -> %d state(s), %d script(s), %d xhr(s), %d post(s), %d worker(s)", urlStr, len(states), len(scripts), len(xhrURLs), len(posts), len(storage.Workers)),
+		status.get(), int64(len(baseHTML)), nil)
+	c.cfg.Archive.writeRenderStates(urlStr, states)
+	return states, scripts, posts, xhrURLs, storage, nil
 }
 
 // exploreStates drives interaction-based state discovery against an already
@@ -371,8 +413,12 @@ func renderStates(urlStr string, explore bool) ([][]byte, []string, []HTTPReques
 // DOM is rebuilt by a route change or a submit navigates to a new page. It is
 // best-effort and defensive — a failed step is skipped, not fatal — and bounded
 // by MaxExploreStates and exploreMaxAttempts so a busy page cannot run away.
-func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{}, winPosts []HTTPRequest) ([][]byte, []HTTPRequest) {
-	maxAttempts := exploreMaxAttempts()
+//
+// When storage is non-nil the page's storage and cookies are dumped into it
+// after every new state: a login form or route change is often what mints the
+// token, and a later step (a logout button) may clear it again.
+func (c *Client) exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{}, winPosts []HTTPRequest, storage *RenderedStorage) ([][]byte, []HTTPRequest) {
+	maxAttempts := c.exploreMaxAttempts()
 
 	record := func(html string) {
 		winPosts = readWindowPosts(ctx, winPosts)
@@ -382,6 +428,9 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 		}
 		seen[sig] = struct{}{}
 		states = append(states, []byte(html))
+		if storage != nil {
+			storage.merge(readRenderedStorage(ctx, false))
+		}
 	}
 
 	// submitForms fills and submits every form in the DOM currently loaded,
@@ -392,7 +441,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 	// after each client-side navigation — a form reached only by navigating to a
 	// route (e.g. /login) is otherwise never exercised.
 	submitForms := func() {
-		for idx := 0; len(states) <= MaxExploreStates && idx < maxAttempts; idx++ {
+		for idx := 0; len(states) <= c.cfg.MaxExploreStates && idx < maxAttempts; idx++ {
 			var forms []exploreForm
 			if err := chromedp.Run(ctx,
 				chromedp.Evaluate(interactionScript, nil),
@@ -411,7 +460,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 			var html string
 			if err := chromedp.Run(ctx,
 				chromedp.Evaluate(fmt.Sprintf("window.__jsm.fillAndSubmit(%d, %s)", idx, string(valsJSON)), &submitted),
-				chromedp.ActionFunc(waitForInteractionSettle),
+				chromedp.ActionFunc(c.waitForInteractionSettle),
 				chromedp.OuterHTML("html", &html, chromedp.ByQuery),
 			); err != nil {
 				continue
@@ -428,7 +477,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 	// Client-side navigation: buttons and in-page/JS links that reveal state
 	// through event handlers rather than a fresh URL (real URL navigations are
 	// already covered by the crawl's link graph).
-	for idx := 0; len(states) <= MaxExploreStates && idx < maxAttempts; idx++ {
+	for idx := 0; len(states) <= c.cfg.MaxExploreStates && idx < maxAttempts; idx++ {
 		var n int
 		if err := chromedp.Run(ctx,
 			chromedp.Evaluate(interactionScript, nil),
@@ -443,7 +492,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 		var html string
 		if err := chromedp.Run(ctx,
 			chromedp.Evaluate(fmt.Sprintf("window.__jsm.click(%d)", idx), &clicked),
-			chromedp.ActionFunc(waitForInteractionSettle),
+			chromedp.ActionFunc(c.waitForInteractionSettle),
 			chromedp.OuterHTML("html", &html, chromedp.ByQuery),
 		); err != nil {
 			continue
@@ -471,7 +520,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 		if p := currentRoutePath(ctx); p != "" {
 			visitedRoutes[p] = struct{}{}
 		}
-		for i := 0; len(states) <= MaxExploreStates && i < maxAttempts; i++ {
+		for i := 0; len(states) <= c.cfg.MaxExploreStates && i < maxAttempts; i++ {
 			var paths []string
 			if err := chromedp.Run(ctx, chromedp.Evaluate("(window.__jsm&&window.__jsm.tagRouteLinks)?window.__jsm.tagRouteLinks():[]", &paths)); err != nil {
 				break
@@ -491,7 +540,7 @@ func exploreStates(ctx context.Context, states [][]byte, seen map[string]struct{
 			var html string
 			if err := chromedp.Run(ctx,
 				chromedp.Evaluate(fmt.Sprintf("(window.__jsm&&window.__jsm.clickRoute)?window.__jsm.clickRoute(%d):false", idx), &clicked),
-				chromedp.ActionFunc(waitForInteractionSettle),
+				chromedp.ActionFunc(c.waitForInteractionSettle),
 				chromedp.OuterHTML("html", &html, chromedp.ByQuery),
 			); err != nil {
 				continue
@@ -522,7 +571,7 @@ const interactionQuietPeriod = 300 * time.Millisecond
 
 // waitForInteractionSettle waits until an interaction has produced no observed
 // DOM, network or short-timer activity for a small quiet period. The previous
-// implementation always slept ExploreSettleDuration after every click and form
+// implementation always slept ExploreSettle after every click and form
 // submit, which made inert controls cost 1.5 seconds apiece. This retains that
 // duration as a hard upper bound for genuinely busy or uninstrumented pages, but
 // lets the common no-op interaction finish as soon as the page is demonstrably
@@ -532,8 +581,8 @@ const interactionQuietPeriod = 300 * time.Millisecond
 // briefly destroy the JavaScript execution context. A new document has no
 // __jsmActivity marker, so it receives the full legacy settle delay before its
 // HTML is snapshotted.
-func waitForInteractionSettle(ctx context.Context) error {
-	deadline := time.Now().Add(ExploreSettleDuration)
+func (c *Client) waitForInteractionSettle(ctx context.Context) error {
+	deadline := time.Now().Add(c.cfg.ExploreSettle)
 	quietMS := interactionQuietPeriod.Milliseconds()
 	expr := fmt.Sprintf(`(function() {
 		var a = window.__jsmActivity;
@@ -577,15 +626,15 @@ func currentRoutePath(ctx context.Context) string {
 // exploreMaxAttempts bounds how many interactions each exploration pass (forms,
 // then clicks) attempts, keeping the exploration time-bounded on pages with many
 // controls even when few of them yield a new state.
-func exploreMaxAttempts() int {
-	return MaxExploreStates * 3
+func (c *Client) exploreMaxAttempts() int {
+	return c.cfg.MaxExploreStates * 3
 }
 
 // exploreBudget is the extra time granted to the render context to perform
 // interactions, sized from the two passes' attempt caps and the per-interaction
 // settle time.
-func exploreBudget() time.Duration {
-	return time.Duration(2*exploreMaxAttempts()) * ExploreSettleDuration
+func (c *Client) exploreBudget() time.Duration {
+	return time.Duration(2*c.exploreMaxAttempts()) * c.cfg.ExploreSettle
 }
 
 // readWindowPosts reads the POST/PUT/PATCH requests the injected hooks recorded
@@ -1073,3 +1122,27 @@ const interactionScript = `
 	};
 })();
 `
+
+// documentStatus records the status of the first document response a render
+// sees, the page's own, from the listener goroutine.
+type documentStatus struct {
+	mu     sync.Mutex
+	status int
+}
+
+func (d *documentStatus) note(e *network.EventResponseReceived) {
+	if e.Type != network.ResourceTypeDocument || e.Response == nil {
+		return
+	}
+	d.mu.Lock()
+	if d.status == 0 {
+		d.status = int(e.Response.Status)
+	}
+	d.mu.Unlock()
+}
+
+func (d *documentStatus) get() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
@@ -112,19 +112,16 @@ func TestFormValuesKeying(t *testing.T) {
 // TestExploreMaxAttemptsBudget checks that the interaction caps scale with
 // MaxExploreStates and that a disabled setting yields no budget.
 func TestExploreBudgetScaling(t *testing.T) {
-	orig := MaxExploreStates
-	defer func() { MaxExploreStates = orig }()
-
-	MaxExploreStates = 0
-	if exploreBudget() != 0 {
-		t.Errorf("disabled exploration should grant no extra budget, got %v", exploreBudget())
+	c := testClient(func(c *ClientConfig) { c.MaxExploreStates = 0 })
+	if c.exploreBudget() != 0 {
+		t.Errorf("disabled exploration should grant no extra budget, got %v", c.exploreBudget())
 	}
 
-	MaxExploreStates = 10
-	if exploreMaxAttempts() != 30 {
-		t.Errorf("exploreMaxAttempts() = %d, want 30", exploreMaxAttempts())
+	c = testClient(func(c *ClientConfig) { c.MaxExploreStates = 10 })
+	if c.exploreMaxAttempts() != 30 {
+		t.Errorf("exploreMaxAttempts() = %d, want 30", c.exploreMaxAttempts())
 	}
-	if exploreBudget() <= 0 {
+	if c.exploreBudget() <= 0 {
 		t.Error("enabled exploration should grant a positive time budget")
 	}
 }
@@ -132,9 +129,8 @@ func TestExploreBudgetScaling(t *testing.T) {
 // TestResolveBrowserPrefersExplicitPath verifies the explicit -chrome-path
 // override wins over every other resolution source.
 func TestResolveBrowserPrefersExplicitPath(t *testing.T) {
-	SetChromePath("/explicit/chrome")
-	defer SetChromePath("")
-	if got := ResolveBrowser(); got != "/explicit/chrome" {
-		t.Fatalf("ResolveBrowser() = %q, want the explicit path", got)
+	c := testClient(func(c *ClientConfig) { c.ChromePath = "/explicit/chrome" })
+	if got := c.browserPath(); got != "/explicit/chrome" {
+		t.Fatalf("browserPath() = %q, want the explicit path", got)
 	}
 }
@@ -0,0 +1,534 @@
+package scan
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// RenderedStorage is what a rendered page left in the browser beyond its DOM:
+// the service workers it registered, its Cache Storage entries, its
+// localStorage, sessionStorage and IndexedDB records, and its cookies. Service
+// workers in particular carry API routes, auth logic and Workbox precache
+// manifests that list every asset the app ships; the stores and cookies hold
+// the tokens an app only mints once it has booted.
+type RenderedStorage struct {
+	Origin  string                `json:"origin"`
+	Workers []string              `json:"workers"`
+	Caches  []RenderedCacheEntry  `json:"caches"`
+	Items   []RenderedStorageItem `json:"items"`
+	Cookies []RenderedCookie      `json:"cookies"`
+
+	// Libraries are the library versions the page's globals expose, read
+	// when library fingerprinting is on.
+	Libraries []RenderedLibrary `json:"libraries,omitempty"`
+}
+
+// RenderedCacheEntry is one Cache Storage response. Body is kept only for
+// text-like content and is bounded in size.
+type RenderedCacheEntry struct {
+	Cache string `json:"cache"`
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	Body  string `json:"body"`
+}
+
+// RenderedStorageItem is one key/value record. Area is localStorage,
+// sessionStorage, or indexedDB/<database>/<store>. Origin is the origin whose
+// storage held it; exploration can move a page between origins, so each record
+// keeps its own.
+type RenderedStorageItem struct {
+	Origin string `json:"origin"`
+	Area   string `json:"area"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+// RenderedCookie is one cookie visible to the rendered page's URL, with the
+// attributes that decide who can read and send it. SameSite is empty when the
+// cookie did not set one.
+type RenderedCookie struct {
+	Origin   string `json:"origin"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	HTTPOnly bool   `json:"http_only"`
+	Secure   bool   `json:"secure"`
+	SameSite string `json:"same_site"`
+}
+
+// renderStorageScript collects RenderedStorage from the settled page. Every
+// read is bounded and best-effort, and the whole collection gives up after a
+// few seconds so a wedged IndexedDB cannot stall the render. With full false
+// only the key/value stores are read: that is the cheap dump taken after each
+// explored state, while workers and caches are read once at the end.
+const renderStorageScript = `(async (full) => {
+  const out = { origin: location.origin, workers: [], caches: [], items: [] };
+  const clip = (s, n) => (s.length > n ? s.slice(0, n) : s);
+  const text = v => { try { return typeof v === 'string' ? v : JSON.stringify(v); } catch (e) { return String(v); } };
+  const work = (async () => {
+    try {
+      if (full && navigator.serviceWorker && navigator.serviceWorker.getRegistrations) {
+        for (const reg of await navigator.serviceWorker.getRegistrations()) {
+          for (const w of [reg.active, reg.waiting, reg.installing]) {
+            if (w && w.scriptURL && out.workers.indexOf(w.scriptURL) < 0 && out.workers.length < 20) out.workers.push(w.scriptURL);
+          }
+        }
+      }
+    } catch (e) {}
+    for (const area of ['localStorage', 'sessionStorage']) {
+      try {
+        const s = window[area];
+        for (let i = 0; i < s.length && out.items.length < 200; i++) {
+          const k = s.key(i);
+          out.items.push({ area: area, key: k, value: clip(String(s.getItem(k) || ''), 65536) });
+        }
+      } catch (e) {}
+    }
+    try {
+      if (full && window.caches) {
+        let total = 0, n = 0;
+        for (const name of (await caches.keys()).slice(0, 10)) {
+          const cache = await caches.open(name);
+          for (const req of await cache.keys()) {
+            if (n++ >= 200) break;
+            const entry = { cache: name, url: req.url, type: '', body: '' };
+            try {
+              const resp = await cache.match(req);
+              entry.type = (resp && resp.headers.get('content-type')) || '';
+              if (resp && /javascript|json|text|xml/i.test(entry.type) && total < 4194304) {
+                entry.body = clip(await resp.text(), 262144);
+                total += entry.body.length;
+              }
+            } catch (e) {}
+            out.caches.push(entry);
+          }
+        }
+      }
+    } catch (e) {}
+    try {
+      if (window.indexedDB && indexedDB.databases) {
+        for (const info of (await indexedDB.databases()).slice(0, 10)) {
+          if (!info.name) continue;
+          const db = await new Promise((res, rej) => {
+            const r = indexedDB.open(info.name);
+            r.onsuccess = () => res(r.result);
+            r.onerror = r.onblocked = () => rej(r.error);
+          });
+          try {
+            for (const store of Array.prototype.slice.call(db.objectStoreNames, 0, 10)) {
+              const rows = await new Promise(res => {
+                try {
+                  const st = db.transaction(store, 'readonly').objectStore(store);
+                  const keys = st.getAllKeys(null, 50), vals = st.getAll(null, 50);
+                  vals.onsuccess = () => res({ keys: keys.result || [], vals: vals.result || [] });
+                  vals.onerror = () => res({ keys: [], vals: [] });
+                } catch (e) { res({ keys: [], vals: [] }); }
+              });
+              for (let i = 0; i < rows.vals.length && out.items.length < 400; i++) {
+                out.items.push({ area: 'indexedDB/' + info.name + '/' + store, key: text(rows.keys[i]), value: clip(text(rows.vals[i]), 65536) });
+              }
+            }
+          } finally { db.close(); }
+        }
+      }
+    } catch (e) {}
+  })();
+  await Promise.race([work, new Promise(r => setTimeout(r, 3000))]);
+  return JSON.stringify(out);
+})`
+
+// readRenderedStorage collects the page's RenderedStorage, including the
+// cookies CDP reports for the current URL (HttpOnly ones too, which
+// document.cookie cannot see). full adds service workers and Cache Storage. A
+// failed read yields an empty value; storage inspection never fails a render.
+func readRenderedStorage(ctx context.Context, full bool) RenderedStorage {
+	var raw string
+	var st RenderedStorage
+	var cookies []*network.Cookie
+	awaitPromise := func(p *runtime.EvaluateParams) *runtime.EvaluateParams { return p.WithAwaitPromise(true) }
+	script := "(" + renderStorageScript + ")(" + strconv.FormatBool(full) + ")"
+	if err := chromedp.Run(ctx, chromedp.Evaluate(script, &raw, awaitPromise)); err != nil {
+		vlog(2, "render storage read failed: %v", err)
+		return st
+	}
+	if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		return RenderedStorage{}
+	}
+	for i := range st.Items {
+		st.Items[i].Origin = st.Origin
+	}
+	_ = chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().Do(ctx)
+		return err
+	}))
+	for _, c := range cookies {
+		if c == nil {
+			continue
+		}
+		st.Cookies = append(st.Cookies, RenderedCookie{
+			Origin: st.Origin, Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+			HTTPOnly: c.HTTPOnly, Secure: c.Secure, SameSite: string(c.SameSite),
+		})
+	}
+	return st
+}
+
+// empty reports whether nothing was collected.
+func (st RenderedStorage) empty() bool {
+	return len(st.Workers) == 0 && len(st.Caches) == 0 && len(st.Items) == 0 && len(st.Cookies) == 0 && len(st.Libraries) == 0
+}
+
+// merge adds what o collected that st does not hold yet. Records are keyed by
+// their value as well as their name, so a token that rotates between explored
+// states is kept in every version seen.
+func (st *RenderedStorage) merge(o RenderedStorage) {
+	if st.Origin == "" {
+		st.Origin = o.Origin
+	}
+	for _, w := range o.Workers {
+		if !slices.Contains(st.Workers, w) {
+			st.Workers = append(st.Workers, w)
+		}
+	}
+	for _, c := range o.Caches {
+		if !slices.ContainsFunc(st.Caches, func(x RenderedCacheEntry) bool { return x.Cache == c.Cache && x.URL == c.URL }) {
+			st.Caches = append(st.Caches, c)
+		}
+	}
+	for _, it := range o.Items {
+		if !slices.Contains(st.Items, it) {
+			st.Items = append(st.Items, it)
+		}
+	}
+	for _, c := range o.Cookies {
+		if !slices.Contains(st.Cookies, c) {
+			st.Cookies = append(st.Cookies, c)
+		}
+	}
+	for _, l := range o.Libraries {
+		if !slices.Contains(st.Libraries, l) {
+			st.Libraries = append(st.Libraries, l)
+		}
+	}
+}
+
+// storageSource names a storage record as a match source, e.g.
+// storage://localStorage/token@https://app.test.
+func storageSource(area, key, origin string) string {
+	return "storage://" + area + "/" + key + "@" + origin
+}
+
+// cacheSource names a Cache Storage entry as a match source.
+func cacheSource(cache, entryURL string) string {
+	return "cache://" + cache + "/" + entryURL
+}
+
+// scanRenderedStorage scans what a rendered page left in the browser. Worker
+// scripts and their importScripts dependencies are fetched and scanned as
+// JavaScript, and their precache manifests become endpoint_url matches so the
+// crawl follows them. Cache Storage bodies and storage records are scanned in
+// place; the cached URLs are reported as endpoints too. In posts mode only the
+// worker scripts' POST requests are extracted.
+func (e *Extractor) scanRenderedStorage(ctx context.Context, pageURL, baseHost string, st RenderedStorage, endpoints, posts bool, visited *visitedSet, external bool) []Match {
+	inScope := func(raw string) bool {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return false
+		}
+		return external || sameScope(baseHost, u.Hostname())
+	}
+	var matches []Match
+	for _, w := range st.Workers {
+		if inScope(w) {
+			matches = append(matches, e.scanServiceWorker(ctx, w, baseHost, endpoints, posts, visited, external, inScope)...)
+		}
+	}
+	if posts {
+		return matches
+	}
+	if inScope(pageURL) {
+		matches = append(matches, e.renderedLibraryMatches(pageURL, st.Libraries)...)
+	}
+	for _, c := range st.Caches {
+		if !inScope(st.Origin) || !inScope(c.URL) {
+			continue
+		}
+		matches = append(matches, Match{Source: pageURL, Pattern: "endpoint_url", Value: c.URL, Severity: SeverityInfo})
+		if c.Body == "" {
+			continue
+		}
+		isJS := isJSFile(c.URL) || isJavaScriptContentType(c.Type)
+		if ms, err := e.scanDataWithEndpoints(cacheSource(c.Cache, c.URL), []byte(c.Body), isJS); err == nil {
+			matches = append(matches, ms...)
+		}
+	}
+	for _, it := range st.Items {
+		origin := cmp.Or(it.Origin, st.Origin)
+		// Skip the DOM scanner's own seeded canaries.
+		if it.Value == "" || strings.Contains(it.Value, "jsmdom") || !inScope(origin) {
+			continue
+		}
+		if ms, err := e.scanDataWithEndpoints(storageSource(it.Area, it.Key, origin), []byte(it.Value), false); err == nil {
+			matches = append(matches, ms...)
+		}
+	}
+	for _, c := range st.Cookies {
+		origin := cmp.Or(c.Origin, st.Origin)
+		// The browser sends every listed cookie to the page, whatever its Domain,
+		// so scope follows the page's origin.
+		if c.Value == "" || strings.Contains(c.Value, "jsmdom") || !inScope(origin) {
+			continue
+		}
+		src := storageSource("cookie", c.Name, origin)
+		if m, ok := cookieAttributeMatch(src, c, strings.HasPrefix(origin, "https://")); ok {
+			matches = append(matches, m)
+		}
+		// HttpOnly values never reach the page's scripts; the server set them in
+		// a response header and they are not the page's leak to report.
+		if c.HTTPOnly {
+			continue
+		}
+		value := c.Value
+		if dec, err := url.QueryUnescape(value); err == nil {
+			value = dec
+		}
+		if ms, err := e.scanDataWithEndpoints(src, []byte(value), false); err == nil {
+			matches = append(matches, ms...)
+		}
+	}
+	if endpoints {
+		matches = FilterEndpointMatches(matches)
+	}
+	return matches
+}
+
+// sessionCookieNameRe matches cookie names that conventionally carry a
+// session or bearer credential. CSRF tokens are left out: they are meant to be
+// readable by the page.
+var sessionCookieNameRe = regexp.MustCompile(`(?i)sess|^sid$|[._-]sid$|token|auth|jwt|bearer|remember`)
+
+// looksLikeSessionCookie reports whether a cookie probably authenticates the
+// visitor: a session-like name, or a JWT for a value.
+func looksLikeSessionCookie(c RenderedCookie) bool {
+	name := strings.ToLower(c.Name)
+	if strings.Contains(name, "csrf") || strings.Contains(name, "xsrf") {
+		return false
+	}
+	return sessionCookieNameRe.MatchString(name) || strings.HasPrefix(c.Value, "eyJ")
+}
+
+// cookieAttributeMatch reports a session-like cookie that lacks the attributes
+// guarding it: HttpOnly keeps it from injected script, Secure from plain-HTTP
+// requests (only checked on HTTPS origins, where it could be set) and SameSite
+// from cross-site requests. A readable session cookie is medium; the rest low.
+func cookieAttributeMatch(source string, c RenderedCookie, https bool) (Match, bool) {
+	if !looksLikeSessionCookie(c) {
+		return Match{}, false
+	}
+	var missing []string
+	if !c.HTTPOnly {
+		missing = append(missing, "HttpOnly")
+	}
+	if https && !c.Secure {
+		missing = append(missing, "Secure")
+	}
+	switch {
+	case c.SameSite == "":
+		missing = append(missing, "SameSite")
+	case strings.EqualFold(c.SameSite, "None"):
+		missing = append(missing, "SameSite (set to None)")
+	}
+	if len(missing) == 0 {
+		return Match{}, false
+	}
+	sev := SeverityLow
+	if !c.HTTPOnly {
+		sev = SeverityMedium
+	}
+	return Match{
+		Source:   source,
+		Pattern:  "insecure_cookie",
+		Value:    c.Name + " (missing " + strings.Join(missing, ", ") + ")",
+		Severity: sev,
+	}, true
+}
+
+// scanServiceWorker fetches and scans one worker script, then follows its
+// importScripts dependencies the same way and reports its precache manifest.
+func (e *Extractor) scanServiceWorker(ctx context.Context, workerURL, baseHost string, endpoints, posts bool, visited *visitedSet, external bool, inScope func(string) bool) []Match {
+	if !visited.visit(workerURL) {
+		return nil
+	}
+	resp, err := e.client.fetchURLResponse(ctx, workerURL)
+	if err != nil {
+		return nil
+	}
+	data, err := readCappedBody(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil
+	}
+	vlog(2, "[render] service worker %s", workerURL)
+	var matches []Match
+	if posts {
+		if ms, err := e.scanDataPostRequests(workerURL, data, true); err == nil {
+			matches = append(matches, ms...)
+		}
+	} else {
+		if ms, err := e.scanDataWithEndpoints(workerURL, data, true); err == nil {
+			if endpoints {
+				ms = FilterEndpointMatches(ms)
+			}
+			matches = append(matches, ms...)
+		}
+		for _, entry := range workboxPrecacheURLs(data, workerURL) {
+			if inScope(entry) {
+				matches = append(matches, Match{Source: workerURL, Pattern: "endpoint_url", Value: entry, Severity: SeverityInfo})
+			}
+		}
+	}
+	for _, dep := range workerImportScripts(data, workerURL) {
+		if inScope(dep) {
+			matches = append(matches, e.scanServiceWorker(ctx, dep, baseHost, endpoints, posts, visited, external, inScope)...)
+		}
+	}
+	return matches
+}
+
+var (
+	importScriptsRe = regexp.MustCompile(`\bimportScripts\s*\(([^)]*)\)`)
+	jsStringLitRe   = regexp.MustCompile("\"([^\"\\\\\n]+)\"|'([^'\\\\\n]+)'|`([^`$\\\\]+)`")
+	// precacheCallRe finds the places a precache list begins: Workbox's
+	// precacheAndRoute/precache calls, a hand-written cache.addAll, and, for
+	// minified builds that renamed those calls, the array of {revision, url}
+	// objects the build injects in place of self.__WB_MANIFEST.
+	precacheCallRe = regexp.MustCompile(`\b(?:precacheAndRoute|precache|addAll)\b|\[\s*\{\s*["']?(?:revision|url)["']?\s*:`)
+)
+
+// workerImportScripts returns the absolute URLs a worker loads with
+// importScripts, resolved against the worker's own URL.
+func workerImportScripts(data []byte, workerURL string) []string {
+	var out []string
+	for _, m := range importScriptsRe.FindAllSubmatch(data, -1) {
+		for _, lit := range jsStringLitRe.FindAllSubmatch(m[1], -1) {
+			v := string(lit[1]) + string(lit[2]) + string(lit[3])
+			out = append(out, resolveURL(workerURL, strings.TrimSpace(v)))
+		}
+	}
+	return uniqueSortedStrings(out)
+}
+
+// maxPrecacheEntries bounds the URLs taken from one worker's manifests.
+const maxPrecacheEntries = 2000
+
+// workboxPrecacheURLs extracts the asset URLs of a worker's precache lists,
+// resolved against the worker URL as Workbox does. Entries are either bare
+// strings or {url, revision} objects, in a minified or pretty-printed array
+// following a precache call.
+func workboxPrecacheURLs(data []byte, workerURL string) []string {
+	var out []string
+	for _, loc := range precacheCallRe.FindAllIndex(data, -1) {
+		rest := data[loc[0]:]
+		start := -1
+		for i := 0; i < len(rest) && i < 64; i++ {
+			if rest[i] == '[' {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			continue
+		}
+		for _, entry := range precacheArrayEntries(rest[start:]) {
+			abs := resolveURL(workerURL, entry)
+			if strings.HasPrefix(abs, "http://") || strings.HasPrefix(abs, "https://") {
+				out = append(out, abs)
+			}
+			if len(out) >= maxPrecacheEntries {
+				return uniqueSortedStrings(out)
+			}
+		}
+	}
+	return uniqueSortedStrings(out)
+}
+
+// precacheArrayEntries walks the array literal at the start of src and returns
+// its string elements plus the url property of its object elements. Other
+// properties (revision, integrity) are ignored.
+func precacheArrayEntries(src []byte) []string {
+	var out []string
+	depth := 0
+	key := ""
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		switch {
+		case ch == '[' || ch == '{':
+			depth++
+			key = ""
+		case ch == ']' || ch == '}':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case ch == ',':
+			key = ""
+		case ch == '"' || ch == '\'' || ch == '`':
+			j := i + 1
+			for j < len(src) && src[j] != ch {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return out
+			}
+			lit := string(src[i+1 : j])
+			i = j
+			if nextNonSpace(src, i+1) == ':' {
+				key = lit
+				continue
+			}
+			if depth == 1 || (depth == 2 && key == "url") {
+				out = append(out, lit)
+			}
+		case isIdentStart(ch) && depth == 2:
+			j := i
+			for j < len(src) && (isIdentStart(src[j]) || (src[j] >= '0' && src[j] <= '9')) {
+				j++
+			}
+			if nextNonSpace(src, j) == ':' {
+				key = string(src[i:j])
+			}
+			i = j - 1
+		}
+	}
+	return out
+}
+
+func nextNonSpace(src []byte, i int) byte {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return src[i]
+	}
+	return 0
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
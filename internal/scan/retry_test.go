@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync/atomic"
@@ -22,13 +23,7 @@ func hijackClose(w http.ResponseWriter) {
 // transport error is retried and ultimately succeeds, so one network hiccup does
 // not silently drop a page from the crawl.
 func TestFetchRetriesTransientError(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-	orig := FetchRetries
-	SetFetchRetries(2)
-	defer SetFetchRetries(orig)
-	ResetThrottle()
+	c := testClient(func(c *ClientConfig) { c.Retries = 2 })
 
 	var n int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -40,7 +35,7 @@ func TestFetchRetriesTransientError(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	resp, err := fetchURLResponse(ts.URL)
+	resp, err := c.fetchURLResponse(context.Background(), ts.URL)
 	if err != nil {
 		t.Fatalf("expected retry to recover, got error: %v", err)
 	}
@@ -54,13 +49,7 @@ func TestFetchRetriesTransientError(t *testing.T) {
 // POST/PUT/PATCH parameter replay) is attempted exactly once even when retries are
 // configured, so a retry can never double-submit it against the target.
 func TestFetchNoRetryForBodyRequests(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-	orig := FetchRetries
-	SetFetchRetries(3)
-	defer SetFetchRetries(orig)
-	ResetThrottle()
+	c := testClient(func(c *ClientConfig) { c.Retries = 3 })
 
 	var n int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -69,7 +58,7 @@ func TestFetchNoRetryForBodyRequests(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	if _, err := fetchURLResponseMethod(ts.URL, "POST", "x=1"); err == nil {
+	if _, err := c.fetchURLResponseMethod(context.Background(), ts.URL, "POST", "x=1"); err == nil {
 		t.Fatal("expected error from an always-failing POST")
 	}
 	if got := atomic.LoadInt64(&n); got != 1 {
@@ -81,13 +70,7 @@ func TestFetchNoRetryForBodyRequests(t *testing.T) {
 // repeated merely because they carry no body. A server may have applied the POST
 // before the connection failed, so retrying it risks executing the mutation twice.
 func TestFetchNoRetryForBodylessMutation(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-	orig := FetchRetries
-	SetFetchRetries(3)
-	defer SetFetchRetries(orig)
-	ResetThrottle()
+	c := testClient(func(c *ClientConfig) { c.Retries = 3 })
 
 	var n int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -96,7 +79,7 @@ func TestFetchNoRetryForBodylessMutation(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	if _, err := fetchURLResponseMethod(ts.URL, "POST", ""); err == nil {
+	if _, err := c.fetchURLResponseMethod(context.Background(), ts.URL, "POST", ""); err == nil {
 		t.Fatal("expected error from an always-failing POST")
 	}
 	if got := atomic.LoadInt64(&n); got != 1 {
@@ -104,16 +87,10 @@ func TestFetchNoRetryForBodylessMutation(t *testing.T) {
 	}
 }
 
-// TestFetchRetriesDisabled verifies SetFetchRetries(0) restores single-attempt
+// TestFetchRetriesDisabled verifies Retries 0 restores single-attempt
 // behaviour on the bodyless path.
 func TestFetchRetriesDisabled(t *testing.T) {
-	resetSharedClient()
-	defer resetSharedClient()
-	SetSkipTLSVerification(true)
-	orig := FetchRetries
-	SetFetchRetries(0)
-	defer SetFetchRetries(orig)
-	ResetThrottle()
+	c := testClient(func(c *ClientConfig) { c.Retries = 0 })
 
 	var n int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -122,7 +99,7 @@ func TestFetchRetriesDisabled(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	if _, err := fetchURLResponse(ts.URL); err == nil {
+	if _, err := c.fetchURLResponse(context.Background(), ts.URL); err == nil {
 		t.Fatal("expected error with retries disabled")
 	}
 	if got := atomic.LoadInt64(&n); got != 1 {
@@ -2,6 +2,7 @@ package scan
 
 import (
 	"bytes"
+	"math"
 	"regexp"
 	"regexp/syntax"
 	"sort"
@@ -19,6 +20,10 @@ type RegexRule struct {
 	RE       *regexp.Regexp
 	Severity string
 
+	// Secret marks the rule's matches as candidate secrets (see Match.Secret).
+	// Rules that report intelligence, such as emails or paths, leave it unset.
+	Secret bool
+
 	// Filter, when non-nil, is applied to every regex hit. Returning false drops
 	// the match. It is used to reject the large volume of false positives that
 	// broad keyword/credential patterns produce on minified bundles (e.g.
@@ -32,6 +37,32 @@ type RegexRule struct {
 	// Returning false drops the match.
 	ContextFilter func(data []byte, start, end int) bool
 
+	// Group selects the capture group reported as the match value, so a rule
+	// can anchor on surrounding context without reporting it. 0 reports the
+	// whole match.
+	Group int
+
+	// Keywords, when non-empty, lists lower-case literals of which at least one
+	// must appear in the input (compared case-insensitively) for the rule to
+	// run at all.
+	Keywords []string
+
+	// MinEntropy drops values whose Shannon entropy, in bits per byte, is below
+	// it: placeholder values like `xxxxxxxx` or `changeme` score low.
+	MinEntropy float64
+
+	// Allow drops values matching any of these expressions (documented example
+	// keys, test fixtures). Deny always reports values matching any of them,
+	// even when Filter, ContextFilter, MinEntropy or Allow would drop them, so
+	// a value known to have leaked is never filtered away.
+	Allow []*regexp.Regexp
+	Deny  []*regexp.Regexp
+
+	// Exclude, when non-nil, drops a hit given the input, the match bounds and
+	// the reported value. Imported gitleaks allowlists use it, since they can
+	// test the whole match or its line as well as the value.
+	Exclude func(data []byte, start, end int, value string) bool
+
 	// prefilters holds literal substrings that must all be present in the input
 	// for RE to have any chance of matching. They are cheap byte scans used to
 	// skip the far more expensive regex pass on inputs that obviously can't
@@ -47,19 +78,28 @@ func (r RegexRule) Find(data []byte) []Match {
 			return nil
 		}
 	}
+	if len(r.Keywords) > 0 && !containsAnyFold(data, r.Keywords) {
+		return nil
+	}
 	var matches []Match
-	// A context filter needs the match offsets, so take the index-returning path
-	// only when one is set; every other rule keeps the cheaper FindAll scan.
-	if r.ContextFilter != nil {
-		for _, loc := range r.RE.FindAllIndex(data, -1) {
+	// Context filters and the value checks need the match offsets, so take the
+	// index-returning path only when one is set; every other rule keeps the
+	// cheaper FindAll scan.
+	if r.ContextFilter != nil || r.Group > 0 || r.MinEntropy > 0 || len(r.Allow) > 0 || len(r.Deny) > 0 || r.Exclude != nil {
+		for _, loc := range r.RE.FindAllSubmatchIndex(data, -1) {
 			s := string(data[loc[0]:loc[1]])
-			if r.Filter != nil && !r.Filter(s) {
-				continue
+			value := s
+			if r.Group > 0 {
+				g := 2 * r.Group
+				if g+1 >= len(loc) || loc[g] < 0 {
+					continue
+				}
+				value = string(data[loc[g]:loc[g+1]])
 			}
-			if !r.ContextFilter(data, loc[0], loc[1]) {
+			if !matchesAny(r.Deny, value) && !r.keep(data, loc[0], loc[1], s, value) {
 				continue
 			}
-			matches = append(matches, Match{Pattern: r.Name, Value: s, Severity: r.Severity})
+			matches = append(matches, Match{Pattern: r.Name, Value: value, Severity: r.Severity, Secret: r.Secret})
 		}
 		return matches
 	}
@@ -68,11 +108,70 @@ func (r RegexRule) Find(data []byte) []Match {
 		if r.Filter != nil && !r.Filter(s) {
 			continue
 		}
-		matches = append(matches, Match{Pattern: r.Name, Value: s, Severity: r.Severity})
+		matches = append(matches, Match{Pattern: r.Name, Value: s, Severity: r.Severity, Secret: r.Secret})
 	}
 	return matches
 }
 
+// keep applies the rule's filters to one hit: s is the whole match and value
+// the reported part of it.
+func (r RegexRule) keep(data []byte, start, end int, s, value string) bool {
+	if r.Filter != nil && !r.Filter(s) {
+		return false
+	}
+	if r.ContextFilter != nil && !r.ContextFilter(data, start, end) {
+		return false
+	}
+	if r.MinEntropy > 0 && shannonEntropy(value) < r.MinEntropy {
+		return false
+	}
+	if r.Exclude != nil && r.Exclude(data, start, end, value) {
+		return false
+	}
+	return !matchesAny(r.Allow, value)
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAnyFold reports whether data contains any of the lower-case
+// keywords, ignoring ASCII case.
+func containsAnyFold(data []byte, keywords []string) bool {
+	lower := bytes.ToLower(data)
+	for _, k := range keywords {
+		if bytes.Contains(lower, []byte(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
 // minPrefilterLen is the shortest literal used as a pre-filter. Short literals
 // like "s3" or "use" are too common to filter usefully and would only add
 // overhead, so they are ignored (the regex simply always runs for such rules).
@@ -91,7 +190,11 @@ func newRegexRule(name, pat, severity string) RegexRule {
 // condition for a match and safe to use as a pre-filter. Case-folded literals
 // are skipped because a case-insensitive substring test is not worthwhile here.
 // Returns nil when no useful literal can be derived.
-func requiredLiterals(pat string) [][]byte {
+func requiredLiterals(pat string) [][]byte { return requiredLiteralsMin(pat, minPrefilterLen) }
+
+// requiredLiteralsMin is requiredLiterals keeping literals of at least minLen
+// bytes.
+func requiredLiteralsMin(pat string, minLen int) [][]byte {
 	re, err := syntax.Parse(pat, syntax.Perl)
 	if err != nil {
 		return nil
@@ -115,7 +218,7 @@ func requiredLiterals(pat string) [][]byte {
 				return
 			}
 			b := []byte(string(r.Rune))
-			if len(b) >= minPrefilterLen {
+			if len(b) >= minLen {
 				lits = append(lits, b)
 			}
 		}
@@ -130,8 +233,9 @@ func requiredLiterals(pat string) [][]byte {
 
 var registeredRules []Rule
 
-// RegisterRule adds r to the global rule registry. Plugin init functions
-// should call this to make their rules available.
+// RegisterRule adds r to the global rule registry, which every Extractor
+// created afterwards picks up. WebAssembly plugins loaded with LoadWASMRule
+// are made available this way.
 func RegisterRule(r Rule) { registeredRules = append(registeredRules, r) }
 
 func getRegisteredRules() []Rule { return registeredRules }
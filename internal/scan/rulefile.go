@@ -0,0 +1,532 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RuleFileVersion is the newest rule schema version this build reads. A file
+// declaring a later version is rejected rather than half understood.
+const RuleFileVersion = 1
+
+// ruleSpec is one rule of a versioned rule file. It maps field for field onto
+// RegexRule; Filter and ContextFilter name one of the built-in validators in
+// ruleFilters and ruleContextFilters. JS marks the rule as relevant to
+// JavaScript so it still runs in -safe mode.
+type ruleSpec struct {
+	ID            string
+	Description   string
+	Regex         string
+	Severity      string
+	Group         int
+	Keywords      []string
+	Entropy       float64
+	Allow         []string
+	Deny          []string
+	Filter        string
+	ContextFilter string
+	JS            bool
+	Tests         ruleTests
+
+	// allowlists carry imported gitleaks allowlists; the versioned schema has
+	// no field for them.
+	allowlists []ruleAllowlist
+}
+
+// ruleTests are the self-tests embedded in a rule. Every positive input must
+// produce a match (with the given value, when one is set) and no negative
+// input may produce one.
+type ruleTests struct {
+	Positive []ruleTestCase
+	Negative []string
+}
+
+type ruleTestCase struct {
+	Input string
+	Value string
+}
+
+// ruleFilters are the match validators a rule file can name. They are the
+// ones the built-in rules use.
+var ruleFilters = map[string]func(string) bool{
+	"credential": credentialValueFilter,
+	"ipv6":       validIPv6Match,
+	"path":       validPathMatch,
+}
+
+// ruleContextFilters are the context-aware validators a rule file can name.
+var ruleContextFilters = map[string]func(data []byte, start, end int) bool{
+	"path": pathNotRegexLiteral,
+	"ipv4": func(data []byte, start, end int) bool { return !ipv4InNumericContext(data, start, end) },
+}
+
+// versionedRuleFileRe recognises a versioned rule file by its top-level
+// version key. Anything else is read as the legacy flat name: regex map.
+var versionedRuleFileRe = regexp.MustCompile(`(?m)^(?:version|"version"|'version')\s*:`)
+
+// readRulePath reads a rule file, or every .yaml, .yml, .toml and .json file
+// under a directory in lexical order. Files in a directory that fail to parse
+// are reported and skipped so that one unsupported template does not sink a
+// whole pack; a rule that fails to compile is still an error.
+func readRulePath(path string) ([]ruleSpec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readRuleFile(path)
+	}
+	var specs []ruleSpec
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".toml", ".json":
+		default:
+			return nil
+		}
+		s, err := readRuleFile(p)
+		if err != nil {
+			vlog(1, "rules: skipping %v", err)
+			return nil
+		}
+		specs = append(specs, s...)
+		return nil
+	})
+	return specs, err
+}
+
+// readRuleFile reads a rule file in any supported format: a gitleaks config
+// (.toml), a nuclei template (top-level id and info), a trufflehog config
+// (top-level detectors), a versioned rule file in YAML or JSON, or the legacy
+// flat mapping, which keeps its historical parsing.
+func readRuleFile(path string) ([]ruleSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return readGitleaksConfig(path, data)
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return decodeRuleFile(path, doc)
+	}
+	if versionedRuleFileRe.Match(data) {
+		doc, err := parseYAMLSubset(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return decodeRuleFile(path, doc)
+	}
+	if isNucleiTemplate(data) {
+		return readNucleiTemplate(path, data)
+	}
+	if isTrufflehogConfig(data) {
+		return readTrufflehogConfig(path, data)
+	}
+	flat, err := parseSimpleYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]ruleSpec, 0, len(flat))
+	for name, pat := range flat {
+		specs = append(specs, ruleSpec{ID: name, Regex: pat, Severity: SeverityInfo})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+	return specs, nil
+}
+
+// decodeRuleFile validates a parsed versioned rule file. Unknown fields are
+// errors so that a misspelt key in a shared rule pack fails loudly instead of
+// silently dropping a constraint.
+func decodeRuleFile(path string, doc any) ([]ruleSpec, error) {
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a mapping with version and rules", path)
+	}
+	if err := checkRuleFields(top, "version", "rules"); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	version, err := ruleInt(top["version"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: version: %w", path, err)
+	}
+	if version < 1 || version > RuleFileVersion {
+		return nil, fmt.Errorf("%s: unsupported rule file version %d (this build reads up to %d)", path, version, RuleFileVersion)
+	}
+	list, ok := top["rules"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: rules must be a list", path)
+	}
+	specs := make([]ruleSpec, 0, len(list))
+	seen := make(map[string]bool)
+	for i, raw := range list {
+		spec, err := decodeRuleSpec(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+		if seen[spec.ID] {
+			return nil, fmt.Errorf("%s: rule %d: duplicate id %q", path, i+1, spec.ID)
+		}
+		seen[spec.ID] = true
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func decodeRuleSpec(raw any) (ruleSpec, error) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return ruleSpec{}, errors.New("expected a mapping")
+	}
+	spec := ruleSpec{Severity: SeverityInfo}
+	var err error
+	if spec.ID, err = ruleString(m["id"]); err != nil || spec.ID == "" || strings.ContainsAny(spec.ID, " \t") {
+		return spec, errors.New("id must be a non-empty name without spaces")
+	}
+	wrap := func(field string, err error) error { return fmt.Errorf("%s: %s: %w", spec.ID, field, err) }
+	if err := checkRuleFields(m, "id", "description", "regex", "severity", "group", "keywords",
+		"entropy", "allow", "deny", "filter", "context_filter", "js", "tests"); err != nil {
+		return spec, fmt.Errorf("%s: %w", spec.ID, err)
+	}
+	if spec.Description, err = ruleString(m["description"]); err != nil {
+		return spec, wrap("description", err)
+	}
+	if spec.Regex, err = ruleString(m["regex"]); err != nil || spec.Regex == "" {
+		return spec, wrap("regex", errors.New("a regular expression is required"))
+	}
+	if v, ok := m["severity"]; ok {
+		if spec.Severity, err = ruleString(v); err != nil || severityRank(spec.Severity) == 0 {
+			return spec, wrap("severity", errors.New("must be high, medium, low or info"))
+		}
+		spec.Severity = strings.ToLower(strings.TrimSpace(spec.Severity))
+	}
+	if spec.Group, err = ruleInt(m["group"]); err != nil {
+		return spec, wrap("group", err)
+	}
+	if spec.Keywords, err = ruleStrings(m["keywords"]); err != nil {
+		return spec, wrap("keywords", err)
+	}
+	if spec.Entropy, err = ruleFloat(m["entropy"]); err != nil {
+		return spec, wrap("entropy", err)
+	}
+	if spec.Allow, err = ruleStrings(m["allow"]); err != nil {
+		return spec, wrap("allow", err)
+	}
+	if spec.Deny, err = ruleStrings(m["deny"]); err != nil {
+		return spec, wrap("deny", err)
+	}
+	if spec.Filter, err = ruleString(m["filter"]); err != nil {
+		return spec, wrap("filter", err)
+	}
+	if spec.ContextFilter, err = ruleString(m["context_filter"]); err != nil {
+		return spec, wrap("context_filter", err)
+	}
+	if spec.JS, err = ruleBool(m["js"]); err != nil {
+		return spec, wrap("js", err)
+	}
+	if spec.Tests, err = decodeRuleTests(m["tests"]); err != nil {
+		return spec, wrap("tests", err)
+	}
+	return spec, nil
+}
+
+func decodeRuleTests(raw any) (ruleTests, error) {
+	var t ruleTests
+	if raw == nil {
+		return t, nil
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return t, errors.New("expected a mapping with positive and negative lists")
+	}
+	if err := checkRuleFields(m, "positive", "negative"); err != nil {
+		return t, err
+	}
+	if m["positive"] != nil {
+		list, ok := m["positive"].([]any)
+		if !ok {
+			return t, errors.New("positive must be a list")
+		}
+		for i, item := range list {
+			// An entry is either the input itself or {input, value}, where value is
+			// the match value the input must produce.
+			if fields, ok := item.(map[string]any); ok {
+				if err := checkRuleFields(fields, "input", "value"); err != nil {
+					return t, fmt.Errorf("positive %d: %w", i+1, err)
+				}
+				in, err1 := ruleString(fields["input"])
+				val, err2 := ruleString(fields["value"])
+				if err := errors.Join(err1, err2); err != nil || in == "" {
+					return t, fmt.Errorf("positive %d: needs a string input", i+1)
+				}
+				t.Positive = append(t.Positive, ruleTestCase{Input: in, Value: val})
+				continue
+			}
+			in, err := ruleString(item)
+			if err != nil || in == "" {
+				return t, fmt.Errorf("positive %d: needs a string input", i+1)
+			}
+			t.Positive = append(t.Positive, ruleTestCase{Input: in})
+		}
+	}
+	var err error
+	if t.Negative, err = ruleStrings(m["negative"]); err != nil {
+		return t, fmt.Errorf("negative: %w", err)
+	}
+	return t, nil
+}
+
+func checkRuleFields(m map[string]any, known ...string) error {
+	var unknown []string
+	for k := range m {
+		found := false
+		for _, n := range known {
+			if k == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown field %q", unknown[0])
+}
+
+// The rule* helpers convert parsed values. YAML scalars arrive as strings,
+// JSON ones as strings, json.Number or bool, and TOML ones typed; nil is the
+// zero value.
+
+func ruleString(v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case json.Number:
+		return x.String(), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	}
+	return "", errors.New("expected a string")
+}
+
+func ruleStrings(v any) ([]string, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			s, err := ruleString(item)
+			if err != nil {
+				return nil, errors.New("expected a list of strings")
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	s, err := ruleString(v)
+	if err != nil {
+		return nil, errors.New("expected a list of strings")
+	}
+	return []string{s}, nil
+}
+
+func ruleInt(v any) (int, error) {
+	s, err := ruleString(v)
+	if err != nil || s == "" {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a non-negative integer, got %q", s)
+	}
+	return n, nil
+}
+
+func ruleFloat(v any) (float64, error) {
+	s, err := ruleString(v)
+	if err != nil || s == "" {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("expected a non-negative number, got %q", s)
+	}
+	return f, nil
+}
+
+func ruleBool(v any) (bool, error) {
+	s, err := ruleString(v)
+	if err != nil || s == "" {
+		return false, err
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("expected true or false, got %q", s)
+	}
+	return b, nil
+}
+
+// compile builds the RegexRule a spec describes.
+func (spec ruleSpec) compile() (RegexRule, error) {
+	re, err := regexp.Compile(spec.Regex)
+	if err != nil {
+		return RegexRule{}, fmt.Errorf("%s: %w", spec.ID, err)
+	}
+	if spec.Group > re.NumSubexp() {
+		return RegexRule{}, fmt.Errorf("%s: group %d but the regex has %d", spec.ID, spec.Group, re.NumSubexp())
+	}
+	r := RegexRule{
+		Name:       spec.ID,
+		RE:         re,
+		Severity:   spec.Severity,
+		Secret:     true,
+		Group:      spec.Group,
+		MinEntropy: spec.Entropy,
+		prefilters: requiredLiterals(spec.Regex),
+	}
+	for _, k := range spec.Keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			r.Keywords = append(r.Keywords, k)
+		}
+	}
+	if r.Allow, err = compileValueREs(spec.Allow); err != nil {
+		return RegexRule{}, fmt.Errorf("%s: allow: %w", spec.ID, err)
+	}
+	if r.Deny, err = compileValueREs(spec.Deny); err != nil {
+		return RegexRule{}, fmt.Errorf("%s: deny: %w", spec.ID, err)
+	}
+	if spec.Filter != "" {
+		if r.Filter = ruleFilters[spec.Filter]; r.Filter == nil {
+			return RegexRule{}, fmt.Errorf("%s: unknown filter %q", spec.ID, spec.Filter)
+		}
+	}
+	if spec.ContextFilter != "" {
+		if r.ContextFilter = ruleContextFilters[spec.ContextFilter]; r.ContextFilter == nil {
+			return RegexRule{}, fmt.Errorf("%s: unknown context_filter %q", spec.ID, spec.ContextFilter)
+		}
+	}
+	if len(spec.allowlists) > 0 {
+		lists := make([]compiledAllowlist, 0, len(spec.allowlists))
+		for _, a := range spec.allowlists {
+			c, err := a.compile()
+			if err != nil {
+				return RegexRule{}, fmt.Errorf("%s: allowlist: %w", spec.ID, err)
+			}
+			lists = append(lists, c)
+		}
+		r.Exclude = func(data []byte, start, end int, value string) bool {
+			for _, a := range lists {
+				if a.excludes(data, start, end, value) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return r, nil
+}
+
+func compileValueREs(pats []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, pat := range pats {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// RuleTestResult is the outcome of one rule's embedded self-tests.
+type RuleTestResult struct {
+	Rule     string
+	Tests    int
+	Failures []string
+}
+
+// RunRuleTests compiles every rule in a rule file or directory and runs its embedded
+// positive and negative tests. Inputs are scanned line by line, as the
+// scanner reads files. An error means the file itself could not be loaded.
+func RunRuleTests(path string) ([]RuleTestResult, error) {
+	specs, err := readRulePath(path)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]RuleTestResult, 0, len(specs))
+	for _, spec := range specs {
+		r, err := spec.compile()
+		if err != nil {
+			return nil, err
+		}
+		res := RuleTestResult{Rule: spec.ID, Tests: len(spec.Tests.Positive) + len(spec.Tests.Negative)}
+		for i, tc := range spec.Tests.Positive {
+			ms := findByLine(r, tc.Input)
+			switch {
+			case len(ms) == 0:
+				res.Failures = append(res.Failures, fmt.Sprintf("positive %d: no match in %q", i+1, tc.Input))
+			case tc.Value != "" && !hasMatchValue(ms, tc.Value):
+				res.Failures = append(res.Failures, fmt.Sprintf("positive %d: matched %q, want %q", i+1, ms[0].Value, tc.Value))
+			}
+		}
+		for i, in := range spec.Tests.Negative {
+			if ms := findByLine(r, in); len(ms) > 0 {
+				res.Failures = append(res.Failures, fmt.Sprintf("negative %d: unexpected match %q in %q", i+1, ms[0].Value, in))
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func findByLine(r Rule, input string) []Match {
+	var out []Match
+	sc := bufio.NewScanner(strings.NewReader(input))
+	sc.Buffer(make([]byte, 0, InitialBufferSize), MaxBufferSize)
+	for sc.Scan() {
+		out = append(out, r.Find(sc.Bytes())...)
+	}
+	return out
+}
+
+func hasMatchValue(ms []Match, value string) bool {
+	for _, m := range ms {
+		if m.Value == value {
+			return true
+		}
+	}
+	return false
+}
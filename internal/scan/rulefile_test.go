@@ -0,0 +1,157 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRuleFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadVersionedRulesFile(t *testing.T) {
+	p := writeRuleFile(t, "rules.yaml", `version: 1
+rules:
+  - id: acme_key
+    regex: 'acme_key\s*=\s*"([A-Za-z0-9]{12,})"'
+    severity: High
+    group: 1
+    keywords: [ACME_KEY]
+    entropy: 3
+    allow: ['^EXAMPLE']
+    deny: ['^aaaaaaaaaaaaleaked$']
+    js: true
+`)
+	e := NewExtractor(true, false)
+	if err := e.LoadRulesFile(p); err != nil {
+		t.Fatal(err)
+	}
+	scan := func(src string) []Match {
+		ms, err := e.ScanReader("app.js", strings.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []Match
+		for _, m := range ms {
+			if m.Pattern == "acme_key" {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	ms := scan(`acme_key = "Qz81mXv2Lp9T"`)
+	if len(ms) != 1 || ms[0].Value != "Qz81mXv2Lp9T" || ms[0].Severity != SeverityHigh {
+		t.Errorf("capture group/severity not applied (js rule must run in safe mode): %+v", ms)
+	}
+	if ms := scan(`acme_key = "aaaaaaaaaaaaaaaa"`); len(ms) != 0 {
+		t.Errorf("low-entropy value was reported: %+v", ms)
+	}
+	if ms := scan(`acme_key = "EXAMPLE9x8c7v6b"`); len(ms) != 0 {
+		t.Errorf("allowed value was reported: %+v", ms)
+	}
+	if ms := scan(`acme_key = "aaaaaaaaaaaaleaked"`); len(ms) != 1 {
+		t.Errorf("denied value must be reported despite low entropy: %+v", ms)
+	}
+}
+
+func TestRegexRuleKeywordsGateTheRule(t *testing.T) {
+	r, err := ruleSpec{ID: "k", Regex: `[0-9a-f]{8}`, Severity: SeverityInfo, Keywords: []string{"Build"}}.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := r.Find([]byte("id deadbeef")); len(ms) != 0 {
+		t.Errorf("rule ran without its keyword: %+v", ms)
+	}
+	if ms := r.Find([]byte("BUILD deadbeef")); len(ms) != 1 {
+		t.Errorf("keyword match must ignore case: %+v", ms)
+	}
+}
+
+func TestLoadVersionedRulesFileJSON(t *testing.T) {
+	p := writeRuleFile(t, "rules.json", `{"version": 1, "rules": [
+		{"id": "pw", "regex": "(?i)svc_pw\\s*=\\s*\\S+", "filter": "credential", "severity": "medium"}
+	]}`)
+	e := NewExtractor(false, false)
+	if err := e.LoadRulesFile(p); err != nil {
+		t.Fatal(err)
+	}
+	ms, _ := e.ScanReader("f.txt", strings.NewReader("svc_pw = Zx81Lm2Qv9\nsvc_pw = !0"))
+	n := 0
+	for _, m := range ms {
+		if m.Pattern == "pw" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("credential filter: got %d pw matches in %+v", n, ms)
+	}
+}
+
+func TestLoadVersionedRulesFileErrors(t *testing.T) {
+	cases := map[string]string{
+		"future version": "version: 2\nrules: []\n",
+		"unknown field":  "version: 1\nrules:\n  - id: a\n    regex: x\n    severty: high\n",
+		"missing regex":  "version: 1\nrules:\n  - id: a\n",
+		"bad severity":   "version: 1\nrules:\n  - id: a\n    regex: x\n    severity: urgent\n",
+		"bad group":      "version: 1\nrules:\n  - id: a\n    regex: x\n    group: 1\n",
+		"bad filter":     "version: 1\nrules:\n  - id: a\n    regex: x\n    filter: nope\n",
+		"bad allow":      "version: 1\nrules:\n  - id: a\n    regex: x\n    allow: ['(']\n",
+		"duplicate id":   "version: 1\nrules:\n  - id: a\n    regex: x\n  - id: a\n    regex: y\n",
+	}
+	for name, src := range cases {
+		p := writeRuleFile(t, "rules.yaml", src)
+		if err := NewExtractor(false, false).LoadRulesFile(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRunRuleTests(t *testing.T) {
+	p := writeRuleFile(t, "rules.yaml", `version: 1
+rules:
+  - id: tok
+    regex: 'tok_([a-z0-9]{6})'
+    group: 1
+    tests:
+      positive:
+        - 'x tok_abc123 y'
+        - input: 'tok_zzz999'
+          value: wrong
+      negative:
+        - 'tok_ABCDEF'
+        - 'tok_abcdef'
+  - id: quiet
+    regex: 'q'
+`)
+	res, err := RunRuleTests(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Rule != "tok" || res[0].Tests != 4 || res[1].Tests != 0 {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if len(res[0].Failures) != 2 ||
+		!strings.Contains(res[0].Failures[0], "positive 2") ||
+		!strings.Contains(res[0].Failures[1], "negative 2") {
+		t.Errorf("failures: %q", res[0].Failures)
+	}
+}
+
+func TestExampleRulePackPasses(t *testing.T) {
+	res, err := RunRuleTests(filepath.Join("..", "..", "examples", "rules-v1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range res {
+		if len(r.Failures) > 0 || r.Tests == 0 {
+			t.Errorf("%s: %d tests, failures %q", r.Rule, r.Tests, r.Failures)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOMLSubset parses the TOML that gitleaks configs are written in into
+// plain values: map[string]any for tables, []any for arrays, and string,
+// int64, float64 or bool for scalars. It supports [table] and [[array.of]]
+// headers (dotted, with the array's last element as the parent, as TOML
+// defines), key = value pairs with bare or quoted keys, the four string
+// forms including both multi-line ones, numbers, booleans,
+// multi-line arrays and one-line inline tables. Dates and dotted keys on the
+// left of "=" are not supported.
+func parseTOMLSubset(data []byte) (map[string]any, error) {
+	t := &tomlParser{src: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	root := make(map[string]any)
+	cur := root
+	for {
+		t.skipSpaceAndComments()
+		if t.eof() {
+			return root, nil
+		}
+		if t.peek() == '[' {
+			table, err := t.parseHeader(root)
+			if err != nil {
+				return nil, err
+			}
+			cur = table
+			continue
+		}
+		key, err := t.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		t.skipBlanks()
+		if t.eof() || t.peek() != '=' {
+			return nil, t.errorf("expected = after %q", key)
+		}
+		t.pos++
+		t.skipBlanks()
+		v, err := t.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := cur[key]; dup {
+			return nil, t.errorf("duplicate key %q", key)
+		}
+		cur[key] = v
+		if err := t.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (t *tomlParser) eof() bool  { return t.pos >= len(t.src) }
+func (t *tomlParser) peek() byte { return t.src[t.pos] }
+
+func (t *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+// skipBlanks skips spaces and tabs on the current line.
+func (t *tomlParser) skipBlanks() {
+	for !t.eof() && (t.peek() == ' ' || t.peek() == '\t') {
+		t.pos++
+	}
+}
+
+// skipSpaceAndComments skips whitespace, newlines and comments.
+func (t *tomlParser) skipSpaceAndComments() {
+	for !t.eof() {
+		switch t.peek() {
+		case ' ', '\t', '\r':
+			t.pos++
+		case '\n':
+			t.pos++
+			t.line++
+		case '#':
+			for !t.eof() && t.peek() != '\n' {
+				t.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine requires the rest of the line to be blank or a comment.
+func (t *tomlParser) endOfLine() error {
+	t.skipBlanks()
+	if t.eof() || t.peek() == '\n' || t.peek() == '#' {
+		return nil
+	}
+	return t.errorf("unexpected %q after value", t.peek())
+}
+
+// parseHeader reads a [table] or [[array]] header and returns the table that
+// following keys belong to.
+func (t *tomlParser) parseHeader(root map[string]any) (map[string]any, error) {
+	array := strings.HasPrefix(t.src[t.pos:], "[[")
+	if array {
+		t.pos += 2
+	} else {
+		t.pos++
+	}
+	var path []string
+	for {
+		t.skipBlanks()
+		key, err := t.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+		t.skipBlanks()
+		if !t.eof() && t.peek() == '.' {
+			t.pos++
+			continue
+		}
+		break
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(t.src[t.pos:], closing) {
+		return nil, t.errorf("unterminated table header")
+	}
+	t.pos += len(closing)
+	if err := t.endOfLine(); err != nil {
+		return nil, err
+	}
+
+	cur := root
+	for i, key := range path {
+		last := i == len(path)-1
+		switch existing := cur[key].(type) {
+		case nil:
+			if last && array {
+				tbl := make(map[string]any)
+				cur[key] = []any{tbl}
+				return tbl, nil
+			}
+			tbl := make(map[string]any)
+			cur[key] = tbl
+			cur = tbl
+		case map[string]any:
+			if last && array {
+				return nil, t.errorf("%q is a table, not an array of tables", strings.Join(path, "."))
+			}
+			cur = existing
+		case []any:
+			if last && array {
+				tbl := make(map[string]any)
+				cur[key] = append(existing, tbl)
+				return tbl, nil
+			}
+			// A dotted header through an array of tables descends into its most
+			// recent element: [rules.allowlist] follows the last [[rules]].
+			tbl, ok := existing[len(existing)-1].(map[string]any)
+			if !ok {
+				return nil, t.errorf("%q is not a table", key)
+			}
+			cur = tbl
+		default:
+			return nil, t.errorf("%q is already a value", key)
+		}
+	}
+	return cur, nil
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (t *tomlParser) parseKey() (string, error) {
+	if t.eof() {
+		return "", t.errorf("expected a key")
+	}
+	if c := t.peek(); c == '"' || c == '\'' {
+		v, err := t.parseString()
+		if err != nil {
+			return "", err
+		}
+		return v, nil
+	}
+	start := t.pos
+	for !t.eof() && isTOMLBareKeyChar(t.peek()) {
+		t.pos++
+	}
+	if t.pos == start {
+		return "", t.errorf("expected a key, found %q", t.peek())
+	}
+	return t.src[start:t.pos], nil
+}
+
+func (t *tomlParser) parseValue() (any, error) {
+	if t.eof() {
+		return nil, t.errorf("expected a value")
+	}
+	switch c := t.peek(); {
+	case c == '"' || c == '\'':
+		return t.parseString()
+	case c == '[':
+		return t.parseArray()
+	case c == '{':
+		return t.parseInlineTable()
+	}
+	start := t.pos
+	for !t.eof() && !strings.ContainsRune(" \t\n#,]}", rune(t.peek())) {
+		t.pos++
+	}
+	word := t.src[start:t.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	clean := strings.ReplaceAll(word, "_", "")
+	if n, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, nil
+	}
+	return nil, t.errorf("unsupported value %q", word)
+}
+
+// parseString reads any of TOML's four string forms.
+func (t *tomlParser) parseString() (string, error) {
+	rest := t.src[t.pos:]
+	switch {
+	case strings.HasPrefix(rest, "'''"):
+		end := strings.Index(rest[3:], "'''")
+		if end < 0 {
+			return "", t.errorf("unterminated ''' string")
+		}
+		// Up to two quotes may directly precede the closing delimiter.
+		for n := 0; n < 2 && 3+end+3 < len(rest) && rest[3+end+3] == '\''; n++ {
+			end++
+		}
+		body := rest[3 : 3+end]
+		t.advance(3 + end + 3)
+		return strings.TrimPrefix(body, "\n"), nil
+	case strings.HasPrefix(rest, `"""`):
+		i := 3
+		for i < len(rest) {
+			if rest[i] == '\\' {
+				i += 2
+				continue
+			}
+			if strings.HasPrefix(rest[i:], `"""`) {
+				break
+			}
+			i++
+		}
+		if i >= len(rest) {
+			return "", t.errorf(`unterminated """ string`)
+		}
+		for n := 0; n < 2 && i+3 < len(rest) && rest[i+3] == '"'; n++ {
+			i++
+		}
+		body := strings.TrimPrefix(rest[3:i], "\n")
+		t.advance(i + 3)
+		// A backslash at the end of a line trims the newline and the
+		// whitespace that follows it.
+		var b strings.Builder
+		for j := 0; j < len(body); j++ {
+			if body[j] == '\\' {
+				k := j + 1
+				for k < len(body) && (body[k] == ' ' || body[k] == '\t') {
+					k++
+				}
+				if k < len(body) && body[k] == '\n' {
+					j = k
+					for j+1 < len(body) && strings.ContainsRune(" \t\n", rune(body[j+1])) {
+						j++
+					}
+					continue
+				}
+				if j+1 < len(body) {
+					// Keep an ordinary escape whole for unescapeTOML.
+					b.WriteByte(body[j])
+					j++
+				}
+			}
+			b.WriteByte(body[j])
+		}
+		return unescapeTOML(b.String(), t)
+	case rest[0] == '\'':
+		end := strings.IndexAny(rest[1:], "'\n")
+		if end < 0 || rest[1+end] != '\'' {
+			return "", t.errorf("unterminated ' string")
+		}
+		t.pos += end + 2
+		return rest[1 : 1+end], nil
+	}
+	i := 1
+	for i < len(rest) && rest[i] != '"' && rest[i] != '\n' {
+		if rest[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(rest) || rest[i] != '"' {
+		return "", t.errorf(`unterminated " string`)
+	}
+	t.pos += i + 1
+	return unescapeTOML(rest[1:i], t)
+}
+
+// advance moves past n bytes, counting the newlines in them.
+func (t *tomlParser) advance(n int) {
+	t.line += strings.Count(t.src[t.pos:t.pos+n], "\n")
+	t.pos += n
+}
+
+// unescapeTOML decodes the escapes of a basic string. TOML shares them with
+// double-quoted YAML.
+func unescapeTOML(s string, t *tomlParser) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	v, err := unquoteYAML(`"` + s + `"`)
+	if err != nil {
+		return "", t.errorf("%v", err)
+	}
+	return v, nil
+}
+
+func (t *tomlParser) parseArray() ([]any, error) {
+	t.pos++
+	out := []any{}
+	for {
+		t.skipSpaceAndComments()
+		if t.eof() {
+			return nil, t.errorf("unterminated array")
+		}
+		if t.peek() == ']' {
+			t.pos++
+			return out, nil
+		}
+		v, err := t.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		t.skipSpaceAndComments()
+		if t.eof() {
+			return nil, t.errorf("unterminated array")
+		}
+		switch t.peek() {
+		case ',':
+			t.pos++
+		case ']':
+		default:
+			return nil, t.errorf("expected , or ] in array, found %q", t.peek())
+		}
+	}
+}
+
+func (t *tomlParser) parseInlineTable() (map[string]any, error) {
+	t.pos++
+	out := make(map[string]any)
+	for {
+		t.skipBlanks()
+		if t.eof() || t.peek() == '\n' {
+			return nil, t.errorf("unterminated inline table")
+		}
+		if t.peek() == '}' {
+			t.pos++
+			return out, nil
+		}
+		key, err := t.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		t.skipBlanks()
+		if t.eof() || t.peek() != '=' {
+			return nil, t.errorf("expected = after %q", key)
+		}
+		t.pos++
+		t.skipBlanks()
+		v, err := t.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+		t.skipBlanks()
+		if !t.eof() && t.peek() == ',' {
+			t.pos++
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOMLSubset(t *testing.T) {
+	src := `title = "gitleaks config" # comment
+[allowlist]
+paths = ['''vendor/''']
+
+[[rules]]
+id = "aws"
+regex = '''(?i)\b(AKIA[0-9A-Z]{16})\b'''
+secretGroup = 1
+entropy = 3.5
+keywords = [
+  "akia", # trailing comment
+  'asia',
+]
+[rules.allowlist]
+regexTarget = "line"
+stopwords = ["example"]
+
+[[rules]]
+id = "multi"
+regex = """
+a\tb\
+    c"""
+tags = []
+inline = { on = true, n = 1_000 }
+`
+	got, err := parseTOMLSubset([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"title":     "gitleaks config",
+		"allowlist": map[string]any{"paths": []any{"vendor/"}},
+		"rules": []any{
+			map[string]any{
+				"id":          "aws",
+				"regex":       `(?i)\b(AKIA[0-9A-Z]{16})\b`,
+				"secretGroup": int64(1),
+				"entropy":     3.5,
+				"keywords":    []any{"akia", "asia"},
+				"allowlist":   map[string]any{"regexTarget": "line", "stopwords": []any{"example"}},
+			},
+			map[string]any{
+				"id":     "multi",
+				"regex":  "a\tbc",
+				"tags":   []any{},
+				"inline": map[string]any{"on": true, "n": int64(1000)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseTOMLSubsetErrors(t *testing.T) {
+	cases := map[string]string{
+		"missing equals":      "a 1\n",
+		"duplicate key":       "a = 1\na = 2\n",
+		"unterminated string": "a = \"x\n",
+		"unterminated array":  "a = [1, 2\n",
+		"trailing garbage":    "a = 1 2\n",
+		"table then array":    "[a]\n[[a]]\n",
+		"date":                "d = 1979-05-27\n",
+	}
+	for name, src := range cases {
+		if _, err := parseTOMLSubset([]byte(src)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package scan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAMLSubset parses the block-style YAML that rule files are written in
+// into plain values: map[string]any for mappings, []any for sequences, string
+// for scalars and nil for empty values. It supports indented mappings and
+// "- " sequences (including sequences of mappings), plain, single- and
+// double-quoted scalars (which may continue over several lines), one-line
+// [a, b] lists, literal and folded block scalars (|, |-, > and >-) and #
+// comments. Anchors, tags, flow mappings and multi-document streams
+// are not supported. Scalars are never typed; the rule decoder converts them.
+func parseYAMLSubset(data []byte) (any, error) {
+	p := &yamlParser{raw: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	line, ok, err := p.peek()
+	if err != nil || !ok {
+		return nil, err
+	}
+	v, err := p.parseNode(line.indent)
+	if err != nil {
+		return nil, err
+	}
+	if line, ok, err := p.peek(); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+	}
+	return v, nil
+}
+
+// yamlLine is one significant line: its 1-based number, indentation and text
+// without the indentation.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	raw []string
+	pos int
+}
+
+// peek returns the next line that is not blank, a comment or a document
+// marker, without consuming it.
+func (p *yamlParser) peek() (yamlLine, bool, error) {
+	for ; p.pos < len(p.raw); p.pos++ {
+		s := strings.TrimRight(p.raw[p.pos], " \t")
+		t := strings.TrimLeft(s, " ")
+		if t == "" || t[0] == '#' || (t == "---" && len(s) == 3) {
+			continue
+		}
+		if t[0] == '\t' {
+			return yamlLine{}, false, fmt.Errorf("line %d: tabs are not allowed in indentation", p.pos+1)
+		}
+		return yamlLine{num: p.pos + 1, indent: len(s) - len(t), text: t}, true, nil
+	}
+	return yamlLine{}, false, nil
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseNode parses the mapping or sequence starting at the next line.
+func (p *yamlParser) parseNode(indent int) (any, error) {
+	line, ok, err := p.peek()
+	if err != nil || !ok {
+		return nil, err
+	}
+	if isYAMLSeqItem(line.text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for {
+		line, ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || line.indent < indent {
+			return m, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+		v, err := p.parseValue(indent, rest, line.num)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+// parseValue parses what follows "key:" on a line of a mapping at indent: an
+// inline scalar, a block scalar, or a nested node on the following lines. A
+// sequence may sit at the key's own indentation, as YAML allows.
+func (p *yamlParser) parseValue(indent int, rest string, num int) (any, error) {
+	switch rest {
+	case "":
+		next, ok, err := p.peek()
+		if err != nil || !ok {
+			return nil, err
+		}
+		if next.indent > indent {
+			return p.parseNode(next.indent)
+		}
+		if next.indent == indent && isYAMLSeqItem(next.text) {
+			return p.parseSeq(indent)
+		}
+		return nil, nil
+	case "|", "|-", ">", ">-":
+		return p.parseBlockScalar(indent, rest), nil
+	}
+	if rest[0] == '|' || rest[0] == '>' {
+		return nil, fmt.Errorf("line %d: block scalar %q is not supported", num, rest)
+	}
+	v, err := parseYAMLScalar(p.continueScalar(indent, rest))
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", num, err)
+	}
+	return v, nil
+}
+
+// parseBlockScalar reads the lines of a block scalar, which are those indented
+// deeper than its key. A literal (|) block keeps its line breaks; a folded (>)
+// one joins lines with spaces, keeping breaks only at blank lines. The - form
+// strips the trailing newline the plain form keeps.
+func (p *yamlParser) parseBlockScalar(indent int, style string) string {
+	var lines []string
+	block := -1
+	for ; p.pos < len(p.raw); p.pos++ {
+		s := p.raw[p.pos]
+		if strings.TrimSpace(s) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(s) - len(strings.TrimLeft(s, " "))
+		if n <= indent || (block >= 0 && n < block) {
+			break
+		}
+		if block < 0 {
+			block = n
+		}
+		lines = append(lines, s[block:])
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var text string
+	if strings.HasPrefix(style, ">") {
+		var b strings.Builder
+		for i, l := range lines {
+			switch {
+			case l == "":
+				b.WriteByte('\n')
+			case i > 0 && lines[i-1] != "":
+				b.WriteByte(' ')
+				b.WriteString(l)
+			default:
+				b.WriteString(l)
+			}
+		}
+		text = b.String()
+	} else {
+		text = strings.Join(lines, "\n")
+	}
+	if !strings.HasSuffix(style, "-") && text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+// continueScalar folds the continuation lines of a multi-line flow scalar
+// into s: for a quoted scalar, the lines up to its closing quote; for a plain
+// one, the following lines indented deeper than indent. A deeper line that
+// reads as a key is left to fail as bad indentation, as YAML requires.
+func (p *yamlParser) continueScalar(indent int, s string) string {
+	quoted := s[0] == '"' || s[0] == '\''
+	if !quoted && (s[0] == '[' || s[0] == '{') {
+		return s
+	}
+	for p.pos < len(p.raw) && !(quoted && yamlClosingQuote(s) >= 0) {
+		raw := strings.TrimRight(p.raw[p.pos], " \t")
+		t := strings.TrimLeft(raw, " ")
+		switch {
+		case t == "" && quoted:
+			s += "\n"
+		case t == "" || t[0] == '#' || len(raw)-len(t) <= indent || isYAMLMapEntry(t):
+			if !quoted {
+				return s
+			}
+			fallthrough
+		default:
+			if !strings.HasSuffix(s, "\n") {
+				s += " "
+			}
+			s += t
+		}
+		p.pos++
+	}
+	return s
+}
+
+func (p *yamlParser) parseSeq(indent int) ([]any, error) {
+	out := []any{}
+	for {
+		line, ok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || line.indent < indent || !isYAMLSeqItem(line.text) {
+			return out, nil
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		item := strings.TrimLeft(line.text[1:], " ")
+		col := line.indent + len(line.text) - len(item)
+		switch {
+		case item == "":
+			p.pos++
+			next, ok, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if ok && next.indent > indent {
+				v, err := p.parseNode(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			} else {
+				out = append(out, nil)
+			}
+		case isYAMLMapEntry(item):
+			// Re-read the item as the first key of a mapping indented to the
+			// item's column, which is where its remaining keys line up.
+			p.raw[p.pos] = strings.Repeat(" ", col) + item
+			v, err := p.parseMap(col)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		case item == "|" || item == "|-" || item == ">" || item == ">-":
+			p.pos++
+			out = append(out, p.parseBlockScalar(indent, item))
+		default:
+			p.pos++
+			v, err := parseYAMLScalar(p.continueScalar(indent, item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.num, err)
+			}
+			out = append(out, v)
+		}
+	}
+}
+
+func isYAMLMapEntry(text string) bool {
+	_, _, ok := splitYAMLKey(text)
+	return ok
+}
+
+// splitYAMLKey splits "key: rest" into its key and the trimmed rest. The key
+// may be quoted.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := yamlClosingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		k, err := unquoteYAML(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		after := text[end+1:]
+		switch {
+		case after == ":":
+			return k, "", true
+		case strings.HasPrefix(after, ": "):
+			return k, strings.TrimSpace(after[2:]), true
+		}
+		return "", "", false
+	}
+	if text[0] == '[' || text[0] == '{' || text[0] == '#' {
+		return "", "", false
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		i = len(text) - 1
+	}
+	if c := strings.Index(text, " #"); c >= 0 && c < i {
+		return "", "", false
+	}
+	key = strings.TrimSpace(text[:i])
+	if key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(text[i+1:]), true
+}
+
+// parseYAMLScalar parses an inline value: a quoted or plain scalar, or a
+// one-line [a, b] list of them. "~" and "null" are nil.
+func parseYAMLScalar(s string) (any, error) {
+	switch s[0] {
+	case '"', '\'':
+		end := yamlClosingQuote(s)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		if tail := strings.TrimSpace(s[end+1:]); tail != "" && tail[0] != '#' {
+			return nil, fmt.Errorf("unexpected %q after string", tail)
+		}
+		return unquoteYAML(s[:end+1])
+	case '[':
+		return parseYAMLFlowSeq(s)
+	case '{':
+		if strings.TrimSpace(s) == "{}" {
+			return map[string]any{}, nil
+		}
+		return nil, fmt.Errorf("flow mappings are not supported")
+	}
+	if c := strings.Index(s, " #"); c >= 0 {
+		s = s[:c]
+	}
+	s = strings.TrimSpace(s)
+	if s == "~" || s == "null" {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// parseYAMLFlowSeq parses a one-line [a, "b", 'c'] list of scalars.
+func parseYAMLFlowSeq(s string) ([]any, error) {
+	out := []any{}
+	start := 1
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			end := yamlClosingQuote(s[i:])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in %s", s)
+			}
+			i += end
+		case '[', '{':
+			return nil, fmt.Errorf("nested flow collections are not supported")
+		case ',', ']':
+			if item := strings.TrimSpace(s[start:i]); item != "" {
+				v, err := parseYAMLScalar(item)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			} else if s[i] == ',' {
+				return nil, fmt.Errorf("empty list item in %s", s)
+			}
+			start = i + 1
+			if s[i] == ']' {
+				if tail := strings.TrimSpace(s[i+1:]); tail != "" && tail[0] != '#' {
+					return nil, fmt.Errorf("unexpected %q after list", tail)
+				}
+				return out, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unterminated list %s", s)
+}
+
+// yamlClosingQuote returns the index of the quote closing the string that
+// starts s, or -1. Double-quoted strings escape with a backslash;
+// single-quoted ones by doubling the quote.
+func yamlClosingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q:
+			if q == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// unquoteYAML decodes a complete quoted scalar. Single-quoted strings are
+// literal apart from the doubled quote, which makes them the natural way to
+// write a regular expression.
+func unquoteYAML(s string) (string, error) {
+	body := s[1 : len(s)-1]
+	if s[0] == '\'' {
+		return strings.ReplaceAll(body, "''", "'"), nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(body) {
+			return "", fmt.Errorf("trailing backslash in %s", s)
+		}
+		switch body[i] {
+		case '0':
+			b.WriteByte(0)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 't', '\t':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			b.WriteByte(body[i])
+		case 'x', 'u', 'U':
+			n := 2
+			switch body[i] {
+			case 'u':
+				n = 4
+			case 'U':
+				n = 8
+			}
+			if i+n >= len(body) {
+				return "", fmt.Errorf("short \\%c escape in %s", body[i], s)
+			}
+			r, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\%c escape in %s", body[i], s)
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in %s (use single quotes for regular expressions)", body[i], s)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,102 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAMLSubset(t *testing.T) {
+	src := `# rule pack
+version: 1
+rules:
+  - id: a
+    regex: '\bkey_(\w+)'' x'
+    keywords: [one, "two", 'th''ree']
+    tests:
+      positive:
+      - "tab\there"
+      - input: |
+          line one
+          line two
+        value: v # trailing comment
+  - id: b
+    empty:
+    block: |-
+      kept
+`
+	got, err := parseYAMLSubset([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"version": "1",
+		"rules": []any{
+			map[string]any{
+				"id":       "a",
+				"regex":    `\bkey_(\w+)' x`,
+				"keywords": []any{"one", "two", "th'ree"},
+				"tests": map[string]any{
+					"positive": []any{
+						"tab\there",
+						map[string]any{"input": "line one\nline two\n", "value": "v"},
+					},
+				},
+			},
+			map[string]any{"id": "b", "empty": nil, "block": "kept"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAMLSubset:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAMLSubsetMultiLineScalars(t *testing.T) {
+	src := `info:
+  description: >
+    folded
+    text
+
+    para
+  summary: a plain value
+    that continues
+  quoted: "spans
+    lines"
+raw:
+  - |
+    GET / HTTP/1.1
+    Host: x
+`
+	got, err := parseYAMLSubset([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"info": map[string]any{
+			"description": "folded text\npara\n",
+			"summary":     "a plain value that continues",
+			"quoted":      "spans lines",
+		},
+		"raw": []any{"GET / HTTP/1.1\nHost: x\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAMLSubset:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAMLSubsetErrors(t *testing.T) {
+	cases := map[string]string{
+		"bad indentation":  "a: 1\n   b: 2\n",
+		"duplicate key":    "a: 1\na: 2\n",
+		"tab indentation":  "a:\n\tb: 1\n",
+		"unknown escape":   `a: "\d+"` + "\n",
+		"unterminated":     "a: 'open\n",
+		"flow mapping":     "a: {b: 1}\n",
+		"not a key":        "a: 1\njust text\n",
+		"text after quote": `a: "x" y` + "\n",
+	}
+	for name, src := range cases {
+		if _, err := parseYAMLSubset([]byte(src)); err == nil {
+			t.Errorf("%s: expected an error for %q", name, src)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"strings"
 	"testing"
 )
@@ -13,7 +14,7 @@ func TestScanReaderWithEndpointsAttachesSnippetsWhenEnabled(t *testing.T) {
 
 	e := NewExtractor(false, false)
 	e.SetSnippet(true)
-	ms, err := e.ScanReaderWithEndpoints("app.js", strings.NewReader(src))
+	ms, err := e.ScanReaderWithEndpoints(context.Background(), "app.js", strings.NewReader(src))
 	if err != nil {
 		t.Fatalf("scan failed: %v", err)
 	}
@@ -45,7 +46,7 @@ func TestScanReaderWithEndpointsNoSnippetByDefault(t *testing.T) {
 	src := `const key="AIzaSyA1234567890abcdefghijklmnopqrstuvw";`
 
 	e := NewExtractor(false, false)
-	ms, err := e.ScanReaderWithEndpoints("app.js", strings.NewReader(src))
+	ms, err := e.ScanReaderWithEndpoints(context.Background(), "app.js", strings.NewReader(src))
 	if err != nil {
 		t.Fatalf("scan failed: %v", err)
 	}
@@ -2,6 +2,7 @@ package scan
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"io"
@@ -31,14 +32,10 @@ const maxSourceMapBytes = MaxBufferSize
 // to, protecting against pathological maps with tens of thousands of entries.
 const maxRecoveredSources = 5000
 
-// Source maps frequently omit sourcesContent while listing hundreds of original
-// files. Fetch those independent files concurrently, but cap the process-wide
-// fan-out so several crawl workers recovering maps at once cannot create an
-// unbounded request burst.
+// maxSourceMapWorkers caps the concurrent original-source fetches a Client
+// allows across all the maps it is recovering (see Client.sourceMapSlots).
 const maxSourceMapWorkers = 8
 
-var sourceMapFetchSlots = make(chan struct{}, maxSourceMapWorkers)
-
 // sourceMapRefRe captures the reference that follows a sourceMappingURL
 // annotation, in either the `//#`/`//@` line-comment or `/*# ... */` block form.
 // The value runs up to the first whitespace or quote; a trailing `*/` from a
@@ -86,8 +83,9 @@ func sourceMapReference(data []byte, header http.Header) string {
 // every original source it carries, returning the matches. bundleURL is the
 // bundle's final URL, header its response headers, and visited the shared
 // fetch-dedup set. posts selects POST-endpoint scanning over secret/endpoint
-// scanning. It is a no-op (nil) when recovery is disabled or no map is found.
-func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.Header, baseHost string, external bool, visited *visitedSet, posts bool) []Match {
+// scanning. It is a no-op (nil) when recovery is disabled or no map is found,
+// and stops handing out sources once ctx is done.
+func (e *Extractor) recoverSourceMap(ctx context.Context, bundleURL string, data []byte, header http.Header, baseHost string, external bool, visited *visitedSet, posts bool) []Match {
 	if !e.recoverSourceMaps {
 		return nil
 	}
@@ -95,7 +93,7 @@ func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.
 	if ref == "" {
 		return nil
 	}
-	raw, mapURL, ok := e.loadSourceMap(ref, bundleURL, baseHost, external, visited)
+	raw, mapURL, ok := e.loadSourceMap(ctx, ref, bundleURL, baseHost, external, visited)
 	if !ok {
 		return nil
 	}
@@ -103,13 +101,17 @@ func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.
 	if err := json.Unmarshal(raw, &sm); err != nil {
 		return nil
 	}
+	var libraries []Match
+	if !posts {
+		libraries = e.scanSourceMapLibraries(bundleURL, sm)
+	}
 
 	limit := len(sm.Sources)
 	if limit > maxRecoveredSources {
 		limit = maxRecoveredSources
 	}
 	if limit == 0 {
-		return nil
+		return libraries
 	}
 
 	bySource := make([][]Match, limit)
@@ -133,9 +135,13 @@ func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.
 					// in-scope http(s) URL. Virtual paths (webpack://, ng://, …) are not
 					// fetchable and fall out at the scheme check.
 					body = func() []byte {
-						sourceMapFetchSlots <- struct{}{}
-						defer func() { <-sourceMapFetchSlots }()
-						return e.fetchOriginalSource(mapURL, sm.SourceRoot, src, baseHost, external, visited)
+						select {
+						case e.client.sourceMapSlots <- struct{}{}:
+						case <-ctx.Done():
+							return nil
+						}
+						defer func() { <-e.client.sourceMapSlots }()
+						return e.fetchOriginalSource(ctx, mapURL, sm.SourceRoot, src, baseHost, external, visited)
 					}()
 					if body == nil {
 						continue
@@ -149,13 +155,13 @@ func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.
 			}
 		}()
 	}
-	for i := 0; i < limit; i++ {
+	for i := 0; i < limit && ctx.Err() == nil; i++ {
 		jobs <- i
 	}
 	close(jobs)
 	wg.Wait()
 
-	var matches []Match
+	matches := libraries
 	for _, ms := range bySource {
 		matches = append(matches, ms...)
 	}
@@ -168,22 +174,22 @@ func (e *Extractor) recoverSourceMap(bundleURL string, data []byte, header http.
 // recording the URL in visited so a map shared by several bundles is fetched
 // once. For a data: URI the map URL is the bundle URL, since embedded relative
 // sources resolve against the bundle's location.
-func (e *Extractor) loadSourceMap(ref, bundleURL, baseHost string, external bool, visited *visitedSet) ([]byte, string, bool) {
+func (e *Extractor) loadSourceMap(ctx context.Context, ref, bundleURL, baseHost string, external bool, visited *visitedSet) ([]byte, string, bool) {
 	if strings.HasPrefix(ref, "data:") {
 		raw, ok := decodeDataURI(ref)
 		return raw, bundleURL, ok
 	}
 	abs := resolveURL(bundleURL, ref)
-	raw, ok := e.fetchInScope(abs, baseHost, external, visited)
+	raw, ok := e.fetchInScope(ctx, abs, baseHost, external, visited)
 	return raw, abs, ok
 }
 
 // fetchOriginalSource fetches the original source for a map entry when it is not
 // embedded. src is resolved against sourceRoot and the map URL; only in-scope
 // http(s) URLs are fetched. Returns nil when the source is not fetchable.
-func (e *Extractor) fetchOriginalSource(mapURL, sourceRoot, src, baseHost string, external bool, visited *visitedSet) []byte {
+func (e *Extractor) fetchOriginalSource(ctx context.Context, mapURL, sourceRoot, src, baseHost string, external bool, visited *visitedSet) []byte {
 	abs := resolveURL(mapURL, joinSourceRoot(sourceRoot, src))
-	raw, ok := e.fetchInScope(abs, baseHost, external, visited)
+	raw, ok := e.fetchInScope(ctx, abs, baseHost, external, visited)
 	if !ok {
 		return nil
 	}
@@ -193,7 +199,7 @@ func (e *Extractor) fetchOriginalSource(mapURL, sourceRoot, src, baseHost string
 // fetchInScope GETs abs when it is an http(s) URL within scope and not already
 // visited, returning the body (capped at maxSourceMapBytes). It shares the
 // crawl's fetch dedup so each URL is retrieved at most once.
-func (e *Extractor) fetchInScope(abs, baseHost string, external bool, visited *visitedSet) ([]byte, bool) {
+func (e *Extractor) fetchInScope(ctx context.Context, abs, baseHost string, external bool, visited *visitedSet) ([]byte, bool) {
 	u, err := url.Parse(abs)
 	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
 		return nil, false
@@ -204,7 +210,7 @@ func (e *Extractor) fetchInScope(abs, baseHost string, external bool, visited *v
 	if !visited.visit(abs) {
 		return nil, false
 	}
-	resp, err := fetchURLResponse(abs)
+	resp, err := e.client.fetchURLResponse(ctx, abs)
 	if err != nil {
 		return nil, false
 	}
@@ -280,10 +286,12 @@ func (e *Extractor) scanRecoveredSource(label string, data []byte, posts bool) (
 		ms  []Match
 		err error
 	)
+	// scanName is JS-recognized by construction, so the data is always scanned
+	// as JavaScript.
 	if posts {
-		ms, err = e.ScanReaderPostRequests(scanName, bytes.NewReader(data))
+		ms, err = e.scanDataPostRequests(scanName, data, true)
 	} else {
-		ms, err = e.ScanReaderWithEndpoints(scanName, bytes.NewReader(data))
+		ms, err = e.scanDataWithEndpoints(scanName, data, true)
 	}
 	if err != nil {
 		return nil, err
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"encoding/base64"
 	"io"
 	"net/http"
@@ -96,7 +97,7 @@ func TestScanURLRecoversSourceMap(t *testing.T) {
 	// Recovery disabled: the minified bundle alone reveals nothing.
 	e := NewExtractor(false, false)
 	e.SetRecoverSourceMaps(false)
-	plain, err := e.ScanURL(ts.URL+"/app.js", false, false, false)
+	plain, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -107,7 +108,7 @@ func TestScanURLRecoversSourceMap(t *testing.T) {
 	// Recovery enabled (default): the JWT and endpoint from the original source
 	// surface, attributed to the original path.
 	e = NewExtractor(false, false)
-	matches, err := e.ScanURL(ts.URL+"/app.js", false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -146,7 +147,7 @@ func TestScanURLCrawlRecoversSourceMap(t *testing.T) {
 	e := NewExtractor(false, false)
 	opts := DefaultCrawlOptions()
 	opts.AutoCalibrate = false
-	matches, err := e.ScanURLCrawl(ts.URL, false, false, false, opts)
+	matches, err := e.ScanURLCrawl(context.Background(), ts.URL, false, false, false, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -169,7 +170,7 @@ func TestSourceMapDataURI(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(false, false)
-	matches, err := e.ScanURL(ts.URL+"/app.js", false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -199,7 +200,7 @@ func TestSourceMapFetchesNonEmbedded(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(false, false)
-	matches, err := e.ScanURL(ts.URL+"/app.js", false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -237,7 +238,7 @@ func TestSourceMapFetchesOriginalsConcurrently(t *testing.T) {
 	defer srv.Close()
 
 	e := NewExtractor(false, false)
-	if _, err := e.ScanURL(srv.URL+"/app.js", false, false, false); err != nil {
+	if _, err := e.ScanURL(context.Background(), srv.URL+"/app.js", false, false, false); err != nil {
 		t.Fatal(err)
 	}
 	if got := maxActive.Load(); got < 2 {
@@ -265,7 +266,7 @@ func TestSourceMapDoesNotScanErrorBodiesAsOriginalSource(t *testing.T) {
 	defer srv.Close()
 
 	e := NewExtractor(false, false)
-	matches, err := e.ScanURL(srv.URL+"/app.js", false, false, false)
+	matches, err := e.ScanURL(context.Background(), srv.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -293,7 +294,7 @@ func TestSourceMapSkipsVirtualScheme(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(false, false)
-	if _, err := e.ScanURL(ts.URL+"/app.js", false, false, false); err != nil {
+	if _, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false); err != nil {
 		t.Fatalf("unexpected error with unfetchable virtual source: %v", err)
 	}
 }
@@ -316,7 +317,7 @@ func TestSourceMapHeader(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(false, false)
-	matches, err := e.ScanURL(ts.URL+"/app.js", false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL+"/app.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -341,7 +342,7 @@ func TestScanURLPostsRecoversSourceMap(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(false, false)
-	matches, err := e.ScanURLPosts(ts.URL+"/app.js", false, false)
+	matches, err := e.ScanURLPosts(context.Background(), ts.URL+"/app.js", false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
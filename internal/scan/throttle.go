@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"math/rand"
 	"net/http"
 	"net/url"
@@ -26,7 +27,7 @@ import (
 // unrelated host, and each host is spaced by its own gap — and does three things:
 //
 //   - Proactive spacing. When a minimum inter-request gap is configured (via
-//     SetRateLimit), consecutive requests to a host are spaced at least that far
+//     ClientConfig.RateLimit), consecutive requests to a host are spaced at least that far
 //     apart. The default gap is zero, so single-target scans are unaffected
 //     unless the user opts in.
 //
@@ -47,7 +48,7 @@ import (
 //     set, so a scan that starts getting rate-limited slows itself down instead of
 //     hammering a host that is already saying "stop".
 //
-// Optional jitter (SetRateLimitJitter) randomises each computed gap by a fraction
+// Optional jitter (ClientConfig.RateLimitJitter) randomises each computed gap by a fraction
 // so a crawl's requests do not form a perfectly regular cadence that some edge
 // rate limiters treat as bot-like; it is off by default so paced scans stay
 // deterministic unless opted in.
@@ -68,9 +69,14 @@ type requestThrottle struct {
 
 	// sleep is the blocking wait, indirected so tests can observe scheduling
 	// without spending wall-clock time. now and rnd are likewise injectable.
-	sleep func(time.Duration)
+	sleep func(ctx context.Context, d time.Duration) error
 	now   func() time.Time
 	rnd   func() float64
+
+	// onWait and onBackoff, when set, report each throttled wait and each
+	// widened gap to the scan's Metrics. onBackoff is called with t.mu held.
+	onWait    func(host string, d time.Duration)
+	onBackoff func(host string)
 }
 
 // hostThrottle is the per-host pacing state: its current gap, the earliest instant
@@ -114,15 +120,12 @@ func newRequestThrottle() *requestThrottle {
 	return &requestThrottle{
 		maxGap: throttleDefaultMaxGap,
 		hosts:  make(map[string]*hostThrottle),
-		sleep:  time.Sleep,
+		sleep:  sleepContext,
 		now:    time.Now,
 		rnd:    rand.Float64,
 	}
 }
 
-// globalThrottle paces every request issued through fetchURLResponseMethod.
-var globalThrottle = newRequestThrottle()
-
 // host returns the pacing state for hostname h, creating it (seeded with the
 // configured base gap so proactive spacing applies from the first request) on
 // first use. The caller must hold t.mu.
@@ -157,96 +160,89 @@ func hostOf(rawURL string) string {
 	return u.Hostname()
 }
 
-// SetRateLimit configures proactive request spacing for the shared HTTP path,
-// expressed as a maximum number of requests per second per host. A value <= 0
-// disables proactive spacing (the default), leaving only adaptive backoff and
-// budget-aware pre-emption active. It is safe to call before a scan starts.
-func SetRateLimit(perSecond float64) {
-	globalThrottle.mu.Lock()
-	defer globalThrottle.mu.Unlock()
+// setRateLimit configures proactive request spacing, expressed as a maximum
+// number of requests per second per host. A value <= 0 disables proactive
+// spacing (the default), leaving only adaptive backoff and budget-aware
+// pre-emption active.
+func (t *requestThrottle) setRateLimit(perSecond float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if perSecond <= 0 {
-		globalThrottle.baseGap = 0
+		t.baseGap = 0
 	} else {
-		globalThrottle.baseGap = time.Duration(float64(time.Second) / perSecond)
+		t.baseGap = time.Duration(float64(time.Second) / perSecond)
 	}
 	// Raise any host already below the new floor so the change takes effect at once.
-	for _, hs := range globalThrottle.hosts {
-		if hs.curGap < globalThrottle.baseGap {
-			hs.curGap = globalThrottle.baseGap
+	for _, hs := range t.hosts {
+		if hs.curGap < t.baseGap {
+			hs.curGap = t.baseGap
 		}
 	}
 }
 
-// SetHostRateFloor records a minimum inter-request gap for a single host, used to
+// setHostFloor records a minimum inter-request gap for a single host, used to
 // honour that site's robots.txt Crawl-delay. The floor is combined with (never
-// lowers) the global base gap and is the level adaptive decay eases back toward,
-// so the crawl never paces faster than the site asked for that host. A larger
-// floor replaces a smaller one; a non-positive gap is ignored. Safe to call before
-// or during a scan.
-func SetHostRateFloor(host string, gap time.Duration) {
+// lowers) the configured base gap and is the level adaptive decay eases back
+// toward, so the crawl never paces faster than the site asked for that host. A
+// larger floor replaces a smaller one; a non-positive gap is ignored. Safe to call
+// during a scan.
+func (t *requestThrottle) setHostFloor(host string, gap time.Duration) {
 	if gap <= 0 {
 		return
 	}
-	globalThrottle.mu.Lock()
-	defer globalThrottle.mu.Unlock()
-	if globalThrottle.hostFloor == nil {
-		globalThrottle.hostFloor = make(map[string]time.Duration)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hostFloor == nil {
+		t.hostFloor = make(map[string]time.Duration)
 	}
-	if gap > globalThrottle.hostFloor[host] {
-		globalThrottle.hostFloor[host] = gap
+	if gap > t.hostFloor[host] {
+		t.hostFloor[host] = gap
 	}
 	// Apply immediately so the floor takes effect on the very next request.
-	hs := globalThrottle.host(host)
+	hs := t.host(host)
 	if hs.curGap < gap {
 		hs.curGap = gap
 	}
 }
 
-// SetMaxBackoff overrides the ceiling on the adaptive gap and any honoured
+// setMaxBackoff overrides the ceiling on the adaptive gap and any honoured
 // Retry-After / reset hold. A non-positive value restores the default.
-func SetMaxBackoff(d time.Duration) {
-	globalThrottle.mu.Lock()
-	defer globalThrottle.mu.Unlock()
+func (t *requestThrottle) setMaxBackoff(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if d <= 0 {
 		d = throttleDefaultMaxGap
 	}
-	globalThrottle.maxGap = d
+	t.maxGap = d
 }
 
-// SetRateLimitJitter sets the fraction (e.g. 0.2 for ±20%) by which each computed
+// setJitter sets the fraction (e.g. 0.2 for ±20%) by which each computed
 // inter-request gap is randomised, breaking up the perfectly regular cadence a
 // paced crawl would otherwise produce. A non-positive value disables jitter (the
 // default). Values above 1 are clamped to 1.
-func SetRateLimitJitter(fraction float64) {
-	globalThrottle.mu.Lock()
-	defer globalThrottle.mu.Unlock()
+func (t *requestThrottle) setJitter(fraction float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if fraction < 0 {
 		fraction = 0
 	}
 	if fraction > 1 {
 		fraction = 1
 	}
-	globalThrottle.jitter = fraction
-}
-
-// ResetThrottle clears all accumulated per-host pacing state, restoring the
-// throttle to its configured base gap. It exists mainly so tests start from a
-// known state.
-func ResetThrottle() {
-	globalThrottle.mu.Lock()
-	defer globalThrottle.mu.Unlock()
-	globalThrottle.hosts = make(map[string]*hostThrottle)
-	globalThrottle.hostFloor = nil
+	t.jitter = fraction
 }
 
 // wait blocks until the throttle permits the next request to the empty-key host.
 // It is retained for callers and tests that do not track a host.
 func (t *requestThrottle) wait() { t.waitHost("") }
 
-// waitHost blocks until the throttle permits the next request to host h to start,
-// and reserves the following slot. It is called immediately before each outbound
-// request.
-func (t *requestThrottle) waitHost(h string) {
+// waitHost is waitHostContext without cancellation.
+func (t *requestThrottle) waitHost(h string) { t.waitHostContext(context.Background(), h) }
+
+// waitHostContext blocks until the throttle permits the next request to host h
+// to start, and reserves the following slot. It is called immediately before
+// each outbound request, and returns ctx's error if ctx ends during the wait.
+func (t *requestThrottle) waitHostContext(ctx context.Context, h string) error {
 	t.mu.Lock()
 	hs := t.host(h)
 	now := t.now()
@@ -258,11 +254,28 @@ func (t *requestThrottle) waitHost(h string) {
 	// second caller cannot slip in ahead of the configured spacing.
 	hs.next = wakeAt.Add(t.jittered(hs.curGap))
 	delay := wakeAt.Sub(now)
+	onWait := t.onWait
 	t.mu.Unlock()
 
 	if delay > 0 {
+		if onWait != nil {
+			onWait(h, delay)
+		}
 		vlog(3, "[throttle] waiting %s before next request to %q", delay.Round(time.Millisecond), h)
-		t.sleep(delay)
+		return t.sleep(ctx, delay)
+	}
+	return ctx.Err()
+}
+
+// sleepContext pauses for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -416,6 +429,9 @@ func (t *requestThrottle) noteThrottledHost(h string, status int, retryAfter str
 		}
 		hs.curGap = next
 		hs.lastBackoff = now
+		if t.onBackoff != nil {
+			t.onBackoff(h)
+		}
 	}
 
 	resumeAt := now.Add(hs.curGap)
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync/atomic"
@@ -10,21 +11,20 @@ import (
 
 // TestFetchAppliesThrottleBackoff exercises the real fetch path: a server that
 // answers the first request with 429+Retry-After must cause the throttle to
-// delay the following request. It runs against the process-wide globalThrottle,
-// so it swaps in a virtual clock and restores the throttle afterwards.
+// delay the following request. It swaps a virtual clock into the client's own
+// throttle so no real time passes.
 func TestFetchAppliesThrottleBackoff(t *testing.T) {
 	base := time.Unix(1_700_000_000, 0)
 	cur := base
 	var totalSlept time.Duration
 
-	saved := globalThrottle
-	globalThrottle = newRequestThrottle()
-	globalThrottle.now = func() time.Time { return cur }
-	globalThrottle.sleep = func(d time.Duration) {
+	c := testClient()
+	c.throttle.now = func() time.Time { return cur }
+	c.throttle.sleep = func(_ context.Context, d time.Duration) error {
 		totalSlept += d
 		cur = cur.Add(d)
+		return nil
 	}
-	defer func() { globalThrottle = saved }()
 
 	var hits int32
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,11 +38,11 @@ func TestFetchAppliesThrottleBackoff(t *testing.T) {
 	defer srv.Close()
 
 	// First request: server returns 429, throttle records the backoff.
-	if _, err := fetchURLResponseMethod(srv.URL, "GET", ""); err != nil {
+	if _, err := c.fetchURLResponseMethod(context.Background(), srv.URL, "GET", ""); err != nil {
 		t.Fatalf("first fetch: %v", err)
 	}
 	// Second request: the throttle should have slept for ~Retry-After before it.
-	if _, err := fetchURLResponseMethod(srv.URL, "GET", ""); err != nil {
+	if _, err := c.fetchURLResponseMethod(context.Background(), srv.URL, "GET", ""); err != nil {
 		t.Fatalf("second fetch: %v", err)
 	}
 
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -16,9 +17,10 @@ func newTestThrottle() (*requestThrottle, *[]time.Duration, func(time.Duration))
 	var slept []time.Duration
 	t := newRequestThrottle()
 	t.now = func() time.Time { return cur }
-	t.sleep = func(d time.Duration) {
+	t.sleep = func(_ context.Context, d time.Duration) error {
 		slept = append(slept, d)
 		cur = cur.Add(d)
+		return nil
 	}
 	advance := func(d time.Duration) { cur = cur.Add(d) }
 	return t, &slept, advance
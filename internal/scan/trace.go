@@ -0,0 +1,156 @@
+package scan
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Trace event kinds, the "kind" field of a structured request record.
+const (
+	TracePage        = "page"        // a crawled or scanned page's own fetch
+	TraceFetch       = "fetch"       // a script, import or source map a page pulled in
+	TraceProbe       = "probe"       // a method, parameter-replay or GraphQL probe
+	TraceCalibration = "calibration" // an auto-calibration probe
+	TraceRender      = "render"      // a headless-browser page render
+	TraceDOMProbe    = "dom_probe"   // a DOM-scanner page load with injected canaries
+)
+
+// traceScope is what a context knows about the page being scanned, so the
+// requests made on its behalf can name it as their parent.
+type traceScope struct {
+	page   string // the page being scanned
+	parent string // the page that linked to it, when crawling
+	kind   string // overrides the kind derived from page, for probes
+}
+
+type traceScopeKey struct{}
+
+// withPageTrace scopes ctx to scanning page, reached from parent.
+func withPageTrace(ctx context.Context, page, parent string) context.Context {
+	sc := traceFrom(ctx)
+	sc.page, sc.parent = page, parent
+	return context.WithValue(ctx, traceScopeKey{}, sc)
+}
+
+// withTraceKind marks the requests made under ctx as kind, keeping its page.
+func withTraceKind(ctx context.Context, kind string) context.Context {
+	sc := traceFrom(ctx)
+	sc.kind = kind
+	return context.WithValue(ctx, traceScopeKey{}, sc)
+}
+
+func traceFrom(ctx context.Context) traceScope {
+	sc, _ := ctx.Value(traceScopeKey{}).(traceScope)
+	return sc
+}
+
+// newTraceID returns a random 16-hex-digit ID for one traced event.
+func newTraceID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// traceEvent is one request, render or DOM probe being timed for the verbose
+// log.
+type traceEvent struct {
+	id, kind, parent string
+	method, url      string
+	start            time.Time
+	took             time.Duration
+}
+
+// startTrace begins an event for a request to u made under ctx. A request for
+// the scope's own page is its page event, parented on the linking page;
+// anything else is parented on the page.
+func startTrace(ctx context.Context, kind, method, u string) *traceEvent {
+	sc := traceFrom(ctx)
+	ev := &traceEvent{id: newTraceID(), kind: kind, parent: sc.page, method: method, url: u, start: time.Now()}
+	switch {
+	case kind != "":
+	case sc.kind != "":
+		ev.kind = sc.kind
+	case u == sc.page:
+		ev.kind, ev.parent = TracePage, sc.parent
+	default:
+		ev.kind = TraceFetch
+	}
+	return ev
+}
+
+// attrs returns the event's fields, with status and bytes when known (>= 0).
+func (ev *traceEvent) attrs(status int, bytes int64, err error) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("trace_id", ev.id),
+		slog.String("kind", ev.kind),
+		slog.String("method", ev.method),
+		slog.String("url", ev.url),
+	}
+	if ev.parent != "" {
+		attrs = append(attrs, slog.String("parent", ev.parent))
+	}
+	if status > 0 {
+		attrs = append(attrs, slog.Int("status", status))
+	}
+	if bytes >= 0 {
+		attrs = append(attrs, slog.Int64("bytes", bytes))
+	}
+	attrs = append(attrs, slog.Int64("duration_ms", ev.elapsed().Milliseconds()))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	return attrs
+}
+
+// elapsed returns how long the event took, fixed at its first call so the text
+// line and the record agree.
+func (ev *traceEvent) elapsed() time.Duration {
+	if ev.took == 0 {
+		ev.took = time.Since(ev.start)
+	}
+	return ev.took
+}
+
+// log writes the finished event at level with msg as its text line.
+func (ev *traceEvent) log(level int, msg string, status int, bytes int64, err error) {
+	vlogAttrs(level, msg, ev.attrs(status, bytes, err)...)
+}
+
+// tracedBody counts what is read from a response body and logs the request
+// once the body is drained or closed, so the record carries the bytes actually
+// received and the time the whole exchange took.
+type tracedBody struct {
+	io.ReadCloser
+	ev     *traceEvent
+	status int
+	line   string
+	n      int64
+	once   sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+func (b *tracedBody) done() {
+	b.once.Do(func() {
+		b.ev.log(2, fmt.Sprintf("%s (%d byte(s), %s)", b.line, b.n, b.ev.elapsed().Round(time.Millisecond)), b.status, b.n, nil)
+	})
+}
@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestTraceRecordsCarryParentAndSize crawls a seed that links a script and a
+// second page, and expects each request's JSON record to name its kind, its
+// parent page, its status and the bytes received.
+func TestTraceRecordsCarryParentAndSize(t *testing.T) {
+	const page = `<html><script src="/app.js"></script><script>fetch('/next');</script></html>`
+	const script = `const t='eyJabc.def.ghi';`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, page)
+	})
+	mux.HandleFunc("/app.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(w, script)
+	})
+	mux.HandleFunc("/next", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><body>next</body></html>`)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	records := withJSONLog(t, 2)
+	e := NewExtractor(true, false)
+	opts := DefaultCrawlOptions()
+	opts.AutoCalibrate = false
+	opts.DiscoverWellKnown = false
+	if _, err := e.ScanURLCrawl(context.Background(), ts.URL+"/", false, false, false, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	byURL := make(map[string]map[string]any)
+	ids := make(map[string]bool)
+	for _, rec := range records() {
+		id, ok := rec["trace_id"].(string)
+		if !ok {
+			continue
+		}
+		if ids[id] {
+			t.Errorf("trace_id %s reused", id)
+		}
+		ids[id] = true
+		if u, _ := rec["url"].(string); byURL[u] == nil {
+			byURL[u] = rec
+		}
+	}
+	for _, want := range []struct {
+		url, kind, parent string
+		bytes             int
+	}{
+		{ts.URL + "/", TracePage, "", len(page)},
+		{ts.URL + "/app.js", TraceFetch, ts.URL + "/", len(script)},
+		{ts.URL + "/next", TracePage, ts.URL + "/", -1},
+	} {
+		rec := byURL[want.url]
+		if rec == nil {
+			t.Errorf("no record for %s", want.url)
+			continue
+		}
+		parent, _ := rec["parent"].(string)
+		if rec["kind"] != want.kind || parent != want.parent || rec["method"] != "GET" || rec["status"] != float64(200) {
+			t.Errorf("%s record = %v, want kind %s parent %q", want.url, rec, want.kind, want.parent)
+		}
+		if _, ok := rec["duration_ms"].(float64); !ok {
+			t.Errorf("%s record lacks duration_ms: %v", want.url, rec)
+		}
+		if want.bytes >= 0 && rec["bytes"] != float64(want.bytes) {
+			t.Errorf("%s bytes = %v, want %d", want.url, rec["bytes"], want.bytes)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TriageFileVersion is the newest triage file schema this build reads.
+const TriageFileVersion = 1
+
+// Triage statuses. Both withhold the finding from the output until the entry
+// expires; they differ only in what the reviewer concluded.
+const (
+	TriageAcceptedRisk  = "accepted_risk"
+	TriageFalsePositive = "false_positive"
+)
+
+// TriageEntry is one reviewed finding in a triage file.
+type TriageEntry struct {
+	// Fingerprint is the finding's fingerprint as printed in machine-readable
+	// output: MatchFingerprint for an ordinary match, the fingerprint field of
+	// a DOM or reflection finding.
+	Fingerprint string
+	Status      string
+	Reason      string
+	Reviewer    string
+	// Expires is when the review lapses and the finding is reported again.
+	// The zero time never expires.
+	Expires time.Time
+}
+
+// Triage is a loaded triage file: the team's reviewed findings, keyed by
+// fingerprint. A nil *Triage suppresses nothing.
+type Triage struct {
+	entries map[string]TriageEntry
+	now     time.Time
+}
+
+// SuppressionSummary counts the findings a scan withheld from its output.
+type SuppressionSummary struct {
+	// Inline counts matches silenced by jsminer:ignore comments.
+	Inline int `json:"inline"`
+	// Triage counts findings withheld by a triage file, and ByStatus splits
+	// them by triage status.
+	Triage   int            `json:"triage"`
+	ByStatus map[string]int `json:"by_status,omitempty"`
+	// ExpiredEntries counts triage entries that have lapsed, so their findings
+	// were reported again.
+	ExpiredEntries int `json:"expired_entries,omitempty"`
+}
+
+// Total is the number of findings withheld for any reason.
+func (s *SuppressionSummary) Total() int {
+	if s == nil {
+		return 0
+	}
+	return s.Inline + s.Triage
+}
+
+// LoadTriageFile reads a triage file in YAML or JSON:
+//
+//	version: 1
+//	findings:
+//	  - fingerprint: 3f9a0c...
+//	    status: false_positive
+//	    reason: test fixture key, never deployed
+//	    reviewer: alice
+//	    expires: 2027-01-31
+//
+// Unknown fields, unknown statuses and duplicate fingerprints are errors. An
+// expiry is an RFC 3339 time or a date, which stays valid through that day
+// (UTC). Entries are judged against the time the file is loaded.
+func LoadTriageFile(path string) (*Triage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := DecodeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t, err := decodeTriage(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t.now = time.Now()
+	return t, nil
+}
+
+// DecodeConfig parses a configuration file written in JSON or in the block
+// YAML subset rule files use into plain values: map[string]any, []any, and
+// strings (YAML) or json.Number, bool and string (JSON) scalars.
+func DecodeConfig(data []byte) (any, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		err := dec.Decode(&doc)
+		return doc, err
+	}
+	return parseYAMLSubset(data)
+}
+
+func decodeTriage(doc any) (*Triage, error) {
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping with version and findings")
+	}
+	if err := checkRuleFields(top, "version", "findings"); err != nil {
+		return nil, err
+	}
+	version, err := ruleInt(top["version"])
+	if err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+	if version < 1 || version > TriageFileVersion {
+		return nil, fmt.Errorf("unsupported triage file version %d (this build reads up to %d)", version, TriageFileVersion)
+	}
+	list, ok := top["findings"].([]any)
+	if !ok && top["findings"] != nil {
+		return nil, errors.New("findings must be a list")
+	}
+	t := &Triage{entries: make(map[string]TriageEntry, len(list))}
+	for i, raw := range list {
+		e, err := decodeTriageEntry(raw)
+		if err != nil {
+			return nil, fmt.Errorf("finding %d: %w", i+1, err)
+		}
+		if _, dup := t.entries[e.Fingerprint]; dup {
+			return nil, fmt.Errorf("finding %d: duplicate fingerprint %q", i+1, e.Fingerprint)
+		}
+		t.entries[e.Fingerprint] = e
+	}
+	return t, nil
+}
+
+func decodeTriageEntry(raw any) (TriageEntry, error) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return TriageEntry{}, errors.New("expected a mapping")
+	}
+	if err := checkRuleFields(m, "fingerprint", "status", "reason", "reviewer", "expires"); err != nil {
+		return TriageEntry{}, err
+	}
+	var e TriageEntry
+	var expires string
+	for _, f := range []struct {
+		name string
+		dst  *string
+	}{
+		{"fingerprint", &e.Fingerprint}, {"status", &e.Status}, {"reason", &e.Reason},
+		{"reviewer", &e.Reviewer}, {"expires", &expires},
+	} {
+		s, err := ruleString(m[f.name])
+		if err != nil {
+			return TriageEntry{}, fmt.Errorf("%s: %w", f.name, err)
+		}
+		*f.dst = strings.TrimSpace(s)
+	}
+	if e.Fingerprint == "" {
+		return TriageEntry{}, errors.New("fingerprint is required")
+	}
+	e.Fingerprint = strings.ToLower(e.Fingerprint)
+	switch e.Status {
+	case TriageAcceptedRisk, TriageFalsePositive:
+	case "":
+		return TriageEntry{}, errors.New("status is required")
+	default:
+		return TriageEntry{}, fmt.Errorf("status: unknown status %q (want %s or %s)", e.Status, TriageAcceptedRisk, TriageFalsePositive)
+	}
+	if expires != "" {
+		if d, err := time.Parse(time.DateOnly, expires); err == nil {
+			e.Expires = d.Add(24 * time.Hour)
+		} else if ts, err := time.Parse(time.RFC3339, expires); err == nil {
+			e.Expires = ts
+		} else {
+			return TriageEntry{}, fmt.Errorf("expires: expected a date (2006-01-02) or RFC 3339 time, got %q", expires)
+		}
+	}
+	return e, nil
+}
+
+// lookup returns the entry withholding the finding with fingerprint fp, if
+// one is in force.
+func (t *Triage) lookup(fp string) (TriageEntry, bool) {
+	if t == nil || fp == "" {
+		return TriageEntry{}, false
+	}
+	e, ok := t.entries[fp]
+	if !ok || t.expired(e) {
+		return TriageEntry{}, false
+	}
+	return e, true
+}
+
+func (t *Triage) expired(e TriageEntry) bool {
+	return !e.Expires.IsZero() && !t.now.Before(e.Expires)
+}
+
+// SuppressesMatch reports whether the triage file withholds m.
+func (t *Triage) SuppressesMatch(m Match) bool {
+	_, ok := t.lookup(MatchFingerprint(m))
+	return ok
+}
+
+// SuppressesDOMFinding reports whether the triage file withholds f.
+func (t *Triage) SuppressesDOMFinding(f DOMFinding) bool {
+	fp := f.Fingerprint
+	if fp == "" {
+		fp = f.computeFingerprint()
+	}
+	_, ok := t.lookup(fp)
+	return ok
+}
+
+// SuppressesReflectionFinding reports whether the triage file withholds f.
+func (t *Triage) SuppressesReflectionFinding(f ReflectionFinding) bool {
+	fp := f.Fingerprint
+	if fp == "" {
+		fp = f.computeFingerprint()
+	}
+	_, ok := t.lookup(fp)
+	return ok
+}
+
+// Expired returns the entries that have lapsed, ordered by fingerprint, so a
+// caller can tell reviewers which decisions need revisiting.
+func (t *Triage) Expired() []TriageEntry {
+	if t == nil {
+		return nil
+	}
+	var out []TriageEntry
+	for _, e := range t.entries {
+		if t.expired(e) {
+			out = append(out, e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Fingerprint < out[j].Fingerprint })
+	return out
+}
+
+// Apply withholds every finding the triage file covers, returning what is
+// left and recording the counts in sum. sum may be nil.
+func (t *Triage) Apply(matches []Match, dom []DOMFinding, refl []ReflectionFinding, sum *SuppressionSummary) ([]Match, []DOMFinding, []ReflectionFinding) {
+	if t == nil {
+		return matches, dom, refl
+	}
+	if sum == nil {
+		sum = &SuppressionSummary{}
+	}
+	note := func(fp string) bool {
+		e, ok := t.lookup(fp)
+		if !ok {
+			return false
+		}
+		sum.Triage++
+		if sum.ByStatus == nil {
+			sum.ByStatus = make(map[string]int)
+		}
+		sum.ByStatus[e.Status]++
+		return true
+	}
+	keptMatches := matches[:0:0]
+	for _, m := range matches {
+		if !note(MatchFingerprint(m)) {
+			keptMatches = append(keptMatches, m)
+		}
+	}
+	keptDOM := dom[:0:0]
+	for _, f := range dom {
+		fp := f.Fingerprint
+		if fp == "" {
+			fp = f.computeFingerprint()
+		}
+		if !note(fp) {
+			keptDOM = append(keptDOM, f)
+		}
+	}
+	keptRefl := refl[:0:0]
+	for _, f := range refl {
+		fp := f.Fingerprint
+		if fp == "" {
+			fp = f.computeFingerprint()
+		}
+		if !note(fp) {
+			keptRefl = append(keptRefl, f)
+		}
+	}
+	sum.ExpiredEntries = len(t.Expired())
+	return keptMatches, keptDOM, keptRefl
+}
@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTriageWithholdsReviewedFindings verifies a triage file withholds the
+// matches, DOM and reflection findings it lists by fingerprint, counts them by
+// status, and lets a lapsed review report its finding again.
+func TestTriageWithholdsReviewedFindings(t *testing.T) {
+	fixture := Match{Source: "app.js", Pattern: "google_api", Value: "AIzaFixture", Severity: SeverityHigh}
+	accepted := Match{Source: "app.js", Pattern: "jwt", Value: "eyJ.a.b", Severity: SeverityHigh}
+	lapsed := Match{Source: "app.js", Pattern: "aws_key", Value: "AKIALAPSED", Severity: SeverityHigh}
+	open := Match{Source: "app.js", Pattern: "aws_key", Value: "AKIAOPEN", Severity: SeverityHigh}
+	dom := DOMFinding{Type: DOMTypeFlow, Target: "https://app.test", PageURL: "https://app.test/",
+		Source: &DOMSource{Kind: SourceURLQuery, Name: "q"}, Sink: &DOMSink{Name: "eval"}}
+	dom.Fingerprint = dom.computeFingerprint()
+	refl := ReflectionFinding{Type: "reflection", Target: "https://app.test", Parameter: "q", Context: "html_text"}
+	refl.Fingerprint = refl.computeFingerprint()
+
+	path := writeRuleFile(t, "triage.yaml", `version: 1
+findings:
+  - fingerprint: `+MatchFingerprint(fixture)+`
+    status: false_positive
+    reason: test fixture key, never deployed
+    reviewer: alice
+  - fingerprint: `+strings.ToUpper(MatchFingerprint(accepted))+`
+    status: accepted_risk
+    reason: public demo token
+    reviewer: bob
+    expires: 2999-01-01
+  - fingerprint: `+MatchFingerprint(lapsed)+`
+    status: accepted_risk
+    expires: 2000-01-01
+  - fingerprint: `+dom.Fingerprint+`
+    status: false_positive
+  - fingerprint: `+refl.Fingerprint+`
+    status: accepted_risk
+`)
+	tr, err := LoadTriageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sum SuppressionSummary
+	ms, doms, refls := tr.Apply([]Match{fixture, accepted, lapsed, open}, []DOMFinding{dom}, []ReflectionFinding{refl}, &sum)
+	if len(ms) != 2 || ms[0].Value != lapsed.Value || ms[1].Value != open.Value {
+		t.Fatalf("kept matches = %+v, want the lapsed and open ones", ms)
+	}
+	if len(doms) != 0 || len(refls) != 0 {
+		t.Fatalf("triaged DOM/reflection findings were kept: %+v %+v", doms, refls)
+	}
+	if sum.Triage != 4 || sum.ByStatus[TriageFalsePositive] != 2 || sum.ByStatus[TriageAcceptedRisk] != 2 || sum.ExpiredEntries != 1 {
+		t.Fatalf("summary = %+v", sum)
+	}
+	if !tr.SuppressesMatch(fixture) || tr.SuppressesMatch(lapsed) {
+		t.Error("SuppressesMatch disagrees with Apply")
+	}
+	if exp := tr.Expired(); len(exp) != 1 || exp[0].Fingerprint != MatchFingerprint(lapsed) {
+		t.Errorf("Expired() = %+v", exp)
+	}
+
+	// A nil triage withholds nothing.
+	var none *Triage
+	if ms, _, _ := none.Apply([]Match{fixture}, nil, nil, nil); len(ms) != 1 || none.SuppressesMatch(fixture) {
+		t.Error("nil triage suppressed a finding")
+	}
+}
+
+// TestTriageFileJSONAndDateExpiry verifies the JSON form loads and a bare
+// expiry date stays in force through that whole day.
+func TestTriageFileJSONAndDateExpiry(t *testing.T) {
+	m := Match{Pattern: "jwt", Value: "eyJ.a.b", Severity: SeverityHigh}
+	path := writeRuleFile(t, "triage.json", `{"version": 1, "findings": [
+		{"fingerprint": "`+MatchFingerprint(m)+`", "status": "accepted_risk", "expires": "2026-03-10"}
+	]}`)
+	tr, err := LoadTriageFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.now = time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)
+	if !tr.SuppressesMatch(m) {
+		t.Error("entry lapsed before the end of its expiry date")
+	}
+	tr.now = time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	if tr.SuppressesMatch(m) {
+		t.Error("entry still in force after its expiry date")
+	}
+}
+
+// TestTriageFileRejectsMistakes verifies a malformed triage file fails loudly
+// rather than silently suppressing the wrong thing, or nothing.
+func TestTriageFileRejectsMistakes(t *testing.T) {
+	for name, body := range map[string]string{
+		"unknown status": "version: 1\nfindings:\n  - fingerprint: ab\n    status: ignored\n",
+		"no status":      "version: 1\nfindings:\n  - fingerprint: ab\n",
+		"no fingerprint": "version: 1\nfindings:\n  - status: false_positive\n",
+		"unknown field":  "version: 1\nfindings:\n  - fingerprint: ab\n    status: false_positive\n    reviwer: bob\n",
+		"duplicate":      "version: 1\nfindings:\n  - fingerprint: ab\n    status: false_positive\n  - fingerprint: AB\n    status: accepted_risk\n",
+		"bad expiry":     "version: 1\nfindings:\n  - fingerprint: ab\n    status: false_positive\n    expires: next week\n",
+		"future version": "version: 9\nfindings: []\n",
+	} {
+		if _, err := LoadTriageFile(writeRuleFile(t, "triage.yaml", body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package scan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// A trufflehog config is recognised by its top-level detectors key.
+var trufflehogConfigRe = regexp.MustCompile(`(?m)^detectors\s*:`)
+
+func isTrufflehogConfig(data []byte) bool {
+	return trufflehogConfigRe.Match(data)
+}
+
+// readTrufflehogConfig converts the custom detectors of a trufflehog config
+// into rule specs. Each named regex of a detector becomes a rule, named after
+// the detector, or "detector-regex" when it has several, reporting the first
+// capture group when there is one, as trufflehog does. The detector's
+// keywords, entropy, exclude_words and exclude_regexes_capture/_match carry
+// over. trufflehog only reports a detector when all of its regexes match a
+// chunk together; here each regex reports on its own. verify endpoints are
+// never called, since a scan must not send the secrets it finds elsewhere.
+// Detectors have no severity, so all are high, like provider-specific
+// gitleaks rules.
+func readTrufflehogConfig(path string, data []byte) ([]ruleSpec, error) {
+	doc, err := parseYAMLSubset(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	top, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a trufflehog config", path)
+	}
+	list, ok := top["detectors"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: detectors must be a list", path)
+	}
+	var specs []ruleSpec
+	for i, raw := range list {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: detector %d: expected a mapping", path, i+1)
+		}
+		name, _ := ruleString(m["name"])
+		if name == "" {
+			name = fmt.Sprintf("trufflehog-%d", i+1)
+		}
+		regexes, ok := m["regex"].(map[string]any)
+		if !ok || len(regexes) == 0 {
+			return nil, fmt.Errorf("%s: %s: regex must map names to patterns", path, name)
+		}
+		keywords, err := ruleStrings(m["keywords"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: keywords: %w", path, name, err)
+		}
+		entropy, err := ruleFloat(m["entropy"])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: entropy: %w", path, name, err)
+		}
+		allowlists, err := trufflehogExclusions(m)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, name, err)
+		}
+		if m["verify"] != nil {
+			vlog(1, "rules: %s: detector %s: verify endpoints are not called", path, name)
+		}
+		keys := make([]string, 0, len(regexes))
+		for k := range regexes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			pat, err := ruleString(regexes[k])
+			if err != nil || pat == "" {
+				return nil, fmt.Errorf("%s: %s: regex %s: expected a pattern", path, name, k)
+			}
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: regex %s: %w", path, name, k, err)
+			}
+			spec := ruleSpec{
+				ID:         name,
+				Regex:      pat,
+				Severity:   SeverityHigh,
+				Keywords:   keywords,
+				Entropy:    entropy,
+				JS:         true,
+				allowlists: allowlists,
+			}
+			if len(keys) > 1 {
+				spec.ID = name + "-" + k
+			}
+			if re.NumSubexp() > 0 {
+				spec.Group = 1
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, nil
+}
+
+// trufflehogExclusions turns a detector's exclude_words and exclude_regexes
+// into allowlists: the words and capture regexes are checked against the
+// reported secret, the match regexes against the whole match.
+func trufflehogExclusions(m map[string]any) ([]ruleAllowlist, error) {
+	words, err := ruleStrings(m["exclude_words"])
+	if err != nil {
+		return nil, fmt.Errorf("exclude_words: %w", err)
+	}
+	capture, err := ruleStrings(m["exclude_regexes_capture"])
+	if err != nil {
+		return nil, fmt.Errorf("exclude_regexes_capture: %w", err)
+	}
+	match, err := ruleStrings(m["exclude_regexes_match"])
+	if err != nil {
+		return nil, fmt.Errorf("exclude_regexes_match: %w", err)
+	}
+	var out []ruleAllowlist
+	if len(words) > 0 || len(capture) > 0 {
+		out = append(out, ruleAllowlist{target: "secret", regexes: capture, stopwords: words})
+	}
+	if len(match) > 0 {
+		out = append(out, ruleAllowlist{target: "match", regexes: match})
+	}
+	return out, nil
+}
@@ -0,0 +1,69 @@
+package scan
+
+import "testing"
+
+const testTrufflehogConfig = `detectors:
+  - name: HogTokenDetector
+    keywords:
+      - hog_
+    regex:
+      hogID: '\b(HOG[0-9A-Z]{17})\b'
+      hogToken: 'hog_token=([A-Za-z0-9]{24})'
+    exclude_words:
+      - example
+    verify:
+      - endpoint: http://localhost:8000/
+        unsafe: true
+  - name: pig
+    keywords:
+      - oink
+    regex:
+      pig: 'oink_[a-z0-9]{16}'
+    entropy: 3
+    exclude_regexes_match:
+      - '^oink_test'
+`
+
+func TestLoadTrufflehogConfig(t *testing.T) {
+	p := writeRuleFile(t, "detectors.yaml", testTrufflehogConfig)
+	e := NewExtractor(true, false)
+	before := len(e.rules)
+	if err := e.LoadRulesFile(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(e.rules) - before; got != 3 {
+		t.Fatalf("loaded %d rules, want 3", got)
+	}
+	for _, id := range []string{"HogTokenDetector-hogID", "HogTokenDetector-hogToken", "pig"} {
+		if !e.jsRules[id] {
+			t.Fatalf("rule %s should run in safe mode", id)
+		}
+	}
+
+	cases := []struct {
+		input, pattern, want string
+	}{
+		{`hog_id: HOG0123456789ABCDEFG`, "HogTokenDetector-hogID", "HOG0123456789ABCDEFG"},
+		{`hog_token=Zx81Lq0Wm3Nv5Tb7Yc9Rk2Pd`, "HogTokenDetector-hogToken", "Zx81Lq0Wm3Nv5Tb7Yc9Rk2Pd"},
+		{`hog_token=exampleZx81Lq0Wm3Nv5Tb7Y`, "", ""},
+		{`HOG0123456789ABCDEFG`, "", ""},
+		{`var k = "oink_k3j9x2m7q4w8z1p5"`, "pig", "oink_k3j9x2m7q4w8z1p5"},
+		{`var k = "oink_aaaaaaaaaaaaaaaa"`, "", ""},
+		{`var k = "oink_testk3j9x2m7q4w8"`, "", ""},
+	}
+	for _, c := range cases {
+		var got []Match
+		for _, r := range e.rules[before:] {
+			got = append(got, r.Find([]byte(c.input))...)
+		}
+		if c.want == "" {
+			if len(got) != 0 {
+				t.Errorf("%q: unexpected %+v", c.input, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0].Pattern != c.pattern || got[0].Value != c.want || got[0].Severity != SeverityHigh || !got[0].Secret {
+			t.Errorf("%q: got %+v, want %s %s", c.input, got, c.pattern, c.want)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package scan
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,76 +10,14 @@ import (
 	"path"
 	"regexp"
 	"strings"
-	"sync"
 	"time"
 )
 
-// newHTTPClient builds an HTTP client for the URL fetch helpers: a cloned default
-// transport that optionally skips TLS verification, a request timeout and a
-// redirect cap. The transport keeps a generous per-host idle-connection pool so a
-// crawl reuses keep-alive connections instead of opening a fresh TCP+TLS
-// connection for every one of its hundreds of requests to the same host.
-func newHTTPClient() *http.Client {
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	// The stdlib default is only 2 idle connections per host, which throttles
-	// connection reuse the moment a crawl issues requests back to back (and, once
-	// crawling is parallelised, forces extra handshakes). Raise it so the pool
-	// spans a crawl's working set of hosts.
-	transport.MaxIdleConns = 100
-	transport.MaxIdleConnsPerHost = 16
-	if SkipTLSVerification {
-		if transport.TLSClientConfig == nil {
-			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		} else {
-			transport.TLSClientConfig.InsecureSkipVerify = true
-		}
-	}
-	return &http.Client{
-		Transport: transport,
-		Timeout:   HTTPClientTimeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= MaxRedirects {
-				return http.ErrUseLastResponse
-			}
-			return nil
-		},
-	}
-}
-
-// The crawl's hot path shares one HTTP client so its transport's keep-alive
-// connection pool is reused across requests rather than discarded after each one.
-// The client is rebuilt only when a setting baked into it changes (TLS
-// verification or the request timeout), which happens once at startup in normal
-// use, so a live crawl always reuses the same pooled connections.
-var (
-	httpClientMu      sync.Mutex
-	sharedClient      *http.Client
-	sharedClientTLS   bool
-	sharedClientTOut  time.Duration
-	sharedClientBuilt bool
-)
-
-// sharedHTTPClient returns the process-wide fetch client, (re)building it when the
-// TLS or timeout settings baked into a cached client no longer match the current
-// configuration. Reusing it is what lets consecutive requests to a host ride the
-// same keep-alive connection instead of re-handshaking every time.
-func sharedHTTPClient() *http.Client {
-	httpClientMu.Lock()
-	defer httpClientMu.Unlock()
-	if !sharedClientBuilt || sharedClientTLS != SkipTLSVerification || sharedClientTOut != HTTPClientTimeout {
-		sharedClient = newHTTPClient()
-		sharedClientTLS = SkipTLSVerification
-		sharedClientTOut = HTTPClientTimeout
-		sharedClientBuilt = true
-	}
-	return sharedClient
-}
-
 // fetchURLResponse retrieves a URL with GET and returns the http.Response.
 // Redirects are followed only when FollowRedirects is enabled.
-func fetchURLResponse(u string) (*http.Response, error) {
-	return fetchURLResponseMethodPolicy(u, "GET", "", func(*url.URL) bool {
-		return FollowRedirects
+func (c *Client) fetchURLResponse(ctx context.Context, u string) (*http.Response, error) {
+	return c.fetchURLResponseMethodPolicy(ctx, u, "GET", "", func(*url.URL) bool {
+		return c.cfg.FollowRedirects
 	})
 }
 
@@ -87,10 +25,10 @@ func fetchURLResponse(u string) (*http.Response, error) {
 // baseHost. Redirect scope has to be enforced by the HTTP client, before the
 // redirected request is sent; checking resp.Request.URL after the fact would
 // already have fetched the off-scope resource. FollowRedirects still acts as the
-// global on/off switch.
-func fetchURLResponseScoped(u, baseHost string) (*http.Response, error) {
-	return fetchURLResponseMethodPolicy(u, "GET", "", func(next *url.URL) bool {
-		return FollowRedirects &&
+// overall on/off switch.
+func (c *Client) fetchURLResponseScoped(ctx context.Context, u, baseHost string) (*http.Response, error) {
+	return c.fetchURLResponseMethodPolicy(ctx, u, "GET", "", func(next *url.URL) bool {
+		return c.cfg.FollowRedirects &&
 			(next.Scheme == "http" || next.Scheme == "https") &&
 			sameScope(baseHost, next.Hostname())
 	})
@@ -102,9 +40,9 @@ func fetchURLResponseScoped(u, baseHost string) (*http.Response, error) {
 // form-encoded otherwise); this lets the crawler replay discovered POST/PUT/PATCH
 // parameters against a target. The default User-Agent and any extra headers still
 // apply.
-func fetchURLResponseMethod(u, method, body string) (*http.Response, error) {
-	return fetchURLResponseMethodPolicy(u, method, body, func(*url.URL) bool {
-		return FollowRedirects
+func (c *Client) fetchURLResponseMethod(ctx context.Context, u, method, body string) (*http.Response, error) {
+	return c.fetchURLResponseMethodPolicy(ctx, u, method, body, func(*url.URL) bool {
+		return c.cfg.FollowRedirects
 	})
 }
 
@@ -112,43 +50,45 @@ func fetchURLResponseMethod(u, method, body string) (*http.Response, error) {
 // redirects within the request URL's host scope. Method probes, calibration and
 // GraphQL checks are target-controlled requests too and must not become a
 // redirect-based SSRF path.
-func fetchURLResponseMethodSameScope(u, method, body string) (*http.Response, error) {
+func (c *Client) fetchURLResponseMethodSameScope(ctx context.Context, u, method, body string) (*http.Response, error) {
 	parsed, err := url.Parse(u)
 	if err != nil || parsed.Hostname() == "" {
 		return nil, fmt.Errorf("invalid scoped URL %q", u)
 	}
 	baseHost := parsed.Hostname()
-	return fetchURLResponseMethodPolicy(u, method, body, func(next *url.URL) bool {
-		return FollowRedirects &&
+	return c.fetchURLResponseMethodPolicy(ctx, u, method, body, func(next *url.URL) bool {
+		return c.cfg.FollowRedirects &&
 			(next.Scheme == "http" || next.Scheme == "https") &&
 			sameScope(baseHost, next.Hostname())
 	})
 }
 
 // fetchURLResponseMethodPolicy is the common request path. allowRedirect, when
-// non-nil, is checked before every redirect request is sent.
-func fetchURLResponseMethodPolicy(u, method, body string, allowRedirect func(*url.URL) bool) (*http.Response, error) {
+// non-nil, is checked before every redirect request is sent. ctx bounds the
+// throttle wait, every attempt and the retries between them.
+func (c *Client) fetchURLResponseMethodPolicy(ctx context.Context, u, method, body string, allowRedirect func(*url.URL) bool) (*http.Response, error) {
 	var rdr io.Reader
 	if body != "" {
 		rdr = strings.NewReader(body)
 	}
-	req, err := http.NewRequest(method, u, rdr)
+	req, err := http.NewRequestWithContext(ctx, method, u, rdr)
 	if err != nil {
 		return nil, err
 	}
-	applyHeaders(req)
+	c.applyHeaders(req)
 	if body != "" && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", inferContentType(body))
 	}
+	req = c.traceOrigin(req)
 	// A transient transport error (connection reset, DNS blip, timeout) is retried
 	// only for safe, bodyless reads. Mutation probes must run exactly once even
 	// when their body is empty: retrying a POST/PATCH/DELETE after an ambiguous
 	// transport failure can execute a state change twice.
 	attempts := 1
 	if body == "" && retryableMethod(method) {
-		attempts += FetchRetries
+		attempts += c.cfg.Retries
 	}
-	client := sharedHTTPClient()
+	client := c.http
 	if allowRedirect != nil {
 		// Shallow-copy the client so this request can install a scope-aware redirect
 		// policy while still sharing the underlying keep-alive transport.
@@ -161,6 +101,7 @@ func fetchURLResponseMethodPolicy(u, method, body string, allowRedirect func(*ur
 		}
 		client = &scoped
 	}
+	ev := startTrace(ctx, "", method, u)
 	var resp *http.Response
 	for attempt := 0; ; attempt++ {
 		// Pace outbound requests so a crawl's burst of fetches, probes and
@@ -168,19 +109,34 @@ func fetchURLResponseMethodPolicy(u, method, body string, allowRedirect func(*ur
 		// server signals 429/503. wait() blocks for the configured/adaptive gap;
 		// observe() adapts the gap to the response.
 		host := hostOf(u)
-		globalThrottle.waitHost(host)
+		if err := c.throttle.waitHostContext(ctx, host); err != nil {
+			return nil, err
+		}
+		if attempt == 0 {
+			ev.start = time.Now() // time the exchange, not the throttle's pacing
+		}
 		resp, err = client.Do(req)
-		globalThrottle.observeHost(host, resp, err)
+		c.throttle.observeHost(host, resp, err)
+		if err != nil {
+			c.metrics().observeRequest(method, 0, err)
+		} else {
+			c.metrics().observeRequest(method, resp.StatusCode, nil)
+		}
 		if err == nil {
 			break
 		}
-		if attempt+1 >= attempts {
-			vlog(2, "http %s %s -> error: %v", method, u, err)
+		if attempt+1 >= attempts || ctx.Err() != nil {
+			ev.log(2, fmt.Sprintf("http %s %s -> error: %v", method, u, err), 0, -1, err)
 			return nil, err
 		}
 		vlog(2, "http %s %s -> transport error (%v); retry %d/%d", method, u, err, attempt+1, attempts-1)
 	}
-	vlog(2, "http %s %s -> %s", method, u, resp.Status)
+	c.ObserveOrigin(resp)
+	if vEnabled(2) {
+		// Logged once the body is consumed, so the record has its size.
+		resp.Body = &tracedBody{ReadCloser: resp.Body, ev: ev, status: resp.StatusCode,
+			line: fmt.Sprintf("http %s %s -> %s", method, u, resp.Status)}
+	}
 	return resp, nil
 }
 
@@ -349,25 +305,42 @@ func isRedirectResponse(resp *http.Response) bool {
 // ScanURL scans urlStr and any discovered script or import references.
 // Cross-domain resources are followed by default. Set external to false to restrict scanning to the same domain. JavaScript files are scanned using the configured rules.
 // ScanURL scans urlStr and any discovered script or import references. When
-// endpoints is true, only endpoint matches are returned.
-func (e *Extractor) ScanURL(urlStr string, endpoints bool, external bool, render bool) ([]Match, error) {
+// endpoints is true, only endpoint matches are returned. If ctx ends mid-scan,
+// the matches found so far are returned together with ctx's error.
+func (e *Extractor) ScanURL(ctx context.Context, urlStr string, endpoints bool, external bool, render bool) ([]Match, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
 	visited := newVisitedSet()
-	return e.scanURL(u.String(), u.Hostname(), endpoints, visited, external, render)
+	ctx = withPageTrace(ctx, u.String(), "")
+	ms, err := e.scanURL(ctx, u.String(), u.Hostname(), endpoints, visited, external, render)
+	return ms, scanContextErr(ctx, err)
 }
 
 // ScanURLPosts scans urlStr and discovered script/import references returning
-// only HTTP POST request endpoints found in JavaScript sources.
-func (e *Extractor) ScanURLPosts(urlStr string, external bool, render bool) ([]Match, error) {
+// only HTTP POST request endpoints found in JavaScript sources. Cancellation
+// behaves as for ScanURL.
+func (e *Extractor) ScanURLPosts(ctx context.Context, urlStr string, external bool, render bool) ([]Match, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
 	}
 	visited := newVisitedSet()
-	return e.scanURLPosts(u.String(), u.Hostname(), visited, external, render)
+	ctx = withPageTrace(ctx, u.String(), "")
+	ms, err := e.scanURLPosts(ctx, u.String(), u.Hostname(), visited, external, render)
+	return ms, scanContextErr(ctx, err)
+}
+
+// scanContextErr reports ctx's error in place of err once ctx has ended, so a
+// caller can tell a cut-short scan (whose matches are partial) from a failed
+// one. Pages and scripts whose fetch was cancelled are otherwise skipped like
+// any unreachable resource, which would leave the scan looking complete.
+func scanContextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
 }
 
 // scanURL performs the recursive scanning used by ScanURL. The baseHost
@@ -375,8 +348,8 @@ func (e *Extractor) ScanURLPosts(urlStr string, external bool, render bool) ([]M
 // already processed URLs to avoid loops and is shared (and synchronised) across
 // a concurrent crawl's workers. When external is false, only resources from the
 // same domain are processed.
-func (e *Extractor) scanURL(urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool) ([]Match, error) {
-	result, err := e.scanURLWithValidationDetailed(urlStr, baseHost, endpoints, visited, external, render, nil)
+func (e *Extractor) scanURL(ctx context.Context, urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool) ([]Match, error) {
+	result, err := e.scanURLWithValidationDetailed(ctx, urlStr, baseHost, endpoints, visited, external, render, nil)
 	return result.matches, err
 }
 
@@ -384,8 +357,8 @@ func (e *Extractor) scanURL(urlStr, baseHost string, endpoints bool, visited *vi
 // that the path still exists. It returns accepted=false for binary responses,
 // error statuses and calibrated catch-all/soft-404 responses, before any rules,
 // imports or rendering are run against that response.
-func (e *Extractor) scanURLValidated(urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool, validator *autoCalibrator) ([]Match, bool, error) {
-	result, err := e.scanURLWithValidationDetailed(urlStr, baseHost, endpoints, visited, external, render, validator)
+func (e *Extractor) scanURLValidated(ctx context.Context, urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool, validator *autoCalibrator) ([]Match, bool, error) {
+	result, err := e.scanURLWithValidationDetailed(ctx, urlStr, baseHost, endpoints, visited, external, render, validator)
 	return result.matches, result.accepted, err
 }
 
@@ -401,7 +374,7 @@ type scanURLResult struct {
 	baseline   *methodProbeBaseline
 }
 
-func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool, validator *autoCalibrator) (scanURLResult, error) {
+func (e *Extractor) scanURLWithValidationDetailed(ctx context.Context, urlStr, baseHost string, endpoints bool, visited *visitedSet, external bool, render bool, validator *autoCalibrator) (scanURLResult, error) {
 	if !visited.visit(urlStr) {
 		return scanURLResult{}, nil
 	}
@@ -411,9 +384,9 @@ func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpo
 	if validator != nil {
 		// A historical hint is target-controlled only after it is rebased. Keep
 		// its validation redirect chain in scope when redirect following is enabled.
-		resp, err = fetchURLResponseScoped(urlStr, baseHost)
+		resp, err = e.client.fetchURLResponseScoped(ctx, urlStr, baseHost)
 	} else {
-		resp, err = fetchURLResponse(urlStr)
+		resp, err = e.client.fetchURLResponse(ctx, urlStr)
 	}
 	if err != nil {
 		return scanURLResult{}, err
@@ -468,7 +441,7 @@ func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpo
 			// Explore application state, not just the initial DOM: scan the seed
 			// render and every state exploration reaches through interaction, so a
 			// single-page app's event-handler-gated surface is covered too.
-			if states, scripts, posts, xhrURLs, err := RenderURLWithStates(finalURL); err == nil && len(states) > 0 {
+			if states, scripts, posts, xhrURLs, storage, err := e.client.RenderURLWithStates(ctx, finalURL); err == nil && len(states) > 0 {
 				for _, p := range posts {
 					matches = append(matches, Match{Source: finalURL, Pattern: "post_url", Value: p.URL, Params: p.Body, Severity: "info"})
 				}
@@ -486,17 +459,20 @@ func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpo
 					if i == 0 {
 						dyn = scripts
 					}
-					matches = append(matches, e.scanHTMLState(finalURL, baseHost, st, dyn, endpoints, visited, external, render)...)
+					matches = append(matches, e.scanHTMLState(ctx, finalURL, baseHost, st, dyn, endpoints, visited, external, render)...)
+				}
+				if !storage.empty() {
+					matches = append(matches, e.scanRenderedStorage(ctx, finalURL, baseHost, storage, endpoints, false, visited, external)...)
 				}
 				return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 			}
 			// Fall back to a plain render if state exploration failed entirely.
-			if rhtml, scripts, err := RenderURL(finalURL); err == nil {
-				matches = append(matches, e.scanHTMLState(finalURL, baseHost, rhtml, scripts, endpoints, visited, external, render)...)
+			if rhtml, scripts, err := e.client.RenderURL(ctx, finalURL); err == nil {
+				matches = append(matches, e.scanHTMLState(ctx, finalURL, baseHost, rhtml, scripts, endpoints, visited, external, render)...)
 				return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 			}
 		}
-		matches = append(matches, e.scanHTMLState(finalURL, baseHost, data, nil, endpoints, visited, external, render)...)
+		matches = append(matches, e.scanHTMLState(ctx, finalURL, baseHost, data, nil, endpoints, visited, external, render)...)
 		return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 	}
 
@@ -527,7 +503,7 @@ func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpo
 		// Recover and scan any original source the bundle advertises via a source
 		// map, so secrets and endpoints that only survive in the pre-bundled source
 		// are found too.
-		if rec := e.recoverSourceMap(finalURL, data, resp.Header, baseHost, external, visited, false); rec != nil {
+		if rec := e.recoverSourceMap(ctx, finalURL, data, resp.Header, baseHost, external, visited, false); rec != nil {
 			if endpoints {
 				rec = FilterEndpointMatches(rec)
 			}
@@ -537,7 +513,7 @@ func (e *Extractor) scanURLWithValidationDetailed(urlStr, baseHost string, endpo
 
 	for _, imp := range inScopeJSImports(data, finalURL, baseHost, external) {
 		vlog(3, "follow import %s (from %s)", imp, finalURL)
-		ms, err := e.scanURL(imp, baseHost, endpoints, visited, external, render)
+		ms, err := e.scanURL(ctx, imp, baseHost, endpoints, visited, external, render)
 		if err != nil {
 			continue
 		}
@@ -571,17 +547,17 @@ func inScopeJSImports(data []byte, finalURL, baseHost string, external bool) []s
 
 // scanURLPosts performs recursive scanning like scanURL but returns only POST
 // request endpoints.
-func (e *Extractor) scanURLPosts(urlStr, baseHost string, visited *visitedSet, external bool, render bool) ([]Match, error) {
-	result, err := e.scanURLPostsWithValidationDetailed(urlStr, baseHost, visited, external, render, nil)
+func (e *Extractor) scanURLPosts(ctx context.Context, urlStr, baseHost string, visited *visitedSet, external bool, render bool) ([]Match, error) {
+	result, err := e.scanURLPostsWithValidationDetailed(ctx, urlStr, baseHost, visited, external, render, nil)
 	return result.matches, err
 }
 
-func (e *Extractor) scanURLPostsValidated(urlStr, baseHost string, visited *visitedSet, external bool, render bool, validator *autoCalibrator) ([]Match, bool, error) {
-	result, err := e.scanURLPostsWithValidationDetailed(urlStr, baseHost, visited, external, render, validator)
+func (e *Extractor) scanURLPostsValidated(ctx context.Context, urlStr, baseHost string, visited *visitedSet, external bool, render bool, validator *autoCalibrator) ([]Match, bool, error) {
+	result, err := e.scanURLPostsWithValidationDetailed(ctx, urlStr, baseHost, visited, external, render, validator)
 	return result.matches, result.accepted, err
 }
 
-func (e *Extractor) scanURLPostsWithValidationDetailed(urlStr, baseHost string, visited *visitedSet, external bool, render bool, validator *autoCalibrator) (scanURLResult, error) {
+func (e *Extractor) scanURLPostsWithValidationDetailed(ctx context.Context, urlStr, baseHost string, visited *visitedSet, external bool, render bool, validator *autoCalibrator) (scanURLResult, error) {
 	if !visited.visit(urlStr) {
 		return scanURLResult{}, nil
 	}
@@ -589,9 +565,9 @@ func (e *Extractor) scanURLPostsWithValidationDetailed(urlStr, baseHost string,
 	var resp *http.Response
 	var err error
 	if validator != nil {
-		resp, err = fetchURLResponseScoped(urlStr, baseHost)
+		resp, err = e.client.fetchURLResponseScoped(ctx, urlStr, baseHost)
 	} else {
-		resp, err = fetchURLResponse(urlStr)
+		resp, err = e.client.fetchURLResponse(ctx, urlStr)
 	}
 	if err != nil {
 		return scanURLResult{}, err
@@ -645,7 +621,7 @@ func (e *Extractor) scanURLPostsWithValidationDetailed(urlStr, baseHost string,
 			// client-side navigation or form submission are captured too. The
 			// XHR/fetch GET URLs are not emitted here: this path is -posts mode,
 			// whose output is intentionally limited to POST request endpoints.
-			if states, scripts, posts, _, err := RenderURLWithStates(finalURL); err == nil && len(states) > 0 {
+			if states, scripts, posts, _, storage, err := e.client.RenderURLWithStates(ctx, finalURL); err == nil && len(states) > 0 {
 				for _, p := range posts {
 					matches = append(matches, Match{Source: finalURL, Pattern: "post_url", Value: p.URL, Params: p.Body, Severity: "info"})
 				}
@@ -654,16 +630,19 @@ func (e *Extractor) scanURLPostsWithValidationDetailed(urlStr, baseHost string,
 					if i == 0 {
 						dyn = scripts
 					}
-					matches = append(matches, e.scanHTMLStatePosts(finalURL, baseHost, st, dyn, visited, external, render)...)
+					matches = append(matches, e.scanHTMLStatePosts(ctx, finalURL, baseHost, st, dyn, visited, external, render)...)
+				}
+				if !storage.empty() {
+					matches = append(matches, e.scanRenderedStorage(ctx, finalURL, baseHost, storage, false, true, visited, external)...)
 				}
 				return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 			}
-			if rhtml, scripts, err := RenderURL(finalURL); err == nil {
-				matches = append(matches, e.scanHTMLStatePosts(finalURL, baseHost, rhtml, scripts, visited, external, render)...)
+			if rhtml, scripts, err := e.client.RenderURL(ctx, finalURL); err == nil {
+				matches = append(matches, e.scanHTMLStatePosts(ctx, finalURL, baseHost, rhtml, scripts, visited, external, render)...)
 				return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 			}
 		}
-		matches = append(matches, e.scanHTMLStatePosts(finalURL, baseHost, data, nil, visited, external, render)...)
+		matches = append(matches, e.scanHTMLStatePosts(ctx, finalURL, baseHost, data, nil, visited, external, render)...)
 		return scanURLResult{matches: matches, accepted: true, baseline: baseline}, nil
 	}
 
@@ -686,13 +665,13 @@ func (e *Extractor) scanURLPostsWithValidationDetailed(urlStr, baseHost string,
 
 		// Recover POST endpoints from any original source the bundle advertises via
 		// a source map.
-		if rec := e.recoverSourceMap(finalURL, data, resp.Header, baseHost, external, visited, true); rec != nil {
+		if rec := e.recoverSourceMap(ctx, finalURL, data, resp.Header, baseHost, external, visited, true); rec != nil {
 			matches = append(matches, rec...)
 		}
 	}
 
 	for _, imp := range inScopeJSImports(data, finalURL, baseHost, external) {
-		ms, err := e.scanURLPosts(imp, baseHost, visited, external, render)
+		ms, err := e.scanURLPosts(ctx, imp, baseHost, visited, external, render)
 		if err != nil {
 			continue
 		}
@@ -719,7 +698,7 @@ func passiveResponseStatusValid(status int) bool {
 // carries script URLs captured during rendering that are not in the static
 // markup; it is supplied only for the first state since the render returns the
 // union across all states and the visited map dedups the rest.
-func (e *Extractor) scanHTMLState(finalURL, baseHost string, data []byte, dynamic []string, endpoints bool, visited *visitedSet, external, render bool) []Match {
+func (e *Extractor) scanHTMLState(ctx context.Context, finalURL, baseHost string, data []byte, dynamic []string, endpoints bool, visited *visitedSet, external, render bool) []Match {
 	var matches []Match
 	if !e.safeMode {
 		if ms, err := e.ScanReader(finalURL, bytes.NewReader(data)); err == nil {
@@ -739,7 +718,7 @@ func (e *Extractor) scanHTMLState(finalURL, baseHost string, data []byte, dynami
 		matches = append(matches, extractHTMLFormMatches(data, finalURL)...)
 	}
 	for _, src := range extractInlineScripts(data) {
-		ms, err := e.ScanReaderWithEndpoints("inline.js", bytes.NewReader([]byte(src)))
+		ms, err := e.ScanReaderWithEndpoints(ctx, "inline.js", bytes.NewReader([]byte(src)))
 		if err == nil {
 			if endpoints {
 				ms = FilterEndpointMatches(ms)
@@ -757,7 +736,7 @@ func (e *Extractor) scanHTMLState(finalURL, baseHost string, data []byte, dynami
 			continue
 		}
 		if external || sameScope(baseHost, u.Hostname()) {
-			ms, err := e.scanURL(u.String(), baseHost, endpoints, visited, external, render)
+			ms, err := e.scanURL(ctx, u.String(), baseHost, endpoints, visited, external, render)
 			if err != nil {
 				continue
 			}
@@ -772,7 +751,7 @@ func (e *Extractor) scanHTMLState(finalURL, baseHost string, data []byte, dynami
 // scanURLPosts. Like scanHTMLState, dynamic is supplied only for the first
 // state. It mirrors the POST-only behaviour of scanURLPosts: inline scripts are
 // not scanned here (POST endpoints come from the linked script sources).
-func (e *Extractor) scanHTMLStatePosts(finalURL, baseHost string, data []byte, dynamic []string, visited *visitedSet, external, render bool) []Match {
+func (e *Extractor) scanHTMLStatePosts(ctx context.Context, finalURL, baseHost string, data []byte, dynamic []string, visited *visitedSet, external, render bool) []Match {
 	var matches []Match
 	// During a crawl (calibrator set), harvest the page's markup links so the posts
 	// crawl follows the HTML link graph to reach deeper pages — and the POST
@@ -796,7 +775,7 @@ func (e *Extractor) scanHTMLStatePosts(finalURL, baseHost string, data []byte, d
 			continue
 		}
 		if external || sameScope(baseHost, u.Hostname()) {
-			ms, err := e.scanURLPosts(u.String(), baseHost, visited, external, render)
+			ms, err := e.scanURLPosts(ctx, u.String(), baseHost, visited, external, render)
 			if err != nil {
 				continue
 			}
@@ -1,6 +1,7 @@
 package scan
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -29,7 +30,7 @@ func TestScanURL(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	matches, err := e.ScanURL(ts.URL, false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL, false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -80,7 +81,7 @@ func TestScanURLExternal(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	restricted, err := e.ScanURL(ts.URL, false, false, false)
+	restricted, err := e.ScanURL(context.Background(), ts.URL, false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -93,7 +94,7 @@ func TestScanURLExternal(t *testing.T) {
 		}
 	}
 
-	matches, err := e.ScanURL(ts.URL, false, true, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL, false, true, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -141,14 +142,11 @@ func TestScanURLRedirectIndependentFromExternal(t *testing.T) {
 	}))
 	defer seed.Close()
 
-	SetFollowRedirects(false)
-	t.Cleanup(func() { SetFollowRedirects(false) })
-
 	e := NewExtractor(false, false)
 	for _, external := range []bool{false, true} {
 		// Keep rendering enabled to prove the renderer does not navigate back to
 		// the original URL and follow the redirect behind the HTTP policy.
-		blocked, err := e.ScanURL(seed.URL+"/inside.js", false, external, true)
+		blocked, err := e.ScanURL(context.Background(), seed.URL+"/inside.js", false, external, true)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -163,8 +161,8 @@ func TestScanURLRedirectIndependentFromExternal(t *testing.T) {
 		}
 	}
 
-	SetFollowRedirects(true)
-	allowed, err := e.ScanURL(seed.URL+"/inside.js", false, false, false)
+	e.SetClient(testClient(func(c *ClientConfig) { c.FollowRedirects = true }))
+	allowed, err := e.ScanURL(context.Background(), seed.URL+"/inside.js", false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -191,19 +189,16 @@ func TestScanURLRedirectDisabledBlocksSameHost(t *testing.T) {
 	srv := httptest.NewServer(mux)
 	defer srv.Close()
 
-	SetFollowRedirects(false)
-	t.Cleanup(func() { SetFollowRedirects(false) })
-
 	e := NewExtractor(false, false)
-	if _, err := e.ScanURL(srv.URL+"/start", false, true, false); err != nil {
+	if _, err := e.ScanURL(context.Background(), srv.URL+"/start", false, true, false); err != nil {
 		t.Fatal(err)
 	}
 	if got := atomic.LoadInt64(&destinationHits); got != 0 {
 		t.Fatalf("redirect=false sent %d request(s) to a same-host destination", got)
 	}
 
-	SetFollowRedirects(true)
-	if _, err := e.ScanURL(srv.URL+"/start", false, true, false); err != nil {
+	e.SetClient(testClient(func(c *ClientConfig) { c.FollowRedirects = true }))
+	if _, err := e.ScanURL(context.Background(), srv.URL+"/start", false, true, false); err != nil {
 		t.Fatal(err)
 	}
 	if got := atomic.LoadInt64(&destinationHits); got != 1 {
@@ -226,7 +221,7 @@ func TestScanURLRender(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	matches, err := e.ScanURL(ts.URL, false, false, true)
+	matches, err := e.ScanURL(context.Background(), ts.URL, false, false, true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -256,7 +251,7 @@ func TestScanURLRecognizesMixedCaseHTMLContentType(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	ms, err := e.ScanURL(ts.URL, true, false, false)
+	ms, err := e.ScanURL(context.Background(), ts.URL, true, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -273,7 +268,7 @@ func TestScanURLExtensionlessJavaScriptUsesContentType(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	ms, err := e.ScanURL(ts.URL+"/bundle", true, false, false)
+	ms, err := e.ScanURL(context.Background(), ts.URL+"/bundle", true, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -297,7 +292,7 @@ func TestScanURLEndpointsOnly(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	matches, err := e.ScanURL(ts.URL, true, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL, true, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -322,7 +317,7 @@ func TestScanURLInlineScript(t *testing.T) {
 	defer ts.Close()
 
 	e := NewExtractor(true, false)
-	matches, err := e.ScanURL(ts.URL, false, false, false)
+	matches, err := e.ScanURL(context.Background(), ts.URL, false, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,10 +1,13 @@
 package scan
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"sync"
+	"time"
 )
 
 // Verbosity controls how much diagnostic detail the scan package writes to the
@@ -21,12 +24,25 @@ import (
 //	         recovered source maps.
 //
 // Diagnostics go to stderr so they never contaminate the machine-readable
-// results written to stdout.
+// results written to stdout. They are slog records: the levels map onto
+// slog.LevelInfo, slog.LevelDebug and LevelTrace, and SetLogFormat chooses
+// between the [v]-prefixed text lines and one JSON object per line.
 var Verbosity int
 
+// LevelTrace is the slog level of -vvv per-item trace records, below
+// slog.LevelDebug. The JSON log names it "TRACE".
+const LevelTrace = slog.LevelDebug - 4
+
+// Log formats accepted by SetLogFormat.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
 var (
-	verboseMu     sync.Mutex
-	verboseWriter io.Writer = os.Stderr
+	verboseMu      sync.Mutex
+	verboseWriter  io.Writer    = os.Stderr
+	verboseHandler slog.Handler = verboseTextHandler{}
 )
 
 // SetVerbosity sets the global verbose logging level (see Verbosity). Negative
@@ -49,19 +65,126 @@ func SetVerboseWriter(w io.Writer) {
 	verboseWriter = w
 }
 
+// SetLogFormat selects how verbose diagnostics are written: LogFormatText, the
+// default [v]/[vv]/[vvv] lines, or LogFormatJSON, one slog JSON record per line
+// carrying each event's structured fields (trace ID, parent page, method,
+// status, bytes, duration).
+func SetLogFormat(format string) error {
+	switch format {
+	case "", LogFormatText:
+		SetLogHandler(nil)
+	case LogFormatJSON:
+		SetLogHandler(slog.NewJSONHandler(verboseSink{}, &slog.HandlerOptions{
+			Level:       LevelTrace,
+			ReplaceAttr: replaceTraceLevel,
+		}))
+	default:
+		return fmt.Errorf("unknown log format %q (want %s or %s)", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// SetLogHandler routes verbose diagnostics to h, for callers that feed their
+// own slog pipeline. Verbosity still decides which records are produced.
+// Passing nil restores the text lines.
+func SetLogHandler(h slog.Handler) {
+	verboseMu.Lock()
+	defer verboseMu.Unlock()
+	if h == nil {
+		h = verboseTextHandler{}
+	}
+	verboseHandler = h
+}
+
+// replaceTraceLevel names LevelTrace in JSON records, which slog would
+// otherwise write as "DEBUG-4".
+func replaceTraceLevel(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if l, ok := a.Value.Any().(slog.Level); ok && l <= LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// verboseSink writes to the current verbose writer, so a handler built before
+// SetVerboseWriter still follows it.
+type verboseSink struct{}
+
+func (verboseSink) Write(p []byte) (int, error) {
+	verboseMu.Lock()
+	defer verboseMu.Unlock()
+	return verboseWriter.Write(p)
+}
+
+// verboseTextHandler writes each record as a prefixed line, the classic -v
+// output. Attributes are left to the JSON format; the messages already read
+// as complete lines.
+type verboseTextHandler struct{}
+
+func (verboseTextHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (verboseTextHandler) Handle(_ context.Context, r slog.Record) error {
+	_, err := io.WriteString(verboseSink{}, verbosePrefix(verbosityOf(r.Level))+r.Message+"\n")
+	return err
+}
+
+func (h verboseTextHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h verboseTextHandler) WithGroup(string) slog.Handler      { return h }
+
+// slogLevel maps a verbosity level onto its slog level.
+func slogLevel(level int) slog.Level {
+	switch level {
+	case 1:
+		return slog.LevelInfo
+	case 2:
+		return slog.LevelDebug
+	default:
+		return LevelTrace
+	}
+}
+
+// verbosityOf is the inverse of slogLevel.
+func verbosityOf(l slog.Level) int {
+	switch {
+	case l >= slog.LevelInfo:
+		return 1
+	case l >= slog.LevelDebug:
+		return 2
+	default:
+		return 3
+	}
+}
+
 // vEnabled reports whether a message at the given level would be emitted. Use it
 // to guard log calls whose arguments are expensive to compute.
 func vEnabled(level int) bool { return Verbosity >= level }
 
-// vlog writes a single diagnostic line, prefixed with its level, when Verbosity
-// is at least level. It is safe for concurrent use.
+// vlog writes a single diagnostic record when Verbosity is at least level. It
+// is safe for concurrent use.
 func vlog(level int, format string, args ...any) {
+	if Verbosity < level {
+		return
+	}
+	vlogAttrs(level, fmt.Sprintf(format, args...))
+}
+
+// vlogAttrs writes msg with structured attributes when Verbosity is at least
+// level. The text format shows only msg, so it should read on its own.
+func vlogAttrs(level int, msg string, attrs ...slog.Attr) {
 	if Verbosity < level {
 		return
 	}
 	verboseMu.Lock()
-	defer verboseMu.Unlock()
-	fmt.Fprintf(verboseWriter, verbosePrefix(level)+format+"\n", args...)
+	h := verboseHandler
+	verboseMu.Unlock()
+	l := slogLevel(level)
+	if !h.Enabled(context.Background(), l) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), l, msg, 0)
+	r.AddAttrs(attrs...)
+	_ = h.Handle(context.Background(), r)
 }
 
 // verbosePrefix tags each line with its level so interleaved output from
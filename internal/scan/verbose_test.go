@@ -2,6 +2,8 @@ package scan
 
 import (
 	"bytes"
+	"encoding/json"
+	"log/slog"
 	"strings"
 	"testing"
 )
@@ -73,3 +75,56 @@ func TestSetVerbosityClampsNegative(t *testing.T) {
 		t.Errorf("negative verbosity should clamp to 0, got %d", Verbosity)
 	}
 }
+
+// withJSONLog is withVerbosity with the JSON log format, returning a function
+// that decodes the records written so far.
+func withJSONLog(t *testing.T, level int) func() []map[string]any {
+	t.Helper()
+	buf := withVerbosity(t, level)
+	if err := SetLogFormat(LogFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetLogFormat(LogFormatText) })
+	return func() []map[string]any {
+		t.Helper()
+		var recs []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var rec map[string]any
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatalf("not a JSON record: %q: %v", line, err)
+			}
+			recs = append(recs, rec)
+		}
+		return recs
+	}
+}
+
+func TestJSONLogMapsLevels(t *testing.T) {
+	records := withJSONLog(t, 3)
+
+	vlog(1, "one")
+	vlogAttrs(2, "two", slog.String("url", "https://a.test/"))
+	vlog(3, "three")
+
+	recs := records()
+	if len(recs) != 3 {
+		t.Fatalf("got %d record(s), want 3: %v", len(recs), recs)
+	}
+	for i, want := range []string{"INFO", "DEBUG", "TRACE"} {
+		if recs[i]["level"] != want {
+			t.Errorf("record %d level = %v, want %s", i, recs[i]["level"], want)
+		}
+	}
+	if recs[1]["msg"] != "two" || recs[1]["url"] != "https://a.test/" {
+		t.Errorf("record with attributes = %v", recs[1])
+	}
+}
+
+func TestSetLogFormatRejectsUnknown(t *testing.T) {
+	if err := SetLogFormat("xml"); err == nil {
+		t.Error("unknown log format should be an error")
+	}
+}
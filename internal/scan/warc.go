@@ -0,0 +1,769 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// warcVersion is the WARC revision written; reading also accepts WARC/1.0.
+const warcVersion = "WARC/1.1"
+
+// maxWARCRecordBytes bounds one record read back from an archive, so a
+// corrupt length field cannot exhaust memory. Recorded bodies are capped at
+// MaxResponseBodyBytes, far below it.
+const maxWARCRecordBytes = 1 << 30
+
+// ErrNotArchived is returned for a request a replayed archive holds no
+// response for. Replay never falls back to the network.
+var ErrNotArchived = errors.New("not in the replayed archive")
+
+// errReplayRender is returned by renders during replay of a page the archive
+// holds no rendered snapshot of: a browser would load the page, and
+// everything it references, from the network.
+var errReplayRender = errors.New("rendering is unavailable while replaying an archive")
+
+// warcRenderStateField marks a resource record holding a rendered DOM state.
+// Its value is the state's index in the render, 0 for the initial load.
+const warcRenderStateField = "JSMiner-Render-State"
+
+// WARCInfo describes the run an archive records. It is written to the
+// archive's leading warcinfo record.
+type WARCInfo struct {
+	// Software names the recording tool, e.g. "JSMiner/1.2.0".
+	Software string
+	// Targets are the scan's seed URLs. Replaying the archive without
+	// explicit targets scans these.
+	Targets []string
+}
+
+// WARCWriter records HTTP exchanges as WARC 1.1: a response record and the
+// request record concurrent to it for every exchange, after a leading warcinfo
+// record. When compressed, each record is its own gzip member, as .warc.gz
+// readers expect. Bodies are stored as the client received them, after
+// transport decompression, and are capped at MaxResponseBodyBytes; a body cut
+// short is marked with WARC-Truncated. The HTML of each rendered page state is
+// stored as a resource record, so replay can scan what rendering saw. A
+// WARCWriter is safe for concurrent use.
+type WARCWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	gzip    bool
+	err     error
+	records int
+}
+
+// CreateWARC creates file and writes its warcinfo record. The archive is gzip
+// compressed when file ends in .gz.
+func CreateWARC(file string, info WARCInfo) (*WARCWriter, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWARCWriter(f, strings.HasSuffix(file, ".gz"), info)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWARCWriter writes an archive to w, starting with its warcinfo record.
+func NewWARCWriter(w io.Writer, compress bool, info WARCInfo) (*WARCWriter, error) {
+	ww := &WARCWriter{w: w, gzip: compress}
+	var fields bytes.Buffer
+	if info.Software != "" {
+		fmt.Fprintf(&fields, "software: %s\r\n", info.Software)
+	}
+	fmt.Fprintf(&fields, "format: WARC File Format 1.1\r\n")
+	fmt.Fprintf(&fields, "conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	for _, t := range info.Targets {
+		fmt.Fprintf(&fields, "jsminer-target: %s\r\n", t)
+	}
+	ww.writeRecord([][2]string{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", newWARCRecordID()},
+		{"WARC-Date", warcDate(time.Now())},
+		{"Content-Type", "application/warc-fields"},
+	}, fields.Bytes())
+	if ww.err != nil {
+		return nil, ww.err
+	}
+	return ww, nil
+}
+
+// Records returns how many exchanges have been written.
+func (w *WARCWriter) Records() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.records
+}
+
+// Close closes the archive file and returns the first write error, if any.
+func (w *WARCWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closer != nil {
+		if err := w.closer.Close(); err != nil && w.err == nil {
+			w.err = err
+		}
+		w.closer = nil
+	}
+	return w.err
+}
+
+// warcExchange is one recorded request and its response.
+type warcExchange struct {
+	Date           time.Time
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	RequestBody    []byte
+	StatusCode     int
+	Status         string // the reason phrase, e.g. "OK"
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	// Truncated is the WARC-Truncated reason when the body is incomplete:
+	// "length" when it hit the body cap, "unspecified" when the reader
+	// stopped early.
+	Truncated string
+}
+
+func (w *WARCWriter) writeExchange(ex *warcExchange) {
+	if w == nil {
+		return
+	}
+	if ex.Date.IsZero() {
+		ex.Date = time.Now()
+	}
+	date := warcDate(ex.Date)
+
+	var resp bytes.Buffer
+	status := ex.Status
+	if status == "" {
+		status = http.StatusText(ex.StatusCode)
+	}
+	fmt.Fprintf(&resp, "HTTP/1.1 %d %s\r\n", ex.StatusCode, status)
+	h := ex.ResponseHeader.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	// The block holds the body as received, so the framing headers are
+	// rewritten to describe it rather than the original wire encoding.
+	h.Del("Transfer-Encoding")
+	h.Set("Content-Length", strconv.Itoa(len(ex.ResponseBody)))
+	h.Write(&resp)
+	resp.WriteString("\r\n")
+	headerLen := resp.Len()
+	resp.Write(ex.ResponseBody)
+
+	var req bytes.Buffer
+	target := ex.URL
+	host := ""
+	if u, err := url.Parse(ex.URL); err == nil {
+		target, host = u.RequestURI(), u.Host
+	}
+	fmt.Fprintf(&req, "%s %s HTTP/1.1\r\n", ex.Method, target)
+	rh := ex.RequestHeader.Clone()
+	if rh == nil {
+		rh = http.Header{}
+	}
+	if host != "" && rh.Get("Host") == "" {
+		rh.Set("Host", host)
+	}
+	rh.Write(&req)
+	req.WriteString("\r\n")
+	req.Write(ex.RequestBody)
+
+	respID := newWARCRecordID()
+	respFields := [][2]string{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", respID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", ex.URL},
+		{"Content-Type", "application/http;msgtype=response"},
+		{"WARC-Block-Digest", warcDigest(resp.Bytes())},
+		{"WARC-Payload-Digest", warcDigest(resp.Bytes()[headerLen:])},
+	}
+	if ex.Truncated != "" {
+		respFields = append(respFields, [2]string{"WARC-Truncated", ex.Truncated})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeRecord(respFields, resp.Bytes())
+	w.writeRecord([][2]string{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", newWARCRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", ex.URL},
+		{"WARC-Concurrent-To", respID},
+		{"Content-Type", "application/http;msgtype=request"},
+		{"WARC-Block-Digest", warcDigest(req.Bytes())},
+	}, req.Bytes())
+	if w.err == nil {
+		w.records++
+	}
+}
+
+// writeRenderStates records the DOM states a render of pageURL produced, the
+// initial load first, as text/html resource records.
+func (w *WARCWriter) writeRenderStates(pageURL string, states [][]byte) {
+	if w == nil {
+		return
+	}
+	date := warcDate(time.Now())
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, html := range states {
+		w.writeRecord([][2]string{
+			{"WARC-Type", "resource"},
+			{"WARC-Record-ID", newWARCRecordID()},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", pageURL},
+			{"Content-Type", "text/html"},
+			{"WARC-Block-Digest", warcDigest(html)},
+			{warcRenderStateField, strconv.Itoa(i)},
+		}, html)
+	}
+}
+
+// writeRecord writes one record. Callers other than NewWARCWriter hold w.mu.
+// After the first error nothing more is written; Close reports it.
+func (w *WARCWriter) writeRecord(fields [][2]string, block []byte) {
+	if w.err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(warcVersion + "\r\n")
+	for _, f := range fields {
+		fmt.Fprintf(&buf, "%s: %s\r\n", f[0], f[1])
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+	if !w.gzip {
+		_, w.err = w.w.Write(buf.Bytes())
+		return
+	}
+	zw := gzip.NewWriter(w.w)
+	if _, w.err = zw.Write(buf.Bytes()); w.err == nil {
+		w.err = zw.Close()
+	}
+}
+
+func newWARCRecordID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func warcDate(t time.Time) string { return t.UTC().Format("2006-01-02T15:04:05Z") }
+
+func warcDigest(b []byte) string {
+	sum := sha1.Sum(b)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// warcRecorder is the transport of a recording client. It sits below the
+// http.Client, so every redirect hop is recorded as its own exchange.
+type warcRecorder struct {
+	base http.RoundTripper
+	w    *WARCWriter
+}
+
+func (t *warcRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		if b, err := req.GetBody(); err == nil {
+			reqBody, _ = readCappedBody(b)
+			b.Close()
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	ex := &warcExchange{
+		Date:           time.Now(),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeader:  req.Header.Clone(),
+		RequestBody:    reqBody,
+		StatusCode:     resp.StatusCode,
+		Status:         strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+		ResponseHeader: resp.Header.Clone(),
+	}
+	// A bodyless answer is complete even if the caller never reads it.
+	if req.Method == http.MethodHead || resp.ContentLength == 0 {
+		t.w.writeExchange(ex)
+		return resp, nil
+	}
+	resp.Body = &warcBody{ReadCloser: resp.Body, w: t.w, ex: ex}
+	return resp, nil
+}
+
+// warcBody tees a response body into its exchange, which is written once the
+// body reaches EOF or is closed. The crawler closes some bodies unread (binary
+// assets); those are recorded as truncated rather than downloaded for the
+// archive's sake.
+type warcBody struct {
+	io.ReadCloser
+	w    *WARCWriter
+	ex   *warcExchange
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (b *warcBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := MaxResponseBodyBytes - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+		if n > room {
+			b.ex.Truncated = "length"
+		}
+	} else if n > 0 {
+		b.ex.Truncated = "length"
+	}
+	if err == io.EOF {
+		b.flush()
+	}
+	return n, err
+}
+
+func (b *warcBody) Close() error {
+	b.once.Do(func() {
+		if b.ex.Truncated == "" {
+			b.ex.Truncated = "unspecified"
+		}
+		b.write()
+	})
+	return b.ReadCloser.Close()
+}
+
+func (b *warcBody) flush() { b.once.Do(b.write) }
+
+func (b *warcBody) write() {
+	b.ex.ResponseBody = b.buf.Bytes()
+	b.w.writeExchange(b.ex)
+}
+
+// renderArchiver records the network traffic of one headless-Chrome render.
+// observe is fed every CDP event from the render's listener; flush fetches
+// the response bodies and writes the exchanges once the page is done with.
+// A nil renderArchiver (no archive configured) ignores both.
+type renderArchiver struct {
+	w        *WARCWriter
+	mu       sync.Mutex
+	order    []network.RequestID
+	inflight map[network.RequestID]*renderExchange
+	// redirects are the hops Chrome followed; they carry no body.
+	redirects []*warcExchange
+}
+
+type renderExchange struct {
+	ex       warcExchange
+	postData bool
+	answered bool
+	finished bool
+}
+
+func (c *Client) newRenderArchiver() *renderArchiver {
+	if c.cfg.Archive == nil {
+		return nil
+	}
+	return &renderArchiver{w: c.cfg.Archive, inflight: make(map[network.RequestID]*renderExchange)}
+}
+
+func (a *renderArchiver) observe(ev interface{}) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		if e.Request == nil || !(strings.HasPrefix(e.Request.URL, "http://") || strings.HasPrefix(e.Request.URL, "https://")) {
+			return
+		}
+		prev, ok := a.inflight[e.RequestID]
+		if ok && e.RedirectResponse != nil {
+			// Chrome reuses the request id across a redirect chain.
+			prev.ex.StatusCode = int(e.RedirectResponse.Status)
+			prev.ex.Status = e.RedirectResponse.StatusText
+			prev.ex.ResponseHeader = cdpHeaders(e.RedirectResponse.Headers)
+			a.redirects = append(a.redirects, &prev.ex)
+		}
+		if !ok {
+			a.order = append(a.order, e.RequestID)
+		}
+		a.inflight[e.RequestID] = &renderExchange{
+			ex: warcExchange{
+				Date:          time.Now(),
+				Method:        e.Request.Method,
+				URL:           e.Request.URL,
+				RequestHeader: cdpHeaders(e.Request.Headers),
+			},
+			postData: e.Request.HasPostData,
+		}
+	case *network.EventResponseReceived:
+		if r, ok := a.inflight[e.RequestID]; ok && e.Response != nil {
+			r.answered = true
+			r.ex.StatusCode = int(e.Response.Status)
+			r.ex.Status = e.Response.StatusText
+			h := cdpHeaders(e.Response.Headers)
+			// Chrome hands bodies back decoded.
+			h.Del("Content-Encoding")
+			r.ex.ResponseHeader = h
+		}
+	case *network.EventLoadingFinished:
+		if r, ok := a.inflight[e.RequestID]; ok {
+			r.finished = true
+		}
+	}
+}
+
+func (a *renderArchiver) flush(ctx context.Context) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	redirects := a.redirects
+	var pending []*renderExchange
+	actions := make([]chromedp.Action, 0, len(a.order))
+	for _, id := range a.order {
+		r := a.inflight[id]
+		if !r.answered {
+			continue
+		}
+		pending = append(pending, r)
+		id := id
+		actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+			if r.postData {
+				if data, err := network.GetRequestPostData(id).Do(ctx); err == nil {
+					r.ex.RequestBody = []byte(data)
+				}
+			}
+			if !r.finished {
+				r.ex.Truncated = "unspecified"
+				return nil
+			}
+			body, err := network.GetResponseBody(id).Do(ctx)
+			switch {
+			case err != nil:
+				r.ex.Truncated = "unspecified"
+			case len(body) > MaxResponseBodyBytes:
+				r.ex.ResponseBody, r.ex.Truncated = body[:MaxResponseBodyBytes], "length"
+			default:
+				r.ex.ResponseBody = body
+			}
+			return nil
+		}))
+	}
+	a.order, a.inflight, a.redirects = nil, make(map[network.RequestID]*renderExchange), nil
+	a.mu.Unlock()
+
+	if len(actions) > 0 {
+		_ = chromedp.Run(ctx, actions...)
+	}
+	for _, ex := range redirects {
+		a.w.writeExchange(ex)
+	}
+	for _, r := range pending {
+		a.w.writeExchange(&r.ex)
+	}
+}
+
+func cdpHeaders(h network.Headers) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		// CDP folds repeated headers into one value separated by newlines.
+		for _, line := range strings.Split(fmt.Sprint(v), "\n") {
+			out.Add(k, line)
+		}
+	}
+	return out
+}
+
+// WARCArchive is a WARC file loaded for replay. It is an http.RoundTripper
+// that answers each request with the recorded response to the same method and
+// URL — and body, when one was recorded with it — and with ErrNotArchived for
+// anything else, so a scan replayed through it runs the full pipeline without
+// network access. A request recorded more than once is answered with the
+// recordings in turn, repeating the last. Calibration probes ask for random
+// paths that differ every run, so a request the archive misses is answered
+// with a recording of the same shape — the same URL with hex tokens of the
+// same lengths in the same places — when there is one. Renders are answered
+// with the page states recorded for the URL. It is safe for concurrent use.
+type WARCArchive struct {
+	targets   []string
+	responses int
+	// rendered holds the DOM states of the last render recorded for each URL.
+	rendered map[string][][]byte
+
+	mu    sync.Mutex
+	byKey map[string][]*warcReplay
+	next  map[string]int
+}
+
+type warcReplay struct {
+	statusCode int
+	status     string
+	header     http.Header
+	body       []byte
+}
+
+// OpenWARC loads the archive in file, gzip compressed or not. It reads WARC
+// 1.0 and 1.1 files written by other tools too.
+func OpenWARC(file string) (*WARCArchive, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a, err := ReadWARC(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return a, nil
+}
+
+// ReadWARC loads an archive from r.
+func ReadWARC(r io.Reader) (*WARCArchive, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+	type rawRecord struct {
+		id, concurrent string
+		method         string
+		reqBody        []byte
+		target         string
+		resp           *warcReplay
+	}
+	var responses []*rawRecord
+	requests := make(map[string]*rawRecord)
+	a := &WARCArchive{byKey: make(map[string][]*warcReplay), next: make(map[string]int), rendered: make(map[string][][]byte)}
+	tp := textproto.NewReader(br)
+	for n := 1; ; n++ {
+		line, err := tp.ReadLine()
+		for err == nil && line == "" {
+			line, err = tp.ReadLine()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if !strings.HasPrefix(line, "WARC/1.") {
+			return nil, fmt.Errorf("record %d: expected a WARC/1.x version line, got %q", n, line)
+		}
+		h, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		size, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+		if err != nil || size < 0 || size > maxWARCRecordBytes {
+			return nil, fmt.Errorf("record %d: bad Content-Length %q", n, h.Get("Content-Length"))
+		}
+		block := make([]byte, size)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		rec := &rawRecord{id: h.Get("WARC-Record-ID"), concurrent: h.Get("WARC-Concurrent-To"), target: h.Get("WARC-Target-URI")}
+		isHTTP := strings.HasPrefix(strings.ToLower(h.Get("Content-Type")), "application/http")
+		switch strings.ToLower(h.Get("WARC-Type")) {
+		case "warcinfo":
+			for _, line := range strings.Split(string(block), "\n") {
+				if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == "jsminer-target" {
+					a.targets = append(a.targets, strings.TrimSpace(v))
+				}
+			}
+		case "response":
+			if !isHTTP || rec.target == "" {
+				continue
+			}
+			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+			if err != nil {
+				return nil, fmt.Errorf("record %d (%s): %w", n, rec.target, err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("record %d (%s): %w", n, rec.target, err)
+			}
+			resp.Header.Del("Transfer-Encoding")
+			resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			rec.resp = &warcReplay{
+				statusCode: resp.StatusCode,
+				status:     resp.Status,
+				header:     resp.Header,
+				body:       body,
+			}
+			responses = append(responses, rec)
+		case "resource":
+			state, err := strconv.Atoi(h.Get(warcRenderStateField))
+			if err != nil || rec.target == "" {
+				continue
+			}
+			// State 0 starts a render; a later render of the page replaces it.
+			if state == 0 {
+				a.rendered[rec.target] = nil
+			}
+			a.rendered[rec.target] = append(a.rendered[rec.target], block)
+		case "request":
+			if !isHTTP {
+				continue
+			}
+			req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(block)))
+			if err != nil {
+				return nil, fmt.Errorf("record %d (%s): %w", n, rec.target, err)
+			}
+			rec.method = req.Method
+			rec.reqBody, _ = io.ReadAll(req.Body)
+			req.Body.Close()
+			requests[rec.id] = rec
+		}
+	}
+	// Requests and responses point at each other with WARC-Concurrent-To;
+	// writers differ on which side carries it.
+	byResponse := make(map[string]*rawRecord, len(requests))
+	for _, req := range requests {
+		if req.concurrent != "" {
+			byResponse[req.concurrent] = req
+		}
+	}
+	for _, resp := range responses {
+		method := http.MethodGet
+		var body []byte
+		req := byResponse[resp.id]
+		if req == nil {
+			req = requests[resp.concurrent]
+		}
+		if req != nil {
+			method, body = req.method, req.reqBody
+		}
+		key := warcKey(method, resp.target, nil)
+		a.byKey[key] = append(a.byKey[key], resp.resp)
+		if shape := warcShape(resp.target); shape != resp.target {
+			key = warcKey(method, shape, nil)
+			a.byKey[key] = append(a.byKey[key], resp.resp)
+		}
+		if len(body) > 0 {
+			key = warcKey(method, resp.target, body)
+			a.byKey[key] = append(a.byKey[key], resp.resp)
+		}
+		a.responses++
+	}
+	return a, nil
+}
+
+// warcHexRun matches the random hex tokens calibration probes are built from.
+var warcHexRun = regexp.MustCompile(`[0-9a-f]{10,}`)
+
+// warcShape replaces the hex tokens in u with placeholders of their length.
+func warcShape(u string) string {
+	return warcHexRun.ReplaceAllStringFunc(u, func(tok string) string {
+		return "{hex" + strconv.Itoa(len(tok)) + "}"
+	})
+}
+
+func warcKey(method, url string, body []byte) string {
+	key := strings.ToUpper(method) + " " + url
+	if len(body) > 0 {
+		key += " " + warcDigest(body)
+	}
+	return key
+}
+
+// Targets returns the seed URLs of the recorded run, from the archive's
+// warcinfo record.
+func (a *WARCArchive) Targets() []string { return append([]string(nil), a.targets...) }
+
+// Len returns how many responses the archive holds.
+func (a *WARCArchive) Len() int { return a.responses }
+
+// renderStates returns the recorded DOM states of pageURL, the initial load
+// first, or errReplayRender when it was not rendered.
+func (a *WARCArchive) renderStates(pageURL string) ([][]byte, error) {
+	states := a.rendered[pageURL]
+	if len(states) == 0 {
+		return nil, fmt.Errorf("%s: %w", pageURL, errReplayRender)
+	}
+	return states, nil
+}
+
+// RoundTrip answers req from the archive.
+func (a *WARCArchive) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = readCappedBody(req.Body)
+		req.Body.Close()
+	}
+	u := req.URL.String()
+	a.mu.Lock()
+	key := warcKey(req.Method, u, body)
+	list := a.byKey[key]
+	if len(list) == 0 && len(body) > 0 {
+		key = warcKey(req.Method, u, nil)
+		list = a.byKey[key]
+	}
+	if shape := warcShape(u); len(list) == 0 && shape != u {
+		key = warcKey(req.Method, shape, nil)
+		list = a.byKey[key]
+	}
+	var rec *warcReplay
+	if len(list) > 0 {
+		i := a.next[key]
+		rec = list[min(i, len(list)-1)]
+		a.next[key] = i + 1
+	}
+	a.mu.Unlock()
+	if rec == nil {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, u, ErrNotArchived)
+	}
+	payload := rec.body
+	if req.Method == http.MethodHead {
+		payload = nil
+	}
+	return &http.Response{
+		Status:        rec.status,
+		StatusCode:    rec.statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(rec.body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestWARCRecordAndReplay records a source-map-recovering scan and a redirect
+// through the client's archive, then replays the archive with the server gone
+// and expects the same findings.
+func TestWARCRecordAndReplay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		io.WriteString(w, "console.log(1);\n//# sourceMappingURL=/app.js.map")
+	})
+	mux.HandleFunc("/app.js.map", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"version":3,"sources":["src/config.js"],"sourcesContent":["const token='eyJabc.def.ghi';"]}`)
+	})
+	mux.HandleFunc("/old.js", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/app.js", http.StatusFound)
+	})
+	ts := httptest.NewServer(mux)
+
+	file := filepath.Join(t.TempDir(), "scan.warc.gz")
+	archive, err := CreateWARC(file, WARCInfo{Software: "JSMiner/test", Targets: []string{ts.URL + "/old.js"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultClientConfig()
+	cfg.FollowRedirects = true
+	cfg.Archive = archive
+	e := NewExtractor(false, false)
+	e.SetClient(NewClient(cfg))
+	live, err := e.ScanURL(context.Background(), ts.URL+"/old.js", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasPattern(live, "jwt") {
+		t.Fatalf("live scan found no jwt: %+v", live)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The redirect hop, the bundle and its source map.
+	if got := archive.Records(); got != 3 {
+		t.Fatalf("archived %d exchanges, want 3", got)
+	}
+	ts.Close()
+
+	replay, err := OpenWARC(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replay.Targets(); len(got) != 1 || got[0] != ts.URL+"/old.js" {
+		t.Fatalf("Targets = %v", got)
+	}
+	cfg = DefaultClientConfig()
+	cfg.FollowRedirects = true
+	cfg.Replay = replay
+	e = NewExtractor(false, false)
+	e.SetClient(NewClient(cfg))
+	replayed, err := e.ScanURL(context.Background(), ts.URL+"/old.js", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warcMatchKeys(replayed) != warcMatchKeys(live) {
+		t.Fatalf("replay differs from the live scan:\nlive   %+v\nreplay %+v", live, replayed)
+	}
+
+	if _, err := e.ScanURL(context.Background(), ts.URL+"/missing.js", false, false, false); !errors.Is(err, ErrNotArchived) {
+		t.Fatalf("unrecorded URL: err = %v, want ErrNotArchived", err)
+	}
+	if _, _, err := NewClient(cfg).RenderURL(context.Background(), ts.URL+"/"); !errors.Is(err, errReplayRender) {
+		t.Fatalf("render during replay: err = %v", err)
+	}
+}
+
+func warcMatchKeys(ms []Match) string {
+	keys := make([]string, 0, len(ms))
+	for _, m := range ms {
+		keys = append(keys, m.Pattern+"|"+m.Value+"|"+m.Source)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
+
+func TestWARCArchiveMatching(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWARCWriter(&buf, false, WARCInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ex := range []*warcExchange{
+		{Method: "GET", URL: "https://a.test/x", StatusCode: 200, ResponseBody: []byte("first")},
+		{Method: "GET", URL: "https://a.test/x", StatusCode: 200, ResponseBody: []byte("second")},
+		{Method: "POST", URL: "https://a.test/x", RequestBody: []byte("a=1"), StatusCode: 201, ResponseBody: []byte("posted")},
+		{Method: "GET", URL: "https://a.test/0123456789abcdef0123", StatusCode: 404, ResponseBody: []byte("probe")},
+		{Method: "GET", URL: "https://a.test/big", StatusCode: 200, ResponseBody: []byte("part"), Truncated: "length"},
+	} {
+		w.writeExchange(ex)
+	}
+	if !strings.HasPrefix(buf.String(), "WARC/1.1\r\nWARC-Type: warcinfo\r\n") {
+		t.Fatalf("archive does not open with a warcinfo record:\n%s", buf.String())
+	}
+	a, err := ReadWARC(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() != 5 {
+		t.Fatalf("Len = %d, want 5", a.Len())
+	}
+	get := func(method, u, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, u, strings.NewReader(body))
+		resp, err := a.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, u, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	for _, want := range []string{"first", "second", "second"} {
+		if _, got := get("GET", "https://a.test/x", ""); got != want {
+			t.Errorf("repeated GET = %q, want %q", got, want)
+		}
+	}
+	if code, got := get("POST", "https://a.test/x", "a=1"); code != 201 || got != "posted" {
+		t.Errorf("POST = %d %q", code, got)
+	}
+	// A calibration probe asks for a different random path every run.
+	if code, got := get("GET", "https://a.test/fedcba9876543210fedc", ""); code != 404 || got != "probe" {
+		t.Errorf("probe of the same shape = %d %q", code, got)
+	}
+	if _, got := get("GET", "https://a.test/big", ""); got != "part" {
+		t.Errorf("truncated body = %q", got)
+	}
+	req, _ := http.NewRequest("GET", "https://a.test/fedcba98765", nil)
+	if _, err := a.RoundTrip(req); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("different shape: err = %v, want ErrNotArchived", err)
+	}
+}
+
+// TestWARCReplayRenderedStates replays a page whose secret only appeared once
+// it was rendered: the recorded DOM states are scanned in place of a browser.
+func TestWARCReplayRenderedStates(t *testing.T) {
+	const page = "https://a.test/"
+	var buf bytes.Buffer
+	w, err := NewWARCWriter(&buf, false, WARCInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.writeExchange(&warcExchange{
+		Method: "GET", URL: page, StatusCode: 200,
+		ResponseHeader: http.Header{"Content-Type": {"text/html"}},
+		ResponseBody:   []byte(`<html><body><div id="app"></div></body></html>`),
+	})
+	w.writeRenderStates(page, [][]byte{
+		[]byte(`<html><body><div id="app">loading</div></body></html>`),
+		[]byte(`<html><body><div id="app">token: eyJabc.def.ghi</div></body></html>`),
+	})
+	a, err := ReadWARC(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultClientConfig()
+	cfg.Replay = a
+	c := NewClient(cfg)
+	states, _, _, _, _, err := c.RenderURLWithStates(context.Background(), page)
+	if err != nil || len(states) != 2 {
+		t.Fatalf("RenderURLWithStates = %d states, %v; want 2", len(states), err)
+	}
+	if _, _, err := c.RenderURL(context.Background(), "https://a.test/other"); !errors.Is(err, errReplayRender) {
+		t.Fatalf("unrendered page: err = %v, want errReplayRender", err)
+	}
+
+	e := NewExtractor(false, false)
+	e.SetClient(c)
+	ms, err := e.ScanURL(context.Background(), page, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasPattern(ms, "jwt") {
+		t.Fatalf("unrendered replay scanned the rendered state: %+v", ms)
+	}
+	ms, err = e.ScanURL(context.Background(), page, false, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasPattern(ms, "jwt") {
+		t.Fatalf("rendered replay found no jwt: %+v", ms)
+	}
+}
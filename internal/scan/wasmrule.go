@@ -0,0 +1,286 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// WASMLimits bounds what a WebAssembly rule plugin may use on each Find.
+type WASMLimits struct {
+	// MaxMemory caps the plugin's linear memory in bytes.
+	MaxMemory uint64
+	// Timeout bounds each call into the plugin.
+	Timeout time.Duration
+}
+
+// DefaultWASMLimits are the limits used by the CLI unless overridden.
+var DefaultWASMLimits = WASMLimits{MaxMemory: 64 << 20, Timeout: 2 * time.Second}
+
+// wasmRule is a Rule implemented by a WebAssembly module. The plugin ABI is
+// deliberately small; strings and buffers cross the boundary as a pointer
+// into the plugin's memory packed with a length into one i64 (ptr<<32 | len):
+//
+//	memory                     the module's exported linear memory
+//	alloc(size i32) i32        reserve size bytes for the host to write into
+//	name() i64                 the rule name reported as the match pattern
+//	find(ptr, len i32) i64     scan the input; returns a buffer of little-endian
+//	                           u32 pairs, the [start, end) byte offsets of each
+//	                           match in the input
+//	severity() i64             optional: high, medium, low or info (default)
+//	verify(ptr, len i32) i32   optional: 0 drops the matched value
+//
+// Plugin matches are candidate secrets, like those of rule files.
+//
+// A plugin may import jsminer.log(ptr, len i32) to write to the verbose log
+// and nothing else. Modules run on wazero, which validates them when they are
+// compiled. Every Find runs on a fresh instance, so a plugin needs no allocator
+// that frees memory and cannot carry anything from one input to the next.
+type wasmRule struct {
+	name     string
+	severity string
+	verify   bool
+	timeout  time.Duration
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// wasmFuncType is the signature an ABI export must have.
+type wasmFuncType struct{ params, results []api.ValueType }
+
+var (
+	wasmPacked = wasmFuncType{results: []api.ValueType{api.ValueTypeI64}}
+	wasmAlloc  = wasmFuncType{params: []api.ValueType{api.ValueTypeI32}, results: []api.ValueType{api.ValueTypeI32}}
+	wasmFind   = wasmFuncType{params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, results: []api.ValueType{api.ValueTypeI64}}
+	wasmVerify = wasmFuncType{params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, results: []api.ValueType{api.ValueTypeI32}}
+)
+
+func (t wasmFuncType) matches(def api.FunctionDefinition) bool {
+	return slices.Equal(def.ParamTypes(), t.params) && slices.Equal(def.ResultTypes(), t.results)
+}
+
+func (t wasmFuncType) String() string {
+	names := func(ts []api.ValueType) string {
+		out := make([]string, len(ts))
+		for i, v := range ts {
+			out[i] = api.ValueTypeName(v)
+		}
+		return "(" + strings.Join(out, ", ") + ")"
+	}
+	return names(t.params) + " -> " + names(t.results)
+}
+
+// LoadWASMRule compiles the WebAssembly rule plugin at path and checks that
+// it implements the plugin ABI. Each call into the plugin runs under limits.
+// The returned rule can be registered with RegisterRule.
+func LoadWASMRule(path string, limits WASMLimits) (Rule, error) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	cfg := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if pages := limits.MaxMemory / wasmPageSize; pages > 0 {
+		cfg = cfg.WithMemoryLimitPages(uint32(min(pages, 1<<16)))
+	}
+	r := &wasmRule{severity: SeverityInfo, timeout: limits.Timeout, runtime: wazero.NewRuntimeWithConfig(ctx, cfg)}
+	fail := func(err error) (Rule, error) {
+		r.runtime.Close(ctx)
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	_, err = r.runtime.NewHostModuleBuilder("jsminer").
+		NewFunctionBuilder().WithFunc(r.log).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	if r.compiled, err = r.runtime.CompileModule(ctx, bin); err != nil {
+		return fail(err)
+	}
+
+	exports := r.compiled.ExportedFunctions()
+	required := map[string]wasmFuncType{"alloc": wasmAlloc, "name": wasmPacked, "find": wasmFind}
+	optional := map[string]wasmFuncType{"severity": wasmPacked, "verify": wasmVerify}
+	for _, set := range []map[string]wasmFuncType{required, optional} {
+		for name, want := range set {
+			def, ok := exports[name]
+			if !ok {
+				if _, req := required[name]; req {
+					return fail(fmt.Errorf("plugin does not export %s", name))
+				}
+				continue
+			}
+			if !want.matches(def) {
+				got := wasmFuncType{params: def.ParamTypes(), results: def.ResultTypes()}
+				return fail(fmt.Errorf("%s has type %v, want %v", name, got, want))
+			}
+		}
+	}
+	if len(r.compiled.ExportedMemories()) == 0 {
+		return fail(errors.New("plugin does not export its memory"))
+	}
+	_, r.verify = exports["verify"]
+
+	err = r.run(func(ctx context.Context, mod api.Module) error {
+		var err error
+		if r.name, err = wasmString(ctx, mod, "name"); err != nil {
+			return fmt.Errorf("name: %w", err)
+		}
+		if r.name == "" {
+			return errors.New("plugin has an empty name")
+		}
+		if _, ok := exports["severity"]; ok {
+			if r.severity, err = wasmString(ctx, mod, "severity"); err != nil {
+				return fmt.Errorf("severity: %w", err)
+			}
+			if severityRank(r.severity) == 0 {
+				return fmt.Errorf("unknown severity %q", r.severity)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+	return r, nil
+}
+
+// wasmPageSize is the size of a WebAssembly linear memory page.
+const wasmPageSize = 64 << 10
+
+func (r *wasmRule) MatchName() string { return r.name }
+
+func (r *wasmRule) log(_ context.Context, mod api.Module, ptr, n uint32) {
+	if msg, ok := mod.Memory().Read(ptr, n); ok {
+		vlog(2, "plugin %s: %s", r.name, msg)
+	}
+}
+
+// run calls fn with a fresh instance of the plugin that is closed when fn
+// returns or the plugin's timeout expires, whichever comes first.
+func (r *wasmRule) run(fn func(context.Context, api.Module) error) error {
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	// An empty name lets instances of one plugin run concurrently.
+	mod, err := r.runtime.InstantiateModule(ctx, r.compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return err
+	}
+	defer mod.Close(context.Background())
+	return fn(ctx, mod)
+}
+
+// Find runs the plugin over data. A plugin that traps, overruns its limits or
+// returns malformed results is logged and contributes no matches.
+func (r *wasmRule) Find(data []byte) []Match {
+	var out []Match
+	err := r.run(func(ctx context.Context, mod api.Module) error {
+		var err error
+		out, err = r.find(ctx, mod, data)
+		return err
+	})
+	if err != nil {
+		vlog(1, "plugin %s: %v", r.name, err)
+		return nil
+	}
+	return out
+}
+
+func (r *wasmRule) find(ctx context.Context, mod api.Module, data []byte) ([]Match, error) {
+	ptr, err := wasmWrite(ctx, mod, data)
+	if err != nil {
+		return nil, err
+	}
+	res, err := mod.ExportedFunction("find").Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	buf, err := wasmRead(mod, res[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(buf)%8 != 0 {
+		return nil, fmt.Errorf("find returned %d bytes, not a whole number of matches", len(buf))
+	}
+	// buf is a view of the plugin's memory, which verify may overwrite.
+	buf = slices.Clone(buf)
+	var out []Match
+	for i := 0; i < len(buf); i += 8 {
+		start := binary.LittleEndian.Uint32(buf[i:])
+		end := binary.LittleEndian.Uint32(buf[i+4:])
+		if start > end || uint64(end) > uint64(len(data)) {
+			return nil, fmt.Errorf("match [%d, %d) is outside the %d-byte input", start, end, len(data))
+		}
+		value := data[start:end]
+		if r.verify {
+			ok, err := r.check(ctx, mod, value)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		out = append(out, Match{Pattern: r.name, Value: string(value), Severity: r.severity, Secret: true})
+	}
+	return out, nil
+}
+
+func (r *wasmRule) check(ctx context.Context, mod api.Module, value []byte) (bool, error) {
+	ptr, err := wasmWrite(ctx, mod, value)
+	if err != nil {
+		return false, err
+	}
+	res, err := mod.ExportedFunction("verify").Call(ctx, uint64(ptr), uint64(len(value)))
+	if err != nil {
+		return false, err
+	}
+	return uint32(res[0]) != 0, nil
+}
+
+// wasmWrite copies b into memory the plugin allocates for it.
+func wasmWrite(ctx context.Context, mod api.Module, b []byte) (uint32, error) {
+	if uint64(len(b)) > 1<<32-1 {
+		return 0, errors.New("input too large for a 32-bit plugin")
+	}
+	res, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(b)))
+	if err != nil {
+		return 0, err
+	}
+	ptr := uint32(res[0])
+	if !mod.Memory().Write(ptr, b) {
+		return 0, fmt.Errorf("alloc(%d): %d is outside the plugin's memory", len(b), ptr)
+	}
+	return ptr, nil
+}
+
+// wasmRead returns the buffer a packed ptr<<32|len result points at.
+func wasmRead(mod api.Module, packed uint64) ([]byte, error) {
+	ptr, n := uint32(packed>>32), uint32(packed)
+	b, ok := mod.Memory().Read(ptr, n)
+	if !ok {
+		return nil, fmt.Errorf("buffer [%d, +%d) is outside the plugin's memory", ptr, n)
+	}
+	return b, nil
+}
+
+func wasmString(ctx context.Context, mod api.Module, fn string) (string, error) {
+	res, err := mod.ExportedFunction(fn).Call(ctx)
+	if err != nil {
+		return "", err
+	}
+	b, err := wasmRead(mod, res[0])
+	return string(b), err
+}
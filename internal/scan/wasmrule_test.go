@@ -0,0 +1,187 @@
+package scan
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wasmPlugin assembles a rule plugin: one page of exported memory, a bump
+// allocator over global 0, name "digits" and severity "medium" in a data
+// segment, the given find body, and a verify that rejects values starting
+// with '0'.
+func wasmPlugin(find []byte) []byte {
+	leb := func(v uint32) []byte {
+		var out []byte
+		for {
+			c := byte(v & 0x7f)
+			v >>= 7
+			if v == 0 {
+				return append(out, c)
+			}
+			out = append(out, c|0x80)
+		}
+	}
+	vec := func(items ...[]byte) []byte {
+		out := leb(uint32(len(items)))
+		for _, it := range items {
+			out = append(out, it...)
+		}
+		return out
+	}
+	section := func(id byte, body []byte) []byte {
+		return append(append([]byte{id}, leb(uint32(len(body)))...), body...)
+	}
+	name := func(s string) []byte { return append(leb(uint32(len(s))), s...) }
+	body := func(locals []byte, code ...byte) []byte {
+		b := append(locals, code...)
+		b = append(b, 0x0b)
+		return append(leb(uint32(len(b))), b...)
+	}
+
+	const i32, i64 = 0x7f, 0x7e
+	out := []byte("\x00asm\x01\x00\x00\x00")
+	out = append(out, section(1, vec(
+		[]byte{0x60, 1, i32, 1, i32},      // 0: alloc
+		[]byte{0x60, 0, 1, i64},           // 1: name, severity
+		[]byte{0x60, 2, i32, i32, 1, i64}, // 2: find
+		[]byte{0x60, 2, i32, i32, 1, i32}, // 3: verify
+	))...)
+	out = append(out, section(3, vec([]byte{0}, []byte{1}, []byte{1}, []byte{2}, []byte{3}))...)
+	out = append(out, section(5, vec([]byte{0, 1}))...)
+	out = append(out, section(6, vec([]byte{i32, 1, 0x41, 0x80, 0x08, 0x0b}))...) // heap = 1024
+	out = append(out, section(7, vec(
+		append(name("memory"), 2, 0),
+		append(name("alloc"), 0, 0),
+		append(name("name"), 0, 1),
+		append(name("severity"), 0, 2),
+		append(name("find"), 0, 3),
+		append(name("verify"), 0, 4),
+	))...)
+	out = append(out, section(10, vec(
+		// alloc: old = heap; heap += n; old
+		body([]byte{0}, 0x23, 0, 0x23, 0, 0x20, 0, 0x6a, 0x24, 0),
+		// name: 16<<32 | 6
+		body([]byte{0}, 0x42, 0x86, 0x80, 0x80, 0x80, 0x80, 0x02),
+		// severity: 32<<32 | 6
+		body([]byte{0}, 0x42, 0x86, 0x80, 0x80, 0x80, 0x80, 0x04),
+		body([]byte{1, 4, i32}, find...),
+		// verify: mem[ptr] != '0'
+		body([]byte{0}, 0x20, 0, 0x2d, 0, 0, 0x41, 0x30, 0x47),
+	))...)
+	out = append(out, section(11, vec(
+		append([]byte{0, 0x41, 16, 0x0b}, name("digits")...),
+		append([]byte{0, 0x41, 32, 0x0b}, name("medium")...),
+	))...)
+	return out
+}
+
+// digitRunsFind reports runs of four or more ASCII digits. Locals: 0 ptr,
+// 1 len, 2 i, 3 start (-1 outside a run), 4 out, 5 w.
+var digitRunsFind = []byte{
+	0x23, 0, 0x22, 4, 0x21, 5, // out = w = heap
+	0x41, 0x7f, 0x21, 3, // start = -1
+	0x02, 0x40, 0x03, 0x40, // block, loop
+	// digit = i < len && mem[ptr+i]-'0' <u 10
+	0x20, 2, 0x20, 1, 0x49,
+	0x04, 0x7f, // if (result i32)
+	0x20, 0, 0x20, 2, 0x6a, 0x2d, 0, 0, 0x41, 0x30, 0x6b, 0x41, 10, 0x49,
+	0x05, 0x41, 0, 0x0b,
+	0x04, 0x40,
+	// in a digit: start a run if none is open
+	0x20, 3, 0x41, 0x7f, 0x46, 0x04, 0x40, 0x20, 2, 0x21, 3, 0x0b,
+	0x05,
+	// otherwise close an open run, recording it when long enough
+	0x20, 3, 0x41, 0x7f, 0x47, 0x04, 0x40,
+	0x20, 2, 0x20, 3, 0x6b, 0x41, 4, 0x4f, 0x04, 0x40,
+	0x20, 5, 0x20, 3, 0x36, 2, 0,
+	0x20, 5, 0x20, 2, 0x36, 2, 4,
+	0x20, 5, 0x41, 8, 0x6a, 0x21, 5,
+	0x0b,
+	0x41, 0x7f, 0x21, 3,
+	0x0b,
+	0x0b,
+	0x20, 2, 0x20, 1, 0x4f, 0x0d, 1, // i >= len: done
+	0x20, 2, 0x41, 1, 0x6a, 0x21, 2,
+	0x0c, 0,
+	0x0b, 0x0b,
+	// out<<32 | (w - out)
+	0x20, 4, 0xad, 0x42, 32, 0x86, 0x20, 5, 0x20, 4, 0x6b, 0xad, 0x84,
+}
+
+func TestWASMRule(t *testing.T) {
+	r, err := LoadWASMRule(writeRuleFile(t, "plugin.wasm", string(wasmPlugin(digitRunsFind))), DefaultWASMLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MatchName() != "digits" {
+		t.Fatalf("name = %q", r.MatchName())
+	}
+	got := r.Find([]byte("pin 1234, zip 0421, short 99, tail 56789"))
+	if len(got) != 2 || got[0].Value != "1234" || got[1].Value != "56789" {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+	if got[0].Pattern != "digits" || got[0].Severity != SeverityMedium {
+		t.Fatalf("unexpected match metadata %+v", got[0])
+	}
+
+	// Instances are reset between calls and pooled across goroutines.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if ms := r.Find([]byte("a 98765 b")); len(ms) != 1 || ms[0].Value != "98765" {
+					t.Errorf("concurrent find: %+v", ms)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWASMRuleLimits(t *testing.T) {
+	spin := []byte{0x03, 0x40, 0x0c, 0, 0x0b, 0x42, 0}
+	r, err := LoadWASMRule(writeRuleFile(t, "plugin.wasm", string(wasmPlugin(spin))), WASMLimits{MaxMemory: 1 << 20, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if ms := r.Find([]byte("1234")); ms != nil {
+		t.Fatalf("a timed-out plugin should contribute nothing, got %+v", ms)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("timeout enforced late: %v", d)
+	}
+
+	// The input does not fit a one-page plugin capped at that page.
+	r, err = LoadWASMRule(writeRuleFile(t, "plugin.wasm", string(wasmPlugin(digitRunsFind))), WASMLimits{MaxMemory: 64 << 10, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := r.Find([]byte(strings.Repeat("7", 70<<10))); ms != nil {
+		t.Fatalf("an oversized input should be refused, got %d matches", len(ms))
+	}
+}
+
+func TestLoadWASMRuleRejects(t *testing.T) {
+	if _, err := LoadWASMRule(writeRuleFile(t, "plugin.wasm", "\x7fELF\x02\x01\x01"), DefaultWASMLimits); err == nil {
+		t.Fatal("expected an error for a non-WebAssembly file")
+	}
+	// find leaves an i32 where its signature promises an i64, which the
+	// validator rejects before anything runs.
+	if _, err := LoadWASMRule(writeRuleFile(t, "plugin.wasm", string(wasmPlugin([]byte{0x41, 0}))), DefaultWASMLimits); err == nil {
+		t.Fatal("expected a validation error for an ill-typed function body")
+	}
+	bin := wasmPlugin(digitRunsFind)
+	// Rename the find export so the plugin no longer satisfies the ABI.
+	i := strings.Index(string(bin), "find")
+	bin[i] = 'F'
+	_, err := LoadWASMRule(writeRuleFile(t, "plugin.wasm", string(bin)), DefaultWASMLimits)
+	if err == nil || !strings.Contains(err.Error(), "does not export find") {
+		t.Fatalf("expected a missing export error, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package scan
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"net/url"
 	"regexp"
@@ -92,7 +93,7 @@ const maxRobotsCrawlDelay = 30 * time.Second
 // wellKnownMaxURLs; ordering follows discovery so robots directories come first.
 // crawlDelay is the robots.txt Crawl-delay for the catch-all group (zero when
 // none), so the caller can pace the crawl to what the site asked for.
-func discoverWellKnownURLs(origin string) (out []string, crawlDelay time.Duration) {
+func discoverWellKnownURLs(ctx context.Context, client *Client, origin string) (out []string, crawlDelay time.Duration) {
 	// Sitemap documents are fetched here, and the sitemap targets that get fetched
 	// are drawn from target-controlled input: robots.txt Sitemap: pointers and the
 	// <loc> children of sitemap indexes. Left unchecked, a hostile robots.txt could
@@ -122,7 +123,7 @@ func discoverWellKnownURLs(origin string) (out []string, crawlDelay time.Duratio
 
 	// robots.txt: directory hints plus explicit sitemap pointers.
 	sitemaps := []string{origin + "/sitemap.xml"}
-	if body, ok := fetchWellKnownBody(origin + "/robots.txt"); ok {
+	if body, ok := fetchWellKnownBody(ctx, client, origin+"/robots.txt"); ok {
 		dirs, sm, cd := parseRobots(body, origin)
 		crawlDelay = cd
 		for _, s := range sm {
@@ -163,7 +164,7 @@ func discoverWellKnownURLs(origin string) (out []string, crawlDelay time.Duratio
 			continue
 		}
 		fetched[sm] = struct{}{}
-		body, ok := fetchWellKnownBody(sm)
+		body, ok := fetchWellKnownBody(ctx, client, sm)
 		if !ok {
 			continue
 		}
@@ -303,10 +304,10 @@ func looksLikeSitemap(raw string) bool {
 		strings.HasSuffix(lower, ".xml") && strings.Contains(lower, "sitemap")
 }
 
-// fetchWellKnownBody GETs u through the shared (throttled) request path and
+// fetchWellKnownBody GETs u through the client's (throttled) request path and
 // returns its body as a string, capped so a large sitemap cannot exhaust memory.
 // ok is false on any transport error or non-2xx status.
-func fetchWellKnownBody(u string) (string, bool) {
+func fetchWellKnownBody(ctx context.Context, client *Client, u string) (string, bool) {
 	parsed, err := url.Parse(u)
 	if err != nil || parsed.Hostname() == "" {
 		return "", false
@@ -314,7 +315,7 @@ func fetchWellKnownBody(u string) (string, bool) {
 	// Sitemap and robots URLs are target-controlled. Keep their redirect chain in
 	// the original URL's scope so an apparently safe sitemap cannot redirect the
 	// scanner to a loopback, cloud-metadata or unrelated host.
-	resp, err := fetchURLResponseScoped(u, parsed.Hostname())
+	resp, err := client.fetchURLResponseScoped(ctx, u, parsed.Hostname())
 	if err != nil {
 		return "", false
 	}
@@ -0,0 +1,275 @@
+// Package jsminer is the importable API of JSMiner. A Scanner is built from an
+// Options value and scans readers, URLs and whole sites for secrets and
+// endpoints, handing each finding to a callback:
+//
+//	s, err := jsminer.New(jsminer.DefaultOptions())
+//	if err != nil {
+//		return err
+//	}
+//	err = s.ScanURL(ctx, "https://example.com", func(m jsminer.Match) {
+//		fmt.Println(m.Pattern, m.Value)
+//	})
+//
+// The types here are a stable surface over the scanner in internal/scan: they
+// only grow, and a field added to the internal options is exposed here
+// deliberately rather than by accident.
+package jsminer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tavgar/JSMiner/internal/scan"
+)
+
+// Match is a single finding.
+type Match struct {
+	Source   string `json:"source"`
+	Pattern  string `json:"pattern"`
+	Value    string `json:"value"`
+	Params   string `json:"params,omitempty"`
+	Severity string `json:"severity"`
+	// Confidence scores a candidate secret from 1 to 100 by how likely it is
+	// to be live; it is zero for endpoints and other unscored findings.
+	Confidence int `json:"confidence,omitempty"`
+	// Snippet is the source surrounding the value when Options.Snippets is
+	// set.
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Handler receives findings as they are produced. A Scanner never calls the
+// handler of one scan from two goroutines at once.
+type Handler func(Match)
+
+// Options configures a Scanner. Start from DefaultOptions; the zero value is
+// also usable, but verifies TLS certificates and never retries a fetch.
+type Options struct {
+	// Safe restricts the built-in rules to JavaScript sources and the
+	// high-signal subset used by the CLI's -safe mode.
+	Safe bool
+	// LongSecrets enables the long generic secret rule.
+	LongSecrets bool
+	// RuleFiles lists extra rule files or directories, as for the CLI's -rules.
+	RuleFiles []string
+	// Allowlist is a file of source suffixes to ignore, as for -allow.
+	Allowlist string
+
+	// Endpoints reports only endpoint findings, as for -endpoints.
+	Endpoints bool
+	// MinConfidence drops candidate secrets scored below it, as for
+	// -min-confidence; zero keeps them all.
+	MinConfidence int
+	// External follows scripts and imports hosted on other domains.
+	External bool
+	// Render loads pages in headless Chrome before scanning them.
+	Render bool
+	// RenderWait is how long a rendered page is given to settle.
+	RenderWait time.Duration
+	// ChromePath overrides the Chrome executable used for rendering.
+	ChromePath string
+	// NoSourceMaps disables recovering original sources from source maps.
+	NoSourceMaps bool
+	// Snippets captures a window of source around each finding.
+	Snippets bool
+
+	// Headers are sent with every request.
+	Headers http.Header
+	// HTTPTimeout bounds each HTTP request.
+	HTTPTimeout time.Duration
+	// Retries is how many extra attempts a transient fetch error earns.
+	Retries int
+	// RateLimit caps requests per second per host; zero means unlimited.
+	RateLimit float64
+	// Insecure skips TLS certificate verification.
+	Insecure bool
+	// FollowRedirects follows 3xx responses.
+	FollowRedirects bool
+
+	// Crawl configures Scanner.Crawl.
+	Crawl CrawlOptions
+}
+
+// CrawlOptions configures how far Scanner.Crawl follows discovered links.
+type CrawlOptions struct {
+	// MaxDepth is the number of link hops beyond the seed; negative is
+	// unlimited.
+	MaxDepth int
+	// MaxPages caps the pages fetched; zero means no cap.
+	MaxPages int
+	// SameScopeOnly keeps the crawl on the seed host and its subdomains.
+	SameScopeOnly bool
+	// Concurrency is the number of pages fetched in parallel.
+	Concurrency int
+	// ProbeMethods requests each page with several HTTP methods and reports
+	// the ones that work.
+	ProbeMethods bool
+	// DiscoverWellKnown seeds the crawl from robots.txt and sitemaps.
+	DiscoverWellKnown bool
+	// DiscoverPassive validates historical paths from public web archives.
+	DiscoverPassive bool
+	// Permute retries discovered paths under every directory level seen.
+	Permute bool
+	// TemplateDedup crawls only a few pages of each templated URL class.
+	TemplateDedup bool
+}
+
+// DefaultOptions returns the settings the CLI uses when no flags are given.
+func DefaultOptions() Options {
+	c := scan.DefaultCrawlOptions()
+	cfg := scan.DefaultClientConfig()
+	return Options{
+		RenderWait:  cfg.RenderSleep,
+		HTTPTimeout: cfg.Timeout,
+		Retries:     cfg.Retries,
+		Insecure:    cfg.SkipTLSVerification,
+		Crawl: CrawlOptions{
+			MaxDepth:          c.MaxDepth,
+			MaxPages:          c.MaxPages,
+			SameScopeOnly:     c.SameScopeOnly,
+			Concurrency:       c.Concurrency,
+			ProbeMethods:      c.ProbeMethods,
+			DiscoverWellKnown: c.DiscoverWellKnown,
+			TemplateDedup:     c.TemplateDedup,
+		},
+	}
+}
+
+// Scanner scans sources with a fixed set of options. It is safe for
+// concurrent use.
+type Scanner struct {
+	opts Options
+	ex   *scan.Extractor
+}
+
+// New builds a Scanner, loading any rule files and allowlist in opts.
+func New(opts Options) (*Scanner, error) {
+	ex := scan.NewExtractor(opts.Safe, opts.LongSecrets)
+	ex.SetSnippet(opts.Snippets)
+	ex.SetRecoverSourceMaps(!opts.NoSourceMaps)
+	for _, rf := range opts.RuleFiles {
+		if err := ex.LoadRulesFile(rf); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Allowlist != "" {
+		if err := ex.LoadAllowlist(opts.Allowlist); err != nil {
+			return nil, err
+		}
+	}
+	cfg := scan.DefaultClientConfig()
+	cfg.Headers = opts.Headers
+	cfg.Timeout = opts.HTTPTimeout
+	cfg.Retries = opts.Retries
+	cfg.RateLimit = opts.RateLimit
+	cfg.SkipTLSVerification = opts.Insecure
+	cfg.FollowRedirects = opts.FollowRedirects
+	cfg.ChromePath = opts.ChromePath
+	if opts.RenderWait > 0 {
+		cfg.RenderSleep = opts.RenderWait
+	}
+	ex.SetClient(scan.NewClient(cfg))
+	return &Scanner{opts: opts, ex: ex}, nil
+}
+
+// ScanReader scans r as the named source. The name decides whether the
+// content is treated as JavaScript, so pass the original file name.
+func (s *Scanner) ScanReader(ctx context.Context, source string, r io.Reader, fn Handler) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ms, err := s.ex.ScanReaderWithEndpoints(ctx, source, r)
+	if err != nil {
+		return err
+	}
+	s.newEmitter(fn).emit(ms)
+	return nil
+}
+
+// ScanURL fetches rawURL and scans it together with the scripts and imports
+// it references. If ctx ends mid-scan, the findings made so far are still
+// handed to fn and ctx's error is returned.
+func (s *Scanner) ScanURL(ctx context.Context, rawURL string, fn Handler) error {
+	if err := checkURL(ctx, rawURL); err != nil {
+		return err
+	}
+	ms, err := s.ex.ScanURL(ctx, rawURL, s.opts.Endpoints, s.opts.External, s.opts.Render)
+	s.newEmitter(fn).emit(ms)
+	return err
+}
+
+// Crawl scans rawURL and follows the in-scope links it discovers, as
+// configured by Options.Crawl. Findings are handed to fn as the crawl
+// confirms them, while it is still running, and never concurrently.
+// Cancellation behaves as for ScanURL.
+func (s *Scanner) Crawl(ctx context.Context, rawURL string, fn Handler) error {
+	if err := checkURL(ctx, rawURL); err != nil {
+		return err
+	}
+	c := s.opts.Crawl
+	opts := scan.DefaultCrawlOptions()
+	opts.MaxDepth = c.MaxDepth
+	opts.MaxPages = c.MaxPages
+	opts.SameScopeOnly = c.SameScopeOnly
+	opts.Concurrency = c.Concurrency
+	opts.ProbeMethods = c.ProbeMethods
+	opts.ParamReplay = opts.ParamReplay && c.ProbeMethods
+	opts.DiscoverWellKnown = c.DiscoverWellKnown
+	opts.DiscoverPassive = c.DiscoverPassive
+	opts.Permute = c.Permute
+	opts.TemplateDedup = c.TemplateDedup
+
+	em := s.newEmitter(fn)
+	opts.OnMatch = func(m scan.Match) { em.emit([]scan.Match{m}) }
+	ms, err := s.ex.ScanURLCrawl(ctx, rawURL, s.opts.Endpoints, s.opts.External, s.opts.Render, opts)
+	em.emit(ms)
+	return err
+}
+
+// emitter applies the output filters the CLI uses and hands each unique match
+// to fn once, however often it is offered.
+type emitter struct {
+	endpoints     bool
+	minConfidence int
+	fn            Handler
+	seen          map[string]struct{}
+}
+
+func (s *Scanner) newEmitter(fn Handler) *emitter {
+	return &emitter{endpoints: s.opts.Endpoints, minConfidence: s.opts.MinConfidence, fn: fn, seen: make(map[string]struct{})}
+}
+
+func (e *emitter) emit(ms []scan.Match) {
+	if e.endpoints {
+		// Gathered URLs are endpoint discoveries too; keep them alongside the
+		// endpoint_* matches.
+		ms = append(scan.FilterEndpointMatches(ms), scan.FilterGatheredMatches(ms)...)
+	} else {
+		ms = scan.FilterMinConfidence(ms, e.minConfidence)
+	}
+	for _, m := range ms {
+		key := m.Pattern + "|" + m.Value + "|" + m.Params
+		if _, ok := e.seen[key]; ok {
+			continue
+		}
+		e.seen[key] = struct{}{}
+		e.fn(Match{Source: m.Source, Pattern: m.Pattern, Value: m.Value, Params: m.Params, Severity: m.Severity, Confidence: m.Confidence, Snippet: m.Snippet})
+	}
+}
+
+func checkURL(ctx context.Context, rawURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("jsminer: URL must be http or https: " + rawURL)
+	}
+	return nil
+}
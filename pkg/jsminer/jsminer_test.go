@@ -0,0 +1,114 @@
+package jsminer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func collect(ms *[]Match) Handler {
+	return func(m Match) { *ms = append(*ms, m) }
+}
+
+func TestScanReader(t *testing.T) {
+	s, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ms []Match
+	src := `const t = "eyJabc.def.ghi"; fetch("/api/v1/users")`
+	if err := s.ScanReader(context.Background(), "app.js", strings.NewReader(src), collect(&ms)); err != nil {
+		t.Fatal(err)
+	}
+	var jwt, endpoint bool
+	for _, m := range ms {
+		jwt = jwt || m.Pattern == "jwt"
+		endpoint = endpoint || m.Value == "/api/v1/users"
+	}
+	if !jwt || !endpoint {
+		t.Fatalf("expected a jwt and an endpoint, got %+v", ms)
+	}
+
+	opts := DefaultOptions()
+	opts.Endpoints = true
+	s, err = New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms = nil
+	if err := s.ScanReader(context.Background(), "app.js", strings.NewReader(src), collect(&ms)); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ms {
+		if !strings.HasPrefix(m.Pattern, "endpoint") {
+			t.Fatalf("endpoints mode reported %+v", m)
+		}
+	}
+}
+
+func TestScanReaderCanceled(t *testing.T) {
+	s, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = s.ScanReader(ctx, "app.js", strings.NewReader("eyJabc.def.ghi"), func(Match) {
+		t.Fatal("a canceled scan should report nothing")
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// Two scanners with different headers can run side by side in one process
+// without seeing each other's settings.
+func TestScannersKeepTheirOwnHeaders(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Get("X-Tenant")
+		mu.Unlock()
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"a", "b"} {
+		opts := DefaultOptions()
+		opts.Crawl.DiscoverWellKnown = false
+		opts.Headers = http.Header{"X-Tenant": {tenant}}
+		s, err := New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(tenant string) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				if err := s.ScanURL(context.Background(), ts.URL+"/"+tenant, func(Match) {}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(tenant)
+	}
+	wg.Wait()
+	if seen["/a"] != "a" || seen["/b"] != "b" {
+		t.Fatalf("headers leaked between scanners: %v", seen)
+	}
+}
+
+func TestScanURLRejectsNonHTTP(t *testing.T) {
+	s, err := New(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ScanURL(context.Background(), "file:///etc/passwd", func(Match) {}); err == nil {
+		t.Fatal("expected an error for a file URL")
+	}
+}